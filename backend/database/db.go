@@ -69,6 +69,19 @@ func AutoMigrate(db *gorm.DB) {
 		&models.OperationPlanStep{},
 		&models.PEMApproval{},
 		&models.ToolpatherFile{},
+		&models.PlantShift{},
+		&models.CalendarException{},
+		&models.CalendarFeedToken{},
+		&models.Setting{},
+		&models.RoutingTemplate{},
+		&models.RoutingTemplateStep{},
+		&models.RoutingStepAlternative{},
+		&models.MachineDowntime{},
+		&models.MaintenancePlan{},
+		&models.MaintenanceWorkOrder{},
+		&models.AndonReasonCode{},
+		&models.AndonEvent{},
+		&models.ProductionReport{},
 	)
 
 	if err != nil {
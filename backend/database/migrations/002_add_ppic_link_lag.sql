@@ -0,0 +1,5 @@
+-- Migration: Add lag/lead offset to PPIC dependency links
+
+ALTER TABLE ppic_links ADD COLUMN IF NOT EXISTS lag_days INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN ppic_links.lag_days IS 'Offset in days applied to the link constraint (positive = lag, negative = lead)';
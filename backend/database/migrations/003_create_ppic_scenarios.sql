@@ -0,0 +1,31 @@
+-- Migration: What-if scenarios for the PPIC board
+-- A scenario holds its own copy of ppic_schedules (scenario_id set), their machine_assignments and ppic_links.
+-- Rows with scenario_id NULL are the live board.
+
+CREATE TABLE IF NOT EXISTS ppic_scenarios (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    created_by BIGINT REFERENCES users(id),
+    promoted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE ppic_schedules ADD COLUMN IF NOT EXISTS scenario_id BIGINT REFERENCES ppic_scenarios(id) ON DELETE CASCADE;
+ALTER TABLE ppic_schedules ADD COLUMN IF NOT EXISTS source_schedule_id BIGINT REFERENCES ppic_schedules(id) ON DELETE SET NULL;
+ALTER TABLE ppic_links ADD COLUMN IF NOT EXISTS scenario_id BIGINT REFERENCES ppic_scenarios(id) ON DELETE CASCADE;
+
+-- NJO stays unique on the live board and within each scenario
+ALTER TABLE ppic_schedules DROP CONSTRAINT IF EXISTS ppic_schedules_njo_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ppic_schedules_njo_live ON ppic_schedules(njo) WHERE scenario_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ppic_schedules_njo_scenario ON ppic_schedules(scenario_id, njo) WHERE scenario_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_ppic_schedules_scenario_id ON ppic_schedules(scenario_id);
+CREATE INDEX IF NOT EXISTS idx_ppic_links_scenario_id ON ppic_links(scenario_id);
+
+COMMENT ON TABLE ppic_scenarios IS 'Named what-if copies of the PPIC board';
+COMMENT ON COLUMN ppic_schedules.scenario_id IS 'Scenario this copy belongs to (NULL = live board)';
+COMMENT ON COLUMN ppic_schedules.source_schedule_id IS 'Live schedule a scenario copy was made from';
+COMMENT ON COLUMN ppic_links.scenario_id IS 'Scenario this link belongs to (NULL = live board)';
@@ -0,0 +1,40 @@
+-- Migration: Schedule baselines
+-- A baseline freezes the live ppic_schedules and machine_assignments dates so variance can be reported later.
+
+CREATE TABLE IF NOT EXISTS ppic_baselines (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_by BIGINT REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ppic_baseline_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    baseline_id BIGINT NOT NULL REFERENCES ppic_baselines(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL REFERENCES ppic_schedules(id) ON DELETE CASCADE,
+    njo VARCHAR(100) NOT NULL,
+    part_name VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    finish_date DATE NOT NULL,
+    UNIQUE (baseline_id, schedule_id)
+);
+
+-- Assignments are re-created when a schedule is edited, so they are matched by schedule and sequence
+CREATE TABLE IF NOT EXISTS ppic_baseline_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    baseline_id BIGINT NOT NULL REFERENCES ppic_baselines(id) ON DELETE CASCADE,
+    schedule_id BIGINT NOT NULL REFERENCES ppic_schedules(id) ON DELETE CASCADE,
+    machine_id BIGINT NOT NULL,
+    sequence INT NOT NULL,
+    target_hours DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    scheduled_start TIMESTAMP WITH TIME ZONE,
+    scheduled_end TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ppic_baseline_schedules_baseline_id ON ppic_baseline_schedules(baseline_id);
+CREATE INDEX IF NOT EXISTS idx_ppic_baseline_assignments_baseline_id ON ppic_baseline_assignments(baseline_id);
+
+COMMENT ON TABLE ppic_baselines IS 'Named snapshots of the live PPIC plan';
+COMMENT ON TABLE ppic_baseline_schedules IS 'Schedule dates captured in a baseline';
+COMMENT ON TABLE ppic_baseline_assignments IS 'Machine assignment windows captured in a baseline';
@@ -0,0 +1,24 @@
+-- Migration: Change history for PPIC schedules and machine assignments
+-- One row per changed field, with who changed it and why (manual edit, cascade, link, auto-schedule, ...)
+
+CREATE TABLE IF NOT EXISTS ppic_schedule_history (
+    id BIGSERIAL PRIMARY KEY,
+    schedule_id BIGINT NOT NULL REFERENCES ppic_schedules(id) ON DELETE CASCADE,
+    entity VARCHAR(30) NOT NULL,
+    assignment_id BIGINT,
+    sequence INT,
+    field VARCHAR(50) NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    cause VARCHAR(255) NOT NULL,
+    changed_by BIGINT REFERENCES users(id),
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ppic_schedule_history_schedule_id ON ppic_schedule_history(schedule_id, changed_at);
+
+COMMENT ON TABLE ppic_schedule_history IS 'Per-field change history of PPIC schedules and their machine assignments';
+COMMENT ON COLUMN ppic_schedule_history.entity IS 'schedule or machine_assignment';
+COMMENT ON COLUMN ppic_schedule_history.sequence IS 'Routing step of the machine assignment (assignment IDs change when a schedule is edited)';
+COMMENT ON COLUMN ppic_schedule_history.cause IS 'manual, cascade from NJO X, link created, auto-schedule, scenario promoted: X';
+COMMENT ON COLUMN ppic_schedule_history.changed_by IS 'User who made the change (NULL = system)';
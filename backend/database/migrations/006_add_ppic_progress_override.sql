@@ -0,0 +1,9 @@
+-- Migration: Manual override for derived schedule progress
+-- Progress and status follow the machine assignments unless a planner overrides them by hand
+
+ALTER TABLE ppic_schedules ADD COLUMN IF NOT EXISTS progress_override BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN ppic_schedules.progress_override IS 'When true, progress and status are set by hand instead of derived from machine assignments';
+
+-- Holds are planner decisions, keep them on existing schedules
+UPDATE ppic_schedules SET progress_override = TRUE WHERE status = 'on_hold';
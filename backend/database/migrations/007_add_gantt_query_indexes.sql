@@ -0,0 +1,6 @@
+-- Migration: Indexes for loading the Gantt board
+-- Links are looked up by the schedules they touch; assignments are batch loaded in sequence order
+
+CREATE INDEX IF NOT EXISTS idx_ppic_links_source_schedule_id ON ppic_links(source_schedule_id);
+CREATE INDEX IF NOT EXISTS idx_ppic_links_target_schedule_id ON ppic_links(target_schedule_id);
+CREATE INDEX IF NOT EXISTS idx_machine_assignments_schedule_sequence ON machine_assignments(schedule_id, sequence);
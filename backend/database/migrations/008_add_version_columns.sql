@@ -0,0 +1,9 @@
+-- Migration: Versions for optimistic concurrency
+-- Every write bumps the version; updates must send the version they last read and fail when it is stale.
+-- Job orders and PEM plans get their version column from AutoMigrate
+
+ALTER TABLE ppic_schedules ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE machine_assignments ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN ppic_schedules.version IS 'Bumped on every change to the schedule or its machine assignments';
+COMMENT ON COLUMN machine_assignments.version IS 'Bumped on every change to the assignment';
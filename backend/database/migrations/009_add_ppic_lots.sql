@@ -0,0 +1,23 @@
+-- Migration: Split PPIC schedules into lots
+-- A split schedule becomes the summary of its lots: it keeps the NJO and the links, spans the lots'
+-- dates, and the lots carry the quantities and machine assignments
+
+ALTER TABLE ppic_schedules ADD COLUMN IF NOT EXISTS quantity INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE ppic_schedules ADD COLUMN IF NOT EXISTS parent_schedule_id BIGINT REFERENCES ppic_schedules(id);
+ALTER TABLE ppic_schedules ADD COLUMN IF NOT EXISTS lot_number INTEGER;
+
+COMMENT ON COLUMN ppic_schedules.quantity IS 'Quantity ordered (or of this lot); 0 = not recorded';
+COMMENT ON COLUMN ppic_schedules.parent_schedule_id IS 'Schedule this lot was split from; NULL for regular schedules';
+COMMENT ON COLUMN ppic_schedules.lot_number IS 'Lot number within the parent, from 1';
+
+CREATE INDEX IF NOT EXISTS idx_ppic_schedules_parent_schedule_id ON ppic_schedules(parent_schedule_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ppic_schedules_lot_number ON ppic_schedules(parent_schedule_id, lot_number)
+    WHERE parent_schedule_id IS NOT NULL AND deleted_at IS NULL;
+
+-- Lots share the NJO of their parent, so NJO is unique among the other schedules only
+DROP INDEX IF EXISTS idx_ppic_schedules_njo_live;
+DROP INDEX IF EXISTS idx_ppic_schedules_njo_scenario;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ppic_schedules_njo_live ON ppic_schedules(njo)
+    WHERE scenario_id IS NULL AND parent_schedule_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ppic_schedules_njo_scenario ON ppic_schedules(scenario_id, njo)
+    WHERE scenario_id IS NOT NULL AND parent_schedule_id IS NULL;
@@ -0,0 +1,15 @@
+-- Migration: One link per source/target pair
+-- Duplicate pairs (keeping the oldest link) are removed before the unique indexes are created.
+-- scenario_id is NULL on the live board, so the live board and the scenarios get one index each
+
+DELETE FROM ppic_links l
+USING ppic_links d
+WHERE l.source_schedule_id = d.source_schedule_id
+  AND l.target_schedule_id = d.target_schedule_id
+  AND l.scenario_id IS NOT DISTINCT FROM d.scenario_id
+  AND l.id > d.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ppic_links_pair_live ON ppic_links(source_schedule_id, target_schedule_id)
+    WHERE scenario_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ppic_links_pair_scenario ON ppic_links(scenario_id, source_schedule_id, target_schedule_id)
+    WHERE scenario_id IS NOT NULL;
@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AndonHandler struct {
+	service *services.AndonService
+}
+
+func NewAndonHandler(service *services.AndonService) *AndonHandler {
+	return &AndonHandler{service: service}
+}
+
+// ========== Reason Codes ==========
+
+// GetReasonCodes returns the andon reason code hierarchy
+// @Summary Get andon reason codes
+// @Description Reason codes nested under their categories. Only active codes unless include_inactive=true
+// @Tags Andon
+// @Produce json
+// @Param include_inactive query bool false "Include inactive codes"
+// @Success 200 {array} models.AndonReasonNode
+// @Router /api/v1/andon/reason-codes [get]
+func (h *AndonHandler) GetReasonCodes(c *gin.Context) {
+	codes, err := h.service.GetReasonCodes(c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": codes, "count": len(codes)})
+}
+
+// CreateReasonCode adds an andon reason code
+// @Summary Create andon reason code
+// @Description Add a top-level category, or a sub-code under parent_id
+// @Tags Andon
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAndonReasonCodeRequest true "Reason code"
+// @Success 201 {object} models.AndonReasonCode
+// @Router /api/v1/admin/andon/reason-codes [post]
+func (h *AndonHandler) CreateReasonCode(c *gin.Context) {
+	var req models.CreateAndonReasonCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	code, err := h.service.CreateReasonCode(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Reason code created successfully", "data": code})
+}
+
+// UpdateReasonCode changes an andon reason code
+// @Summary Update andon reason code
+// @Description Change a reason code; omitted fields are kept. parent_id 0 moves it to the top level, active=false retires it
+// @Tags Andon
+// @Accept json
+// @Produce json
+// @Param id path int true "Reason code ID"
+// @Param request body models.UpdateAndonReasonCodeRequest true "Reason code changes"
+// @Success 200 {object} models.AndonReasonCode
+// @Router /api/v1/admin/andon/reason-codes/{id} [put]
+func (h *AndonHandler) UpdateReasonCode(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid reason code ID"})
+		return
+	}
+
+	var req models.UpdateAndonReasonCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	code, err := h.service.UpdateReasonCode(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Reason code updated successfully", "data": code})
+}
+
+// DeleteReasonCode deletes an unused andon reason code
+// @Summary Delete andon reason code
+// @Description Only codes without sub-codes or recorded events can be deleted; deactivate the others
+// @Tags Andon
+// @Param id path int true "Reason code ID"
+// @Success 200
+// @Router /api/v1/admin/andon/reason-codes/{id} [delete]
+func (h *AndonHandler) DeleteReasonCode(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid reason code ID"})
+		return
+	}
+
+	if err := h.service.DeleteReasonCode(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Reason code deleted successfully"})
+}
+
+// ========== Events ==========
+
+// GetBoard returns the open andon events
+// @Summary Get andon board
+// @Description Machines that are down right now, longest-running stop first, with the running duration
+// @Tags Andon
+// @Produce json
+// @Success 200 {array} models.AndonEventView
+// @Router /api/v1/andon/board [get]
+func (h *AndonHandler) GetBoard(c *gin.Context) {
+	events, err := h.service.GetBoard()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": events, "count": len(events)})
+}
+
+// GetAllEvents returns andon events
+// @Summary Get andon events
+// @Description List andon events, newest first, overlapping start_date .. end_date
+// @Tags Andon
+// @Produce json
+// @Param machine_id query int false "Machine ID"
+// @Param reason_code_id query int false "Reason code ID"
+// @Param job_order_id query int false "Job order ID"
+// @Param status query string false "open or closed"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.AndonEventView
+// @Router /api/v1/andon/events [get]
+func (h *AndonHandler) GetAllEvents(c *gin.Context) {
+	var filter models.AndonEventFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	events, err := h.service.GetEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": events, "count": len(events)})
+}
+
+// GetEvent returns a single andon event
+// @Summary Get andon event
+// @Tags Andon
+// @Produce json
+// @Param id path int true "Event ID"
+// @Success 200 {object} models.AndonEventView
+// @Router /api/v1/andon/events/{id} [get]
+func (h *AndonHandler) GetEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid event ID"})
+		return
+	}
+
+	event, err := h.service.GetEvent(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": event})
+}
+
+// CreateEvent raises an andon
+// @Summary Create andon event
+// @Description Report a machine stop. The reporting operator is the logged-in user. While the event is open the machine's status is "down"; with end_time the stop is logged as already over
+// @Tags Andon
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAndonEventRequest true "Event details"
+// @Success 201 {object} models.AndonEventView
+// @Router /api/v1/andon/events [post]
+func (h *AndonHandler) CreateEvent(c *gin.Context) {
+	var req models.CreateAndonEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	event, err := h.service.CreateEvent(&req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Andon event created successfully", "data": event})
+}
+
+// UpdateEvent classifies or corrects an andon event
+// @Summary Update andon event
+// @Description Set the reason code, linked job order, start time or notes; omitted fields are kept. job_order_id 0 unlinks the job order
+// @Tags Andon
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param request body models.UpdateAndonEventRequest true "Event changes"
+// @Success 200 {object} models.AndonEventView
+// @Router /api/v1/andon/events/{id} [put]
+func (h *AndonHandler) UpdateEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid event ID"})
+		return
+	}
+
+	var req models.UpdateAndonEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	event, err := h.service.UpdateEvent(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Andon event updated successfully", "data": event})
+}
+
+// CloseEvent ends an open andon event
+// @Summary Close andon event
+// @Description End the stop and put the machine back to its status before the stop. A reason code is required to close
+// @Tags Andon
+// @Accept json
+// @Produce json
+// @Param id path int true "Event ID"
+// @Param request body models.CloseAndonEventRequest false "End time, reason and notes"
+// @Success 200 {object} models.AndonEventView
+// @Router /api/v1/andon/events/{id}/close [post]
+func (h *AndonHandler) CloseEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid event ID"})
+		return
+	}
+
+	var req models.CloseAndonEventRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	event, err := h.service.CloseEvent(id, &req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Andon event closed", "data": event})
+}
+
+// DeleteEvent deletes an andon event raised by mistake
+// @Summary Delete andon event
+// @Description Deleting an open event puts the machine back to its status before the stop
+// @Tags Andon
+// @Param id path int true "Event ID"
+// @Success 200
+// @Router /api/v1/andon/events/{id} [delete]
+func (h *AndonHandler) DeleteEvent(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid event ID"})
+		return
+	}
+
+	if err := h.service.DeleteEvent(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Andon event deleted successfully"})
+}
+
+// GetPareto returns the downtime Pareto report
+// @Summary Get downtime Pareto
+// @Description Andon downtime per reason code (or top-level category) between start_date and end_date, largest first, with percent and cumulative percent. Open events count up to now
+// @Tags Andon
+// @Produce json
+// @Param machine_id query int false "Machine ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD), default 30 days before end_date"
+// @Param end_date query string false "End date (YYYY-MM-DD), default today"
+// @Param group_by query string false "reason (default) or category"
+// @Success 200 {object} models.AndonParetoReport
+// @Router /api/v1/andon/pareto [get]
+func (h *AndonHandler) GetPareto(c *gin.Context) {
+	var req models.AndonParetoRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	report, err := h.service.GetPareto(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": report})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"ganttpro-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarFeedHandler struct {
+	service *services.CalendarFeedService
+}
+
+func NewCalendarFeedHandler(service *services.CalendarFeedService) *CalendarFeedHandler {
+	return &CalendarFeedHandler{service: service}
+}
+
+// GetFeedStatus tells whether the current user has an active feed token
+// @Summary Get calendar feed token status
+// @Tags Calendar Feeds
+// @Produce json
+// @Success 200 {object} models.CalendarFeedStatus
+// @Router /api/v1/calendar-feeds [get]
+func (h *CalendarFeedHandler) GetFeedStatus(c *gin.Context) {
+	status, err := h.service.GetFeedStatus(getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": status})
+}
+
+// IssueFeedToken creates a new feed token for the current user, revoking the previous one
+// @Summary Issue calendar feed token
+// @Description The token is only shown in this response. Subscribe to the returned feed paths in a calendar app
+// @Tags Calendar Feeds
+// @Produce json
+// @Success 201 {object} models.CalendarFeedTokenResponse
+// @Router /api/v1/calendar-feeds/token [post]
+func (h *CalendarFeedHandler) IssueFeedToken(c *gin.Context) {
+	token, err := h.service.IssueFeedToken(getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": token})
+}
+
+// RevokeFeedToken disables the current user's calendar feeds
+// @Summary Revoke calendar feed token
+// @Tags Calendar Feeds
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/calendar-feeds/token [delete]
+func (h *CalendarFeedHandler) RevokeFeedToken(c *gin.Context) {
+	if err := h.service.RevokeFeedToken(getUserIDFromContext(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Calendar feed token revoked"})
+}
+
+// GetMachineFeed returns the scheduled windows of a machine as an iCalendar feed
+// @Summary Machine iCalendar feed
+// @Description Authenticated with a calendar feed token instead of the JWT header
+// @Tags Calendar Feeds
+// @Produce text/calendar
+// @Param machine_id path int true "Machine ID"
+// @Param token query string true "Calendar feed token"
+// @Success 200 {string} string "iCalendar feed"
+// @Router /api/v1/feeds/machines/{machine_id} [get]
+func (h *CalendarFeedHandler) GetMachineFeed(c *gin.Context) {
+	if _, err := h.service.Authenticate(c.Query("token")); err != nil {
+		feedError(c, err)
+		return
+	}
+
+	machineID, err := strconv.ParseInt(c.Param("machine_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid machine ID"})
+		return
+	}
+
+	feed, err := h.service.MachineFeed(machineID)
+	if err != nil {
+		feedError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="machine-`+c.Param("machine_id")+`.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed)
+}
+
+// GetUserFeed returns the token owner's pending PEM approvals and job orders as an iCalendar feed
+// @Summary User iCalendar feed
+// @Description Authenticated with a calendar feed token instead of the JWT header
+// @Tags Calendar Feeds
+// @Produce text/calendar
+// @Param token query string true "Calendar feed token"
+// @Success 200 {string} string "iCalendar feed"
+// @Router /api/v1/feeds/me [get]
+func (h *CalendarFeedHandler) GetUserFeed(c *gin.Context) {
+	user, err := h.service.Authenticate(c.Query("token"))
+	if err != nil {
+		feedError(c, err)
+		return
+	}
+
+	feed, err := h.service.UserFeed(user)
+	if err != nil {
+		feedError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="my-schedule.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed)
+}
+
+func feedError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrInvalidFeedToken):
+		status = http.StatusUnauthorized
+	case errors.Is(err, services.ErrFeedMachineNotFound):
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"success": false, "error": err.Error()})
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CalendarHandler struct {
+	service *services.CalendarService
+}
+
+func NewCalendarHandler(service *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// GetCalendar returns working days and hours for a date range
+// @Summary Get working calendar
+// @Description List each day in the range with its working hours, for the plant or a single machine
+// @Tags Calendar
+// @Produce json
+// @Param start_date query string true "Range start (YYYY-MM-DD)"
+// @Param end_date query string true "Range end (YYYY-MM-DD)"
+// @Param machine_id query int false "Machine ID (defaults to the plant calendar)"
+// @Success 200 {array} models.CalendarDay
+// @Router /api/v1/calendar [get]
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	var filter models.CalendarFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "start_date and end_date are required"})
+		return
+	}
+
+	days, err := h.service.GetCalendarDays(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": days})
+}
+
+// GetShifts returns all shift patterns
+// @Summary Get shift patterns
+// @Description List plant-wide and per-machine weekly shifts
+// @Tags Calendar
+// @Produce json
+// @Success 200 {array} models.PlantShift
+// @Router /api/v1/admin/calendar/shifts [get]
+func (h *CalendarHandler) GetShifts(c *gin.Context) {
+	shifts, err := h.service.GetShifts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": shifts, "count": len(shifts)})
+}
+
+// CreateShift creates a shift pattern entry
+// @Summary Create shift
+// @Description Add a weekly shift for the plant (no machine_id) or a single machine
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Param request body models.CreatePlantShiftRequest true "Shift details"
+// @Success 201 {object} models.PlantShift
+// @Router /api/v1/admin/calendar/shifts [post]
+func (h *CalendarHandler) CreateShift(c *gin.Context) {
+	var req models.CreatePlantShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	shift, err := h.service.CreateShift(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Shift created successfully", "data": shift})
+}
+
+// UpdateShift updates a shift pattern entry
+// @Summary Update shift
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Param id path int true "Shift ID"
+// @Param request body models.UpdatePlantShiftRequest true "Shift changes"
+// @Success 200 {object} models.PlantShift
+// @Router /api/v1/admin/calendar/shifts/{id} [put]
+func (h *CalendarHandler) UpdateShift(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid shift ID"})
+		return
+	}
+
+	var req models.UpdatePlantShiftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	shift, err := h.service.UpdateShift(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Shift updated successfully", "data": shift})
+}
+
+// DeleteShift deletes a shift pattern entry
+// @Summary Delete shift
+// @Tags Calendar
+// @Param id path int true "Shift ID"
+// @Success 200
+// @Router /api/v1/admin/calendar/shifts/{id} [delete]
+func (h *CalendarHandler) DeleteShift(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid shift ID"})
+		return
+	}
+
+	if err := h.service.DeleteShift(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Shift deleted successfully"})
+}
+
+// GetExceptions returns holidays and special working days
+// @Summary Get calendar exceptions
+// @Tags Calendar
+// @Produce json
+// @Param start_date query string false "Range start (YYYY-MM-DD)"
+// @Param end_date query string false "Range end (YYYY-MM-DD)"
+// @Success 200 {array} models.CalendarException
+// @Router /api/v1/admin/calendar/exceptions [get]
+func (h *CalendarHandler) GetExceptions(c *gin.Context) {
+	exceptions, err := h.service.GetExceptions(c.Query("start_date"), c.Query("end_date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": exceptions, "count": len(exceptions)})
+}
+
+// CreateException creates a holiday or special working day
+// @Summary Create calendar exception
+// @Description Add a plant holiday or a special working day, plant-wide or for a single machine
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Param request body models.CreateCalendarExceptionRequest true "Exception details"
+// @Success 201 {object} models.CalendarException
+// @Router /api/v1/admin/calendar/exceptions [post]
+func (h *CalendarHandler) CreateException(c *gin.Context) {
+	var req models.CreateCalendarExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	exception, err := h.service.CreateException(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Calendar exception created successfully", "data": exception})
+}
+
+// DeleteException deletes a holiday or special working day
+// @Summary Delete calendar exception
+// @Tags Calendar
+// @Param id path int true "Exception ID"
+// @Success 200
+// @Router /api/v1/admin/calendar/exceptions/{id} [delete]
+func (h *CalendarHandler) DeleteException(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid exception ID"})
+		return
+	}
+
+	if err := h.service.DeleteException(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Calendar exception deleted successfully"})
+}
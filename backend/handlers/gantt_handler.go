@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"ganttpro-backend/models"
 	"ganttpro-backend/services"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,11 +13,38 @@ import (
 )
 
 type GanttHandler struct {
-	service *services.GanttService
+	service         *services.GanttService
+	scenarioService *services.PPICScenarioService
 }
 
-func NewGanttHandler(service *services.GanttService) *GanttHandler {
-	return &GanttHandler{service: service}
+func NewGanttHandler(service *services.GanttService, scenarioService *services.PPICScenarioService) *GanttHandler {
+	return &GanttHandler{service: service, scenarioService: scenarioService}
+}
+
+// serviceFor returns the live service, or the scenario's when ?scenario_id is given.
+// Writes an error response and returns false if the scenario can't be used
+func (h *GanttHandler) serviceFor(c *gin.Context, forWrite bool) (*services.GanttService, bool) {
+	scenarioParam := c.Query("scenario_id")
+	if scenarioParam == "" {
+		return h.service, true
+	}
+
+	scenarioID, err := strconv.ParseInt(scenarioParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid scenario ID"})
+		return nil, false
+	}
+
+	service, err := h.scenarioService.GanttService(scenarioID, forWrite)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrScenarioNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return nil, false
+	}
+	return service, true
 }
 
 // GetGanttChart returns Gantt chart data with filters
@@ -29,33 +58,104 @@ func NewGanttHandler(service *services.GanttService) *GanttHandler {
 // @Param status query string false "Filter by status (pending, in_progress, completed)"
 // @Param machine_id query int false "Filter by machine ID"
 // @Param group_by query string false "Group by: priority, machine, or empty for all"
+// @Param critical_path query bool false "Mark critical tasks and total float on each task"
+// @Param baseline_id query int false "Add baseline start/end and slip days to each task"
+// @Param format query string false "json (default), csv, xlsx or pdf (file download)"
+// @Param window_start query string false "Date-window pagination: only tasks overlapping the window starting here (YYYY-MM-DD)"
+// @Param window_days query int false "Window length in days (default 28, max 366)"
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/pdf
 // @Success 200 {object} models.GanttChartResponse
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/gantt-chart [get]
 func (h *GanttHandler) GetGanttChart(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
 	var filter models.GanttFilterRequest
 	if err := c.ShouldBindQuery(&filter); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid filter parameters"})
 		return
 	}
 
-	response, err := h.service.GetGanttChartData(filter)
+	if filter.Format != "" && filter.Format != models.GanttFormatJSON {
+		if !models.IsGanttExportFormat(filter.Format) {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid format. Must be: json, csv, xlsx, or pdf"})
+			return
+		}
+		export, err := service.ExportGanttChart(filter)
+		if err != nil {
+			c.JSON(baselineErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+export.Filename+`"`)
+		c.Data(http.StatusOK, export.ContentType, export.Data)
+		return
+	}
+
+	response, err := service.GetGanttChartData(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		c.JSON(baselineErrorStatus(err), gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": response})
 }
 
+// GetCriticalPath returns the critical path analysis for the filtered schedules
+// @Summary Get critical path
+// @Description Compute early/late start, total float and critical flag per task from schedule dates and PPIC links
+// @Tags Gantt
+// @Produce json
+// @Param start_date query string false "Filter by start date (YYYY-MM-DD)"
+// @Param end_date query string false "Filter by end date (YYYY-MM-DD)"
+// @Param priority query string false "Filter by priority (Low, Medium, Urgent, Top Urgent)"
+// @Param status query string false "Filter by status (pending, in_progress, completed)"
+// @Param machine_id query int false "Filter by machine ID"
+// @Param window_start query string false "Only tasks overlapping the window starting here (YYYY-MM-DD)"
+// @Param window_days query int false "Window length in days (default 28, max 366)"
+// @Success 200 {object} models.CriticalPathResponse
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Router /api/v1/gantt-chart/critical-path [get]
+func (h *GanttHandler) GetCriticalPath(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	var filter models.GanttFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid filter parameters"})
+		return
+	}
+
+	result, err := service.GetCriticalPath(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
 // GetAllPPICSchedules returns all PPIC schedules
 // @Summary Get all PPIC schedules
 // @Description Get all PPIC schedule entries
 // @Tags PPIC Schedules
 // @Produce json
 // @Success 200 {array} models.PPICSchedule
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules [get]
 func (h *GanttHandler) GetAllPPICSchedules(c *gin.Context) {
-	schedules, err := h.service.GetAllPPICSchedules()
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	schedules, err := service.GetAllPPICSchedules()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
@@ -71,20 +171,27 @@ func (h *GanttHandler) GetAllPPICSchedules(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Schedule ID"
 // @Success 200 {object} models.PPICSchedule
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules/{id} [get]
 func (h *GanttHandler) GetPPICSchedule(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid ID"})
 		return
 	}
 
-	schedule, err := h.service.GetPPICSchedule(id)
+	schedule, err := service.GetPPICSchedule(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
+	c.Header("ETag", models.VersionETag(schedule.Version))
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedule})
 }
 
@@ -96,8 +203,14 @@ func (h *GanttHandler) GetPPICSchedule(c *gin.Context) {
 // @Produce json
 // @Param request body models.CreatePPICScheduleRequest true "Schedule details"
 // @Success 201 {object} models.PPICSchedule
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules [post]
 func (h *GanttHandler) CreatePPICSchedule(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
 	var req models.CreatePPICScheduleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
@@ -107,7 +220,7 @@ func (h *GanttHandler) CreatePPICSchedule(c *gin.Context) {
 	// Get user ID from context
 	userID := getUserIDFromContext(c)
 
-	schedule, err := h.service.CreatePPICSchedule(&req, userID)
+	schedule, err := service.CreatePPICSchedule(&req, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
@@ -116,17 +229,98 @@ func (h *GanttHandler) CreatePPICSchedule(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Schedule created successfully", "data": schedule})
 }
 
+// maxImportFileSize limits uploaded schedule import files
+const maxImportFileSize = 10 << 20
+
+// ImportPPICSchedules bulk-creates PPIC schedules from a CSV or XLSX file
+// @Summary Import PPIC schedules
+// @Description Columns: NJO, Part Name, Priority, Priority Alpha, Material Status, Start Date, Finish Date, PPIC Notes and numbered pairs Machine N / Hours N (machine code and target hours of sequence N).
+// @Description All rows are written in one transaction, or none if any row has an error (422 with the per-row report)
+// @Tags PPIC Schedules
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run query bool false "Validate only, return the per-row report without writing"
+// @Param upsert query bool false "Update schedules whose NJO already exists instead of rejecting the row"
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Success 200 {object} models.PPICImportResult
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} models.PPICImportResult
+// @Router /api/v1/ppic-schedules/import [post]
+func (h *GanttHandler) ImportPPICSchedules(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
+	var opts models.PPICImportOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid query parameters", "details": err.Error()})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "File is required"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "File is too large (max 10 MB)"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	result, err := service.ImportPPICSchedules(fileHeader.Filename, data, opts, userID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, models.ErrStaleVersion) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	if result.ErrorCount > 0 && !opts.DryRun {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"success": false, "error": "Import rejected: fix the rows with errors and upload again", "data": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
 // UpdatePPICSchedule updates an existing PPIC schedule
 // @Summary Update PPIC schedule
-// @Description Update an existing PPIC schedule entry
+// @Description Update an existing PPIC schedule entry. Date changes cascade to dependent schedules in the same transaction; "changes" lists every schedule that moved.
+// @Description The version last read is required (If-Match header or "version" field): 428 without it, 409 with the current schedule when it is stale
 // @Tags PPIC Schedules
 // @Accept json
 // @Produce json
 // @Param id path int true "Schedule ID"
+// @Param If-Match header string false "Version last read, e.g. \"3\""
 // @Param request body models.UpdatePPICScheduleRequest true "Update details"
+// @Param dry_run query bool false "Only preview the schedules the update and its cascade would move"
 // @Success 200 {object} models.PPICSchedule
+// @Failure 409 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules/{id} [put]
 func (h *GanttHandler) UpdatePPICSchedule(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid ID"})
@@ -138,14 +332,30 @@ func (h *GanttHandler) UpdatePPICSchedule(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
 		return
 	}
+	if req.Version, err = requestVersion(c, req.Version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
 
-	schedule, err := h.service.UpdatePPICSchedule(id, &req)
+	if c.Query("dry_run") == "true" {
+		impact, err := service.PreviewUpdatePPICSchedule(id, &req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": impact})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	schedule, changes, err := service.UpdatePPICSchedule(id, &req, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		respondScheduleWriteError(c, service, id, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule updated successfully", "data": schedule})
+	c.Header("ETag", models.VersionETag(schedule.Version))
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule updated successfully", "data": schedule, "changes": changes})
 }
 
 // DeletePPICSchedule deletes a PPIC schedule
@@ -154,15 +364,21 @@ func (h *GanttHandler) UpdatePPICSchedule(c *gin.Context) {
 // @Tags PPIC Schedules
 // @Param id path int true "Schedule ID"
 // @Success 200 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules/{id} [delete]
 func (h *GanttHandler) DeletePPICSchedule(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid ID"})
 		return
 	}
 
-	if err := h.service.DeletePPICSchedule(id); err != nil {
+	if err := service.DeletePPICSchedule(id, getUserIDFromContext(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -170,6 +386,103 @@ func (h *GanttHandler) DeletePPICSchedule(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule deleted successfully"})
 }
 
+// SplitPPICSchedule splits a schedule into lots
+// @Summary Split PPIC schedule into lots
+// @Description Split a schedule into lots for partial batches, each with its own quantity, dates and machines. The schedule keeps its NJO and links and spans its lots; "changes" lists the schedules the new span moved.
+// @Description Lot quantities must add up to the schedule quantity when it has one. Lots without machine_assignments get the schedule's routing with target hours prorated by quantity.
+// @Description The version last read is required (If-Match header or "version" field): 428 without it, 409 with the current schedule when it is stale
+// @Tags PPIC Schedules
+// @Accept json
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param If-Match header string false "Version last read, e.g. \"3\""
+// @Param request body models.SplitPPICScheduleRequest true "Lots"
+// @Success 200 {object} models.PPICSchedule
+// @Failure 409 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Router /api/v1/ppic-schedules/{id}/split [post]
+func (h *GanttHandler) SplitPPICSchedule(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid ID"})
+		return
+	}
+
+	var req models.SplitPPICScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
+		return
+	}
+	if req.Version, err = requestVersion(c, req.Version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	schedule, changes, err := service.SplitPPICSchedule(id, &req, getUserIDFromContext(c))
+	if err != nil {
+		respondScheduleWriteError(c, service, id, err)
+		return
+	}
+
+	c.Header("ETag", models.VersionETag(schedule.Version))
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Schedule split into lots successfully", "data": schedule, "changes": changes})
+}
+
+// MergePPICLots merges lots of a split schedule
+// @Summary Merge lots of a split PPIC schedule
+// @Description Merge lots back together. Without lot_ids (or with all lots) the schedule is no longer split; otherwise the selected lots are merged into the lowest numbered one.
+// @Description Merged lots get one routing with the target hours of all of them and no scheduled windows. Lots with work recorded cannot be merged.
+// @Description The split schedule's version last read is required (If-Match header or "version" field): 428 without it, 409 with the current schedule when it is stale
+// @Tags PPIC Schedules
+// @Accept json
+// @Produce json
+// @Param id path int true "Split schedule ID"
+// @Param If-Match header string false "Version last read, e.g. \"3\""
+// @Param request body models.MergePPICLotsRequest false "Lots to merge"
+// @Success 200 {object} models.PPICSchedule
+// @Failure 409 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Router /api/v1/ppic-schedules/{id}/merge [post]
+func (h *GanttHandler) MergePPICLots(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid ID"})
+		return
+	}
+
+	// The body is optional: without it every lot is merged back
+	var req models.MergePPICLotsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
+			return
+		}
+	}
+	if req.Version, err = requestVersion(c, req.Version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	schedule, err := service.MergePPICLots(id, &req, getUserIDFromContext(c))
+	if err != nil {
+		respondScheduleWriteError(c, service, id, err)
+		return
+	}
+
+	c.Header("ETag", models.VersionETag(schedule.Version))
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Lots merged successfully", "data": schedule})
+}
+
 // GetSchedulesByMachine returns schedules for a specific machine
 // @Summary Get schedules by machine
 // @Description Get all PPIC schedules assigned to a specific machine
@@ -177,15 +490,21 @@ func (h *GanttHandler) DeletePPICSchedule(c *gin.Context) {
 // @Produce json
 // @Param machine_id path int true "Machine ID"
 // @Success 200 {array} models.PPICSchedule
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules/machine/{machine_id} [get]
 func (h *GanttHandler) GetSchedulesByMachine(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
 	machineID, err := strconv.ParseInt(c.Param("machine_id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid machine ID"})
 		return
 	}
 
-	schedules, err := h.service.GetSchedulesByMachine(machineID)
+	schedules, err := service.GetSchedulesByMachine(machineID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
@@ -194,6 +513,145 @@ func (h *GanttHandler) GetSchedulesByMachine(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": schedules, "count": len(schedules)})
 }
 
+// GetMachineConflicts returns overlapping machine bookings
+// @Summary Get machine double-booking conflicts
+// @Description List every pair of machine assignments with overlapping scheduled windows, grouped per machine
+// @Tags PPIC Schedules
+// @Produce json
+// @Param start_date query string false "Range start (YYYY-MM-DD)"
+// @Param end_date query string false "Range end (YYYY-MM-DD)"
+// @Param machine_id query int false "Filter by machine ID"
+// @Success 200 {object} models.MachineConflictResponse
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Router /api/v1/ppic-schedules/conflicts [get]
+func (h *GanttHandler) GetMachineConflicts(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	var filter models.MachineConflictFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid filter parameters"})
+		return
+	}
+
+	result, err := service.GetMachineConflicts(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": result})
+}
+
+// GetMachineUtilization reports how loaded each machine is
+// @Summary Get machine utilization and load
+// @Description Planned, actual and available hours per machine and day or week, with load/utilization percentages and an overload flag when planned exceeds available
+// @Tags Machines
+// @Produce json
+// @Param start_date query string true "Range start (YYYY-MM-DD)"
+// @Param end_date query string true "Range end, inclusive (YYYY-MM-DD)"
+// @Param period query string false "day (default) or week"
+// @Param machine_type query string false "Filter by machine type"
+// @Param location query string false "Filter by location"
+// @Param machine_id query int false "Filter by machine ID"
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Success 200 {object} models.MachineUtilizationReport
+// @Router /api/v1/machines/utilization [get]
+func (h *GanttHandler) GetMachineUtilization(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	var filter models.MachineUtilizationFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid filter parameters"})
+		return
+	}
+
+	report, err := service.GetMachineUtilization(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": report})
+}
+
+// PreviewAutoSchedule proposes machine windows for pending schedules without saving them
+// @Summary Preview auto-schedule
+// @Description Forward-schedule pending PPIC schedules onto their machines (finite capacity, routing sequence, links, priority) and return the proposed plan
+// @Tags PPIC Schedules
+// @Accept json
+// @Produce json
+// @Param request body models.AutoScheduleRequest false "Schedules to plan and start time"
+// @Success 200 {object} models.AutoSchedulePlan
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Router /api/v1/ppic-schedules/auto-schedule/preview [post]
+func (h *GanttHandler) PreviewAutoSchedule(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	var req models.AutoScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	plan, err := service.PreviewAutoSchedule(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": plan})
+}
+
+// CommitAutoSchedule computes the auto-schedule plan and saves it
+// @Summary Commit auto-schedule
+// @Description Recompute the auto-schedule plan for the same request and write the machine windows and schedule dates, cascading to dependents outside the plan.
+// @Description "versions" must hold the version of every scheduled schedule as returned by the preview: 428 without it, 409 when a schedule changed since
+// @Tags PPIC Schedules
+// @Accept json
+// @Produce json
+// @Param request body models.AutoScheduleRequest true "Schedules to plan, start time and the versions from the preview"
+// @Success 200 {object} models.AutoSchedulePlan
+// @Failure 409 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Router /api/v1/ppic-schedules/auto-schedule/commit [post]
+func (h *GanttHandler) CommitAutoSchedule(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.AutoScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	plan, err := service.CommitAutoSchedule(&req, getUserIDFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrVersionRequired):
+			c.JSON(http.StatusPreconditionRequired, gin.H{"success": false, "error": err.Error()})
+		case errors.Is(err, models.ErrStaleVersion):
+			c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Auto-schedule committed successfully", "data": plan})
+}
+
 // AddMachineAssignment adds a machine to a schedule
 // @Summary Add machine assignment
 // @Description Add a machine to an existing PPIC schedule
@@ -203,8 +661,14 @@ func (h *GanttHandler) GetSchedulesByMachine(c *gin.Context) {
 // @Param id path int true "Schedule ID"
 // @Param request body models.CreateMachineAssignmentRequest true "Machine assignment details"
 // @Success 201 {object} models.MachineAssignment
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules/{id}/machines [post]
 func (h *GanttHandler) AddMachineAssignment(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
 	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid schedule ID"})
@@ -217,7 +681,8 @@ func (h *GanttHandler) AddMachineAssignment(c *gin.Context) {
 		return
 	}
 
-	assignment, err := h.service.AddMachineAssignment(scheduleID, &req)
+	userID := getUserIDFromContext(c)
+	assignment, err := service.AddMachineAssignment(scheduleID, &req, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
@@ -233,15 +698,28 @@ func (h *GanttHandler) AddMachineAssignment(c *gin.Context) {
 // @Param id path int true "Schedule ID"
 // @Param assignment_id path int true "Assignment ID"
 // @Success 200 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules/{id}/machines/{assignment_id} [delete]
 func (h *GanttHandler) RemoveMachineAssignment(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
+	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid schedule ID"})
+		return
+	}
+
 	assignmentID, err := strconv.ParseInt(c.Param("assignment_id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid assignment ID"})
 		return
 	}
 
-	if err := h.service.RemoveMachineAssignment(assignmentID); err != nil {
+	userID := getUserIDFromContext(c)
+	if err := service.RemoveMachineAssignment(scheduleID, assignmentID, userID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
@@ -251,15 +729,25 @@ func (h *GanttHandler) RemoveMachineAssignment(c *gin.Context) {
 
 // UpdateMachineAssignmentStatus updates status of a machine assignment
 // @Summary Update machine assignment status
-// @Description Update the status of a machine assignment (pending, in_progress, completed)
+// @Description Update the status of a machine assignment (pending, in_progress, completed). The assignment version last read is required
+// @Description (If-Match header or "version" field): 428 without it, 409 with the current schedule when it is stale
 // @Tags PPIC Schedules
 // @Accept json
 // @Produce json
 // @Param id path int true "Schedule ID"
 // @Param assignment_id path int true "Assignment ID"
-// @Success 200 {object} map[string]interface{}
+// @Param If-Match header string false "Assignment version last read, e.g. \"3\""
+// @Success 200 {object} models.PPICSchedule
+// @Failure 409 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-schedules/{id}/machines/{assignment_id}/status [put]
 func (h *GanttHandler) UpdateMachineAssignmentStatus(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
 	scheduleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid schedule ID"})
@@ -276,18 +764,88 @@ func (h *GanttHandler) UpdateMachineAssignmentStatus(c *gin.Context) {
 		Status      string     `json:"status" binding:"required"`
 		ActualStart *time.Time `json:"actual_start"`
 		ActualEnd   *time.Time `json:"actual_end"`
+		Version     *int       `json:"version"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
 		return
 	}
-
-	if err := h.service.UpdateMachineAssignmentStatus(scheduleID, assignmentID, req.Status, req.ActualStart, req.ActualEnd); err != nil {
+	if req.Version, err = requestVersion(c, req.Version); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Machine assignment status updated"})
+	userID := getUserIDFromContext(c)
+	schedule, err := service.UpdateMachineAssignmentStatus(scheduleID, assignmentID, req.Version, req.Status, req.ActualStart, req.ActualEnd, userID)
+	if err != nil {
+		respondScheduleWriteError(c, service, scheduleID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Machine assignment status updated", "data": schedule})
+}
+
+// GetPPICScheduleHistory returns the change history of a schedule
+// @Summary Get PPIC schedule change history
+// @Description Per-field changes of the schedule and its machine assignments with old/new value, user, time and cause, newest first
+// @Tags PPIC Schedules
+// @Produce json
+// @Param id path int true "Schedule ID"
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Success 200 {array} models.PPICScheduleChange
+// @Router /api/v1/ppic-schedules/{id}/history [get]
+func (h *GanttHandler) GetPPICScheduleHistory(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid schedule ID"})
+		return
+	}
+
+	history, err := service.GetPPICScheduleHistory(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": history})
+}
+
+// requestVersion returns the version the client last read: the If-Match header, or else the
+// version field of the body. nil means the client sent neither
+func requestVersion(c *gin.Context, bodyVersion *int) (*int, error) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return bodyVersion, nil
+	}
+	version, err := models.ParseIfMatch(header)
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// respondScheduleWriteError answers a failed schedule write: 428 without a version, 409 with the
+// current schedule when the version is stale, 400 otherwise
+func respondScheduleWriteError(c *gin.Context, service *services.GanttService, id int64, err error) {
+	switch {
+	case errors.Is(err, models.ErrVersionRequired):
+		c.JSON(http.StatusPreconditionRequired, gin.H{"success": false, "error": err.Error()})
+	case errors.Is(err, models.ErrStaleVersion):
+		current, getErr := service.GetPPICSchedule(id)
+		if getErr != nil {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "error": getErr.Error()})
+			return
+		}
+		c.Header("ETag", models.VersionETag(current.Version))
+		c.JSON(http.StatusConflict, gin.H{"success": false, "error": err.Error(), "current": current})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+	}
 }
 
 // Helper function to get user ID from context
@@ -63,6 +63,7 @@ func (h *JobOrderHandler) GetJobOrder(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", models.VersionETag(job.Version))
 	c.JSON(http.StatusOK, job)
 }
 
@@ -120,13 +121,17 @@ func (h *JobOrderHandler) CreateJobOrder(c *gin.Context) {
 
 // UpdateJobOrder godoc
 // @Summary Update job order
-// @Description Update an existing job order
+// @Description Update an existing job order. The version last read is required (If-Match header or "version" field):
+// @Description 428 without it, 409 with the current job order when it is stale
 // @Tags job_orders
 // @Accept json
 // @Produce json
 // @Param id path int true "Job Order ID"
+// @Param If-Match header string false "Version last read, e.g. \"3\""
 // @Param job_order body models.UpdateJobOrderRequest true "Job Order data"
 // @Success 200 {object} models.JobOrder
+// @Failure 409 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Router /api/v1/job-orders/{id} [put]
 func (h *JobOrderHandler) UpdateJobOrder(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
@@ -140,6 +145,14 @@ func (h *JobOrderHandler) UpdateJobOrder(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Version, err = requestVersion(c, req.Version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Version == nil {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": models.ErrVersionRequired.Error()})
+		return
+	}
 
 	job, err := h.repo.Update(id, &req)
 	if err != nil {
@@ -148,10 +161,22 @@ func (h *JobOrderHandler) UpdateJobOrder(c *gin.Context) {
 	}
 
 	if job == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Job order not found"})
-		return
-	}
-
+		// Either gone or updated by someone else since it was read
+		current, err := h.repo.GetByID(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch job order"})
+			return
+		}
+		if current == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job order not found"})
+			return
+		}
+		c.Header("ETag", models.VersionETag(current.Version))
+		c.JSON(http.StatusConflict, gin.H{"error": models.ErrStaleVersion.Error(), "current": current})
+		return
+	}
+
+	c.Header("ETag", models.VersionETag(job.Version))
 	c.JSON(http.StatusOK, job)
 }
 
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MachineDowntimeHandler struct {
+	service *services.MachineDowntimeService
+}
+
+func NewMachineDowntimeHandler(service *services.MachineDowntimeService) *MachineDowntimeHandler {
+	return &MachineDowntimeHandler{service: service}
+}
+
+// GetAllDowntimes returns machine downtime windows
+// @Summary Get machine downtime
+// @Description List downtime windows (planned maintenance, breakdown, calibration), optionally of one machine and type, overlapping start_date .. end_date
+// @Tags Machine Downtime
+// @Produce json
+// @Param machine_id query int false "Machine ID"
+// @Param type query string false "planned_maintenance, breakdown or calibration"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.MachineDowntime
+// @Router /api/v1/machine-downtimes [get]
+func (h *MachineDowntimeHandler) GetAllDowntimes(c *gin.Context) {
+	var filter models.MachineDowntimeFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	downtimes, err := h.service.GetDowntimes(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": downtimes, "count": len(downtimes)})
+}
+
+// GetDowntime returns a single downtime window
+// @Summary Get machine downtime window
+// @Tags Machine Downtime
+// @Produce json
+// @Param id path int true "Downtime ID"
+// @Success 200 {object} models.MachineDowntime
+// @Router /api/v1/machine-downtimes/{id} [get]
+func (h *MachineDowntimeHandler) GetDowntime(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid downtime ID"})
+		return
+	}
+
+	downtime, err := h.service.GetDowntime(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": downtime})
+}
+
+// CreateDowntime records a downtime window
+// @Summary Create machine downtime
+// @Description Block a machine from start_time to end_time. New machine assignments can't overlap it and cascades and auto-scheduling move work past it. Assignments already scheduled in it are not moved; they are listed as affected_assignments
+// @Tags Machine Downtime
+// @Accept json
+// @Produce json
+// @Param request body models.CreateMachineDowntimeRequest true "Downtime details"
+// @Success 201 {object} models.MachineDowntimeResult
+// @Router /api/v1/machine-downtimes [post]
+func (h *MachineDowntimeHandler) CreateDowntime(c *gin.Context) {
+	var req models.CreateMachineDowntimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := h.service.CreateDowntime(&req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Machine downtime created successfully", "data": result})
+}
+
+// UpdateDowntime changes a downtime window
+// @Summary Update machine downtime
+// @Description Change a downtime window; omitted fields are kept. Lists the scheduled assignments the changed window overlaps
+// @Tags Machine Downtime
+// @Accept json
+// @Produce json
+// @Param id path int true "Downtime ID"
+// @Param request body models.UpdateMachineDowntimeRequest true "Downtime changes"
+// @Success 200 {object} models.MachineDowntimeResult
+// @Router /api/v1/machine-downtimes/{id} [put]
+func (h *MachineDowntimeHandler) UpdateDowntime(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid downtime ID"})
+		return
+	}
+
+	var req models.UpdateMachineDowntimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	result, err := h.service.UpdateDowntime(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Machine downtime updated successfully", "data": result})
+}
+
+// DeleteDowntime deletes a downtime window
+// @Summary Delete machine downtime
+// @Tags Machine Downtime
+// @Param id path int true "Downtime ID"
+// @Success 200
+// @Router /api/v1/machine-downtimes/{id} [delete]
+func (h *MachineDowntimeHandler) DeleteDowntime(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid downtime ID"})
+		return
+	}
+
+	if err := h.service.DeleteDowntime(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Machine downtime deleted successfully"})
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceHandler struct {
+	service *services.MaintenanceService
+}
+
+func NewMaintenanceHandler(service *services.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{service: service}
+}
+
+// ========== Plans ==========
+
+// GetAllPlans returns preventive maintenance plans with their due status
+// @Summary Get maintenance plans
+// @Description List preventive maintenance plans, optionally of one machine, with run hours since the last PM, next due date and due/overdue flags
+// @Tags Maintenance
+// @Produce json
+// @Param machine_id query int false "Machine ID"
+// @Success 200 {array} models.MaintenancePlanStatus
+// @Router /api/v1/maintenance/plans [get]
+func (h *MaintenanceHandler) GetAllPlans(c *gin.Context) {
+	var machineID int64
+	if value := c.Query("machine_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid machine ID"})
+			return
+		}
+		machineID = id
+	}
+
+	plans, err := h.service.GetPlans(machineID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": plans, "count": len(plans)})
+}
+
+// GetPlan returns a single maintenance plan
+// @Summary Get maintenance plan
+// @Tags Maintenance
+// @Produce json
+// @Param id path int true "Plan ID"
+// @Success 200 {object} models.MaintenancePlanStatus
+// @Router /api/v1/maintenance/plans/{id} [get]
+func (h *MaintenanceHandler) GetPlan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid plan ID"})
+		return
+	}
+
+	plan, err := h.service.GetPlan(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": plan})
+}
+
+// CreatePlan creates a maintenance plan
+// @Summary Create maintenance plan
+// @Description Plan a PM routine for a machine, due every interval_days calendar days and/or every interval_run_hours of actual run time, whichever comes first
+// @Tags Maintenance
+// @Accept json
+// @Produce json
+// @Param request body models.CreateMaintenancePlanRequest true "Plan details"
+// @Success 201 {object} models.MaintenancePlanStatus
+// @Router /api/v1/maintenance/plans [post]
+func (h *MaintenanceHandler) CreatePlan(c *gin.Context) {
+	var req models.CreateMaintenancePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	plan, err := h.service.CreatePlan(&req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Maintenance plan created successfully", "data": plan})
+}
+
+// UpdatePlan changes a maintenance plan
+// @Summary Update maintenance plan
+// @Description Change a plan; omitted fields are kept and an interval of 0 removes it
+// @Tags Maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Plan ID"
+// @Param request body models.UpdateMaintenancePlanRequest true "Plan changes"
+// @Success 200 {object} models.MaintenancePlanStatus
+// @Router /api/v1/maintenance/plans/{id} [put]
+func (h *MaintenanceHandler) UpdatePlan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid plan ID"})
+		return
+	}
+
+	var req models.UpdateMaintenancePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	plan, err := h.service.UpdatePlan(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Maintenance plan updated successfully", "data": plan})
+}
+
+// DeletePlan deletes a maintenance plan
+// @Summary Delete maintenance plan
+// @Description Delete a plan; its work orders are kept as history
+// @Tags Maintenance
+// @Param id path int true "Plan ID"
+// @Success 200
+// @Router /api/v1/maintenance/plans/{id} [delete]
+func (h *MaintenanceHandler) DeletePlan(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid plan ID"})
+		return
+	}
+
+	if err := h.service.DeletePlan(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Maintenance plan deleted successfully"})
+}
+
+// GetOverdue returns the active plans that are overdue
+// @Summary Get overdue maintenance
+// @Description List active plans past their due date or run-hour interval, most overdue first
+// @Tags Maintenance
+// @Produce json
+// @Success 200 {array} models.MaintenancePlanStatus
+// @Router /api/v1/maintenance/overdue [get]
+func (h *MaintenanceHandler) GetOverdue(c *gin.Context) {
+	plans, err := h.service.GetOverdue()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": plans, "count": len(plans)})
+}
+
+// ========== Work Orders ==========
+
+// GetAllWorkOrders returns maintenance work orders
+// @Summary Get maintenance work orders
+// @Description List work orders, newest first. Work orders of due plans are generated first
+// @Tags Maintenance
+// @Produce json
+// @Param machine_id query int false "Machine ID"
+// @Param plan_id query int false "Plan ID"
+// @Param status query string false "open, in_progress, completed or cancelled"
+// @Success 200 {array} models.MaintenanceWorkOrder
+// @Router /api/v1/maintenance/work-orders [get]
+func (h *MaintenanceHandler) GetAllWorkOrders(c *gin.Context) {
+	var filter models.MaintenanceWorkOrderFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	orders, err := h.service.GetWorkOrders(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": orders, "count": len(orders)})
+}
+
+// GetWorkOrder returns a single work order
+// @Summary Get maintenance work order
+// @Tags Maintenance
+// @Produce json
+// @Param id path int true "Work order ID"
+// @Success 200 {object} models.MaintenanceWorkOrder
+// @Router /api/v1/maintenance/work-orders/{id} [get]
+func (h *MaintenanceHandler) GetWorkOrder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid work order ID"})
+		return
+	}
+
+	order, err := h.service.GetWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": order})
+}
+
+// GenerateWorkOrders creates the work orders of all due plans
+// @Summary Generate due maintenance work orders
+// @Description Create a work order for each active plan that is due and has none open. Returns only the new ones
+// @Tags Maintenance
+// @Produce json
+// @Success 200 {array} models.MaintenanceWorkOrder
+// @Router /api/v1/maintenance/work-orders/generate [post]
+func (h *MaintenanceHandler) GenerateWorkOrders(c *gin.Context) {
+	orders, err := h.service.GenerateDueWorkOrders(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": orders, "count": len(orders)})
+}
+
+// CreateWorkOrder raises a work order for a plan by hand
+// @Summary Create maintenance work order
+// @Description Raise a manual work order for a plan, e.g. to do it early. Refused while the plan has one open
+// @Tags Maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Plan ID"
+// @Param request body models.CreateMaintenanceWorkOrderRequest false "Work order details"
+// @Success 201 {object} models.MaintenanceWorkOrder
+// @Router /api/v1/maintenance/plans/{id}/work-orders [post]
+func (h *MaintenanceHandler) CreateWorkOrder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid plan ID"})
+		return
+	}
+
+	var req models.CreateMaintenanceWorkOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	order, err := h.service.CreateWorkOrder(id, &req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Maintenance work order created successfully", "data": order})
+}
+
+// StartWorkOrder marks a work order in progress
+// @Summary Start maintenance work order
+// @Tags Maintenance
+// @Produce json
+// @Param id path int true "Work order ID"
+// @Success 200 {object} models.MaintenanceWorkOrder
+// @Router /api/v1/maintenance/work-orders/{id}/start [post]
+func (h *MaintenanceHandler) StartWorkOrder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid work order ID"})
+		return
+	}
+
+	order, err := h.service.StartWorkOrder(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Maintenance work order started", "data": order})
+}
+
+// CompleteWorkOrder records a work order as done
+// @Summary Complete maintenance work order
+// @Description Record the technician, notes and completion time. The plan's interval restarts from completed_at
+// @Tags Maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Work order ID"
+// @Param request body models.CompleteMaintenanceWorkOrderRequest true "Completion record"
+// @Success 200 {object} models.MaintenanceWorkOrder
+// @Router /api/v1/maintenance/work-orders/{id}/complete [post]
+func (h *MaintenanceHandler) CompleteWorkOrder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid work order ID"})
+		return
+	}
+
+	var req models.CompleteMaintenanceWorkOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	order, err := h.service.CompleteWorkOrder(id, &req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Maintenance work order completed", "data": order})
+}
+
+// CancelWorkOrder cancels a work order
+// @Summary Cancel maintenance work order
+// @Description Cancel an open work order. No new one is generated for the plan until the next interval
+// @Tags Maintenance
+// @Accept json
+// @Produce json
+// @Param id path int true "Work order ID"
+// @Param request body models.CancelMaintenanceWorkOrderRequest false "Reason"
+// @Success 200 {object} models.MaintenanceWorkOrder
+// @Router /api/v1/maintenance/work-orders/{id}/cancel [post]
+func (h *MaintenanceHandler) CancelWorkOrder(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid work order ID"})
+		return
+	}
+
+	var req models.CancelMaintenanceWorkOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	order, err := h.service.CancelWorkOrder(id, req.Notes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Maintenance work order cancelled", "data": order})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -88,13 +89,15 @@ func (h *PEMOperationPlanHandler) GetPEMPlan(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", models.VersionETag(plan.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    plan,
 	})
 }
 
-// UpdatePEMPlan updates an existing PEM operation plan
+// UpdatePEMPlan updates an existing PEM operation plan. The version last read is required
+// (If-Match header or "version" field): 428 without it, 409 with the current plan when it is stale
 func (h *PEMOperationPlanHandler) UpdatePEMPlan(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -107,13 +110,30 @@ func (h *PEMOperationPlanHandler) UpdatePEMPlan(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
 		return
 	}
+	if request.Version, err = requestVersion(c, request.Version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
 
 	// Get user from context
 	user, _ := c.Get("user")
 	userObj := user.(*models.User)
 
 	if err := h.service.UpdatePlan(id, request, int64(userObj.ID)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update plan", "details": err.Error()})
+		switch {
+		case errors.Is(err, models.ErrVersionRequired):
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "Failed to update plan", "details": err.Error()})
+		case errors.Is(err, models.ErrStaleVersion):
+			current, getErr := h.service.GetPlanByID(id)
+			if getErr != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Plan not found", "details": getErr.Error()})
+				return
+			}
+			c.Header("ETag", models.VersionETag(current.Version))
+			c.JSON(http.StatusConflict, gin.H{"error": "Failed to update plan", "details": err.Error(), "current": current})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update plan", "details": err.Error()})
+		}
 		return
 	}
 
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PPICBaselineHandler struct {
+	service *services.PPICBaselineService
+}
+
+func NewPPICBaselineHandler(service *services.PPICBaselineService) *PPICBaselineHandler {
+	return &PPICBaselineHandler{service: service}
+}
+
+// GetAllBaselines returns all schedule baselines
+// @Summary Get all PPIC baselines
+// @Tags PPIC Baselines
+// @Produce json
+// @Success 200 {array} models.PPICBaseline
+// @Router /api/v1/ppic-baselines [get]
+func (h *PPICBaselineHandler) GetAllBaselines(c *gin.Context) {
+	baselines, err := h.service.GetAllBaselines()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": baselines})
+}
+
+// CreateBaseline snapshots the live plan
+// @Summary Create PPIC baseline
+// @Description Freeze all live schedule and machine assignment dates as a named baseline
+// @Tags PPIC Baselines
+// @Accept json
+// @Produce json
+// @Param baseline body models.CreatePPICBaselineRequest true "Baseline data"
+// @Success 201 {object} models.PPICBaseline
+// @Router /api/v1/ppic-baselines [post]
+func (h *PPICBaselineHandler) CreateBaseline(c *gin.Context) {
+	var req models.CreatePPICBaselineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	baseline, err := h.service.CreateBaseline(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": baseline})
+}
+
+// GetBaseline returns a baseline with the dates it captured
+// @Summary Get PPIC baseline by ID
+// @Tags PPIC Baselines
+// @Produce json
+// @Param id path int true "Baseline ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/ppic-baselines/{id} [get]
+func (h *PPICBaselineHandler) GetBaseline(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid baseline ID"})
+		return
+	}
+
+	baseline, err := h.service.GetBaseline(id)
+	if err != nil {
+		c.JSON(baselineErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	schedules, err := h.service.GetBaselineSchedules(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"baseline": baseline, "schedules": schedules}})
+}
+
+// DeleteBaseline removes a baseline
+// @Summary Delete PPIC baseline
+// @Tags PPIC Baselines
+// @Param id path int true "Baseline ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/ppic-baselines/{id} [delete]
+func (h *PPICBaselineHandler) DeleteBaseline(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid baseline ID"})
+		return
+	}
+
+	if err := h.service.DeleteBaseline(id); err != nil {
+		c.JSON(baselineErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Baseline deleted successfully"})
+}
+
+// GetVariance compares the current plan and actuals against a baseline
+// @Summary Get baseline variance
+// @Description Baseline vs current start/finish per schedule, and baseline vs scheduled vs actual per machine assignment, with slip in days
+// @Tags PPIC Baselines
+// @Produce json
+// @Param id path int true "Baseline ID"
+// @Success 200 {object} models.BaselineVarianceResponse
+// @Router /api/v1/ppic-baselines/{id}/variance [get]
+func (h *PPICBaselineHandler) GetVariance(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid baseline ID"})
+		return
+	}
+
+	variance, err := h.service.GetVariance(id)
+	if err != nil {
+		c.JSON(baselineErrorStatus(err), gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": variance})
+}
+
+func baselineErrorStatus(err error) int {
+	if errors.Is(err, services.ErrBaselineNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"ganttpro-backend/models"
 	"ganttpro-backend/services"
 	"net/http"
@@ -10,11 +11,38 @@ import (
 )
 
 type PPICLinkHandler struct {
-	service *services.PPICLinkService
+	service         *services.PPICLinkService
+	scenarioService *services.PPICScenarioService
 }
 
-func NewPPICLinkHandler(service *services.PPICLinkService) *PPICLinkHandler {
-	return &PPICLinkHandler{service: service}
+func NewPPICLinkHandler(service *services.PPICLinkService, scenarioService *services.PPICScenarioService) *PPICLinkHandler {
+	return &PPICLinkHandler{service: service, scenarioService: scenarioService}
+}
+
+// serviceFor returns the live service, or the scenario's when ?scenario_id is given.
+// Writes an error response and returns false if the scenario can't be used
+func (h *PPICLinkHandler) serviceFor(c *gin.Context, forWrite bool) (*services.PPICLinkService, bool) {
+	scenarioParam := c.Query("scenario_id")
+	if scenarioParam == "" {
+		return h.service, true
+	}
+
+	scenarioID, err := strconv.ParseInt(scenarioParam, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid scenario ID"})
+		return nil, false
+	}
+
+	service, err := h.scenarioService.LinkService(scenarioID, forWrite)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrScenarioNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "error": err.Error()})
+		return nil, false
+	}
+	return service, true
 }
 
 // CreatePPICLink creates a new link between two schedules
@@ -24,16 +52,34 @@ func NewPPICLinkHandler(service *services.PPICLinkService) *PPICLinkHandler {
 // @Accept json
 // @Produce json
 // @Param request body models.CreatePPICLinkRequest true "Link details"
+// @Param dry_run query bool false "Only preview the schedules the link would move"
 // @Success 201 {object} models.PPICLink
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-links [post]
 func (h *PPICLinkHandler) CreatePPICLink(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
 	var req models.CreatePPICLinkRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid request", "details": err.Error()})
 		return
 	}
 
-	link, err := h.service.CreateLink(&req)
+	if c.Query("dry_run") == "true" {
+		impact, err := service.PreviewLink(&req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": impact})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	link, err := service.CreateLink(&req, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
@@ -48,9 +94,15 @@ func (h *PPICLinkHandler) CreatePPICLink(c *gin.Context) {
 // @Tags PPIC Links
 // @Produce json
 // @Success 200 {array} models.PPICLink
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-links [get]
 func (h *PPICLinkHandler) GetAllPPICLinks(c *gin.Context) {
-	links, err := h.service.GetAllLinks()
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	links, err := service.GetAllLinks()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
 		return
@@ -59,21 +111,50 @@ func (h *PPICLinkHandler) GetAllPPICLinks(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": links, "count": len(links)})
 }
 
+// CheckPPICLinkIntegrity checks the PPIC link graph
+// @Summary Check PPIC link integrity
+// @Description Report dependency cycles, links to deleted schedules, duplicate links and links between tasks that no longer share a machine
+// @Tags PPIC Links
+// @Produce json
+// @Success 200 {object} models.PPICLinkIntegrityReport
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
+// @Router /api/v1/ppic-links/integrity [get]
+func (h *PPICLinkHandler) CheckPPICLinkIntegrity(c *gin.Context) {
+	service, ok := h.serviceFor(c, false)
+	if !ok {
+		return
+	}
+
+	report, err := service.CheckIntegrity()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": report})
+}
+
 // DeletePPICLink deletes a PPIC link
 // @Summary Delete PPIC link
 // @Description Delete a dependency link between PPIC schedules
 // @Tags PPIC Links
 // @Param id path int true "Link ID"
 // @Success 200 {object} map[string]interface{}
+// @Param scenario_id query int false "What-if scenario ID (omit for the live board)"
 // @Router /api/v1/ppic-links/{id} [delete]
 func (h *PPICLinkHandler) DeletePPICLink(c *gin.Context) {
+	service, ok := h.serviceFor(c, true)
+	if !ok {
+		return
+	}
+
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid ID"})
 		return
 	}
 
-	if err := h.service.DeleteLink(id); err != nil {
+	if err := service.DeleteLink(id); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 		return
 	}
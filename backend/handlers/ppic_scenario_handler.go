@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PPICScenarioHandler struct {
+	service *services.PPICScenarioService
+}
+
+func NewPPICScenarioHandler(service *services.PPICScenarioService) *PPICScenarioHandler {
+	return &PPICScenarioHandler{service: service}
+}
+
+// GetAllScenarios returns all what-if scenarios
+// @Summary Get all PPIC scenarios
+// @Tags PPIC Scenarios
+// @Produce json
+// @Success 200 {array} models.PPICScenario
+// @Router /api/v1/ppic-scenarios [get]
+func (h *PPICScenarioHandler) GetAllScenarios(c *gin.Context) {
+	scenarios, err := h.service.GetAllScenarios()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": scenarios})
+}
+
+// CreateScenario copies the live board into a new scenario
+// @Summary Create PPIC scenario
+// @Description Copy all live PPIC schedules, machine assignments and links into a named what-if scenario
+// @Tags PPIC Scenarios
+// @Accept json
+// @Produce json
+// @Param scenario body models.CreatePPICScenarioRequest true "Scenario data"
+// @Success 201 {object} models.PPICScenario
+// @Router /api/v1/ppic-scenarios [post]
+func (h *PPICScenarioHandler) CreateScenario(c *gin.Context) {
+	var req models.CreatePPICScenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	userID := getUserIDFromContext(c)
+	scenario, err := h.service.CreateScenario(&req, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": scenario})
+}
+
+// GetScenario returns a single scenario
+// @Summary Get PPIC scenario by ID
+// @Tags PPIC Scenarios
+// @Produce json
+// @Param id path int true "Scenario ID"
+// @Success 200 {object} models.PPICScenario
+// @Router /api/v1/ppic-scenarios/{id} [get]
+func (h *PPICScenarioHandler) GetScenario(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid scenario ID"})
+		return
+	}
+
+	scenario, err := h.service.GetScenario(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": scenario})
+}
+
+// DeleteScenario discards a scenario
+// @Summary Delete PPIC scenario
+// @Tags PPIC Scenarios
+// @Param id path int true "Scenario ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/ppic-scenarios/{id} [delete]
+func (h *PPICScenarioHandler) DeleteScenario(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid scenario ID"})
+		return
+	}
+
+	if err := h.service.DeleteScenario(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Scenario deleted successfully"})
+}
+
+// CompareScenario compares a scenario against the live board
+// @Summary Compare PPIC scenario with live
+// @Description Per-schedule date changes, project finish and machine load of the scenario versus the live board
+// @Tags PPIC Scenarios
+// @Produce json
+// @Param id path int true "Scenario ID"
+// @Success 200 {object} models.ScenarioComparison
+// @Router /api/v1/ppic-scenarios/{id}/compare [get]
+func (h *PPICScenarioHandler) CompareScenario(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid scenario ID"})
+		return
+	}
+
+	comparison, err := h.service.CompareScenario(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": comparison})
+}
+
+// PromoteScenario copies a draft scenario to the live board in one transaction
+// @Summary Promote PPIC scenario to live
+// @Description Fails if live schedules changed after the scenario was created, unless force=true
+// @Tags PPIC Scenarios
+// @Produce json
+// @Param id path int true "Scenario ID"
+// @Param force query bool false "Overwrite live schedules changed since the scenario was created"
+// @Success 200 {object} models.PPICScenario
+// @Router /api/v1/ppic-scenarios/{id}/promote [post]
+func (h *PPICScenarioHandler) PromoteScenario(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid scenario ID"})
+		return
+	}
+
+	force := c.Query("force") == "true"
+	userID := getUserIDFromContext(c)
+	scenario, err := h.service.PromoteScenario(id, force, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": scenario, "message": "Scenario promoted to live"})
+}
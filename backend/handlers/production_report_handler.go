@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductionReportHandler struct {
+	service *services.ProductionReportService
+}
+
+func NewProductionReportHandler(service *services.ProductionReportService) *ProductionReportHandler {
+	return &ProductionReportHandler{service: service}
+}
+
+// GetAllReports returns production reports
+// @Summary Get production reports
+// @Description List good/scrap part reports, newest first, produced between start_date and end_date
+// @Tags Production Reports
+// @Produce json
+// @Param machine_id query int false "Machine ID"
+// @Param schedule_id query int false "PPIC schedule ID"
+// @Param assignment_id query int false "Machine assignment ID"
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {array} models.ProductionReport
+// @Router /api/v1/production-reports [get]
+func (h *ProductionReportHandler) GetAllReports(c *gin.Context) {
+	var filter models.ProductionReportFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	reports, err := h.service.GetReports(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": reports, "count": len(reports)})
+}
+
+// GetReport returns a single production report
+// @Summary Get production report
+// @Tags Production Reports
+// @Produce json
+// @Param id path int true "Report ID"
+// @Success 200 {object} models.ProductionReport
+// @Router /api/v1/production-reports/{id} [get]
+func (h *ProductionReportHandler) GetReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid report ID"})
+		return
+	}
+
+	report, err := h.service.GetReport(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": report})
+}
+
+// CreateReport records produced parts
+// @Summary Create production report
+// @Description Report good and scrap parts of a machine, or of an operation (schedule_id + assignment_id). ideal_cycle_seconds defaults to the previous report of the operation
+// @Tags Production Reports
+// @Accept json
+// @Produce json
+// @Param request body models.CreateProductionReportRequest true "Report details"
+// @Success 201 {object} models.ProductionReport
+// @Router /api/v1/production-reports [post]
+func (h *ProductionReportHandler) CreateReport(c *gin.Context) {
+	var req models.CreateProductionReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	report, err := h.service.CreateReport(&req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Production report created successfully", "data": report})
+}
+
+// UpdateReport corrects a production report
+// @Summary Update production report
+// @Description Change the counts, production time, ideal cycle time or notes; omitted fields are kept
+// @Tags Production Reports
+// @Accept json
+// @Produce json
+// @Param id path int true "Report ID"
+// @Param request body models.UpdateProductionReportRequest true "Report changes"
+// @Success 200 {object} models.ProductionReport
+// @Router /api/v1/production-reports/{id} [put]
+func (h *ProductionReportHandler) UpdateReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid report ID"})
+		return
+	}
+
+	var req models.UpdateProductionReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	report, err := h.service.UpdateReport(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Production report updated successfully", "data": report})
+}
+
+// DeleteReport deletes a production report
+// @Summary Delete production report
+// @Tags Production Reports
+// @Param id path int true "Report ID"
+// @Success 200
+// @Router /api/v1/production-reports/{id} [delete]
+func (h *ProductionReportHandler) DeleteReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid report ID"})
+		return
+	}
+
+	if err := h.service.DeleteReport(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Production report deleted successfully"})
+}
+
+// GetOEE reports the overall equipment effectiveness of each machine
+// @Summary Get machine OEE
+// @Description Availability (run / planned time), performance (ideal cycle time x parts / run time), quality (good / total parts) and OEE per machine and shift, day or week. Andon stops and breakdowns count as downtime; planned maintenance and calibration are not planned time
+// @Tags Machines
+// @Produce json
+// @Param start_date query string true "Range start (YYYY-MM-DD)"
+// @Param end_date query string true "Range end, inclusive (YYYY-MM-DD)"
+// @Param period query string false "shift, day (default) or week"
+// @Param machine_type query string false "Filter by machine type"
+// @Param location query string false "Filter by location"
+// @Param machine_id query int false "Filter by machine ID"
+// @Success 200 {object} models.OEEReport
+// @Router /api/v1/machines/oee [get]
+func (h *ProductionReportHandler) GetOEE(c *gin.Context) {
+	var filter models.OEEFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid filter parameters"})
+		return
+	}
+
+	report, err := h.service.GetOEE(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": report})
+}
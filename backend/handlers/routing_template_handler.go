@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoutingTemplateHandler struct {
+	service *services.RoutingTemplateService
+}
+
+func NewRoutingTemplateHandler(service *services.RoutingTemplateService) *RoutingTemplateHandler {
+	return &RoutingTemplateHandler{service: service}
+}
+
+// GetAllTemplates returns all routing templates
+// @Summary Get routing templates
+// @Description List routing templates with their steps, optionally of one part family
+// @Tags Routing Templates
+// @Produce json
+// @Param part_family query string false "Part family"
+// @Success 200 {array} models.RoutingTemplate
+// @Router /api/v1/routing-templates [get]
+func (h *RoutingTemplateHandler) GetAllTemplates(c *gin.Context) {
+	templates, err := h.service.GetTemplates(c.Query("part_family"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": templates, "count": len(templates)})
+}
+
+// GetTemplate returns a single routing template
+// @Summary Get routing template
+// @Tags Routing Templates
+// @Produce json
+// @Param id path int true "Template ID"
+// @Success 200 {object} models.RoutingTemplate
+// @Router /api/v1/routing-templates/{id} [get]
+func (h *RoutingTemplateHandler) GetTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid template ID"})
+		return
+	}
+
+	template, err := h.service.GetTemplate(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": template})
+}
+
+// CreateTemplate creates a routing template
+// @Summary Create routing template
+// @Description Add a named routing for a part family. Steps are given in operation order, each on a machine_id or any active machine of a machine_type, with default target hours and optional alternatives in order of preference
+// @Tags Routing Templates
+// @Accept json
+// @Produce json
+// @Param request body models.CreateRoutingTemplateRequest true "Template details"
+// @Success 201 {object} models.RoutingTemplate
+// @Router /api/v1/routing-templates [post]
+func (h *RoutingTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req models.CreateRoutingTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	template, err := h.service.CreateTemplate(&req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "Routing template created successfully", "data": template})
+}
+
+// UpdateTemplate updates a routing template
+// @Summary Update routing template
+// @Description Change a routing template. Steps, when given, replace all of its steps; schedules created from it keep their machines
+// @Tags Routing Templates
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param request body models.UpdateRoutingTemplateRequest true "Template changes"
+// @Success 200 {object} models.RoutingTemplate
+// @Router /api/v1/routing-templates/{id} [put]
+func (h *RoutingTemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid template ID"})
+		return
+	}
+
+	var req models.UpdateRoutingTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	template, err := h.service.UpdateTemplate(id, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Routing template updated successfully", "data": template})
+}
+
+// DeleteTemplate deletes a routing template
+// @Summary Delete routing template
+// @Tags Routing Templates
+// @Param id path int true "Template ID"
+// @Success 200
+// @Router /api/v1/routing-templates/{id} [delete]
+func (h *RoutingTemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid template ID"})
+		return
+	}
+
+	if err := h.service.DeleteTemplate(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Routing template deleted successfully"})
+}
+
+// ApplyTemplate previews the machine assignments a routing template gives a schedule
+// @Summary Apply routing template
+// @Description Resolve each step to the first active machine among its machine (or type) and alternatives, then apply the overrides. The machine_assignments of the response can be sent to POST /ppic-schedules as they are
+// @Tags Routing Templates
+// @Accept json
+// @Produce json
+// @Param id path int true "Template ID"
+// @Param request body models.ApplyRoutingTemplateRequest false "Step overrides"
+// @Success 200 {object} models.AppliedRouting
+// @Router /api/v1/routing-templates/{id}/apply [post]
+func (h *RoutingTemplateHandler) ApplyTemplate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid template ID"})
+		return
+	}
+
+	// The body is optional: without it the template is applied as it is
+	var req models.ApplyRoutingTemplateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+	}
+
+	applied, err := h.service.ApplyTemplate(id, req.MachineAssignments, req.SkipSequences)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": applied})
+}
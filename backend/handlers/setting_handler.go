@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SettingHandler struct {
+	service *services.SettingService
+}
+
+func NewSettingHandler(service *services.SettingService) *SettingHandler {
+	return &SettingHandler{service: service}
+}
+
+// GetPlanningSettings returns the planning settings
+// @Summary Get planning settings
+// @Description Settings PPIC schedules are validated against, such as the maximum of machines per schedule
+// @Tags Settings
+// @Produce json
+// @Success 200 {object} models.PlanningSettings
+// @Router /api/v1/settings/planning [get]
+func (h *SettingHandler) GetPlanningSettings(c *gin.Context) {
+	settings, err := h.service.GetPlanningSettings()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": settings})
+}
+
+// UpdatePlanningSettings changes the planning settings
+// @Summary Update planning settings
+// @Description Change planning settings; omitted fields are kept. A lower machine maximum applies to new and edited schedules, existing schedules keep their machines
+// @Tags Settings
+// @Accept json
+// @Produce json
+// @Param request body models.UpdatePlanningSettingsRequest true "Settings"
+// @Success 200 {object} models.PlanningSettings
+// @Router /api/v1/admin/settings/planning [put]
+func (h *SettingHandler) UpdatePlanningSettings(c *gin.Context) {
+	var req models.UpdatePlanningSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	settings, err := h.service.UpdatePlanningSettings(&req, getUserIDFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Settings updated successfully", "data": settings})
+}
@@ -44,12 +44,23 @@ func main() {
 	machineRepo := repository.NewMachineRepository(sqlDB)
 	jobOrderRepo := repository.NewJobOrderRepository(sqlDB)
 	ppicScheduleRepo := repository.NewPPICScheduleRepository(sqlDB)
+	ppicScenarioRepo := repository.NewPPICScenarioRepository(sqlDB)
+	ppicBaselineRepo := repository.NewPPICBaselineRepository(sqlDB)
+	ppicHistoryRepo := repository.NewPPICHistoryRepository(sqlDB)
 	ppicLinkRepo := repository.NewPPICLinkRepository(db)
 	tokenBlacklistRepo := repository.NewTokenBlacklistRepository(db)
 	opPlanRepo := repository.NewOperationPlanRepository(db)
 	gcodeRepo := repository.NewGCodeFileRepository(db)
 	pemPlanRepo := repository.NewPEMOperationPlanRepository(db)
 	toolpatherFileRepo := repository.NewToolpatherFileRepository(db)
+	calendarRepo := repository.NewCalendarRepository(db)
+	calendarFeedRepo := repository.NewCalendarFeedRepository(db)
+	settingRepo := repository.NewSettingRepository(db)
+	routingTemplateRepo := repository.NewRoutingTemplateRepository(db)
+	machineDowntimeRepo := repository.NewMachineDowntimeRepository(db)
+	maintenanceRepo := repository.NewMaintenanceRepository(db)
+	andonRepo := repository.NewAndonRepository(db)
+	productionReportRepo := repository.NewProductionReportRepository(db)
 
 	uploadPath := "./uploads/gcodes"
 	pemUploadPath := "./uploads/operation-plan-images"
@@ -60,10 +71,20 @@ func main() {
 	emailService := services.NewEmailService(cfg)
 	opPlanService := services.NewOperationPlanService(opPlanRepo, gcodeRepo, jobOrderRepo, userRepo, emailService)
 	gcodeService := services.NewGCodeService(gcodeRepo, opPlanRepo, uploadPath)
-	ganttService := services.NewGanttService(ppicScheduleRepo, ppicLinkRepo)
-	ppicLinkService := services.NewPPICLinkService(ppicLinkRepo, ppicScheduleRepo)
-	pemPlanService := services.NewPEMOperationPlanService(pemPlanRepo, userRepo, ppicScheduleRepo, emailService, pemUploadPath)
+	calendarService := services.NewCalendarService(calendarRepo)
+	settingService := services.NewSettingService(settingRepo)
+	routingTemplateService := services.NewRoutingTemplateService(routingTemplateRepo, machineRepo, settingService)
+	machineDowntimeService := services.NewMachineDowntimeService(machineDowntimeRepo, machineRepo, ppicScheduleRepo)
+	maintenanceService := services.NewMaintenanceService(maintenanceRepo, machineRepo)
+	andonService := services.NewAndonService(andonRepo, machineRepo, jobOrderRepo, userRepo)
+	productionReportService := services.NewProductionReportService(productionReportRepo, machineRepo, ppicScheduleRepo, jobOrderRepo, andonRepo, machineDowntimeService, calendarService)
+	ganttService := services.NewGanttService(ppicScheduleRepo, ppicLinkRepo, ppicBaselineRepo, ppicHistoryRepo, calendarService, settingService, routingTemplateService, machineDowntimeService)
+	ppicLinkService := services.NewPPICLinkService(ppicLinkRepo, ppicScheduleRepo, ppicHistoryRepo, calendarService, machineDowntimeService, ganttService)
+	ppicScenarioService := services.NewPPICScenarioService(ppicScenarioRepo, ganttService, ppicLinkService)
+	ppicBaselineService := services.NewPPICBaselineService(ppicBaselineRepo, ppicScheduleRepo)
+	pemPlanService := services.NewPEMOperationPlanService(pemPlanRepo, userRepo, ppicScheduleRepo, ppicHistoryRepo, emailService, pemUploadPath)
 	toolpatherFileService := services.NewToolpatherFileService(toolpatherFileRepo, userRepo, toolpatherUploadPath)
+	calendarFeedService := services.NewCalendarFeedService(calendarFeedRepo, userRepo, machineRepo, jobOrderRepo, pemPlanRepo, ganttService)
 
 	// Initialize and start cleanup service (cleans expired tokens every hour)
 	cleanupService := services.NewCleanupService(tokenBlacklistRepo, services.DefaultCleanupConfig())
@@ -84,12 +105,22 @@ func main() {
 	adminHandler := handlers.NewAdminHandler(userRepo)
 	opPlanHandler := handlers.NewOperationPlanHandler(opPlanService)
 	gcodeHandler := handlers.NewGCodeHandler(gcodeService)
-	ganttHandler := handlers.NewGanttHandler(ganttService)
-	ppicLinkHandler := handlers.NewPPICLinkHandler(ppicLinkService)
+	ganttHandler := handlers.NewGanttHandler(ganttService, ppicScenarioService)
+	ppicLinkHandler := handlers.NewPPICLinkHandler(ppicLinkService, ppicScenarioService)
+	ppicScenarioHandler := handlers.NewPPICScenarioHandler(ppicScenarioService)
+	ppicBaselineHandler := handlers.NewPPICBaselineHandler(ppicBaselineService)
 	emailHandler := handlers.NewEmailHandler(emailService, opPlanRepo, userRepo)
 	googleSheetsHandler := handlers.NewGoogleSheetsHandler()
 	pemPlanHandler := handlers.NewPEMOperationPlanHandler(pemPlanService)
 	toolpatherFileHandler := handlers.NewToolpatherFileHandler(toolpatherFileService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	calendarFeedHandler := handlers.NewCalendarFeedHandler(calendarFeedService)
+	routingTemplateHandler := handlers.NewRoutingTemplateHandler(routingTemplateService)
+	settingHandler := handlers.NewSettingHandler(settingService)
+	machineDowntimeHandler := handlers.NewMachineDowntimeHandler(machineDowntimeService)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService)
+	andonHandler := handlers.NewAndonHandler(andonService)
+	productionReportHandler := handlers.NewProductionReportHandler(productionReportService)
 
 	// Setup Gin router
 	router := gin.Default()
@@ -113,6 +144,16 @@ func main() {
 		googleSheetsHandler,
 		pemPlanHandler,
 		toolpatherFileHandler,
+		calendarHandler,
+		ppicScenarioHandler,
+		ppicBaselineHandler,
+		calendarFeedHandler,
+		routingTemplateHandler,
+		settingHandler,
+		machineDowntimeHandler,
+		maintenanceHandler,
+		andonHandler,
+		productionReportHandler,
 		authService,
 	)
 
@@ -0,0 +1,356 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Andon event status filter constants
+const (
+	AndonEventOpen   = "open"
+	AndonEventClosed = "closed"
+)
+
+// Andon Pareto grouping constants
+const (
+	AndonParetoByReason   = "reason"   // The reason code recorded on the event
+	AndonParetoByCategory = "category" // Its top-level reason code
+)
+
+// AndonReasonCode is a stop reason operators pick when a machine goes down. Codes form a
+// hierarchy through ParentID, e.g. "Mechanical" > "Spindle" > "Bearing noise"
+type AndonReasonCode struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code        string    `gorm:"size:30;uniqueIndex;not null" json:"code"` // e.g. "MECH-SPN"
+	Name        string    `gorm:"size:100;not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description,omitempty"`
+	ParentID    *int64    `gorm:"index" json:"parent_id,omitempty"` // Nil for a top-level category
+	SortOrder   int       `gorm:"not null;default:0" json:"sort_order"`
+	Active      bool      `gorm:"not null;default:true" json:"active"` // Inactive codes are kept for history only
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (AndonReasonCode) TableName() string {
+	return "andon_reason_codes"
+}
+
+// AndonEvent is a machine stop reported from the shop floor. While EndTime is nil the event is
+// open and the machine's status is "down"
+type AndonEvent struct {
+	ID             int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID      int64      `gorm:"index;not null" json:"machine_id"`
+	ReasonCodeID   *int64     `gorm:"index" json:"reason_code_id,omitempty"` // May be filled in after the stop
+	JobOrderID     *int64     `gorm:"index" json:"job_order_id,omitempty"`   // Job running when the machine stopped
+	StartTime      time.Time  `gorm:"index;not null" json:"start_time"`
+	EndTime        *time.Time `json:"end_time,omitempty"`
+	Notes          string     `gorm:"type:text" json:"notes,omitempty"`
+	ReportedBy     int64      `gorm:"not null" json:"reported_by"` // Operator who raised the andon
+	ClosedBy       *int64     `json:"closed_by,omitempty"`
+	PreviousStatus string     `gorm:"size:20" json:"-"` // Machine status restored when the last open event closes
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (AndonEvent) TableName() string {
+	return "andon_events"
+}
+
+// ErrAndonEventClosed is returned when closing an event that is already closed
+var ErrAndonEventClosed = errors.New("andon event is already closed")
+
+// IsOpen reports whether the machine is still down
+func (e *AndonEvent) IsOpen() bool {
+	return e.EndTime == nil
+}
+
+// DurationMinutes returns how long the machine was down, up to now for an open event
+func (e *AndonEvent) DurationMinutes(now time.Time) float64 {
+	end := now
+	if e.EndTime != nil {
+		end = *e.EndTime
+	}
+	if !end.After(e.StartTime) {
+		return 0
+	}
+	return math.Round(end.Sub(e.StartTime).Minutes()*10) / 10
+}
+
+// Request DTOs
+
+type CreateAndonReasonCodeRequest struct {
+	Code        string `json:"code" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	ParentID    *int64 `json:"parent_id"`
+	SortOrder   int    `json:"sort_order"`
+}
+
+type UpdateAndonReasonCodeRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	ParentID    *int64  `json:"parent_id"` // 0 makes it a top-level category
+	SortOrder   *int    `json:"sort_order"`
+	Active      *bool   `json:"active"`
+}
+
+type CreateAndonEventRequest struct {
+	MachineID    int64  `json:"machine_id" binding:"required"`
+	ReasonCodeID *int64 `json:"reason_code_id"` // Required when end_time is given
+	JobOrderID   *int64 `json:"job_order_id"`
+	StartTime    string `json:"start_time"` // Optional: RFC3339, default now
+	EndTime      string `json:"end_time"`   // Optional: logs a stop that is already over
+	Notes        string `json:"notes"`
+}
+
+type UpdateAndonEventRequest struct {
+	ReasonCodeID *int64  `json:"reason_code_id"`
+	JobOrderID   *int64  `json:"job_order_id"` // 0 unlinks the job order
+	StartTime    string  `json:"start_time"`
+	Notes        *string `json:"notes"`
+}
+
+type CloseAndonEventRequest struct {
+	EndTime      string `json:"end_time"`       // Optional: RFC3339, default now
+	ReasonCodeID *int64 `json:"reason_code_id"` // Required unless already set
+	Notes        string `json:"notes"`          // Appended to the event notes
+}
+
+type AndonEventFilterRequest struct {
+	MachineID    int64  `form:"machine_id"`
+	ReasonCodeID int64  `form:"reason_code_id"`
+	JobOrderID   int64  `form:"job_order_id"`
+	Status       string `form:"status"`     // open or closed
+	StartDate    string `form:"start_date"` // YYYY-MM-DD
+	EndDate      string `form:"end_date"`   // YYYY-MM-DD
+}
+
+type AndonParetoRequest struct {
+	MachineID int64  `form:"machine_id"`
+	StartDate string `form:"start_date"` // YYYY-MM-DD, default 30 days before end_date
+	EndDate   string `form:"end_date"`   // YYYY-MM-DD, default today
+	GroupBy   string `form:"group_by"`   // reason (default) or category
+}
+
+// Response DTOs
+
+// AndonReasonNode is a reason code with its sub-codes
+type AndonReasonNode struct {
+	AndonReasonCode
+	Path     string            `json:"path"` // e.g. "Mechanical > Spindle"
+	Children []AndonReasonNode `json:"children"`
+}
+
+// AndonEventView is an event with the names the board shows
+type AndonEventView struct {
+	AndonEvent
+	MachineName     string  `json:"machine_name"`
+	ReasonCode      string  `json:"reason_code,omitempty"`
+	ReasonPath      string  `json:"reason_path,omitempty"`
+	NJO             string  `json:"njo,omitempty"`
+	ReportedByName  string  `json:"reported_by_name,omitempty"`
+	DurationMinutes float64 `json:"duration_minutes"` // Up to now while open
+}
+
+// AndonParetoItem is one bar of the downtime Pareto chart
+type AndonParetoItem struct {
+	ReasonCodeID      *int64  `json:"reason_code_id"` // Nil for stops without a reason yet
+	Code              string  `json:"code"`
+	Name              string  `json:"name"`
+	Path              string  `json:"path"`
+	Events            int     `json:"events"`
+	Minutes           float64 `json:"minutes"`
+	Percent           float64 `json:"percent"`
+	CumulativePercent float64 `json:"cumulative_percent"`
+}
+
+// AndonParetoReport ranks stop reasons by downtime in a date range
+type AndonParetoReport struct {
+	StartDate    string            `json:"start_date"`
+	EndDate      string            `json:"end_date"`
+	MachineID    int64             `json:"machine_id,omitempty"`
+	GroupBy      string            `json:"group_by"`
+	TotalEvents  int               `json:"total_events"`
+	TotalMinutes float64           `json:"total_minutes"`
+	Items        []AndonParetoItem `json:"items"`
+}
+
+// Validation functions
+
+func ValidateAndonEventStatus(status string) bool {
+	return status == AndonEventOpen || status == AndonEventClosed
+}
+
+func ValidateAndonParetoGroup(groupBy string) bool {
+	return groupBy == AndonParetoByReason || groupBy == AndonParetoByCategory
+}
+
+// CheckAndonReasonParent returns an error when parentID can't be the parent of code id,
+// i.e. it is unknown or is the code itself or one of its descendants
+func CheckAndonReasonParent(codes map[int64]AndonReasonCode, id, parentID int64) error {
+	for current := parentID; ; {
+		code, ok := codes[current]
+		if !ok {
+			return fmt.Errorf("parent reason code %d not found", parentID)
+		}
+		if code.ID == id {
+			return fmt.Errorf("reason code %d can't be placed under itself or one of its sub-codes", id)
+		}
+		if code.ParentID == nil {
+			return nil
+		}
+		current = *code.ParentID
+	}
+}
+
+// AndonReasonPath returns the names from the top-level category down to the code, e.g. "Mechanical > Spindle"
+func AndonReasonPath(codes map[int64]AndonReasonCode, id int64) string {
+	var names []string
+	for current, depth := id, 0; depth <= len(codes); depth++ {
+		code, ok := codes[current]
+		if !ok {
+			break
+		}
+		names = append([]string{code.Name}, names...)
+		if code.ParentID == nil {
+			break
+		}
+		current = *code.ParentID
+	}
+	return strings.Join(names, " > ")
+}
+
+// andonReasonRoot returns the top-level category of a code
+func andonReasonRoot(codes map[int64]AndonReasonCode, id int64) AndonReasonCode {
+	code := codes[id]
+	for depth := 0; code.ParentID != nil && depth < len(codes); depth++ {
+		parent, ok := codes[*code.ParentID]
+		if !ok {
+			break
+		}
+		code = parent
+	}
+	return code
+}
+
+// BuildAndonReasonTree nests reason codes under their parents, ordered by sort_order then code.
+// A code whose parent isn't in the list is shown at the top level
+func BuildAndonReasonTree(codes []AndonReasonCode) []AndonReasonNode {
+	byID := make(map[int64]AndonReasonCode, len(codes))
+	for _, code := range codes {
+		byID[code.ID] = code
+	}
+	children := make(map[int64][]AndonReasonCode)
+	var roots []AndonReasonCode
+	for _, code := range codes {
+		if code.ParentID != nil {
+			if _, ok := byID[*code.ParentID]; ok {
+				children[*code.ParentID] = append(children[*code.ParentID], code)
+				continue
+			}
+		}
+		roots = append(roots, code)
+	}
+
+	var build func(level []AndonReasonCode) []AndonReasonNode
+	build = func(level []AndonReasonCode) []AndonReasonNode {
+		sort.SliceStable(level, func(i, j int) bool {
+			if level[i].SortOrder != level[j].SortOrder {
+				return level[i].SortOrder < level[j].SortOrder
+			}
+			return level[i].Code < level[j].Code
+		})
+		nodes := make([]AndonReasonNode, 0, len(level))
+		for _, code := range level {
+			nodes = append(nodes, AndonReasonNode{
+				AndonReasonCode: code,
+				Path:            AndonReasonPath(byID, code.ID),
+				Children:        build(children[code.ID]),
+			})
+		}
+		return nodes
+	}
+	return build(roots)
+}
+
+// BuildAndonPareto totals the downtime of events within [from, to) per reason code, or per
+// top-level category, largest first. Events are clipped to the range and open events count up to now
+func BuildAndonPareto(events []AndonEvent, codes map[int64]AndonReasonCode, groupBy string, from, to, now time.Time) AndonParetoReport {
+	report := AndonParetoReport{
+		StartDate: from.Format("2006-01-02"),
+		EndDate:   to.AddDate(0, 0, -1).Format("2006-01-02"),
+		GroupBy:   groupBy,
+		Items:     []AndonParetoItem{},
+	}
+
+	type bucket struct {
+		item     AndonParetoItem
+		duration time.Duration
+	}
+	buckets := make(map[int64]*bucket) // 0 holds events without a reason
+	var total time.Duration
+	for _, event := range events {
+		start, end := event.StartTime, now
+		if event.EndTime != nil {
+			end = *event.EndTime
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		var key int64
+		if event.ReasonCodeID != nil {
+			if _, ok := codes[*event.ReasonCodeID]; ok {
+				key = *event.ReasonCodeID
+				if groupBy == AndonParetoByCategory {
+					key = andonReasonRoot(codes, key).ID
+				}
+			}
+		}
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{item: AndonParetoItem{Code: "-", Name: "Unclassified", Path: "Unclassified"}}
+			if key != 0 {
+				id := key
+				b.item = AndonParetoItem{ReasonCodeID: &id, Code: codes[key].Code, Name: codes[key].Name, Path: AndonReasonPath(codes, key)}
+			}
+			buckets[key] = b
+		}
+		b.item.Events++
+		b.duration += end.Sub(start)
+		total += end.Sub(start)
+		report.TotalEvents++
+	}
+
+	sorted := make([]*bucket, 0, len(buckets))
+	for _, b := range buckets {
+		sorted = append(sorted, b)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].duration != sorted[j].duration {
+			return sorted[i].duration > sorted[j].duration
+		}
+		return sorted[i].item.Path < sorted[j].item.Path
+	})
+
+	var cumulative time.Duration
+	for _, b := range sorted {
+		cumulative += b.duration
+		b.item.Minutes = math.Round(b.duration.Minutes()*10) / 10
+		b.item.Percent = math.Round(float64(b.duration)/float64(total)*1000) / 10
+		b.item.CumulativePercent = math.Round(float64(cumulative)/float64(total)*1000) / 10
+		report.Items = append(report.Items, b.item)
+	}
+	report.TotalMinutes = math.Round(total.Minutes()*10) / 10
+	return report
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Auto-schedule DTOs
+
+type AutoScheduleRequest struct {
+	ScheduleIDs []int64       `json:"schedule_ids"` // Optional: only these pending schedules (default: all pending)
+	StartFrom   string        `json:"start_from"`   // Optional: RFC3339 or YYYY-MM-DD (default: now)
+	Versions    map[int64]int `json:"versions"`     // Commit only: schedule ID -> version returned by the preview
+}
+
+// AutoScheduleOperation is one machine assignment placed by the auto-scheduler
+type AutoScheduleOperation struct {
+	AssignmentID   int64     `json:"assignment_id"`
+	MachineID      int64     `json:"machine_id"`
+	MachineName    string    `json:"machine_name"`
+	MachineCode    string    `json:"machine_code"`
+	Sequence       int       `json:"sequence"`
+	TargetHours    float64   `json:"target_hours"`
+	ScheduledStart time.Time `json:"scheduled_start"`
+	ScheduledEnd   time.Time `json:"scheduled_end"`
+}
+
+// AutoScheduledTask is a PPIC schedule with its proposed dates and machine windows
+type AutoScheduledTask struct {
+	ScheduleID         int64                   `json:"schedule_id"`
+	NJO                string                  `json:"njo"`
+	PartName           string                  `json:"part_name"`
+	Priority           string                  `json:"priority"`
+	CurrentStartDate   time.Time               `json:"current_start_date"`
+	CurrentFinishDate  time.Time               `json:"current_finish_date"`
+	ProposedStartDate  time.Time               `json:"proposed_start_date"`
+	ProposedFinishDate time.Time               `json:"proposed_finish_date"`
+	Operations         []AutoScheduleOperation `json:"operations"`
+	Version            int                     `json:"version"` // Version of the schedule the plan was built from
+}
+
+// AutoScheduleSkipped is a schedule the auto-scheduler could not place
+type AutoScheduleSkipped struct {
+	ScheduleID int64  `json:"schedule_id"`
+	NJO        string `json:"njo"`
+	Reason     string `json:"reason"`
+}
+
+type AutoSchedulePlan struct {
+	StartFrom  time.Time             `json:"start_from"`
+	PlanFinish *time.Time            `json:"plan_finish"`
+	Scheduled  []AutoScheduledTask   `json:"scheduled"`
+	Skipped    []AutoScheduleSkipped `json:"skipped"`
+	Committed  bool                  `json:"committed"`
+}
+
+// CheckAutoScheduleVersions compares the schedules of a plan with the versions the preview returned.
+// A schedule missing from versions gives ErrVersionRequired, one changed since gives ErrStaleVersion
+func CheckAutoScheduleVersions(tasks []AutoScheduledTask, versions map[int64]int) error {
+	for _, task := range tasks {
+		version, ok := versions[task.ScheduleID]
+		if !ok {
+			return ErrVersionRequired
+		}
+		if version != task.Version {
+			return ErrStaleVersion
+		}
+	}
+	return nil
+}
@@ -0,0 +1,481 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Calendar exception type constants
+const (
+	CalendarExceptionHoliday    = "holiday"     // Plant closed (e.g. Lebaran)
+	CalendarExceptionWorkingDay = "working_day" // Special working day (e.g. Saturday overtime)
+)
+
+// maxCalendarScanDays bounds calendar walks so a calendar without working time can't loop forever
+const maxCalendarScanDays = 3660
+
+// PlantShift is a recurring weekly shift. MachineID nil means the shift applies plant-wide;
+// a machine with its own shifts ignores the plant-wide ones
+type PlantShift struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID *int64    `gorm:"index" json:"machine_id,omitempty"`
+	Name      string    `gorm:"size:100" json:"name"`              // e.g. "Shift 1"
+	DayOfWeek int       `gorm:"not null" json:"day_of_week"`       // 0=Sunday ... 6=Saturday
+	StartTime string    `gorm:"size:5;not null" json:"start_time"` // HH:MM
+	EndTime   string    `gorm:"size:5;not null" json:"end_time"`   // HH:MM, earlier than start = overnight
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (PlantShift) TableName() string {
+	return "plant_shifts"
+}
+
+// CalendarException overrides the weekly shifts on a single date. MachineID nil means plant-wide
+type CalendarException struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID *int64    `gorm:"index" json:"machine_id,omitempty"`
+	Date      time.Time `gorm:"type:date;index;not null" json:"date"`
+	Type      string    `gorm:"size:20;not null" json:"type"` // holiday, working_day
+	Name      string    `gorm:"size:255" json:"name"`
+	StartTime string    `gorm:"size:5" json:"start_time,omitempty"` // working_day only
+	EndTime   string    `gorm:"size:5" json:"end_time,omitempty"`   // working_day only
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (CalendarException) TableName() string {
+	return "calendar_exceptions"
+}
+
+// Request DTOs
+
+type CreatePlantShiftRequest struct {
+	MachineID *int64 `json:"machine_id"`
+	Name      string `json:"name"`
+	DayOfWeek *int   `json:"day_of_week" binding:"required,min=0,max=6"`
+	StartTime string `json:"start_time" binding:"required"`
+	EndTime   string `json:"end_time" binding:"required"`
+}
+
+type UpdatePlantShiftRequest struct {
+	Name      string `json:"name"`
+	DayOfWeek *int   `json:"day_of_week" binding:"omitempty,min=0,max=6"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+type CreateCalendarExceptionRequest struct {
+	MachineID *int64 `json:"machine_id"`
+	Date      string `json:"date" binding:"required"`
+	Type      string `json:"type" binding:"required"`
+	Name      string `json:"name"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time"`
+}
+
+type CalendarFilterRequest struct {
+	StartDate string `form:"start_date" binding:"required"`
+	EndDate   string `form:"end_date" binding:"required"`
+	MachineID int64  `form:"machine_id"`
+}
+
+// Response DTOs
+
+type CalendarDay struct {
+	Date         string  `json:"date"`
+	IsWorkingDay bool    `json:"is_working_day"`
+	WorkingHours float64 `json:"working_hours"`
+	Note         string  `json:"note,omitempty"`
+}
+
+// Validation functions
+
+func ValidateCalendarExceptionType(exceptionType string) bool {
+	return exceptionType == CalendarExceptionHoliday || exceptionType == CalendarExceptionWorkingDay
+}
+
+// ParseShiftClock parses "HH:MM" into minutes after midnight
+func ParseShiftClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q. Use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// shiftWindow is a working window in minutes after midnight; end may exceed 1440 for overnight shifts
+type shiftWindow struct {
+	start int
+	end   int
+	name  string
+}
+
+// ShiftPeriod is one shift on a given date
+type ShiftPeriod struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+func newShiftWindow(startTime, endTime string) (shiftWindow, error) {
+	start, err := ParseShiftClock(startTime)
+	if err != nil {
+		return shiftWindow{}, err
+	}
+	end, err := ParseShiftClock(endTime)
+	if err != nil {
+		return shiftWindow{}, err
+	}
+	if end == start {
+		return shiftWindow{}, errors.New("shift start and end time must be different")
+	}
+	if end < start {
+		end += 24 * 60
+	}
+	return shiftWindow{start: start, end: end}, nil
+}
+
+// WorkingCalendar answers working-time questions for the plant or a single machine
+type WorkingCalendar struct {
+	weekly  [7][]shiftWindow
+	holiday map[string]string
+	special map[string][]shiftWindow
+	notes   map[string]string
+	blocked map[string]string // Days taken by machine downtime, see WithDowntime
+}
+
+// DefaultPlantShifts is used when no shifts are configured: Monday-Friday 08:00-17:00
+func DefaultPlantShifts() []PlantShift {
+	var shifts []PlantShift
+	for day := int(time.Monday); day <= int(time.Friday); day++ {
+		shifts = append(shifts, PlantShift{Name: "Default", DayOfWeek: day, StartTime: "08:00", EndTime: "17:00"})
+	}
+	return shifts
+}
+
+// NewWorkingCalendar builds a calendar from weekly shifts and date exceptions.
+// Invalid shift rows are skipped; an empty shift list falls back to DefaultPlantShifts
+func NewWorkingCalendar(shifts []PlantShift, exceptions []CalendarException) *WorkingCalendar {
+	if len(shifts) == 0 {
+		shifts = DefaultPlantShifts()
+	}
+
+	c := &WorkingCalendar{
+		holiday: make(map[string]string),
+		special: make(map[string][]shiftWindow),
+		notes:   make(map[string]string),
+	}
+
+	for _, shift := range shifts {
+		if shift.DayOfWeek < 0 || shift.DayOfWeek > 6 {
+			continue
+		}
+		window, err := newShiftWindow(shift.StartTime, shift.EndTime)
+		if err != nil {
+			continue
+		}
+		window.name = shift.Name
+		c.weekly[shift.DayOfWeek] = append(c.weekly[shift.DayOfWeek], window)
+	}
+	for day := range c.weekly {
+		sortWindows(c.weekly[day])
+	}
+
+	for _, exception := range exceptions {
+		key := exception.Date.Format("2006-01-02")
+		switch exception.Type {
+		case CalendarExceptionHoliday:
+			c.holiday[key] = exception.Name
+		case CalendarExceptionWorkingDay:
+			window, err := newShiftWindow(exception.StartTime, exception.EndTime)
+			if err != nil {
+				continue
+			}
+			window.name = exception.Name
+			c.special[key] = append(c.special[key], window)
+			sortWindows(c.special[key])
+		}
+		if exception.Name != "" {
+			c.notes[key] = exception.Name
+		}
+	}
+
+	return c
+}
+
+// AllDaysCalendar treats every day as 24 working hours, i.e. plain calendar-day math
+func AllDaysCalendar() *WorkingCalendar {
+	c := &WorkingCalendar{
+		holiday: make(map[string]string),
+		special: make(map[string][]shiftWindow),
+		notes:   make(map[string]string),
+	}
+	for day := range c.weekly {
+		c.weekly[day] = []shiftWindow{{start: 0, end: 24 * 60}}
+	}
+	return c
+}
+
+func sortWindows(windows []shiftWindow) {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start < windows[j].start })
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// windowsOn returns the working windows starting on the given date.
+// A special working day overrides both the weekly pattern and a holiday; downtime overrides all
+func (c *WorkingCalendar) windowsOn(date time.Time) []shiftWindow {
+	key := date.Format("2006-01-02")
+	if _, ok := c.blocked[key]; ok {
+		return nil
+	}
+	if windows, ok := c.special[key]; ok {
+		return windows
+	}
+	if _, ok := c.holiday[key]; ok {
+		return nil
+	}
+	return c.weekly[date.Weekday()]
+}
+
+// IsWorkingDay reports whether any shift starts on the date
+func (c *WorkingCalendar) IsWorkingDay(date time.Time) bool {
+	return len(c.windowsOn(date)) > 0
+}
+
+// WorkingHoursOn returns the total shift hours starting on the date
+func (c *WorkingCalendar) WorkingHoursOn(date time.Time) float64 {
+	minutes := 0
+	for _, w := range c.windowsOn(date) {
+		minutes += w.end - w.start
+	}
+	return float64(minutes) / 60
+}
+
+// ShiftsOn returns the shifts starting on the date, in start order. Unnamed shifts are called "Shift 1", "Shift 2", ...
+func (c *WorkingCalendar) ShiftsOn(date time.Time) []ShiftPeriod {
+	day := startOfDay(date)
+	windows := c.windowsOn(day)
+	shifts := make([]ShiftPeriod, 0, len(windows))
+	for i, w := range windows {
+		name := w.name
+		if name == "" {
+			name = fmt.Sprintf("Shift %d", i+1)
+		}
+		shifts = append(shifts, ShiftPeriod{
+			Name:  name,
+			Start: day.Add(time.Duration(w.start) * time.Minute),
+			End:   day.Add(time.Duration(w.end) * time.Minute),
+		})
+	}
+	return shifts
+}
+
+// Day describes a single date for display
+func (c *WorkingCalendar) Day(date time.Time) CalendarDay {
+	key := date.Format("2006-01-02")
+	note := c.notes[key]
+	if downtime, ok := c.blocked[key]; ok {
+		note = downtime
+	}
+	return CalendarDay{
+		Date:         key,
+		IsWorkingDay: c.IsWorkingDay(date),
+		WorkingHours: c.WorkingHoursOn(date),
+		Note:         note,
+	}
+}
+
+// NextWorkingDay returns the date itself if it is a working day, otherwise the next working day
+func (c *WorkingCalendar) NextWorkingDay(date time.Time) time.Time {
+	d := date
+	for i := 0; i < maxCalendarScanDays; i++ {
+		if c.IsWorkingDay(d) {
+			return d
+		}
+		d = d.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// AddWorkingDays moves the date by n working days (negative n moves backwards).
+// n = 0 returns the date unchanged
+func (c *WorkingCalendar) AddWorkingDays(date time.Time, n int) time.Time {
+	step := 1
+	if n < 0 {
+		step = -1
+		n = -n
+	}
+
+	d := date
+	for moved, scanned := 0, 0; moved < n; scanned++ {
+		if scanned >= maxCalendarScanDays {
+			return date.AddDate(0, 0, step*n)
+		}
+		d = d.AddDate(0, 0, step)
+		if c.IsWorkingDay(d) {
+			moved++
+		}
+	}
+	return d
+}
+
+// WorkingDaysOffset counts the working days from one date to another, the first date excluded;
+// negative when to is earlier
+func (c *WorkingCalendar) WorkingDaysOffset(from, to time.Time) int {
+	if to.Before(from) {
+		return -c.WorkingDaysOffset(to, from)
+	}
+	days := 0
+	for d, i := from.AddDate(0, 0, 1), 0; !d.After(to) && i < maxCalendarScanDays; d, i = d.AddDate(0, 0, 1), i+1 {
+		if c.IsWorkingDay(d) {
+			days++
+		}
+	}
+	return days
+}
+
+// ShiftWorkingDays moves a time by n working days, keeping its time of day. A time on a
+// non-working day counts from the next working day
+func (c *WorkingCalendar) ShiftWorkingDays(t time.Time, n int) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return c.AddWorkingDays(c.NextWorkingDay(day), n).Add(t.Sub(day))
+}
+
+// WorkingDaysBetween counts working days from start to finish inclusive, with a minimum of 1
+func (c *WorkingCalendar) WorkingDaysBetween(start, finish time.Time) int {
+	count := 0
+	for d, i := start, 0; !d.After(finish) && i < maxCalendarScanDays; d, i = d.AddDate(0, 0, 1), i+1 {
+		if c.IsWorkingDay(d) {
+			count++
+		}
+	}
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+// AddWorkingHours returns the moment the given number of working hours is used up, starting at start
+func (c *WorkingCalendar) AddWorkingHours(start time.Time, hours float64) time.Time {
+	if hours <= 0 {
+		return start
+	}
+
+	remaining := time.Duration(hours * float64(time.Hour))
+	cursor := start
+	// Start one day earlier to pick up overnight shifts running into the start day
+	day := startOfDay(start).AddDate(0, 0, -1)
+
+	for i := 0; i < maxCalendarScanDays; i++ {
+		for _, w := range c.windowsOn(day) {
+			windowStart := day.Add(time.Duration(w.start) * time.Minute)
+			windowEnd := day.Add(time.Duration(w.end) * time.Minute)
+			if !windowEnd.After(cursor) {
+				continue
+			}
+			if windowStart.After(cursor) {
+				cursor = windowStart
+			}
+			available := windowEnd.Sub(cursor)
+			if available >= remaining {
+				return cursor.Add(remaining)
+			}
+			remaining -= available
+			cursor = windowEnd
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return start.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+// WorkingHoursBetween returns the working hours between start and end
+func (c *WorkingCalendar) WorkingHoursBetween(start, end time.Time) float64 {
+	if !end.After(start) {
+		return 0
+	}
+
+	var total time.Duration
+	// Start one day earlier to pick up overnight shifts running into the start day
+	for day := startOfDay(start).AddDate(0, 0, -1); day.Before(end); day = day.AddDate(0, 0, 1) {
+		for _, w := range c.windowsOn(day) {
+			windowStart := day.Add(time.Duration(w.start) * time.Minute)
+			windowEnd := day.Add(time.Duration(w.end) * time.Minute)
+			if windowStart.Before(start) {
+				windowStart = start
+			}
+			if windowEnd.After(end) {
+				windowEnd = end
+			}
+			if windowEnd.After(windowStart) {
+				total += windowEnd.Sub(windowStart)
+			}
+		}
+	}
+	return total.Hours()
+}
+
+// NextWorkingTime returns t if it falls inside a shift, otherwise the start of the next shift
+func (c *WorkingCalendar) NextWorkingTime(t time.Time) time.Time {
+	// Start one day earlier to pick up overnight shifts running into t's day
+	day := startOfDay(t).AddDate(0, 0, -1)
+	for i := 0; i < maxCalendarScanDays; i++ {
+		for _, w := range c.windowsOn(day) {
+			windowStart := day.Add(time.Duration(w.start) * time.Minute)
+			windowEnd := day.Add(time.Duration(w.end) * time.Minute)
+			if !windowEnd.After(t) {
+				continue
+			}
+			if windowStart.After(t) {
+				return windowStart
+			}
+			return t
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// LinkConstraintDate returns the earliest date allowed by the link and whether it constrains the
+// target's start (FS, SS) or finish (FF, SF). Lag is counted in working days
+func (c *WorkingCalendar) LinkConstraintDate(linkType string, lagDays int, sourceStart, sourceFinish time.Time) (time.Time, bool) {
+	switch linkType {
+	case LinkTypeStartToStart:
+		return c.AddWorkingDays(sourceStart, lagDays), true
+	case LinkTypeFinishToFinish:
+		return c.AddWorkingDays(sourceFinish, lagDays), false
+	case LinkTypeStartToFinish:
+		return c.AddWorkingDays(sourceStart, lagDays), false
+	default:
+		// Finish-to-start: target starts the working day after source finishes
+		return c.AddWorkingDays(sourceFinish, 1+lagDays), true
+	}
+}
+
+// IsLinkSatisfied checks whether the target dates respect the link constraint on this calendar
+func (c *WorkingCalendar) IsLinkSatisfied(linkType string, lagDays int, sourceStart, sourceFinish, targetStart, targetFinish time.Time) bool {
+	constraint, onStart := c.LinkConstraintDate(linkType, lagDays, sourceStart, sourceFinish)
+	if onStart {
+		return !targetStart.Before(constraint)
+	}
+	return !targetFinish.Before(constraint)
+}
+
+// LinkedTargetDates returns new target dates on the link constraint, landing on working days
+// and preserving the target's number of working days
+func (c *WorkingCalendar) LinkedTargetDates(linkType string, lagDays int, sourceStart, sourceFinish, targetStart, targetFinish time.Time) (time.Time, time.Time) {
+	workingDays := c.WorkingDaysBetween(targetStart, targetFinish)
+	constraint, onStart := c.LinkConstraintDate(linkType, lagDays, sourceStart, sourceFinish)
+	if onStart {
+		start := c.NextWorkingDay(constraint)
+		return start, c.AddWorkingDays(start, workingDays-1)
+	}
+	finish := c.NextWorkingDay(constraint)
+	return c.AddWorkingDays(finish, -(workingDays - 1)), finish
+}
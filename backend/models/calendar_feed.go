@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// CalendarFeedToken authenticates a user's read-only iCalendar feeds. Calendar apps can't send
+// the JWT header, so the token travels in the feed URL; only its SHA-256 hash is stored
+type CalendarFeedToken struct {
+	ID         int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     int64      `gorm:"uniqueIndex;not null" json:"user_id"` // One active token per user
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (CalendarFeedToken) TableName() string {
+	return "calendar_feed_tokens"
+}
+
+// CalendarFeedTokenResponse is returned when a token is issued; the token is shown only once
+type CalendarFeedTokenResponse struct {
+	Token           string    `json:"token"`
+	UserFeedPath    string    `json:"user_feed_path"`
+	MachineFeedPath string    `json:"machine_feed_path"` // Replace {machine_id}
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// CalendarFeedStatus tells a user whether they have an active feed token
+type CalendarFeedStatus struct {
+	Active     bool       `json:"active"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
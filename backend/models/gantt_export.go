@@ -0,0 +1,21 @@
+package models
+
+// Gantt export format constants
+const (
+	GanttFormatJSON = "json"
+	GanttFormatCSV  = "csv"
+	GanttFormatXLSX = "xlsx"
+	GanttFormatPDF  = "pdf"
+)
+
+// GanttExport is a rendered Gantt chart file
+type GanttExport struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// IsGanttExportFormat reports whether format is a file export rather than the JSON response
+func IsGanttExportFormat(format string) bool {
+	return format == GanttFormatCSV || format == GanttFormatXLSX || format == GanttFormatPDF
+}
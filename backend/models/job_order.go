@@ -15,6 +15,7 @@ type JobOrder struct {
 	OperatorID   *int64     `json:"operator_id,omitempty"`
 	OperatorName string     `json:"operator_name,omitempty"` // For JOIN queries
 	Status       string     `json:"status"`
+	Version      int        `gorm:"not null;default:1" json:"version"` // Bumped on every update
 	CreatedAt    time.Time  `json:"created_at"`
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
 	UpdatedAt    time.Time  `json:"updated_at"`
@@ -58,6 +59,7 @@ type UpdateJobOrderRequest struct {
 	Deadline   string `json:"deadline"`
 	OperatorID *int64 `json:"operator_id"`
 	Status     string `json:"status"`
+	Version    *int   `json:"version"` // Version last read; the If-Match header takes precedence
 }
 
 // UpdateProcessStageRequest for updating process stage
@@ -2,6 +2,15 @@ package models
 
 import "time"
 
+// Machine status constants
+const (
+	MachineStatusActive      = "active"
+	MachineStatusInactive    = "inactive"
+	MachineStatusMaintenance = "maintenance"
+	MachineStatusOffline     = "offline"
+	MachineStatusDown        = "down" // Stopped by an open andon event
+)
+
 // Machine represents a production machine
 type Machine struct {
 	ID          int64      `json:"id"`
@@ -32,6 +41,12 @@ type UpdateMachineRequest struct {
 	Status      string `json:"status"`
 }
 
+// IsAvailable reports whether the machine can take work; only active machines have capacity.
+// A machine down on an andon stop keeps its capacity; the stop is logged as andon downtime
+func (m *Machine) IsAvailable() bool {
+	return m.Status == MachineStatusActive || m.Status == MachineStatusDown || m.Status == ""
+}
+
 type DeleteMachineRequest struct {
 	MachineCode string `json:"machine_code" binding:"required"`
 	Reason string `json:"reason" binding:"required"`
@@ -0,0 +1,233 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Machine downtime type constants
+const (
+	DowntimePlannedMaintenance = "planned_maintenance"
+	DowntimeBreakdown          = "breakdown"
+	DowntimeCalibration        = "calibration"
+)
+
+// MachineDowntime is a period a machine can't run: no machine assignment may overlap it
+type MachineDowntime struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID int64     `gorm:"index;not null" json:"machine_id"`
+	Type      string    `gorm:"size:30;not null" json:"type"` // planned_maintenance, breakdown, calibration
+	Reason    string    `gorm:"size:255" json:"reason"`
+	StartTime time.Time `gorm:"index;not null" json:"start_time"`
+	EndTime   time.Time `gorm:"index;not null" json:"end_time"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (MachineDowntime) TableName() string {
+	return "machine_downtimes"
+}
+
+// Describe names the downtime for messages, e.g. "Breakdown (spindle bearing) 2025-01-06 08:00 - 2025-01-07 17:00"
+func (d MachineDowntime) Describe() string {
+	label := GetDowntimeTypeName(d.Type)
+	if d.Reason != "" {
+		label += " (" + d.Reason + ")"
+	}
+	return fmt.Sprintf("%s %s - %s", label, d.StartTime.Format("2006-01-02 15:04"), d.EndTime.Format("2006-01-02 15:04"))
+}
+
+// Request DTOs
+
+type CreateMachineDowntimeRequest struct {
+	MachineID int64  `json:"machine_id" binding:"required"`
+	Type      string `json:"type" binding:"required"`
+	Reason    string `json:"reason"`
+	StartTime string `json:"start_time" binding:"required"` // RFC3339 or YYYY-MM-DDTHH:MM
+	EndTime   string `json:"end_time" binding:"required"`
+}
+
+type UpdateMachineDowntimeRequest struct {
+	Type      string  `json:"type"`
+	Reason    *string `json:"reason"`
+	StartTime string  `json:"start_time"`
+	EndTime   string  `json:"end_time"`
+}
+
+type MachineDowntimeFilterRequest struct {
+	MachineID int64  `form:"machine_id"`
+	Type      string `form:"type"`
+	StartDate string `form:"start_date"` // Downtime overlapping start_date .. end_date
+	EndDate   string `form:"end_date"`
+}
+
+// Response DTOs
+
+// MachineDowntimeResult is a saved downtime with the scheduled machine assignments it overlaps.
+// Those are not moved: they have to be rescheduled or moved to another machine
+type MachineDowntimeResult struct {
+	Downtime            MachineDowntime           `json:"downtime"`
+	AffectedAssignments []MachineAssignmentWindow `json:"affected_assignments"`
+}
+
+// GanttDowntimeBar is a blocked bar in a machine row of the Gantt chart
+type GanttDowntimeBar struct {
+	BarID     string    `json:"bar_id"` // "downtime-{id}"
+	Text      string    `json:"text"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+func NewGanttDowntimeBar(d MachineDowntime) GanttDowntimeBar {
+	text := GetDowntimeTypeName(d.Type)
+	if d.Reason != "" {
+		text += ": " + d.Reason
+	}
+	return GanttDowntimeBar{
+		BarID:     fmt.Sprintf("downtime-%d", d.ID),
+		Text:      text,
+		Type:      d.Type,
+		Reason:    d.Reason,
+		StartTime: d.StartTime,
+		EndTime:   d.EndTime,
+	}
+}
+
+// Validation functions
+
+func ValidateDowntimeType(downtimeType string) bool {
+	switch downtimeType {
+	case DowntimePlannedMaintenance, DowntimeBreakdown, DowntimeCalibration:
+		return true
+	}
+	return false
+}
+
+func GetDowntimeTypeName(downtimeType string) string {
+	switch downtimeType {
+	case DowntimePlannedMaintenance:
+		return "Planned maintenance"
+	case DowntimeBreakdown:
+		return "Breakdown"
+	case DowntimeCalibration:
+		return "Calibration"
+	}
+	return downtimeType
+}
+
+// ParseDowntimeTime parses a downtime start or end. Accepts RFC3339 or "YYYY-MM-DDTHH:MM" (datetime-local input)
+func ParseDowntimeTime(field, value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s format. Use RFC3339 (e.g. 2024-01-01T08:00:00Z)", field)
+}
+
+// ValidateDowntimeWindow checks that a downtime ends after it starts
+func ValidateDowntimeWindow(start, end time.Time) error {
+	if !end.After(start) {
+		return errors.New("end_time must be after start_time")
+	}
+	return nil
+}
+
+// OverlappingDowntime returns the first downtime (by start) overlapping [start, end), or nil
+func OverlappingDowntime(downtimes []MachineDowntime, start, end time.Time) *MachineDowntime {
+	var first *MachineDowntime
+	for i := range downtimes {
+		d := &downtimes[i]
+		if WindowsOverlap(start, end, d.StartTime, d.EndTime) && (first == nil || d.StartTime.Before(first.StartTime)) {
+			first = d
+		}
+	}
+	return first
+}
+
+// DowntimeWindows turns downtime into machine windows the auto-scheduler books around
+func DowntimeWindows(downtimes []MachineDowntime) []MachineAssignmentWindow {
+	windows := make([]MachineAssignmentWindow, 0, len(downtimes))
+	for _, d := range downtimes {
+		windows = append(windows, MachineAssignmentWindow{
+			PartName:       GetDowntimeTypeName(d.Type),
+			ScheduledStart: d.StartTime,
+			ScheduledEnd:   d.EndTime,
+		})
+	}
+	return windows
+}
+
+// downtimeCovers reports whether downtime (sorted by start) covers all of [from, to) without a gap
+func downtimeCovers(downtimes []MachineDowntime, from, to time.Time) bool {
+	cursor := from
+	for _, d := range downtimes {
+		if !cursor.Before(to) {
+			break
+		}
+		if d.StartTime.After(cursor) {
+			return false
+		}
+		if d.EndTime.After(cursor) {
+			cursor = d.EndTime
+		}
+	}
+	return !cursor.Before(to)
+}
+
+// WithDowntime returns a copy of the calendar on which a day is not a working day when all of its
+// working time is covered by the downtime of one machine
+func (c *WorkingCalendar) WithDowntime(downtimes []MachineDowntime) *WorkingCalendar {
+	if len(downtimes) == 0 {
+		return c
+	}
+
+	blocked := make(map[string]string, len(c.blocked))
+	for key, note := range c.blocked {
+		blocked[key] = note
+	}
+
+	byMachine := make(map[int64][]MachineDowntime)
+	for _, d := range downtimes {
+		byMachine[d.MachineID] = append(byMachine[d.MachineID], d)
+	}
+	for _, list := range byMachine {
+		sort.Slice(list, func(i, j int) bool { return list[i].StartTime.Before(list[j].StartTime) })
+		for _, d := range list {
+			// Start a day early: an overnight shift of the previous day can fall in the downtime
+			day := startOfDay(d.StartTime).AddDate(0, 0, -1)
+			for i := 0; day.Before(d.EndTime) && i < maxCalendarScanDays; day, i = day.AddDate(0, 0, 1), i+1 {
+				key := day.Format("2006-01-02")
+				if _, ok := blocked[key]; ok {
+					continue
+				}
+				windows := c.windowsOn(day)
+				if len(windows) == 0 {
+					continue
+				}
+				covered := true
+				for _, w := range windows {
+					from := day.Add(time.Duration(w.start) * time.Minute)
+					to := day.Add(time.Duration(w.end) * time.Minute)
+					if !downtimeCovers(list, from, to) {
+						covered = false
+						break
+					}
+				}
+				if covered {
+					blocked[key] = GetDowntimeTypeName(d.Type)
+				}
+			}
+		}
+	}
+
+	withDowntime := *c
+	withDowntime.blocked = blocked
+	return &withDowntime
+}
@@ -0,0 +1,52 @@
+package models
+
+// Utilization report periods
+const (
+	UtilizationPeriodDay  = "day"
+	UtilizationPeriodWeek = "week"
+)
+
+type MachineUtilizationFilterRequest struct {
+	StartDate   string `form:"start_date"`   // YYYY-MM-DD, required
+	EndDate     string `form:"end_date"`     // YYYY-MM-DD inclusive, required
+	Period      string `form:"period"`       // "day" (default) or "week" (Monday to Sunday)
+	MachineType string `form:"machine_type"` // Case-insensitive
+	Location    string `form:"location"`     // Case-insensitive
+	MachineID   int64  `form:"machine_id"`
+}
+
+// MachineUtilizationPeriod is the load of one machine in one day or week.
+// Planned hours spread each assignment's target hours over the working time of its scheduled window;
+// actual hours are the recorded actual start/end (still running assignments count up to now)
+type MachineUtilizationPeriod struct {
+	PeriodStart        string  `json:"period_start"`
+	PeriodEnd          string  `json:"period_end"`
+	PlannedHours       float64 `json:"planned_hours"`
+	ActualHours        float64 `json:"actual_hours"`
+	AvailableHours     float64 `json:"available_hours"`     // Working calendar hours; 0 unless the machine is active
+	LoadPercent        float64 `json:"load_percent"`        // Planned / available
+	UtilizationPercent float64 `json:"utilization_percent"` // Actual / available
+	Overloaded         bool    `json:"overloaded"`          // Planned exceeds available
+}
+
+type MachineUtilization struct {
+	MachineID         int64                      `json:"machine_id"`
+	MachineCode       string                     `json:"machine_code"`
+	MachineName       string                     `json:"machine_name"`
+	MachineType       string                     `json:"machine_type"`
+	Location          string                     `json:"location"`
+	Status            string                     `json:"status"`
+	Periods           []MachineUtilizationPeriod `json:"periods"`
+	Total             MachineUtilizationPeriod   `json:"total"`
+	OverloadedPeriods int                        `json:"overloaded_periods"`
+}
+
+type MachineUtilizationReport struct {
+	StartDate           string               `json:"start_date"`
+	EndDate             string               `json:"end_date"`
+	Period              string               `json:"period"`
+	Machines            []MachineUtilization `json:"machines"`
+	OverloadedMachines  int                  `json:"overloaded_machines"`
+	TotalPlannedHours   float64              `json:"total_planned_hours"`
+	TotalAvailableHours float64              `json:"total_available_hours"`
+}
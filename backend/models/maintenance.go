@@ -0,0 +1,278 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Maintenance work order status constants
+const (
+	WorkOrderStatusOpen       = "open"
+	WorkOrderStatusInProgress = "in_progress"
+	WorkOrderStatusCompleted  = "completed"
+	WorkOrderStatusCancelled  = "cancelled"
+)
+
+// Maintenance work order trigger constants
+const (
+	WorkOrderTriggerCalendar = "calendar"  // Interval days passed
+	WorkOrderTriggerRunHours = "run_hours" // Interval run hours reached
+	WorkOrderTriggerManual   = "manual"    // Raised by hand, e.g. done early
+)
+
+// MaintenancePlan is a preventive maintenance routine of a machine. It is due every IntervalDays
+// calendar days and/or every IntervalRunHours of actual machine run time, whichever comes first
+type MaintenancePlan struct {
+	ID               int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID        int64     `gorm:"index;not null" json:"machine_id"`
+	Name             string    `gorm:"size:255;not null" json:"name"` // e.g. "Spindle lubrication"
+	Description      string    `gorm:"type:text" json:"description"`
+	IntervalDays     *int      `json:"interval_days,omitempty"`
+	IntervalRunHours *float64  `json:"interval_run_hours,omitempty"`
+	EstimatedHours   float64   `json:"estimated_hours"` // Expected duration of the work
+	Active           bool      `gorm:"not null;default:true" json:"active"`
+	LastCompletedAt  time.Time `gorm:"not null" json:"last_completed_at"` // Start of the current interval
+	CreatedBy        int64     `json:"created_by"`
+	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (MaintenancePlan) TableName() string {
+	return "maintenance_plans"
+}
+
+// MaintenanceWorkOrder is one execution of a maintenance plan, generated when the plan is due.
+// Completing it records the technician and notes and restarts the plan's interval
+type MaintenanceWorkOrder struct {
+	ID          int64      `gorm:"primaryKey;autoIncrement" json:"id"`
+	PlanID      int64      `gorm:"index;not null" json:"plan_id"`
+	MachineID   int64      `gorm:"index;not null" json:"machine_id"`
+	Title       string     `gorm:"size:255;not null" json:"title"`
+	Status      string     `gorm:"size:20;index;not null" json:"status"` // open, in_progress, completed, cancelled
+	Trigger     string     `gorm:"size:20;not null" json:"trigger"`      // calendar, run_hours, manual
+	DueDate     *time.Time `gorm:"type:date" json:"due_date,omitempty"`  // Calendar due date of the plan
+	RunHours    float64    `json:"run_hours"`                            // Run hours since the last PM when generated
+	Technician  string     `gorm:"size:100" json:"technician,omitempty"` // Who did the work
+	Notes       string     `gorm:"type:text" json:"notes,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CompletedBy *int64     `json:"completed_by,omitempty"` // User who recorded the completion
+	CreatedBy   *int64     `json:"created_by,omitempty"`   // Nil when generated
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (MaintenanceWorkOrder) TableName() string {
+	return "maintenance_work_orders"
+}
+
+// IsOpen reports whether the work order still has to be done
+func (w *MaintenanceWorkOrder) IsOpen() bool {
+	return w.Status == WorkOrderStatusOpen || w.Status == WorkOrderStatusInProgress
+}
+
+// Request DTOs
+
+type CreateMaintenancePlanRequest struct {
+	MachineID        int64    `json:"machine_id" binding:"required"`
+	Name             string   `json:"name" binding:"required"`
+	Description      string   `json:"description"`
+	IntervalDays     *int     `json:"interval_days" binding:"omitempty,min=1"`
+	IntervalRunHours *float64 `json:"interval_run_hours" binding:"omitempty,gt=0"`
+	EstimatedHours   float64  `json:"estimated_hours" binding:"min=0"`
+	LastCompletedAt  string   `json:"last_completed_at"` // Optional: RFC3339 or YYYY-MM-DD, default now
+}
+
+type UpdateMaintenancePlanRequest struct {
+	Name             string   `json:"name"`
+	Description      *string  `json:"description"`
+	IntervalDays     *int     `json:"interval_days" binding:"omitempty,min=0"`      // 0 removes the calendar interval
+	IntervalRunHours *float64 `json:"interval_run_hours" binding:"omitempty,min=0"` // 0 removes the run-hour interval
+	EstimatedHours   *float64 `json:"estimated_hours" binding:"omitempty,min=0"`
+	Active           *bool    `json:"active"`
+}
+
+type CreateMaintenanceWorkOrderRequest struct {
+	Title string `json:"title"` // Default: the plan name
+	Notes string `json:"notes"`
+}
+
+type CompleteMaintenanceWorkOrderRequest struct {
+	Technician  string `json:"technician" binding:"required"`
+	Notes       string `json:"notes"`
+	CompletedAt string `json:"completed_at"` // Optional: RFC3339, default now
+}
+
+type CancelMaintenanceWorkOrderRequest struct {
+	Notes string `json:"notes"`
+}
+
+type MaintenanceWorkOrderFilterRequest struct {
+	MachineID int64  `form:"machine_id"`
+	PlanID    int64  `form:"plan_id"`
+	Status    string `form:"status"`
+}
+
+// Response DTOs
+
+// MaintenancePlanStatus is a plan with how far it is into its interval
+type MaintenancePlanStatus struct {
+	MaintenancePlan
+	MachineName       string   `json:"machine_name"`
+	RunHoursSinceLast float64  `json:"run_hours_since_last"`
+	NextDueDate       *string  `json:"next_due_date,omitempty"`       // Calendar interval only
+	RunHoursRemaining *float64 `json:"run_hours_remaining,omitempty"` // Run-hour interval only, negative when over
+	Due               bool     `json:"due"`
+	DueTrigger        string   `json:"due_trigger,omitempty"` // calendar or run_hours
+	Overdue           bool     `json:"overdue"`
+	DaysOverdue       int      `json:"days_overdue,omitempty"`
+	RunHoursOver      float64  `json:"run_hours_over,omitempty"`
+	OpenWorkOrderID   *int64   `json:"open_work_order_id,omitempty"`
+}
+
+// MachineRunInterval is a period a machine actually ran; End nil means it is still running
+type MachineRunInterval struct {
+	Start time.Time
+	End   *time.Time
+}
+
+// Validation functions
+
+func ValidateWorkOrderStatus(status string) bool {
+	switch status {
+	case WorkOrderStatusOpen, WorkOrderStatusInProgress, WorkOrderStatusCompleted, WorkOrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ValidateMaintenanceIntervals requires at least one interval
+func ValidateMaintenanceIntervals(intervalDays *int, intervalRunHours *float64) error {
+	if (intervalDays == nil || *intervalDays <= 0) && (intervalRunHours == nil || *intervalRunHours <= 0) {
+		return errors.New("interval_days or interval_run_hours is required")
+	}
+	return nil
+}
+
+// CheckWorkOrderTransition returns an error when a work order can't move to the status.
+// Work goes open -> in_progress -> completed; open work can be completed directly or cancelled
+func CheckWorkOrderTransition(from, to string) error {
+	allowed := false
+	switch from {
+	case WorkOrderStatusOpen:
+		allowed = to == WorkOrderStatusInProgress || to == WorkOrderStatusCompleted || to == WorkOrderStatusCancelled
+	case WorkOrderStatusInProgress:
+		allowed = to == WorkOrderStatusCompleted || to == WorkOrderStatusCancelled
+	}
+	if !allowed {
+		return fmt.Errorf("cannot change a %s work order to %s", from, to)
+	}
+	return nil
+}
+
+// RunHoursBetween sums the time the machine ran in [from, to). Overlapping intervals, such as a
+// machine assignment and the process stage of the same job, are counted once
+func RunHoursBetween(intervals []MachineRunInterval, from, to time.Time) float64 {
+	type span struct{ start, end time.Time }
+	var spans []span
+	for _, iv := range intervals {
+		start, end := iv.Start, to
+		if iv.End != nil && iv.End.Before(to) {
+			end = *iv.End
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(start) {
+			spans = append(spans, span{start, end})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	var total time.Duration
+	var cursor time.Time
+	for _, s := range spans {
+		if s.start.Before(cursor) {
+			s.start = cursor
+		}
+		if s.end.After(s.start) {
+			total += s.end.Sub(s.start)
+			cursor = s.end
+		}
+	}
+	return math.Round(total.Hours()*100) / 100
+}
+
+// EvaluateMaintenancePlan works out whether a plan is due from its intervals, the run hours since it
+// was last done and the current time. A calendar plan is due on its due date and overdue after it;
+// a run-hour plan is due once the interval is reached and overdue when the machine runs past it
+func EvaluateMaintenancePlan(plan MaintenancePlan, runHours float64, now time.Time) MaintenancePlanStatus {
+	status := MaintenancePlanStatus{MaintenancePlan: plan, RunHoursSinceLast: runHours}
+
+	if plan.IntervalDays != nil && *plan.IntervalDays > 0 {
+		due := startOfDay(plan.LastCompletedAt).AddDate(0, 0, *plan.IntervalDays)
+		dueDate := due.Format("2006-01-02")
+		status.NextDueDate = &dueDate
+
+		today := startOfDay(now.In(due.Location()))
+		if !today.Before(due) {
+			status.Due = true
+			status.DueTrigger = WorkOrderTriggerCalendar
+		}
+		if today.After(due) {
+			status.Overdue = true
+			status.DaysOverdue = int(math.Round(today.Sub(due).Hours() / 24))
+		}
+	}
+
+	if plan.IntervalRunHours != nil && *plan.IntervalRunHours > 0 {
+		remaining := math.Round((*plan.IntervalRunHours-runHours)*100) / 100
+		status.RunHoursRemaining = &remaining
+		if remaining <= 0 {
+			if !status.Due {
+				status.DueTrigger = WorkOrderTriggerRunHours
+			}
+			status.Due = true
+		}
+		if remaining < 0 {
+			status.Overdue = true
+			status.RunHoursOver = -remaining
+		}
+	}
+
+	return status
+}
+
+// SortOverdueMaintenance puts the most overdue plans first: by days, then by run hours over
+func SortOverdueMaintenance(statuses []MaintenancePlanStatus) {
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if statuses[i].DaysOverdue != statuses[j].DaysOverdue {
+			return statuses[i].DaysOverdue > statuses[j].DaysOverdue
+		}
+		if statuses[i].RunHoursOver != statuses[j].RunHoursOver {
+			return statuses[i].RunHoursOver > statuses[j].RunHoursOver
+		}
+		return statuses[i].ID < statuses[j].ID
+	})
+}
+
+// NewMaintenanceWorkOrder generates the work order of a due plan
+func NewMaintenanceWorkOrder(status MaintenancePlanStatus) *MaintenanceWorkOrder {
+	order := &MaintenanceWorkOrder{
+		PlanID:    status.ID,
+		MachineID: status.MachineID,
+		Title:     status.Name,
+		Status:    WorkOrderStatusOpen,
+		Trigger:   status.DueTrigger,
+		RunHours:  status.RunHoursSinceLast,
+	}
+	if status.NextDueDate != nil {
+		if due, err := time.Parse("2006-01-02", *status.NextDueDate); err == nil {
+			order.DueDate = &due
+		}
+	}
+	return order
+}
@@ -0,0 +1,136 @@
+package models
+
+import "time"
+
+// OEE report periods; day and week are the utilization periods
+const (
+	OEEPeriodShift = "shift"
+)
+
+// ProductionReport is a count of parts a machine produced, reported by the operator. The counts
+// belong to the shift, day and week containing ProducedAt
+type ProductionReport struct {
+	ID                int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	MachineID         int64     `gorm:"index;not null" json:"machine_id"`
+	ScheduleID        *int64    `gorm:"index" json:"schedule_id,omitempty"`   // PPIC schedule (live board)
+	AssignmentID      *int64    `gorm:"index" json:"assignment_id,omitempty"` // Machine assignment (operation) of the schedule
+	JobOrderID        *int64    `gorm:"index" json:"job_order_id,omitempty"`
+	PartName          string    `gorm:"size:255" json:"part_name,omitempty"` // From the schedule
+	ProducedAt        time.Time `gorm:"index;not null" json:"produced_at"`
+	GoodQuantity      int       `gorm:"not null;default:0" json:"good_quantity"`
+	ScrapQuantity     int       `gorm:"not null;default:0" json:"scrap_quantity"`
+	ScrapReason       string    `gorm:"size:255" json:"scrap_reason,omitempty"`
+	IdealCycleSeconds float64   `gorm:"not null" json:"ideal_cycle_seconds"` // Fastest possible time per part
+	Notes             string    `gorm:"type:text" json:"notes,omitempty"`
+	ReportedBy        int64     `json:"reported_by"`
+	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt         time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ProductionReport) TableName() string {
+	return "production_reports"
+}
+
+// TotalQuantity returns good plus scrap parts
+func (r *ProductionReport) TotalQuantity() int {
+	return r.GoodQuantity + r.ScrapQuantity
+}
+
+// IdealHours returns the time the reported parts take at the ideal cycle time
+func (r *ProductionReport) IdealHours() float64 {
+	return r.IdealCycleSeconds * float64(r.TotalQuantity()) / 3600
+}
+
+// Request DTOs
+
+type CreateProductionReportRequest struct {
+	MachineID         int64    `json:"machine_id"`    // Optional with assignment_id
+	ScheduleID        *int64   `json:"schedule_id"`   // Required with assignment_id
+	AssignmentID      *int64   `json:"assignment_id"` // Operation the parts were made in
+	JobOrderID        *int64   `json:"job_order_id"`
+	ProducedAt        string   `json:"produced_at"` // Optional: RFC3339, default now
+	GoodQuantity      int      `json:"good_quantity" binding:"min=0"`
+	ScrapQuantity     int      `json:"scrap_quantity" binding:"min=0"`
+	ScrapReason       string   `json:"scrap_reason"`
+	IdealCycleSeconds *float64 `json:"ideal_cycle_seconds" binding:"omitempty,gt=0"` // Default: the previous report of the operation
+	Notes             string   `json:"notes"`
+}
+
+type UpdateProductionReportRequest struct {
+	ProducedAt        string   `json:"produced_at"`
+	GoodQuantity      *int     `json:"good_quantity" binding:"omitempty,min=0"`
+	ScrapQuantity     *int     `json:"scrap_quantity" binding:"omitempty,min=0"`
+	ScrapReason       *string  `json:"scrap_reason"`
+	IdealCycleSeconds *float64 `json:"ideal_cycle_seconds" binding:"omitempty,gt=0"`
+	Notes             *string  `json:"notes"`
+}
+
+type ProductionReportFilterRequest struct {
+	MachineID    int64  `form:"machine_id"`
+	ScheduleID   int64  `form:"schedule_id"`
+	AssignmentID int64  `form:"assignment_id"`
+	StartDate    string `form:"start_date"` // YYYY-MM-DD
+	EndDate      string `form:"end_date"`   // YYYY-MM-DD inclusive
+}
+
+type OEEFilterRequest struct {
+	StartDate   string `form:"start_date"`   // YYYY-MM-DD, required
+	EndDate     string `form:"end_date"`     // YYYY-MM-DD inclusive, required
+	Period      string `form:"period"`       // "shift", "day" (default) or "week" (Monday to Sunday)
+	MachineType string `form:"machine_type"` // Case-insensitive
+	Location    string `form:"location"`     // Case-insensitive
+	MachineID   int64  `form:"machine_id"`
+}
+
+// OEEMachineData is what the OEE of one machine is computed from
+type OEEMachineData struct {
+	Runs     []MachineRunInterval // Actual run time of assignments and process stages
+	Stops    []AndonEvent         // Unplanned stops
+	Downtime []MachineDowntime    // Planned maintenance and calibration are excluded from planned time; breakdowns are stops
+	Reports  []ProductionReport
+}
+
+// Response DTOs
+
+// OEEPeriod is the OEE of one machine (or the plant) in one shift, day or week.
+// Availability = run / planned time, performance = ideal time of the parts / run time,
+// quality = good / total parts, OEE = availability x performance x quality
+type OEEPeriod struct {
+	PeriodStart   string  `json:"period_start"`
+	PeriodEnd     string  `json:"period_end"`
+	Shift         string  `json:"shift,omitempty"`
+	PlannedHours  float64 `json:"planned_hours"`  // Working calendar time less planned downtime, plus run time outside it
+	RunHours      float64 `json:"run_hours"`      // Actual run time less andon stops and breakdowns
+	DowntimeHours float64 `json:"downtime_hours"` // Planned - run
+	IdealHours    float64 `json:"ideal_hours"`    // Ideal cycle time x parts
+	GoodQuantity  int     `json:"good_quantity"`
+	ScrapQuantity int     `json:"scrap_quantity"`
+	Availability  float64 `json:"availability"` // Percent
+	Performance   float64 `json:"performance"`  // Percent; above 100 means the ideal cycle time is set too long
+	Quality       float64 `json:"quality"`      // Percent
+	OEE           float64 `json:"oee"`          // Percent
+}
+
+type MachineOEE struct {
+	MachineID   int64       `json:"machine_id"`
+	MachineCode string      `json:"machine_code"`
+	MachineName string      `json:"machine_name"`
+	MachineType string      `json:"machine_type"`
+	Location    string      `json:"location"`
+	Periods     []OEEPeriod `json:"periods"`
+	Total       OEEPeriod   `json:"total"`
+}
+
+type OEEReport struct {
+	StartDate string       `json:"start_date"`
+	EndDate   string       `json:"end_date"`
+	Period    string       `json:"period"`
+	Machines  []MachineOEE `json:"machines"`
+	Total     OEEPeriod    `json:"total"` // All listed machines together
+}
+
+// Validation functions
+
+func ValidateOEEPeriod(period string) bool {
+	return period == OEEPeriodShift || period == UtilizationPeriodDay || period == UtilizationPeriodWeek
+}
@@ -52,6 +52,7 @@ type PEMOperationPlan struct {
 	NoWP           string         `gorm:"size:100" json:"no_wp"`
 	Page           string         `gorm:"size:50" json:"page"`
 	Status         string         `gorm:"size:50;default:'draft'" json:"status"` // draft, pending_approval, approved, rejected
+	Version        int            `gorm:"not null;default:1" json:"version"`     // Bumped on every change, including to the steps
 	CreatedBy      int64          `gorm:"index;not null" json:"created_by"`
 	Creator        *User          `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
 	Steps          []OperationPlanStep `gorm:"foreignKey:OperationPlanID" json:"steps,omitempty"`
@@ -127,6 +128,7 @@ type UpdatePEMPlanRequest struct {
 	NoWP           string                   `json:"no_wp"`
 	Page           string                   `json:"page"`
 	Steps          []UpdateStepRequest      `json:"steps"`
+	Version        *int                     `json:"version"` // Version last read; the If-Match header takes precedence
 }
 
 type CreateStepRequest struct {
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// PPICBaseline is a frozen, named snapshot of the live schedule and machine assignment dates
+type PPICBaseline struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	CreatedBy     int64     `json:"created_by"`
+	ScheduleCount int       `json:"schedule_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// BaselineSchedule is a schedule's dates as captured in a baseline
+type BaselineSchedule struct {
+	ScheduleID  int64                `json:"schedule_id"`
+	NJO         string               `json:"njo"`
+	PartName    string               `json:"part_name"`
+	StartDate   time.Time            `json:"start_date"`
+	FinishDate  time.Time            `json:"finish_date"`
+	Assignments []BaselineAssignment `json:"assignments"`
+}
+
+// BaselineAssignment is a machine assignment's window as captured in a baseline.
+// Assignments are matched to the current ones by schedule and sequence
+type BaselineAssignment struct {
+	ScheduleID     int64      `json:"schedule_id"`
+	MachineID      int64      `json:"machine_id"`
+	Sequence       int        `json:"sequence"`
+	TargetHours    float64    `json:"target_hours"`
+	ScheduledStart *time.Time `json:"scheduled_start"`
+	ScheduledEnd   *time.Time `json:"scheduled_end"`
+}
+
+// Request DTOs
+
+type CreatePPICBaselineRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// Variance DTOs
+
+type ScheduleVariance struct {
+	ScheduleID         int64                `json:"schedule_id"`
+	NJO                string               `json:"njo"`
+	PartName           string               `json:"part_name"`
+	Status             string               `json:"status"`
+	InBaseline         bool                 `json:"in_baseline"` // false = created after the baseline
+	BaselineStartDate  *time.Time           `json:"baseline_start_date"`
+	BaselineFinishDate *time.Time           `json:"baseline_finish_date"`
+	CurrentStartDate   time.Time            `json:"current_start_date"`
+	CurrentFinishDate  time.Time            `json:"current_finish_date"`
+	StartSlipDays      *int                 `json:"start_slip_days"`  // Positive = later than baseline
+	FinishSlipDays     *int                 `json:"finish_slip_days"` // Positive = later than baseline
+	Assignments        []AssignmentVariance `json:"assignments"`
+}
+
+type AssignmentVariance struct {
+	AssignmentID        int64      `json:"assignment_id"`
+	MachineID           int64      `json:"machine_id"`
+	MachineName         string     `json:"machine_name"`
+	Sequence            int        `json:"sequence"`
+	Status              string     `json:"status"`
+	BaselineStart       *time.Time `json:"baseline_start"`
+	BaselineEnd         *time.Time `json:"baseline_end"`
+	ScheduledStart      *time.Time `json:"scheduled_start"`
+	ScheduledEnd        *time.Time `json:"scheduled_end"`
+	ActualStart         *time.Time `json:"actual_start"`
+	ActualEnd           *time.Time `json:"actual_end"`
+	BaselineSlipDays    *float64   `json:"baseline_slip_days"`     // Scheduled end vs baseline end
+	ActualStartSlipDays *float64   `json:"actual_start_slip_days"` // Actual start vs scheduled start
+	ActualEndSlipDays   *float64   `json:"actual_end_slip_days"`   // Actual end vs scheduled end
+}
+
+type BaselineVarianceSummary struct {
+	TotalSchedules        int     `json:"total_schedules"`
+	LateCount             int     `json:"late_count"`
+	EarlyCount            int     `json:"early_count"`
+	OnTrackCount          int     `json:"on_track_count"`
+	NotInBaselineCount    int     `json:"not_in_baseline_count"`
+	RemovedCount          int     `json:"removed_count"` // In the baseline but deleted since
+	MaxFinishSlipDays     int     `json:"max_finish_slip_days"`
+	AverageFinishSlipDays float64 `json:"average_finish_slip_days"`
+}
+
+type BaselineVarianceResponse struct {
+	Baseline  *PPICBaseline           `json:"baseline"`
+	Schedules []ScheduleVariance      `json:"schedules"`
+	Summary   BaselineVarianceSummary `json:"summary"`
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Change entity constants
+const (
+	ChangeEntitySchedule          = "schedule"
+	ChangeEntityMachineAssignment = "machine_assignment"
+)
+
+// Change cause constants
+const (
+	ChangeCauseManual       = "manual"
+	ChangeCauseLinkCreated  = "link created"
+	ChangeCauseAutoSchedule = "auto-schedule"
+	ChangeCausePEMApproved  = "PEM plan approved"
+	ChangeCauseImport       = "import"
+	ChangeCauseSplit        = "split into lots"
+	ChangeCauseMerge        = "lots merged"
+)
+
+// CascadeChangeCause is the cause recorded on schedules moved by a cascade from another schedule
+func CascadeChangeCause(njo string) string {
+	return "cascade from NJO " + njo
+}
+
+// ScenarioPromotedChangeCause is the cause recorded on schedules overwritten by a promoted scenario
+func ScenarioPromotedChangeCause(scenarioName string) string {
+	return "scenario promoted: " + scenarioName
+}
+
+// PPICScheduleChange is one field change on a schedule or one of its machine assignments
+type PPICScheduleChange struct {
+	ID            int64     `json:"id"`
+	ScheduleID    int64     `json:"schedule_id"`
+	Entity        string    `json:"entity"`                  // schedule or machine_assignment
+	AssignmentID  *int64    `json:"assignment_id,omitempty"` // Assignment IDs change when a schedule is edited;
+	Sequence      *int      `json:"sequence,omitempty"`      // the sequence identifies the routing step
+	Field         string    `json:"field"`
+	OldValue      string    `json:"old_value"`
+	NewValue      string    `json:"new_value"`
+	Cause         string    `json:"cause"`
+	ChangedBy     int64     `json:"changed_by"` // 0 = system
+	ChangedByName string    `json:"changed_by_name"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
+
+// DiffPPICSchedule lists the field changes between two versions of a schedule.
+// Machine assignments are matched by sequence. Cause, user and time are left for the caller
+func DiffPPICSchedule(before, after *PPICSchedule) []PPICScheduleChange {
+	var changes []PPICScheduleChange
+	field := func(name, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, PPICScheduleChange{
+				ScheduleID: after.ID,
+				Entity:     ChangeEntitySchedule,
+				Field:      name,
+				OldValue:   oldValue,
+				NewValue:   newValue,
+			})
+		}
+	}
+
+	field("njo", before.NJO, after.NJO)
+	field("part_name", before.PartName, after.PartName)
+	field("priority", before.Priority, after.Priority)
+	field("priority_alpha", before.PriorityAlpha, after.PriorityAlpha)
+	field("material_status", before.MaterialStatus, after.MaterialStatus)
+	field("status", before.Status, after.Status)
+	field("progress", strconv.Itoa(before.Progress), strconv.Itoa(after.Progress))
+	field("start_date", formatChangeDate(before.StartDate), formatChangeDate(after.StartDate))
+	field("finish_date", formatChangeDate(before.FinishDate), formatChangeDate(after.FinishDate))
+	field("ppic_notes", before.PPICNotes, after.PPICNotes)
+	field("quantity", strconv.Itoa(before.Quantity), strconv.Itoa(after.Quantity))
+
+	beforeBySequence := make(map[int]MachineAssignment, len(before.MachineAssignments))
+	for _, ma := range before.MachineAssignments {
+		beforeBySequence[ma.Sequence] = ma
+	}
+	afterBySequence := make(map[int]bool, len(after.MachineAssignments))
+
+	for _, ma := range after.MachineAssignments {
+		afterBySequence[ma.Sequence] = true
+		old, existed := beforeBySequence[ma.Sequence]
+		if !existed {
+			changes = append(changes, assignmentChange(after.ID, ma, "machine", "", describeAssignmentMachine(ma)))
+			continue
+		}
+		assignmentField := func(name, oldValue, newValue string) {
+			if oldValue != newValue {
+				changes = append(changes, assignmentChange(after.ID, ma, name, oldValue, newValue))
+			}
+		}
+		assignmentField("machine", describeAssignmentMachine(old), describeAssignmentMachine(ma))
+		assignmentField("target_hours", formatChangeHours(old.TargetHours), formatChangeHours(ma.TargetHours))
+		assignmentField("scheduled_start", formatChangeTime(old.ScheduledStart), formatChangeTime(ma.ScheduledStart))
+		assignmentField("scheduled_end", formatChangeTime(old.ScheduledEnd), formatChangeTime(ma.ScheduledEnd))
+		assignmentField("actual_start", formatChangeTime(old.ActualStart), formatChangeTime(ma.ActualStart))
+		assignmentField("actual_end", formatChangeTime(old.ActualEnd), formatChangeTime(ma.ActualEnd))
+		assignmentField("status", old.Status, ma.Status)
+	}
+
+	for _, ma := range before.MachineAssignments {
+		if !afterBySequence[ma.Sequence] {
+			changes = append(changes, assignmentChange(after.ID, ma, "machine", describeAssignmentMachine(ma), ""))
+		}
+	}
+
+	return changes
+}
+
+func assignmentChange(scheduleID int64, ma MachineAssignment, field, oldValue, newValue string) PPICScheduleChange {
+	assignmentID, sequence := ma.ID, ma.Sequence
+	return PPICScheduleChange{
+		ScheduleID:   scheduleID,
+		Entity:       ChangeEntityMachineAssignment,
+		AssignmentID: &assignmentID,
+		Sequence:     &sequence,
+		Field:        field,
+		OldValue:     oldValue,
+		NewValue:     newValue,
+	}
+}
+
+func describeAssignmentMachine(ma MachineAssignment) string {
+	if ma.MachineCode != "" {
+		return ma.MachineCode
+	}
+	return fmt.Sprintf("machine %d", ma.MachineID)
+}
+
+func formatChangeDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func formatChangeTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatChangeHours(hours float64) string {
+	return strconv.FormatFloat(hours, 'f', -1, 64)
+}
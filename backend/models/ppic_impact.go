@@ -0,0 +1,61 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// ScheduleDateChange is one schedule whose dates a change moves
+type ScheduleDateChange struct {
+	ScheduleID    int64  `json:"schedule_id"`
+	NJO           string `json:"njo"`
+	PartName      string `json:"part_name"`
+	OldStartDate  string `json:"old_start_date"`
+	OldFinishDate string `json:"old_finish_date"`
+	NewStartDate  string `json:"new_start_date"`
+	NewFinishDate string `json:"new_finish_date"`
+	ShiftDays     int    `json:"shift_days"` // Calendar days the start moves; negative = earlier
+	Cause         string `json:"cause"`      // Same wording as the change history
+}
+
+// NewScheduleDateChange describes the move of a schedule from before to after
+func NewScheduleDateChange(before, after *PPICSchedule, cause string) ScheduleDateChange {
+	return ScheduleDateChange{
+		ScheduleID:    after.ID,
+		NJO:           after.NJO,
+		PartName:      after.PartName,
+		OldStartDate:  before.StartDate.Format("2006-01-02"),
+		OldFinishDate: before.FinishDate.Format("2006-01-02"),
+		NewStartDate:  after.StartDate.Format("2006-01-02"),
+		NewFinishDate: after.FinishDate.Format("2006-01-02"),
+		ShiftDays:     ShiftDays(before.StartDate, after.StartDate),
+		Cause:         cause,
+	}
+}
+
+// ShiftDays returns the calendar days between two dates; negative = earlier
+func ShiftDays(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// ScheduleLinkConflict is a link left unsatisfied by the planned dates
+type ScheduleLinkConflict struct {
+	LinkID           int64  `json:"link_id"` // 0 = the link being created
+	SourceScheduleID int64  `json:"source_schedule_id"`
+	SourceNJO        string `json:"source_njo"`
+	TargetScheduleID int64  `json:"target_schedule_id"`
+	TargetNJO        string `json:"target_njo"`
+	LinkType         string `json:"link_type"`
+	LagDays          int    `json:"lag_days"`
+	RequiredDate     string `json:"required_date"` // Earliest date allowed for the constrained end of the target
+	Message          string `json:"message"`
+}
+
+// ScheduleImpact is the preview returned by a dry run: every schedule that would move and
+// every link the new dates would break. Nothing is written
+type ScheduleImpact struct {
+	DryRun    bool                   `json:"dry_run"`
+	Blocked   bool                   `json:"blocked"` // The change itself would be rejected because of a conflict
+	Changes   []ScheduleDateChange   `json:"changes"`
+	Conflicts []ScheduleLinkConflict `json:"conflicts"`
+}
@@ -0,0 +1,405 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Import row action constants
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+)
+
+// PPICImportOptions are the query options of a schedule import
+type PPICImportOptions struct {
+	DryRun bool `form:"dry_run"` // Validate only, write nothing
+	Upsert bool `form:"upsert"`  // Update schedules whose NJO already exists instead of rejecting the row
+}
+
+// PPICImportRow is the outcome of one spreadsheet row
+type PPICImportRow struct {
+	Row        int      `json:"row"` // Spreadsheet row number, the header is row 1
+	NJO        string   `json:"njo"`
+	Action     string   `json:"action"`      // create or update
+	ScheduleID *int64   `json:"schedule_id"` // Existing schedule for updates, new schedule after commit
+	Errors     []string `json:"errors"`
+
+	Create     *CreatePPICScheduleRequest `json:"-"`
+	Existing   *PPICSchedule              `json:"-"` // Schedule overwritten by an update row
+	StartDate  time.Time                  `json:"-"`
+	FinishDate time.Time                  `json:"-"`
+}
+
+// Valid reports whether the row can be imported
+func (r *PPICImportRow) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// UpdateRequest converts the row into an update of an existing schedule.
+// Machine assignments from the row replace the existing ones; a row without machines keeps them
+func (r *PPICImportRow) UpdateRequest() *UpdatePPICScheduleRequest {
+	req := &UpdatePPICScheduleRequest{
+		PartName:       r.Create.PartName,
+		Priority:       r.Create.Priority,
+		PriorityAlpha:  r.Create.PriorityAlpha,
+		MaterialStatus: r.Create.MaterialStatus,
+		StartDate:      r.Create.StartDate,
+		FinishDate:     r.Create.FinishDate,
+		PPICNotes:      r.Create.PPICNotes,
+	}
+	for _, ma := range r.Create.MachineAssignments {
+		start, end, _ := ma.ParseScheduledWindow()
+		req.MachineAssignments = append(req.MachineAssignments, UpdateMachineAssignmentRequest{
+			MachineID:      ma.MachineID,
+			Sequence:       ma.Sequence,
+			TargetHours:    ma.TargetHours,
+			ScheduledStart: start,
+			ScheduledEnd:   end,
+		})
+	}
+	return req
+}
+
+// PPICImportResult is the per-row report of an import or dry run
+type PPICImportResult struct {
+	DryRun      bool            `json:"dry_run"`
+	Committed   bool            `json:"committed"`
+	TotalRows   int             `json:"total_rows"`
+	CreateCount int             `json:"create_count"`
+	UpdateCount int             `json:"update_count"`
+	ErrorCount  int             `json:"error_count"`
+	Rows        []PPICImportRow `json:"rows"`
+}
+
+// PPICImportColumns maps spreadsheet headers to CreatePPICScheduleRequest fields
+type PPICImportColumns struct {
+	fields   map[string]int
+	machines []importMachineColumns
+}
+
+type importMachineColumns struct {
+	sequence int
+	code     int // -1 if missing
+	hours    int // -1 if missing
+	start    int // -1 if missing
+	end      int // -1 if missing
+}
+
+var importFieldAliases = map[string][]string{
+	"njo":             {"njo", "no_njo", "order_number"},
+	"part_name":       {"part_name", "part"},
+	"priority":        {"priority"},
+	"priority_alpha":  {"priority_alpha", "alpha"},
+	"material_status": {"material_status", "material"},
+	"start_date":      {"start_date", "start"},
+	"finish_date":     {"finish_date", "finish", "end_date"},
+	"ppic_notes":      {"ppic_notes", "notes"},
+}
+
+var requiredImportFields = []string{"njo", "part_name", "priority", "material_status", "start_date", "finish_date"}
+
+var (
+	importMachineCodeHeader   = regexp.MustCompile(`^machine(?:_code)?_?(\d+)$`)
+	importMachineHoursHeader  = regexp.MustCompile(`^(?:(?:target_)?hours_?(\d+)|machine_?(\d+)_(?:target_)?hours)$`)
+	importMachineWindowHeader = regexp.MustCompile(`^machine_?(\d+)_(?:scheduled_)?(start|end)$`)
+	importHeaderSeparators    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func normalizeImportHeader(header string) string {
+	return strings.Trim(importHeaderSeparators.ReplaceAllString(strings.ToLower(strings.TrimSpace(header)), "_"), "_")
+}
+
+// MapPPICImportColumns reads the header row. Machine columns are numbered by sequence, e.g.
+// "Machine 1" (machine code), "Hours 1" (target hours) and the optional scheduled window
+// "Machine 1 Start" and "Machine 1 End"
+func MapPPICImportColumns(header []string) (*PPICImportColumns, error) {
+	columns := &PPICImportColumns{fields: make(map[string]int)}
+	machines := make(map[int]*importMachineColumns)
+	machine := func(sequence int) *importMachineColumns {
+		if machines[sequence] == nil {
+			machines[sequence] = &importMachineColumns{sequence: sequence, code: -1, hours: -1, start: -1, end: -1}
+		}
+		return machines[sequence]
+	}
+
+	for i, raw := range header {
+		name := normalizeImportHeader(raw)
+		if name == "" {
+			continue
+		}
+		if m := importMachineHoursHeader.FindStringSubmatch(name); m != nil {
+			number := m[1] + m[2]
+			sequence, _ := strconv.Atoi(number)
+			machine(sequence).hours = i
+			continue
+		}
+		if m := importMachineWindowHeader.FindStringSubmatch(name); m != nil {
+			sequence, _ := strconv.Atoi(m[1])
+			if m[2] == "start" {
+				machine(sequence).start = i
+			} else {
+				machine(sequence).end = i
+			}
+			continue
+		}
+		if m := importMachineCodeHeader.FindStringSubmatch(name); m != nil {
+			sequence, _ := strconv.Atoi(m[1])
+			machine(sequence).code = i
+			continue
+		}
+		for field, aliases := range importFieldAliases {
+			for _, alias := range aliases {
+				if name == alias {
+					if _, dup := columns.fields[field]; dup {
+						return nil, fmt.Errorf("duplicate column for %s", field)
+					}
+					columns.fields[field] = i
+				}
+			}
+		}
+	}
+
+	var missing []string
+	for _, field := range requiredImportFields {
+		if _, ok := columns.fields[field]; !ok {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required columns: %s", strings.Join(missing, ", "))
+	}
+
+	for sequence := 1; len(columns.machines) < len(machines); sequence++ {
+		if m, ok := machines[sequence]; ok {
+			if m.code < 0 {
+				return nil, fmt.Errorf("hours or start/end column for machine %d has no machine column", sequence)
+			}
+			columns.machines = append(columns.machines, *m)
+		}
+	}
+
+	return columns, nil
+}
+
+// ParseRow converts a data row into a create request. machineIDs maps upper-case machine codes to IDs.
+// All problems in the row are returned, not only the first
+func (c *PPICImportColumns) ParseRow(row []string, machineIDs map[string]int64) (*CreatePPICScheduleRequest, []string) {
+	cell := func(field string) string {
+		i, ok := c.fields[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return unescapeImportCell(strings.TrimSpace(row[i]))
+	}
+	at := func(i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return unescapeImportCell(strings.TrimSpace(row[i]))
+	}
+
+	var errs []string
+	req := &CreatePPICScheduleRequest{
+		NJO:           cell("njo"),
+		PartName:      cell("part_name"),
+		PriorityAlpha: cell("priority_alpha"),
+		PPICNotes:     cell("ppic_notes"),
+	}
+
+	for _, field := range requiredImportFields {
+		if cell(field) == "" {
+			errs = append(errs, field+" is required")
+		}
+	}
+
+	if value := cell("priority"); value != "" {
+		req.Priority = matchImportChoice(value, []string{PriorityLow, PriorityMedium, PriorityUrgent, PriorityTopUrgent})
+		if req.Priority == "" {
+			errs = append(errs, fmt.Sprintf("invalid priority %q. Must be: Low, Medium, Urgent, or Top Urgent", value))
+		}
+	}
+	if value := cell("material_status"); value != "" {
+		req.MaterialStatus = matchImportChoice(value, []string{MaterialReady, MaterialPending, MaterialOrdered, MaterialNotReady})
+		if req.MaterialStatus == "" {
+			errs = append(errs, fmt.Sprintf("invalid material status %q. Must be: Ready, Pending, Ordered, or Not Ready", value))
+		}
+	}
+	for _, field := range []string{"start_date", "finish_date"} {
+		value := cell(field)
+		if value == "" {
+			continue
+		}
+		date, err := ParseImportDate(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+			continue
+		}
+		if field == "start_date" {
+			req.StartDate = date.Format("2006-01-02")
+		} else {
+			req.FinishDate = date.Format("2006-01-02")
+		}
+	}
+
+	for _, m := range c.machines {
+		code, hoursValue, startValue, endValue := at(m.code), at(m.hours), at(m.start), at(m.end)
+		if code == "" {
+			if hoursValue != "" || startValue != "" || endValue != "" {
+				errs = append(errs, fmt.Sprintf("machine %d has hours or a window but no machine code", m.sequence))
+			}
+			continue
+		}
+		machineID, ok := machineIDs[strings.ToUpper(code)]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("machine %d: unknown machine code %q", m.sequence, code))
+			continue
+		}
+		assignment := CreateMachineAssignmentRequest{MachineID: machineID, Sequence: m.sequence}
+		if hoursValue != "" {
+			hours, err := strconv.ParseFloat(strings.Replace(hoursValue, ",", ".", 1), 64)
+			if err != nil || hours < 0 {
+				errs = append(errs, fmt.Sprintf("machine %d: invalid hours %q", m.sequence, hoursValue))
+				continue
+			}
+			assignment.TargetHours = hours
+		}
+		if startValue != "" {
+			start, err := ParseImportTime(startValue)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("machine %d start: %v", m.sequence, err))
+				continue
+			}
+			assignment.ScheduledStart = start.Format(time.RFC3339)
+		}
+		if endValue != "" {
+			end, err := ParseImportTime(endValue)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("machine %d end: %v", m.sequence, err))
+				continue
+			}
+			assignment.ScheduledEnd = end.Format(time.RFC3339)
+		}
+		req.MachineAssignments = append(req.MachineAssignments, assignment)
+	}
+
+	return req, errs
+}
+
+// unescapeImportCell drops the quote the CSV export puts before text starting with =, +, - or @
+func unescapeImportCell(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// IsBlankImportRow reports whether every cell of a row is empty
+func IsBlankImportRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseImportDate accepts YYYY-MM-DD, DD/MM/YYYY and Excel date serial numbers
+func ParseImportDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "02/01/2006", "2/1/2006", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial >= 1 && serial < 2958466 {
+		// Excel counts days from 1899-12-30 (including its 1900 leap year bug)
+		return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(serial)), nil
+	}
+	return time.Time{}, errors.New("invalid date " + strconv.Quote(value) + ". Use YYYY-MM-DD or DD/MM/YYYY")
+}
+
+// ParseImportTime accepts the machine window formats of the export (YYYY-MM-DD HH:MM and Excel
+// date-time serial numbers) as well as RFC3339
+func ParseImportTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02 15:04:05", time.RFC3339, "2006-01-02T15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial >= 1 && serial < 2958466 {
+		minutes := math.Round(serial * 24 * 60)
+		return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).Add(time.Duration(minutes) * time.Minute), nil
+	}
+	return time.Time{}, errors.New("invalid time " + strconv.Quote(value) + ". Use YYYY-MM-DD HH:MM")
+}
+
+// PPICImportBookings are the scheduled machine windows of the rows of an import accepted so far,
+// per machine. Rows are written together, so the board can't catch overlaps between them
+type PPICImportBookings map[int64][]importBooking
+
+type importBooking struct {
+	row        int
+	njo        string
+	sequence   int
+	start, end time.Time
+}
+
+// Check returns an error for every machine window of the row that overlaps a window of an accepted row
+func (b PPICImportBookings) Check(row *PPICImportRow) []string {
+	var errs []string
+	for _, w := range importRowWindows(row) {
+		for _, other := range b[w.machineID] {
+			if WindowsOverlap(w.start, w.end, other.start, other.end) {
+				errs = append(errs, fmt.Sprintf("machine double-booked: sequence %d (%s - %s) overlaps NJO %s sequence %d on row %d (%s - %s)",
+					w.sequence, w.start.Format("2006-01-02 15:04"), w.end.Format("2006-01-02 15:04"),
+					other.njo, other.sequence, other.row, other.start.Format("2006-01-02 15:04"), other.end.Format("2006-01-02 15:04")))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// Add books the machine windows of an accepted row
+func (b PPICImportBookings) Add(row *PPICImportRow) {
+	for _, w := range importRowWindows(row) {
+		b[w.machineID] = append(b[w.machineID], importBooking{row: row.Row, njo: row.NJO, sequence: w.sequence, start: w.start, end: w.end})
+	}
+}
+
+type importWindow struct {
+	machineID  int64
+	sequence   int
+	start, end time.Time
+}
+
+// importRowWindows returns the machine windows of a row that have both a start and an end
+func importRowWindows(row *PPICImportRow) []importWindow {
+	if row.Create == nil {
+		return nil
+	}
+	var windows []importWindow
+	for i := range row.Create.MachineAssignments {
+		ma := &row.Create.MachineAssignments[i]
+		start, end, err := ma.ParseScheduledWindow()
+		if err != nil || start == nil || end == nil {
+			continue
+		}
+		windows = append(windows, importWindow{machineID: ma.MachineID, sequence: ma.Sequence, start: *start, end: *end})
+	}
+	return windows
+}
+
+func matchImportChoice(value string, choices []string) string {
+	for _, choice := range choices {
+		if strings.EqualFold(strings.Join(strings.Fields(value), " "), choice) {
+			return choice
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package models
+
+import "sort"
+
+// PPICLinkCycle describes a dependency loop between schedules
+type PPICLinkCycle struct {
+	ScheduleIDs []int64  `json:"schedule_ids"` // First and last entries are the same schedule
+	NJOs        []string `json:"njos"`
+	Path        string   `json:"path"` // e.g. "NJO-A → NJO-B → NJO-A"
+}
+
+// PPICLinkIssue describes a link that fails an integrity check
+type PPICLinkIssue struct {
+	Link   PPICLink `json:"link"`
+	Reason string   `json:"reason"`
+}
+
+// PPICLinkIntegrityReport is the result of checking the whole link graph
+type PPICLinkIntegrityReport struct {
+	Healthy         bool            `json:"healthy"`
+	TotalLinks      int             `json:"total_links"`
+	Cycles          []PPICLinkCycle `json:"cycles"`
+	DanglingLinks   []PPICLinkIssue `json:"dangling_links"`
+	MachineMismatch []PPICLinkIssue `json:"machine_mismatch"`
+	DuplicateLinks  []PPICLinkIssue `json:"duplicate_links"`
+}
+
+// buildLinkAdjacency builds source -> targets adjacency from links
+func buildLinkAdjacency(links []PPICLink) map[int64][]int64 {
+	adjacency := make(map[int64][]int64)
+	for _, link := range links {
+		adjacency[link.SourceScheduleID] = append(adjacency[link.SourceScheduleID], link.TargetScheduleID)
+	}
+	return adjacency
+}
+
+// CascadeScheduleIDs returns the schedules a reschedule of id with its cascade reads or writes,
+// sorted by ID: the schedule itself, its direct predecessors and every schedule depending on it
+func CascadeScheduleIDs(links []PPICLink, id int64) []int64 {
+	adjacency := buildLinkAdjacency(links)
+	seen := map[int64]bool{id: true}
+	queue := []int64{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range adjacency[current] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	for _, link := range links {
+		if link.TargetScheduleID == id {
+			seen[link.SourceScheduleID] = true
+		}
+	}
+
+	ids := make([]int64, 0, len(seen))
+	for scheduleID := range seen {
+		ids = append(ids, scheduleID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// FindLinkPath returns the schedule IDs along a dependency path from one schedule to another,
+// or nil if the second schedule is not reachable from the first
+func FindLinkPath(links []PPICLink, fromID, toID int64) []int64 {
+	adjacency := buildLinkAdjacency(links)
+	visited := make(map[int64]bool)
+
+	var walk func(id int64, path []int64) []int64
+	walk = func(id int64, path []int64) []int64 {
+		path = append(path, id)
+		if id == toID {
+			return path
+		}
+		visited[id] = true
+		for _, next := range adjacency[id] {
+			if visited[next] {
+				continue
+			}
+			if found := walk(next, path); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+
+	return walk(fromID, nil)
+}
+
+// FindLinkCycles returns every dependency loop found in the link graph.
+// Each cycle lists its schedule IDs with the first ID repeated at the end
+func FindLinkCycles(links []PPICLink) [][]int64 {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+
+	adjacency := buildLinkAdjacency(links)
+	state := make(map[int64]int)
+	var stack []int64
+	var cycles [][]int64
+
+	var visit func(id int64)
+	visit = func(id int64) {
+		state[id] = inProgress
+		stack = append(stack, id)
+
+		for _, next := range adjacency[id] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case inProgress:
+				// Back edge: the loop is the stack from next up to the current node
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == next {
+						cycle := append([]int64{}, stack[i:]...)
+						cycles = append(cycles, append(cycle, next))
+						break
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	// Visit in link order so results are stable
+	for _, link := range links {
+		if state[link.SourceScheduleID] == unvisited {
+			visit(link.SourceScheduleID)
+		}
+	}
+
+	return cycles
+}
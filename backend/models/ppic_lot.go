@@ -0,0 +1,203 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Splitting a schedule into lots: the schedule becomes the summary of its lots. It keeps the NJO
+// and the links and spans the lots' dates; each lot has its own quantity, dates and machines
+
+// SplitPPICScheduleRequest splits a schedule into lots
+type SplitPPICScheduleRequest struct {
+	Lots    []SplitLotRequest `json:"lots" binding:"required,min=2,dive"`
+	Version *int              `json:"version"` // Version last read; required (or If-Match)
+}
+
+// SplitLotRequest is one lot of a split. Dates default to the schedule's dates and machine
+// assignments to the schedule's routing, with target hours prorated by quantity
+type SplitLotRequest struct {
+	Quantity           int                              `json:"quantity" binding:"required,min=1"`
+	StartDate          string                           `json:"start_date"`
+	FinishDate         string                           `json:"finish_date"`
+	PPICNotes          string                           `json:"ppic_notes"`
+	MachineAssignments []CreateMachineAssignmentRequest `json:"machine_assignments"`
+}
+
+// MergePPICLotsRequest merges lots of a split schedule. Without lot IDs (or with all of them)
+// the schedule is no longer split; otherwise the lots are merged into the lowest numbered one
+type MergePPICLotsRequest struct {
+	LotIDs  []int64 `json:"lot_ids"`
+	Version *int    `json:"version"` // Version of the split schedule last read; required (or If-Match)
+}
+
+// ValidateLotQuantities checks the lot quantities add up to the schedule's quantity (when it
+// has one) and returns their total
+func (r *SplitPPICScheduleRequest) ValidateLotQuantities(scheduleQuantity int) (int, error) {
+	if len(r.Lots) < 2 {
+		return 0, errors.New("a split needs at least 2 lots")
+	}
+	total := 0
+	for i, lot := range r.Lots {
+		if lot.Quantity < 1 {
+			return 0, fmt.Errorf("lot %d: quantity must be at least 1", i+1)
+		}
+		total += lot.Quantity
+	}
+	if scheduleQuantity > 0 && total != scheduleQuantity {
+		return 0, fmt.Errorf("lot quantities add up to %d, but the schedule quantity is %d", total, scheduleQuantity)
+	}
+	return total, nil
+}
+
+// LotSpan returns the earliest start and latest finish of the lots
+func LotSpan(lots []PPICSchedule) (time.Time, time.Time) {
+	var start, finish time.Time
+	for i, lot := range lots {
+		if i == 0 || lot.StartDate.Before(start) {
+			start = lot.StartDate
+		}
+		if i == 0 || lot.FinishDate.After(finish) {
+			finish = lot.FinishDate
+		}
+	}
+	return start, finish
+}
+
+// LotQuantity returns the total quantity of the lots
+func LotQuantity(lots []PPICSchedule) int {
+	total := 0
+	for _, lot := range lots {
+		total += lot.Quantity
+	}
+	return total
+}
+
+// DeriveSplitProgress computes a split schedule's progress and status from its lots. Progress is
+// weighted by lot quantity (if no lot has a quantity, each counts the same). The schedule is
+// completed once every lot is. ok is false when there are no lots
+func DeriveSplitProgress(lots []PPICSchedule) (progress int, status string, ok bool) {
+	if len(lots) == 0 {
+		return 0, "", false
+	}
+
+	totalQuantity := LotQuantity(lots)
+	var done, total float64
+	completed, started := true, false
+	for _, lot := range lots {
+		weight := float64(lot.Quantity)
+		if totalQuantity <= 0 {
+			weight = 1
+		}
+		total += weight
+		done += weight * float64(lot.Progress) / 100
+
+		if lot.Status != ScheduleStatusCompleted {
+			completed = false
+		}
+		if lot.Progress > 0 || lot.Status == ScheduleStatusInProgress || lot.Status == ScheduleStatusCompleted {
+			started = true
+		}
+	}
+
+	if completed {
+		return 100, ScheduleStatusCompleted, true
+	}
+	if !started {
+		return 0, ScheduleStatusPending, true
+	}
+
+	progress = int(math.Round(done / total * 100))
+	if progress < 1 {
+		progress = 1
+	}
+	if progress > 99 {
+		progress = 99
+	}
+	return progress, ScheduleStatusInProgress, true
+}
+
+// WorkRecorded reports whether work was recorded on any machine of the schedule
+func WorkRecorded(schedule *PPICSchedule) bool {
+	for _, ma := range schedule.MachineAssignments {
+		if ma.ActualStart != nil || ma.ActualEnd != nil || ma.Status != AssignmentStatusPending {
+			return true
+		}
+	}
+	return false
+}
+
+// ProrateAssignments copies a routing for a lot holding share (0..1] of the quantity. Target
+// hours are prorated; scheduled windows are dropped since they belong to the whole quantity
+func ProrateAssignments(assignments []MachineAssignment, share float64) []CreateMachineAssignmentRequest {
+	requests := make([]CreateMachineAssignmentRequest, 0, len(assignments))
+	for _, ma := range assignments {
+		requests = append(requests, CreateMachineAssignmentRequest{
+			MachineID:   ma.MachineID,
+			Sequence:    ma.Sequence,
+			TargetHours: math.Round(ma.TargetHours*share*100) / 100,
+		})
+	}
+	return requests
+}
+
+// MergeLotAssignments combines the routings of lots into one: per sequence the machine of the
+// lowest numbered lot with that sequence, with the target hours of all lots. Scheduled windows
+// are dropped since none of them covers the merged quantity
+func MergeLotAssignments(lots []PPICSchedule) []CreateMachineAssignmentRequest {
+	ordered := append([]PPICSchedule{}, lots...)
+	sort.SliceStable(ordered, func(i, j int) bool { return lotNumber(ordered[i]) < lotNumber(ordered[j]) })
+
+	bySequence := make(map[int]*CreateMachineAssignmentRequest)
+	var sequences []int
+	for _, lot := range ordered {
+		for _, ma := range lot.MachineAssignments {
+			if merged, ok := bySequence[ma.Sequence]; ok {
+				merged.TargetHours += ma.TargetHours
+				continue
+			}
+			bySequence[ma.Sequence] = &CreateMachineAssignmentRequest{MachineID: ma.MachineID, Sequence: ma.Sequence, TargetHours: ma.TargetHours}
+			sequences = append(sequences, ma.Sequence)
+		}
+	}
+
+	sort.Ints(sequences)
+	requests := make([]CreateMachineAssignmentRequest, 0, len(sequences))
+	for _, seq := range sequences {
+		requests = append(requests, *bySequence[seq])
+	}
+	return requests
+}
+
+// ShiftLot returns the lot moved by days working days, keeping its number of working days.
+// Its scheduled machine windows move by the same working days, keeping their time of day and length
+func ShiftLot(calendar *WorkingCalendar, lot PPICSchedule, days int) PPICSchedule {
+	moved := lot
+	moved.StartDate = calendar.ShiftWorkingDays(lot.StartDate, days)
+	moved.FinishDate = calendar.AddWorkingDays(moved.StartDate, calendar.WorkingDaysBetween(lot.StartDate, lot.FinishDate)-1)
+	moved.MachineAssignments = make([]MachineAssignment, len(lot.MachineAssignments))
+	for i, ma := range lot.MachineAssignments {
+		if ma.ScheduledStart != nil && ma.ScheduledEnd != nil {
+			start := calendar.ShiftWorkingDays(*ma.ScheduledStart, days)
+			end := start.Add(ma.ScheduledEnd.Sub(*ma.ScheduledStart))
+			ma.ScheduledStart, ma.ScheduledEnd = &start, &end
+		}
+		moved.MachineAssignments[i] = ma
+	}
+	return moved
+}
+
+// LotTaskName is the Gantt task name of a lot
+func LotTaskName(lot *PPICSchedule) string {
+	return fmt.Sprintf("%s - Lot %d", lot.PartName, lotNumber(*lot))
+}
+
+func lotNumber(lot PPICSchedule) int {
+	if lot.LotNumber == nil {
+		return 0
+	}
+	return *lot.LotNumber
+}
@@ -0,0 +1,69 @@
+package models
+
+import "math"
+
+// DeriveScheduleProgress computes a schedule's progress and status from its machine assignments.
+// Progress is the share of target hours done: completed assignments count in full, in-progress
+// assignments count half (if no assignment has target hours, each counts the same).
+// The schedule is in_progress from the first actual start and completed once the last sequence
+// finishes. ok is false when there are no assignments to derive from
+func DeriveScheduleProgress(assignments []MachineAssignment) (progress int, status string, ok bool) {
+	if len(assignments) == 0 {
+		return 0, "", false
+	}
+
+	totalHours := 0.0
+	for _, ma := range assignments {
+		totalHours += ma.TargetHours
+	}
+
+	var done, total float64
+	started := false
+	last := assignments[0]
+	for _, ma := range assignments {
+		weight := ma.TargetHours
+		if totalHours <= 0 {
+			weight = 1
+		}
+		total += weight
+
+		switch {
+		case assignmentFinished(ma):
+			done += weight
+			started = true
+		case ma.ActualStart != nil || ma.Status == AssignmentStatusInProgress:
+			done += weight / 2
+			started = true
+		}
+
+		if ma.Sequence > last.Sequence {
+			last = ma
+		}
+	}
+
+	if assignmentFinished(last) {
+		return 100, ScheduleStatusCompleted, true
+	}
+	if !started {
+		return 0, ScheduleStatusPending, true
+	}
+
+	// Work is recorded but the last sequence isn't done: never show 0% or 100%
+	progress = int(math.Round(done / total * 100))
+	if progress < 1 {
+		progress = 1
+	}
+	if progress > 99 {
+		progress = 99
+	}
+	return progress, ScheduleStatusInProgress, true
+}
+
+// HasStarted reports whether work on the assignment has been recorded
+func (ma *MachineAssignment) HasStarted() bool {
+	return ma.ActualStart != nil || ma.ActualEnd != nil || ma.Status != AssignmentStatusPending
+}
+
+func assignmentFinished(ma MachineAssignment) bool {
+	return ma.Status == AssignmentStatusCompleted || ma.ActualEnd != nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Scenario status constants
+const (
+	ScenarioStatusDraft    = "draft"    // Open for what-if changes
+	ScenarioStatusPromoted = "promoted" // Copied to the live board, read-only
+)
+
+// PPICScenario is a named what-if copy of the PPIC board (schedules, machine assignments and links)
+type PPICScenario struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	Status        string     `json:"status"`
+	CreatedBy     int64      `json:"created_by"`
+	PromotedAt    *time.Time `json:"promoted_at"`
+	ScheduleCount int        `json:"schedule_count"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ScenarioScheduleSource maps a scenario schedule to the live schedule it was copied from
+type ScenarioScheduleSource struct {
+	ScheduleID       int64  // Scenario copy
+	SourceScheduleID *int64 // Live schedule, nil when added in the scenario
+	Deleted          bool   // Copy was deleted in the scenario
+}
+
+// Request DTOs
+
+type CreatePPICScenarioRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// Comparison DTOs
+
+// Scenario schedule change values
+const (
+	ScenarioChangeAdded     = "added"     // Only in the scenario
+	ScenarioChangeRemoved   = "removed"   // Deleted in the scenario
+	ScenarioChangeMoved     = "moved"     // Dates differ from live
+	ScenarioChangeUnchanged = "unchanged" // Same dates as live
+	ScenarioChangeLiveOnly  = "live_only" // Created on the live board after the scenario was copied
+)
+
+type ScenarioScheduleDiff struct {
+	NJO                string     `json:"njo"`
+	PartName           string     `json:"part_name"`
+	Change             string     `json:"change"`
+	LiveScheduleID     *int64     `json:"live_schedule_id"`
+	ScenarioScheduleID *int64     `json:"scenario_schedule_id"`
+	LiveStartDate      *time.Time `json:"live_start_date"`
+	LiveFinishDate     *time.Time `json:"live_finish_date"`
+	ScenarioStartDate  *time.Time `json:"scenario_start_date"`
+	ScenarioFinishDate *time.Time `json:"scenario_finish_date"`
+	FinishDeltaDays    int        `json:"finish_delta_days"` // Positive = finishes later in the scenario
+}
+
+type ScenarioMachineLoad struct {
+	MachineID     int64   `json:"machine_id"`
+	MachineName   string  `json:"machine_name"`
+	MachineCode   string  `json:"machine_code"`
+	LiveHours     float64 `json:"live_hours"`
+	ScenarioHours float64 `json:"scenario_hours"`
+	DeltaHours    float64 `json:"delta_hours"`
+}
+
+type ScenarioComparison struct {
+	Scenario       *PPICScenario          `json:"scenario"`
+	LiveFinish     *time.Time             `json:"live_finish"`
+	ScenarioFinish *time.Time             `json:"scenario_finish"`
+	ChangedCount   int                    `json:"changed_count"`
+	Schedules      []ScenarioScheduleDiff `json:"schedules"`
+	MachineLoad    []ScenarioMachineLoad  `json:"machine_load"`
+}
+
+// ScenarioLiveState is a live schedule a scenario copy was made from, as it is at promotion
+type ScenarioLiveState struct {
+	NJO       string
+	UpdatedAt time.Time
+	Deleted   bool
+}
+
+// ScenarioLiveLink is a live link touching a schedule the scenario promotes
+type ScenarioLiveLink struct {
+	SourceNJO string
+	TargetNJO string
+	CreatedAt time.Time
+}
+
+// ScenarioPromoteConflicts lists what changed on the live board after the scenario was created:
+// schedules changed or deleted since, and links added since, which promoting would overwrite or drop
+func ScenarioPromoteConflicts(createdAt time.Time, live []ScenarioLiveState, links []ScenarioLiveLink) []string {
+	var conflicts []string
+	for _, l := range live {
+		switch {
+		case l.Deleted:
+			conflicts = append(conflicts, l.NJO+" (deleted)")
+		case l.UpdatedAt.After(createdAt):
+			conflicts = append(conflicts, l.NJO)
+		}
+	}
+	for _, link := range links {
+		if link.CreatedAt.After(createdAt) {
+			conflicts = append(conflicts, fmt.Sprintf("link %s -> %s", link.SourceNJO, link.TargetNJO))
+		}
+	}
+	return conflicts
+}
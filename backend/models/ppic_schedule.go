@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Priority constants
 const (
@@ -26,6 +30,13 @@ const (
 	ScheduleStatusOnHold     = "on_hold"
 )
 
+// Machine assignment status constants
+const (
+	AssignmentStatusPending    = "pending"
+	AssignmentStatusInProgress = "in_progress"
+	AssignmentStatusCompleted  = "completed"
+)
+
 // PPICSchedule represents a PPIC schedule entry for Gantt chart
 type PPICSchedule struct {
 	ID                 int64               `json:"id"`
@@ -36,13 +47,19 @@ type PPICSchedule struct {
 	MaterialStatus     string              `json:"material_status"`
 	Status             string              `json:"status"`
 	Progress           int                 `json:"progress"`
+	ProgressOverride   bool                `json:"progress_override"` // Progress and status set by hand instead of derived from the machines
 	StartDate          time.Time           `json:"start_date"`
 	FinishDate         time.Time           `json:"finish_date"`
 	PPICNotes          string              `json:"ppic_notes"`
+	Quantity           int                 `json:"quantity"`                     // 0 = not recorded
+	ParentScheduleID   *int64              `json:"parent_schedule_id,omitempty"` // Set on lots split from another schedule
+	LotNumber          *int                `json:"lot_number,omitempty"`
+	Version            int                 `json:"version"` // Bumped on every change, including to the machine assignments
 	CreatedBy          int64               `json:"created_by"`
 	CreatedAt          time.Time           `json:"created_at"`
 	UpdatedAt          time.Time           `json:"updated_at"`
 	MachineAssignments []MachineAssignment `gorm:"-" json:"machine_assignments"`
+	Lots               []PPICSchedule      `gorm:"-" json:"lots,omitempty"` // Only loaded for a single schedule
 }
 
 // MachineAssignment represents a machine assigned to a schedule
@@ -59,6 +76,7 @@ type MachineAssignment struct {
 	ActualStart    *time.Time `json:"actual_start"`
 	ActualEnd      *time.Time `json:"actual_end"`
 	Status         string     `json:"status"`
+	Version        int        `json:"version"`
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 }
@@ -74,12 +92,15 @@ type CreatePPICScheduleRequest struct {
 	StartDate          string                           `json:"start_date" binding:"required"`
 	FinishDate         string                           `json:"finish_date" binding:"required"`
 	PPICNotes          string                           `json:"ppic_notes"`
-	MachineAssignments []CreateMachineAssignmentRequest `json:"machine_assignments" binding:"max=5"`
+	Quantity           int                              `json:"quantity" binding:"min=0"`
+	MachineAssignments []CreateMachineAssignmentRequest `json:"machine_assignments"` // With a routing template: overrides of its steps, by sequence
+	RoutingTemplateID  *int64                           `json:"routing_template_id"`
+	SkipSequences      []int                            `json:"skip_sequences"` // Template steps to leave out
 }
 
 type CreateMachineAssignmentRequest struct {
 	MachineID      int64   `json:"machine_id" binding:"required"`
-	Sequence       int     `json:"sequence" binding:"required,min=1,max=5"`
+	Sequence       int     `json:"sequence" binding:"required,min=1"`
 	TargetHours    float64 `json:"target_hours"`
 	ScheduledStart string  `json:"scheduled_start"`
 	ScheduledEnd   string  `json:"scheduled_end"`
@@ -92,31 +113,105 @@ type UpdatePPICScheduleRequest struct {
 	MaterialStatus     string                           `json:"material_status"`
 	Status             string                           `json:"status"`
 	Progress           *int                             `json:"progress"`
+	ProgressOverride   *bool                            `json:"progress_override"` // Required to set status/progress by hand
 	StartDate          string                           `json:"start_date"`
 	FinishDate         string                           `json:"finish_date"`
 	PPICNotes          string                           `json:"ppic_notes"`
+	Quantity           *int                             `json:"quantity"`
 	MachineAssignments []UpdateMachineAssignmentRequest `json:"machine_assignments"`
+	Version            *int                             `json:"version"` // Version last read; the If-Match header takes precedence
 }
 
 type UpdateMachineAssignmentRequest struct {
-	ID          int64      `json:"id"`
-	MachineID   int64      `json:"machine_id"`
-	Sequence    int        `json:"sequence"`
-	TargetHours float64    `json:"target_hours"`
-	Status      string     `json:"status"`
-	ActualStart *time.Time `json:"actual_start"`
-	ActualEnd   *time.Time `json:"actual_end"`
+	ID             int64      `json:"id"`
+	MachineID      int64      `json:"machine_id"`
+	Sequence       int        `json:"sequence"`
+	TargetHours    float64    `json:"target_hours"`
+	ScheduledStart *time.Time `json:"scheduled_start"`
+	ScheduledEnd   *time.Time `json:"scheduled_end"`
+	Status         string     `json:"status"`
+	ActualStart    *time.Time `json:"actual_start"`
+	ActualEnd      *time.Time `json:"actual_end"`
+}
+
+// CreateRequest returns the assignment as a create request, so a new or changed assignment of an
+// update goes through the same window parsing and checks as a created one
+func (r *UpdateMachineAssignmentRequest) CreateRequest() CreateMachineAssignmentRequest {
+	format := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	return CreateMachineAssignmentRequest{
+		MachineID:      r.MachineID,
+		Sequence:       r.Sequence,
+		TargetHours:    r.TargetHours,
+		ScheduledStart: format(r.ScheduledStart),
+		ScheduledEnd:   format(r.ScheduledEnd),
+	}
+}
+
+// ParseScheduledWindow parses the optional scheduled start/end of a machine assignment request.
+// Accepts RFC3339 or "YYYY-MM-DDTHH:MM" (datetime-local input)
+func (r *CreateMachineAssignmentRequest) ParseScheduledWindow() (*time.Time, *time.Time, error) {
+	parse := func(field, value string) (*time.Time, error) {
+		if value == "" {
+			return nil, nil
+		}
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return &t, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid %s format. Use RFC3339 (e.g. 2024-01-01T08:00:00Z)", field)
+	}
+
+	start, err := parse("scheduled_start", r.ScheduledStart)
+	if err != nil {
+		return nil, nil, err
+	}
+	end, err := parse("scheduled_end", r.ScheduledEnd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if start != nil && end != nil && !end.After(*start) {
+		return nil, nil, errors.New("scheduled_end must be after scheduled_start")
+	}
+	return start, end, nil
 }
 
 // Gantt Chart DTOs
 
 type GanttFilterRequest struct {
-	StartDate string `form:"start_date"`
-	EndDate   string `form:"end_date"`
-	Priority  string `form:"priority"`
-	Status    string `form:"status"`
-	MachineID int64  `form:"machine_id"`
-	GroupBy   string `form:"group_by"` // "priority", "machine", or empty for all
+	StartDate    string `form:"start_date"`
+	EndDate      string `form:"end_date"`
+	Priority     string `form:"priority"`
+	Status       string `form:"status"`
+	MachineID    int64  `form:"machine_id"`
+	GroupBy      string `form:"group_by"`      // "priority", "machine", or empty for all
+	CriticalPath bool   `form:"critical_path"` // Mark critical tasks and float on each task
+	BaselineID   int64  `form:"baseline_id"`   // Add baseline dates and slip to each task
+	Format       string `form:"format"`        // "json" (default), "csv", "xlsx" or "pdf"
+	WindowStart  string `form:"window_start"`  // Date-window pagination: only tasks overlapping window_start .. +window_days
+	WindowDays   int    `form:"window_days"`   // Window length, default DefaultGanttWindowDays
+}
+
+// Date-window pagination limits for the Gantt chart
+const (
+	DefaultGanttWindowDays = 28
+	MaxGanttWindowDays     = 366
+)
+
+// GanttDateWindow describes the page of a date-window paginated Gantt chart
+type GanttDateWindow struct {
+	Start        string `json:"start"`
+	End          string `json:"end"` // Inclusive
+	Days         int    `json:"days"`
+	PrevStart    string `json:"prev_start"`
+	NextStart    string `json:"next_start"`
+	EarlierTasks int    `json:"earlier_tasks"` // Matching tasks finishing before the window
+	LaterTasks   int    `json:"later_tasks"`   // Matching tasks starting after the window
 }
 
 type GanttChartResponse struct {
@@ -125,29 +220,42 @@ type GanttChartResponse struct {
 	Links    []GanttLink         `json:"links"`
 	Summary  GanttSummary        `json:"summary"`
 	Filters  GanttFiltersApplied `json:"filters_applied"`
+	Window   *GanttDateWindow    `json:"window,omitempty"` // Only set when window_start is given
 }
 
 type GanttSection struct {
-	SectionID   string      `json:"section_id"`
-	SectionName string      `json:"section_name"`
-	Tasks       []GanttTask `json:"tasks"`
+	SectionID   string             `json:"section_id"`
+	SectionName string             `json:"section_name"`
+	Tasks       []GanttTask        `json:"tasks"`
+	Downtime    []GanttDowntimeBar `json:"downtime,omitempty"` // Machine sections only
 }
 
 type GanttTask struct {
-	TaskID         string             `json:"task_id"`
-	TaskName       string             `json:"task_name"`
-	NJO            string             `json:"njo"`
-	PartName       string             `json:"part_name"`
-	Start          time.Time          `json:"start"`
-	End            time.Time          `json:"end"`
-	Priority       string             `json:"priority"`
-	PriorityAlpha  string             `json:"priority_alpha"`
-	MaterialStatus string             `json:"material_status"`
-	Status         string             `json:"status"`
-	Progress       int                `json:"progress"`
-	PPICNotes      string             `json:"ppic_notes"`
-	Color          string             `json:"color"`
-	Machines       []GanttMachineInfo `json:"machines"`
+	TaskID           string             `json:"task_id"`
+	TaskName         string             `json:"task_name"`
+	NJO              string             `json:"njo"`
+	PartName         string             `json:"part_name"`
+	Start            time.Time          `json:"start"`
+	End              time.Time          `json:"end"`
+	Priority         string             `json:"priority"`
+	PriorityAlpha    string             `json:"priority_alpha"`
+	MaterialStatus   string             `json:"material_status"`
+	Status           string             `json:"status"`
+	Progress         int                `json:"progress"`
+	ProgressOverride bool               `json:"progress_override"`
+	PPICNotes        string             `json:"ppic_notes"`
+	Version          int                `json:"version"`
+	Quantity         int                `json:"quantity"`
+	Parent           string             `json:"parent,omitempty"`     // Lots: task ID of the schedule they were split from
+	LotNumber        *int               `json:"lot_number,omitempty"` // Lots only
+	IsSplit          bool               `json:"is_split"`             // Summary of lots; dates follow the lots
+	Color            string             `json:"color"`
+	Machines         []GanttMachineInfo `json:"machines"`
+	IsCritical       bool               `json:"is_critical"`
+	FloatDays        *int               `json:"float_days,omitempty"`     // Only set when critical_path=true
+	BaselineStart    *time.Time         `json:"baseline_start,omitempty"` // Only set when baseline_id is given
+	BaselineEnd      *time.Time         `json:"baseline_end,omitempty"`
+	SlipDays         *int               `json:"slip_days,omitempty"` // Finish vs baseline finish, positive = late
 }
 
 type GanttMachineInfo struct {
@@ -159,6 +267,8 @@ type GanttMachineInfo struct {
 	ScheduledEnd   *time.Time `json:"scheduled_end"`
 	Status         string     `json:"status"`
 	Sequence       int        `json:"sequence"`
+	BaselineStart  *time.Time `json:"baseline_start,omitempty"` // Only set when baseline_id is given
+	BaselineEnd    *time.Time `json:"baseline_end,omitempty"`
 }
 
 type GanttSummary struct {
@@ -179,14 +289,78 @@ type GanttLink struct {
 	Source string `json:"source"` // Format: "task-{schedule_id}"
 	Target string `json:"target"` // Format: "task-{schedule_id}"
 	Type   string `json:"type"`   // Link type (0, 1, 2, 3)
+	Lag    int    `json:"lag"`    // Lag in days (negative = lead)
+}
+
+// Machine conflict DTOs
+
+type MachineConflictFilterRequest struct {
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
+	MachineID int64  `form:"machine_id"`
+}
+
+// MachineAssignmentWindow is one scheduled booking of a machine
+type MachineAssignmentWindow struct {
+	AssignmentID   int64     `json:"assignment_id"`
+	ScheduleID     int64     `json:"schedule_id"`
+	NJO            string    `json:"njo"`
+	PartName       string    `json:"part_name"`
+	Sequence       int       `json:"sequence"`
+	ScheduledStart time.Time `json:"scheduled_start"`
+	ScheduledEnd   time.Time `json:"scheduled_end"`
+}
+
+type MachineConflict struct {
+	First        MachineAssignmentWindow `json:"first"`
+	Second       MachineAssignmentWindow `json:"second"`
+	OverlapStart time.Time               `json:"overlap_start"`
+	OverlapEnd   time.Time               `json:"overlap_end"`
+	OverlapHours float64                 `json:"overlap_hours"`
+}
+
+type MachineConflictGroup struct {
+	MachineID   int64             `json:"machine_id"`
+	MachineName string            `json:"machine_name"`
+	MachineCode string            `json:"machine_code"`
+	Conflicts   []MachineConflict `json:"conflicts"`
+}
+
+type MachineConflictResponse struct {
+	Machines       []MachineConflictGroup `json:"machines"`
+	TotalConflicts int                    `json:"total_conflicts"`
+}
+
+// Critical path DTOs
+
+type CriticalPathTask struct {
+	ScheduleID      int64     `json:"schedule_id"`
+	NJO             string    `json:"njo"`
+	PartName        string    `json:"part_name"`
+	ScheduledStart  time.Time `json:"scheduled_start"`
+	ScheduledFinish time.Time `json:"scheduled_finish"`
+	EarlyStart      time.Time `json:"early_start"`
+	EarlyFinish     time.Time `json:"early_finish"`
+	LateStart       time.Time `json:"late_start"`
+	LateFinish      time.Time `json:"late_finish"`
+	TotalFloatDays  int       `json:"total_float_days"`
+	IsCritical      bool      `json:"is_critical"`
+}
+
+type CriticalPathResponse struct {
+	ProjectStart  time.Time          `json:"project_start"`
+	ProjectFinish time.Time          `json:"project_finish"`
+	Tasks         []CriticalPathTask `json:"tasks"`
+	CriticalChain []CriticalPathTask `json:"critical_chain"` // Critical tasks ordered by early start
 }
 
 type GanttFiltersApplied struct {
-	StartDate *time.Time `json:"start_date"`
-	EndDate   *time.Time `json:"end_date"`
-	Priority  string     `json:"priority"`
-	Status    string     `json:"status"`
-	MachineID *int64     `json:"machine_id"`
+	StartDate  *time.Time `json:"start_date"`
+	EndDate    *time.Time `json:"end_date"`
+	Priority   string     `json:"priority"`
+	Status     string     `json:"status"`
+	MachineID  *int64     `json:"machine_id"`
+	BaselineID *int64     `json:"baseline_id,omitempty"`
 }
 
 // Validation functions
@@ -211,6 +385,26 @@ func ValidateMaterialStatus(status string) bool {
 	return false
 }
 
+func ValidateScheduleStatus(status string) bool {
+	validStatuses := []string{ScheduleStatusPending, ScheduleStatusInProgress, ScheduleStatusCompleted, ScheduleStatusOnHold}
+	for _, s := range validStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateAssignmentStatus(status string) bool {
+	validStatuses := []string{AssignmentStatusPending, AssignmentStatusInProgress, AssignmentStatusCompleted}
+	for _, s := range validStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 func GetPriorityColor(priority string) string {
 	colors := map[string]string{
 		PriorityTopUrgent: "#dc3545", // Red
@@ -224,12 +418,36 @@ func GetPriorityColor(priority string) string {
 	return "#6c757d" // Gray default
 }
 
+// PriorityRank orders priorities like the ORDER BY in GetWithFilters: Top Urgent first, unknown last
+func PriorityRank(priority string) int {
+	ranks := map[string]int{
+		PriorityTopUrgent: 1,
+		PriorityUrgent:    2,
+		PriorityMedium:    3,
+		PriorityLow:       4,
+	}
+	if rank, ok := ranks[priority]; ok {
+		return rank
+	}
+	return 5
+}
+
+// Link type constants (matches the frontend Gantt link types)
+const (
+	LinkTypeFinishToStart  = "0"
+	LinkTypeStartToStart   = "1"
+	LinkTypeFinishToFinish = "2"
+	LinkTypeStartToFinish  = "3"
+)
+
 // PPICLink represents a dependency link between schedules
 type PPICLink struct {
 	ID               int64     `json:"id" gorm:"primaryKey"`
-	SourceScheduleID int64     `json:"source_schedule_id" gorm:"not null"`
-	TargetScheduleID int64     `json:"target_schedule_id" gorm:"not null"`
+	SourceScheduleID int64     `json:"source_schedule_id" gorm:"not null;index"`
+	TargetScheduleID int64     `json:"target_schedule_id" gorm:"not null;index"`
 	LinkType         string    `json:"link_type" gorm:"size:20;default:'0'"` // 0=finish-to-start, 1=start-to-start, 2=finish-to-finish, 3=start-to-finish
+	LagDays          int       `json:"lag_days" gorm:"not null;default:0"`   // Positive = lag (wait), negative = lead (overlap)
+	ScenarioID       *int64    `json:"scenario_id,omitempty" gorm:"index"`   // nil = live board
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 }
@@ -238,4 +456,33 @@ type CreatePPICLinkRequest struct {
 	SourceScheduleID int64  `json:"source_schedule_id" binding:"required"`
 	TargetScheduleID int64  `json:"target_schedule_id" binding:"required"`
 	LinkType         string `json:"link_type"`
+	LagDays          int    `json:"lag_days"`
+}
+
+func ValidateLinkType(linkType string) bool {
+	validTypes := []string{LinkTypeFinishToStart, LinkTypeStartToStart, LinkTypeFinishToFinish, LinkTypeStartToFinish}
+	for _, t := range validTypes {
+		if t == linkType {
+			return true
+		}
+	}
+	return false
+}
+
+func GetLinkTypeName(linkType string) string {
+	names := map[string]string{
+		LinkTypeFinishToStart:  "finish-to-start",
+		LinkTypeStartToStart:   "start-to-start",
+		LinkTypeFinishToFinish: "finish-to-finish",
+		LinkTypeStartToFinish:  "start-to-finish",
+	}
+	if name, ok := names[linkType]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// WindowsOverlap reports whether two half-open time windows [aStart, aEnd) and [bStart, bEnd) overlap
+func WindowsOverlap(aStart, aEnd, bStart, bEnd time.Time) bool {
+	return aStart.Before(bEnd) && bStart.Before(aEnd)
 }
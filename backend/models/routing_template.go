@@ -0,0 +1,311 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RoutingTemplate is a reusable routing for a part family: the ordered operations a schedule
+// goes through, each on a specific machine or any machine of a type
+type RoutingTemplate struct {
+	ID          int64                 `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string                `gorm:"size:100;uniqueIndex;not null" json:"name"`
+	PartFamily  string                `gorm:"size:100;index" json:"part_family"`
+	Description string                `gorm:"type:text" json:"description"`
+	CreatedBy   int64                 `json:"created_by"`
+	Steps       []RoutingTemplateStep `gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE" json:"steps"`
+	CreatedAt   time.Time             `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time             `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (RoutingTemplate) TableName() string {
+	return "routing_templates"
+}
+
+// RoutingTemplateStep is one operation of a routing. The step runs on MachineID when set, else on
+// an active machine of MachineType. Alternatives are machines that can run the operation instead
+type RoutingTemplateStep struct {
+	ID           int64                    `gorm:"primaryKey;autoIncrement" json:"id"`
+	TemplateID   int64                    `gorm:"index;not null" json:"template_id"`
+	Sequence     int                      `gorm:"not null" json:"sequence"` // 1..n in operation order
+	Operation    string                   `gorm:"size:100" json:"operation"`
+	MachineType  string                   `gorm:"size:100" json:"machine_type,omitempty"`
+	MachineID    *int64                   `json:"machine_id,omitempty"`
+	TargetHours  float64                  `json:"target_hours"`
+	Alternatives []RoutingStepAlternative `gorm:"foreignKey:StepID;constraint:OnDelete:CASCADE" json:"alternatives,omitempty"`
+}
+
+func (RoutingTemplateStep) TableName() string {
+	return "routing_template_steps"
+}
+
+// RoutingStepAlternative is a machine (or machine type) that can run a step instead of its own,
+// in order of preference. TargetHours 0 means the step's target hours
+type RoutingStepAlternative struct {
+	ID          int64   `gorm:"primaryKey;autoIncrement" json:"id"`
+	StepID      int64   `gorm:"index;not null" json:"step_id"`
+	Preference  int     `gorm:"not null" json:"preference"` // 1 = tried first
+	MachineType string  `gorm:"size:100" json:"machine_type,omitempty"`
+	MachineID   *int64  `json:"machine_id,omitempty"`
+	TargetHours float64 `json:"target_hours"`
+}
+
+func (RoutingStepAlternative) TableName() string {
+	return "routing_step_alternatives"
+}
+
+// Request DTOs
+
+type CreateRoutingTemplateRequest struct {
+	Name        string                       `json:"name" binding:"required"`
+	PartFamily  string                       `json:"part_family"`
+	Description string                       `json:"description"`
+	Steps       []RoutingTemplateStepRequest `json:"steps" binding:"required,min=1,dive"`
+}
+
+// UpdateRoutingTemplateRequest changes a template; steps, when given, replace all of them
+type UpdateRoutingTemplateRequest struct {
+	Name        string                       `json:"name"`
+	PartFamily  *string                      `json:"part_family"`
+	Description *string                      `json:"description"`
+	Steps       []RoutingTemplateStepRequest `json:"steps" binding:"omitempty,dive"`
+}
+
+// RoutingTemplateStepRequest is a step in operation order; sequences are numbered from the order
+type RoutingTemplateStepRequest struct {
+	Operation    string                  `json:"operation"`
+	MachineType  string                  `json:"machine_type"`
+	MachineID    *int64                  `json:"machine_id"`
+	TargetHours  float64                 `json:"target_hours" binding:"min=0"`
+	Alternatives []RoutingMachineRequest `json:"alternatives" binding:"omitempty,dive"`
+}
+
+// RoutingMachineRequest is an alternative machine of a step, in order of preference
+type RoutingMachineRequest struct {
+	MachineType string  `json:"machine_type"`
+	MachineID   *int64  `json:"machine_id"`
+	TargetHours float64 `json:"target_hours" binding:"min=0"`
+}
+
+// ApplyRoutingTemplateRequest previews the machine assignments a template gives a schedule
+type ApplyRoutingTemplateRequest struct {
+	MachineAssignments []CreateMachineAssignmentRequest `json:"machine_assignments"` // Overrides by sequence; new sequences add steps
+	SkipSequences      []int                            `json:"skip_sequences"`
+}
+
+// RoutingStepResolution is a step of an applied template with the machine it was given
+type RoutingStepResolution struct {
+	Sequence     int       `json:"sequence"`
+	Operation    string    `json:"operation"`
+	MachineID    int64     `json:"machine_id"`
+	MachineCode  string    `json:"machine_code"`
+	MachineName  string    `json:"machine_name"`
+	TargetHours  float64   `json:"target_hours"`
+	Overridden   bool      `json:"overridden"`
+	Alternatives []Machine `json:"alternatives"` // Other active machines that can run the step
+}
+
+// AppliedRouting is the result of applying a template: the steps and the machine assignments to
+// create the schedule with
+type AppliedRouting struct {
+	TemplateID         int64                            `json:"template_id"`
+	TemplateName       string                           `json:"template_name"`
+	Steps              []RoutingStepResolution          `json:"steps"`
+	MachineAssignments []CreateMachineAssignmentRequest `json:"machine_assignments"`
+}
+
+// ValidateRoutingSteps checks the steps of a template: at most maxSteps, each with a machine or a
+// machine type, and the same for their alternatives
+func ValidateRoutingSteps(steps []RoutingTemplateStepRequest, maxSteps int) error {
+	if len(steps) == 0 {
+		return errors.New("a routing template needs at least 1 step")
+	}
+	if len(steps) > maxSteps {
+		return fmt.Errorf("a routing template can have at most %d steps", maxSteps)
+	}
+	for i, step := range steps {
+		if step.MachineID == nil && strings.TrimSpace(step.MachineType) == "" {
+			return fmt.Errorf("step %d: machine_id or machine_type is required", i+1)
+		}
+		for j, alt := range step.Alternatives {
+			if alt.MachineID == nil && strings.TrimSpace(alt.MachineType) == "" {
+				return fmt.Errorf("step %d, alternative %d: machine_id or machine_type is required", i+1, j+1)
+			}
+		}
+	}
+	return nil
+}
+
+// NewRoutingTemplateSteps builds the steps of a template, numbering sequences in operation order
+func NewRoutingTemplateSteps(requests []RoutingTemplateStepRequest) []RoutingTemplateStep {
+	steps := make([]RoutingTemplateStep, 0, len(requests))
+	for i, req := range requests {
+		step := RoutingTemplateStep{
+			Sequence:    i + 1,
+			Operation:   strings.TrimSpace(req.Operation),
+			MachineType: strings.TrimSpace(req.MachineType),
+			MachineID:   req.MachineID,
+			TargetHours: req.TargetHours,
+		}
+		for j, alt := range req.Alternatives {
+			step.Alternatives = append(step.Alternatives, RoutingStepAlternative{
+				Preference:  j + 1,
+				MachineType: strings.TrimSpace(alt.MachineType),
+				MachineID:   alt.MachineID,
+				TargetHours: alt.TargetHours,
+			})
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// routingCandidate is a machine or machine type that can run a step
+type routingCandidate struct {
+	machineID   *int64
+	machineType string
+	targetHours float64
+}
+
+func (c routingCandidate) matches(m *Machine) bool {
+	if c.machineID != nil {
+		return m.ID == *c.machineID
+	}
+	return strings.EqualFold(m.MachineType, c.machineType)
+}
+
+// candidates returns the step's own machine followed by its alternatives in order of preference
+func (step *RoutingTemplateStep) candidates() []routingCandidate {
+	list := []routingCandidate{{machineID: step.MachineID, machineType: step.MachineType, targetHours: step.TargetHours}}
+	alternatives := append([]RoutingStepAlternative{}, step.Alternatives...)
+	sort.SliceStable(alternatives, func(i, j int) bool { return alternatives[i].Preference < alternatives[j].Preference })
+	for _, alt := range alternatives {
+		hours := alt.TargetHours
+		if hours == 0 {
+			hours = step.TargetHours
+		}
+		list = append(list, routingCandidate{machineID: alt.MachineID, machineType: alt.MachineType, targetHours: hours})
+	}
+	return list
+}
+
+// ApplyRoutingTemplate turns a template into machine assignments. Each step gets the first active
+// machine among its own machine (or type) and its alternatives. Overrides replace the step with
+// the same sequence (target hours 0 keeps the template's), overrides with a new sequence add a
+// step, and skipped sequences are left out. machines are the machines to choose from
+func ApplyRoutingTemplate(template *RoutingTemplate, machines []Machine, overrides []CreateMachineAssignmentRequest, skip []int) (*AppliedRouting, error) {
+	skipped := make(map[int]bool, len(skip))
+	for _, seq := range skip {
+		skipped[seq] = true
+	}
+	overrideBySequence := make(map[int]CreateMachineAssignmentRequest, len(overrides))
+	for _, o := range overrides {
+		if _, dup := overrideBySequence[o.Sequence]; dup {
+			return nil, fmt.Errorf("sequence %d is overridden more than once", o.Sequence)
+		}
+		overrideBySequence[o.Sequence] = o
+	}
+	machineByID := make(map[int64]*Machine, len(machines))
+	for i := range machines {
+		machineByID[machines[i].ID] = &machines[i]
+	}
+
+	applied := &AppliedRouting{TemplateID: template.ID, TemplateName: template.Name}
+	steps := append([]RoutingTemplateStep{}, template.Steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Sequence < steps[j].Sequence })
+	for i := range steps {
+		step := &steps[i]
+		if skipped[step.Sequence] {
+			continue
+		}
+
+		// Every active machine that can run the step, in order of preference
+		var able []*Machine
+		var hours []float64
+		seen := make(map[int64]bool)
+		for _, c := range step.candidates() {
+			for j := range machines {
+				m := &machines[j]
+				if seen[m.ID] || !m.IsAvailable() || !c.matches(m) {
+					continue
+				}
+				seen[m.ID] = true
+				able = append(able, m)
+				hours = append(hours, c.targetHours)
+			}
+		}
+
+		resolution := RoutingStepResolution{Sequence: step.Sequence, Operation: step.Operation}
+		assignment := CreateMachineAssignmentRequest{Sequence: step.Sequence}
+		if o, ok := overrideBySequence[step.Sequence]; ok {
+			m := machineByID[o.MachineID]
+			if m == nil {
+				return nil, fmt.Errorf("sequence %d: machine %d not found", step.Sequence, o.MachineID)
+			}
+			resolution.MachineID, resolution.MachineCode, resolution.MachineName = m.ID, m.MachineCode, m.MachineName
+			resolution.TargetHours = step.TargetHours
+			if o.TargetHours > 0 {
+				resolution.TargetHours = o.TargetHours
+			}
+			resolution.Overridden = true
+			assignment.ScheduledStart, assignment.ScheduledEnd = o.ScheduledStart, o.ScheduledEnd
+			delete(overrideBySequence, step.Sequence)
+		} else {
+			if len(able) == 0 {
+				return nil, fmt.Errorf("sequence %d (%s): no active machine can run this step. Choose a machine for it", step.Sequence, stepLabel(step))
+			}
+			resolution.MachineID, resolution.MachineCode, resolution.MachineName = able[0].ID, able[0].MachineCode, able[0].MachineName
+			resolution.TargetHours = hours[0]
+		}
+		for _, m := range able {
+			if m.ID != resolution.MachineID {
+				resolution.Alternatives = append(resolution.Alternatives, *m)
+			}
+		}
+
+		applied.Steps = append(applied.Steps, resolution)
+		assignment.MachineID, assignment.TargetHours = resolution.MachineID, resolution.TargetHours
+		applied.MachineAssignments = append(applied.MachineAssignments, assignment)
+	}
+
+	// Overrides with a sequence of their own are extra steps
+	var extra []int
+	for seq := range overrideBySequence {
+		extra = append(extra, seq)
+	}
+	sort.Ints(extra)
+	for _, seq := range extra {
+		o := overrideBySequence[seq]
+		if skipped[seq] {
+			continue
+		}
+		m := machineByID[o.MachineID]
+		if m == nil {
+			return nil, fmt.Errorf("sequence %d: machine %d not found", seq, o.MachineID)
+		}
+		applied.Steps = append(applied.Steps, RoutingStepResolution{
+			Sequence: seq, MachineID: m.ID, MachineCode: m.MachineCode, MachineName: m.MachineName,
+			TargetHours: o.TargetHours, Overridden: true,
+		})
+		applied.MachineAssignments = append(applied.MachineAssignments, o)
+	}
+	sort.SliceStable(applied.Steps, func(i, j int) bool { return applied.Steps[i].Sequence < applied.Steps[j].Sequence })
+	sort.SliceStable(applied.MachineAssignments, func(i, j int) bool {
+		return applied.MachineAssignments[i].Sequence < applied.MachineAssignments[j].Sequence
+	})
+
+	return applied, nil
+}
+
+func stepLabel(step *RoutingTemplateStep) string {
+	if step.Operation != "" {
+		return step.Operation
+	}
+	if step.MachineType != "" {
+		return step.MachineType
+	}
+	return "machine"
+}
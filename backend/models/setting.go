@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Setting keys
+const (
+	SettingMaxMachinesPerSchedule = "max_machines_per_schedule"
+)
+
+// DefaultMaxMachinesPerSchedule applies until an admin configures another maximum
+const DefaultMaxMachinesPerSchedule = 5
+
+// Setting is an admin-configurable value, stored as text under its key
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"size:255;not null" json:"value"`
+	UpdatedBy int64     `json:"updated_by"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
+
+// PlanningSettings are the settings PPIC planning is validated against
+type PlanningSettings struct {
+	MaxMachinesPerSchedule int `json:"max_machines_per_schedule"` // Machine assignments per schedule (and steps per routing template); sequences run 1..max
+}
+
+// UpdatePlanningSettingsRequest changes planning settings; omitted fields are kept
+type UpdatePlanningSettingsRequest struct {
+	MaxMachinesPerSchedule *int `json:"max_machines_per_schedule" binding:"omitempty,min=1,max=50"`
+}
+
+// NewPlanningSettings reads planning settings from stored values, keyed by setting key.
+// Missing or unreadable values fall back to their defaults
+func NewPlanningSettings(values map[string]string) PlanningSettings {
+	settings := PlanningSettings{MaxMachinesPerSchedule: DefaultMaxMachinesPerSchedule}
+	if n, err := strconv.Atoi(values[SettingMaxMachinesPerSchedule]); err == nil && n > 0 {
+		settings.MaxMachinesPerSchedule = n
+	}
+	return settings
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Optimistic concurrency: editable records carry a version that every write bumps. A PUT must
+// send the version it last read (If-Match header or "version" field) and fails when it is stale
+var (
+	ErrVersionRequired = errors.New("version is required: send the If-Match header or a version field with the version you last read")
+	ErrStaleVersion    = errors.New("the record was changed by someone else since you read it. Reload and try again")
+)
+
+// ParseIfMatch parses the version in an If-Match header. Accepts 3, "3" and W/"3"
+func ParseIfMatch(header string) (int, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(header), "W/")
+	value = strings.Trim(value, `"`)
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid If-Match header %q: expected a version number", header)
+	}
+	return version, nil
+}
+
+// VersionETag formats a version as the ETag clients send back in If-Match
+func VersionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AndonRepository struct {
+	db *gorm.DB
+}
+
+func NewAndonRepository(db *gorm.DB) *AndonRepository {
+	return &AndonRepository{db: db}
+}
+
+// ========== Reason Codes ==========
+
+// GetReasonCodes retrieves reason codes, optionally only the active ones
+func (r *AndonRepository) GetReasonCodes(activeOnly bool) ([]models.AndonReasonCode, error) {
+	var codes []models.AndonReasonCode
+	query := r.db.Order("sort_order ASC, code ASC")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Find(&codes).Error
+	return codes, err
+}
+
+// GetReasonCodeByID retrieves a reason code by ID
+func (r *AndonRepository) GetReasonCodeByID(id int64) (*models.AndonReasonCode, error) {
+	var code models.AndonReasonCode
+	if err := r.db.First(&code, id).Error; err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// CreateReasonCode stores a reason code
+func (r *AndonRepository) CreateReasonCode(code *models.AndonReasonCode) error {
+	return r.db.Create(code).Error
+}
+
+// UpdateReasonCode saves changes to a reason code
+func (r *AndonRepository) UpdateReasonCode(code *models.AndonReasonCode) error {
+	return r.db.Save(code).Error
+}
+
+// DeleteReasonCode deletes a reason code
+func (r *AndonRepository) DeleteReasonCode(id int64) error {
+	result := r.db.Delete(&models.AndonReasonCode{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReasonCodeInUse reports whether a reason code has sub-codes or is recorded on an event
+func (r *AndonRepository) ReasonCodeInUse(id int64) (bool, error) {
+	var children, events int64
+	if err := r.db.Model(&models.AndonReasonCode{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return false, err
+	}
+	if err := r.db.Model(&models.AndonEvent{}).Where("reason_code_id = ?", id).Count(&events).Error; err != nil {
+		return false, err
+	}
+	return children > 0 || events > 0, nil
+}
+
+// ========== Events ==========
+
+// GetEventByID retrieves an event by ID
+func (r *AndonRepository) GetEventByID(id int64) (*models.AndonEvent, error) {
+	var event models.AndonEvent
+	if err := r.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// GetEvents retrieves events, newest first, overlapping [from, to). A zero from or to leaves that side open
+func (r *AndonRepository) GetEvents(filter models.AndonEventFilterRequest, from, to time.Time) ([]models.AndonEvent, error) {
+	var events []models.AndonEvent
+	query := r.db.Order("start_time DESC, id DESC")
+	if filter.MachineID > 0 {
+		query = query.Where("machine_id = ?", filter.MachineID)
+	}
+	if filter.ReasonCodeID > 0 {
+		query = query.Where("reason_code_id = ?", filter.ReasonCodeID)
+	}
+	if filter.JobOrderID > 0 {
+		query = query.Where("job_order_id = ?", filter.JobOrderID)
+	}
+	switch filter.Status {
+	case models.AndonEventOpen:
+		query = query.Where("end_time IS NULL")
+	case models.AndonEventClosed:
+		query = query.Where("end_time IS NOT NULL")
+	}
+	if !from.IsZero() {
+		query = query.Where("(end_time IS NULL OR end_time > ?)", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("start_time < ?", to)
+	}
+	err := query.Find(&events).Error
+	return events, err
+}
+
+// GetOpenEvents retrieves the open events, optionally of one machine, oldest first
+func (r *AndonRepository) GetOpenEvents(machineID int64) ([]models.AndonEvent, error) {
+	var events []models.AndonEvent
+	query := r.db.Where("end_time IS NULL").Order("start_time ASC, id ASC")
+	if machineID > 0 {
+		query = query.Where("machine_id = ?", machineID)
+	}
+	err := query.Find(&events).Error
+	return events, err
+}
+
+// WithLockedMachine runs fn in a transaction holding the machine's row lock (SELECT ... FOR UPDATE),
+// with a repository bound to that transaction; machine is nil if it doesn't exist. Events of the same
+// machine are then raised one at a time, so the overlap check and the status read stay valid until commit
+func (r *AndonRepository) WithLockedMachine(machineID int64, fn func(repo *AndonRepository, machine *models.Machine) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var machine models.Machine
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NULL").First(&machine, machineID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fn(&AndonRepository{db: tx}, nil)
+		}
+		if err != nil {
+			return err
+		}
+		return fn(&AndonRepository{db: tx}, &machine)
+	})
+}
+
+// CreateEvent stores an event and, when machineStatus is set, changes the machine's status in the same transaction
+func (r *AndonRepository) CreateEvent(event *models.AndonEvent, machineStatus string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+		if machineStatus == "" {
+			return nil
+		}
+		return setMachineStatus(tx, event.MachineID, machineStatus, "")
+	})
+}
+
+// UpdateEvent saves the reason code, job order, start time and notes of an event; the other
+// columns are left as they are
+func (r *AndonRepository) UpdateEvent(event *models.AndonEvent) error {
+	result := r.db.Model(event).Select("reason_code_id", "job_order_id", "start_time", "notes", "updated_at").Updates(event)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CloseEvent saves the end, reason code, notes and closer of an event that is still open and,
+// when restoreStatus is set, puts the machine back to it if it is still down. It returns
+// models.ErrAndonEventClosed when the event was closed already
+func (r *AndonRepository) CloseEvent(event *models.AndonEvent, restoreStatus string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(event).Where("end_time IS NULL").
+			Select("end_time", "reason_code_id", "notes", "closed_by", "updated_at").Updates(event)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrAndonEventClosed
+		}
+		if restoreStatus == "" {
+			return nil
+		}
+		return setMachineStatus(tx, event.MachineID, restoreStatus, models.MachineStatusDown)
+	})
+}
+
+// DeleteEvent deletes an event and, when restoreStatus is set, puts the machine back to it if it is still down
+func (r *AndonRepository) DeleteEvent(event *models.AndonEvent, restoreStatus string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.AndonEvent{}, event.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if restoreStatus == "" {
+			return nil
+		}
+		return setMachineStatus(tx, event.MachineID, restoreStatus, models.MachineStatusDown)
+	})
+}
+
+// setMachineStatus changes a machine's status, only from the given status when one is given
+func setMachineStatus(tx *gorm.DB, machineID int64, status, from string) error {
+	query := tx.Table("machines").Where("id = ? AND deleted_at IS NULL", machineID)
+	if from != "" {
+		query = query.Where("status = ?", from)
+	}
+	return query.Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
+}
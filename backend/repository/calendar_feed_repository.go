@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type CalendarFeedRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarFeedRepository(db *gorm.DB) *CalendarFeedRepository {
+	return &CalendarFeedRepository{db: db}
+}
+
+// ReplaceToken revokes the user's current token, if any, and stores a new one
+func (r *CalendarFeedRepository) ReplaceToken(token *models.CalendarFeedToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", token.UserID).Delete(&models.CalendarFeedToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+// GetByUser retrieves the user's token, or nil if they have none
+func (r *CalendarFeedRepository) GetByUser(userID int64) (*models.CalendarFeedToken, error) {
+	var token models.CalendarFeedToken
+	err := r.db.Where("user_id = ?", userID).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByTokenHash retrieves a token by its hash, or nil if it doesn't exist or was revoked
+func (r *CalendarFeedRepository) GetByTokenHash(tokenHash string) (*models.CalendarFeedToken, error) {
+	var token models.CalendarFeedToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteByUser revokes the user's token
+func (r *CalendarFeedRepository) DeleteByUser(userID int64) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.CalendarFeedToken{}).Error
+}
+
+// TouchLastUsed records that a feed was fetched with the token
+func (r *CalendarFeedRepository) TouchLastUsed(id int64, at time.Time) error {
+	return r.db.Model(&models.CalendarFeedToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
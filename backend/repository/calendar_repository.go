@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"time"
+
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type CalendarRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarRepository(db *gorm.DB) *CalendarRepository {
+	return &CalendarRepository{db: db}
+}
+
+// ========== Shifts ==========
+
+// CreateShift creates a new shift pattern entry
+func (r *CalendarRepository) CreateShift(shift *models.PlantShift) error {
+	return r.db.Create(shift).Error
+}
+
+// GetShiftByID retrieves a shift by ID
+func (r *CalendarRepository) GetShiftByID(id int64) (*models.PlantShift, error) {
+	var shift models.PlantShift
+	if err := r.db.First(&shift, id).Error; err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// GetShifts retrieves shifts for a machine, or plant-wide shifts when machineID is nil
+func (r *CalendarRepository) GetShifts(machineID *int64) ([]models.PlantShift, error) {
+	var shifts []models.PlantShift
+	query := r.db.Order("day_of_week ASC, start_time ASC")
+	if machineID == nil {
+		query = query.Where("machine_id IS NULL")
+	} else {
+		query = query.Where("machine_id = ?", *machineID)
+	}
+	err := query.Find(&shifts).Error
+	return shifts, err
+}
+
+// GetAllShifts retrieves every shift, plant-wide and per machine
+func (r *CalendarRepository) GetAllShifts() ([]models.PlantShift, error) {
+	var shifts []models.PlantShift
+	err := r.db.Order("machine_id ASC, day_of_week ASC, start_time ASC").Find(&shifts).Error
+	return shifts, err
+}
+
+// UpdateShift saves changes to a shift
+func (r *CalendarRepository) UpdateShift(shift *models.PlantShift) error {
+	return r.db.Save(shift).Error
+}
+
+// DeleteShift deletes a shift
+func (r *CalendarRepository) DeleteShift(id int64) error {
+	result := r.db.Delete(&models.PlantShift{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ========== Exceptions ==========
+
+// CreateException creates a holiday or special working day
+func (r *CalendarRepository) CreateException(exception *models.CalendarException) error {
+	return r.db.Create(exception).Error
+}
+
+// GetExceptions retrieves exceptions in a date range. When machineID is set, plant-wide
+// exceptions are included together with the machine's own
+func (r *CalendarRepository) GetExceptions(start, end *time.Time, machineID *int64) ([]models.CalendarException, error) {
+	var exceptions []models.CalendarException
+	query := r.db.Order("date ASC")
+	if start != nil {
+		query = query.Where("date >= ?", *start)
+	}
+	if end != nil {
+		query = query.Where("date <= ?", *end)
+	}
+	if machineID == nil {
+		query = query.Where("machine_id IS NULL")
+	} else {
+		query = query.Where("(machine_id IS NULL OR machine_id = ?)", *machineID)
+	}
+	err := query.Find(&exceptions).Error
+	return exceptions, err
+}
+
+// DeleteException deletes a calendar exception
+func (r *CalendarRepository) DeleteException(id int64) error {
+	result := r.db.Delete(&models.CalendarException{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -27,6 +27,7 @@ type nullableJobOrder struct {
 	OperatorID   sql.NullInt64
 	OperatorName sql.NullString
 	Status       string
+	Version      int
 	CreatedAt    time.Time
 	CompletedAt  sql.NullTime
 	UpdatedAt    time.Time
@@ -41,6 +42,7 @@ func (n *nullableJobOrder) toJobOrder() models.JobOrder {
 		Project:   n.Project,
 		Item:      n.Item,
 		Status:    n.Status,
+		Version:   n.Version,
 		CreatedAt: n.CreatedAt,
 		UpdatedAt: n.UpdatedAt,
 	}
@@ -83,6 +85,7 @@ func scanJobOrder(scanner interface{ Scan(...any) error }) (models.JobOrder, err
 		&n.OperatorID,
 		&n.OperatorName,
 		&n.Status,
+		&n.Version,
 		&n.CreatedAt,
 		&n.CompletedAt,
 		&n.UpdatedAt,
@@ -98,7 +101,7 @@ func (r *JobOrderRepository) GetAll() ([]models.JobOrder, error) {
 	query := `
 		SELECT 
 			jo.id, jo.machine_id, COALESCE(m.machine_name, ''), jo.njo, jo.project, jo.item, 
-			jo.note, jo.deadline, jo.operator_id, u.username, jo.status, 
+			jo.note, jo.deadline, jo.operator_id, u.username, jo.status, jo.version,
 			jo.created_at, jo.completed_at, jo.updated_at
 		FROM job_orders jo
 		LEFT JOIN machines m ON m.id = jo.machine_id
@@ -134,7 +137,7 @@ func (r *JobOrderRepository) GetByID(id int64) (*models.JobOrder, error) {
 	query := `
 		SELECT 
 			jo.id, jo.machine_id, COALESCE(m.machine_name, ''), jo.njo, jo.project, jo.item, 
-			jo.note, jo.deadline, jo.operator_id, u.username, jo.status, 
+			jo.note, jo.deadline, jo.operator_id, u.username, jo.status, jo.version,
 			jo.created_at, jo.completed_at, jo.updated_at
 		FROM job_orders jo
 		LEFT JOIN machines m ON m.id = jo.machine_id
@@ -165,7 +168,7 @@ func (r *JobOrderRepository) GetByMachineID(machineID int64) ([]models.JobOrder,
 	query := `
 		SELECT 
 			jo.id, jo.machine_id, COALESCE(m.machine_name, ''), jo.njo, jo.project, jo.item, 
-			jo.note, jo.deadline, jo.operator_id, u.username, jo.status, 
+			jo.note, jo.deadline, jo.operator_id, u.username, jo.status, jo.version,
 			jo.created_at, jo.completed_at, jo.updated_at
 		FROM job_orders jo
 		LEFT JOIN machines m ON m.id = jo.machine_id
@@ -196,6 +199,42 @@ func (r *JobOrderRepository) GetByMachineID(machineID int64) ([]models.JobOrder,
 	return jobs, nil
 }
 
+// GetByOperatorID retrieves all job orders assigned to an operator
+func (r *JobOrderRepository) GetByOperatorID(operatorID int64) ([]models.JobOrder, error) {
+	query := `
+		SELECT 
+			jo.id, jo.machine_id, COALESCE(m.machine_name, ''), jo.njo, jo.project, jo.item, 
+			jo.note, jo.deadline, jo.operator_id, u.username, jo.status, jo.version,
+			jo.created_at, jo.completed_at, jo.updated_at
+		FROM job_orders jo
+		LEFT JOIN machines m ON m.id = jo.machine_id
+		LEFT JOIN users u ON u.id = jo.operator_id
+		WHERE jo.operator_id = $1 AND jo.deleted_at IS NULL
+		ORDER BY jo.deadline
+	`
+
+	rows, err := r.db.Query(query, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.JobOrder
+	for rows.Next() {
+		j, err := scanJobOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
 // Create creates a new job order with default process stages
 func (r *JobOrderRepository) Create(req *models.CreateJobOrderRequest) (*models.JobOrder, error) {
 	tx, err := r.db.Begin()
@@ -208,7 +247,7 @@ func (r *JobOrderRepository) Create(req *models.CreateJobOrderRequest) (*models.
 	query := `
 		INSERT INTO job_orders (machine_id, njo, project, item, note, deadline, operator_id, status)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending')
-		RETURNING id, machine_id, njo, project, item, note, deadline, operator_id, status, created_at, updated_at
+		RETURNING id, machine_id, njo, project, item, note, deadline, operator_id, status, version, created_at, updated_at
 	`
 
 	// Use nullable types for scanning RETURNING clause
@@ -222,6 +261,7 @@ func (r *JobOrderRepository) Create(req *models.CreateJobOrderRequest) (*models.
 		deadline   sql.NullString
 		operatorID sql.NullInt64
 		status     string
+		version    int
 		createdAt  time.Time
 		updatedAt  time.Time
 	)
@@ -245,6 +285,7 @@ func (r *JobOrderRepository) Create(req *models.CreateJobOrderRequest) (*models.
 		&deadline,
 		&operatorID,
 		&status,
+		&version,
 		&createdAt,
 		&updatedAt,
 	)
@@ -261,6 +302,7 @@ func (r *JobOrderRepository) Create(req *models.CreateJobOrderRequest) (*models.
 		Project:   project,
 		Item:      item,
 		Status:    status,
+		Version:   version,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 	}
@@ -296,13 +338,18 @@ func (r *JobOrderRepository) Create(req *models.CreateJobOrderRequest) (*models.
 	return &j, nil
 }
 
-// Update updates a job order
+// Update updates a job order still at req.Version and bumps its version. Returns nil if the job
+// order doesn't exist or has a newer version; GetByID tells the two apart
 func (r *JobOrderRepository) Update(id int64, req *models.UpdateJobOrderRequest) (*models.JobOrder, error) {
+	if req.Version == nil {
+		return nil, models.ErrVersionRequired
+	}
+
 	query := `
 		UPDATE job_orders
-		SET project = $1, item = $2, note = $3, deadline = $4, operator_id = $5, status = $6, updated_at = $7
-		WHERE id = $8 AND deleted_at IS NULL
-		RETURNING id, machine_id, njo, project, item, note, deadline, operator_id, status, created_at, updated_at
+		SET project = $1, item = $2, note = $3, deadline = $4, operator_id = $5, status = $6, updated_at = $7, version = version + 1
+		WHERE id = $8 AND deleted_at IS NULL AND version = $9
+		RETURNING id, machine_id, njo, project, item, note, deadline, operator_id, status, version, created_at, updated_at
 	`
 
 	// Use nullable types for scanning RETURNING clause
@@ -316,6 +363,7 @@ func (r *JobOrderRepository) Update(id int64, req *models.UpdateJobOrderRequest)
 		deadline   sql.NullString
 		operatorID sql.NullInt64
 		status     string
+		version    int
 		createdAt  time.Time
 		updatedAt  time.Time
 	)
@@ -330,6 +378,7 @@ func (r *JobOrderRepository) Update(id int64, req *models.UpdateJobOrderRequest)
 		req.Status,
 		time.Now(),
 		id,
+		*req.Version,
 	).Scan(
 		&jobID,
 		&machineID,
@@ -340,6 +389,7 @@ func (r *JobOrderRepository) Update(id int64, req *models.UpdateJobOrderRequest)
 		&deadline,
 		&operatorID,
 		&status,
+		&version,
 		&createdAt,
 		&updatedAt,
 	)
@@ -359,6 +409,7 @@ func (r *JobOrderRepository) Update(id int64, req *models.UpdateJobOrderRequest)
 		Project:   project,
 		Item:      item,
 		Status:    status,
+		Version:   version,
 		CreatedAt: createdAt,
 		UpdatedAt: updatedAt,
 	}
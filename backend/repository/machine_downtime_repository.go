@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"time"
+
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type MachineDowntimeRepository struct {
+	db *gorm.DB
+}
+
+func NewMachineDowntimeRepository(db *gorm.DB) *MachineDowntimeRepository {
+	return &MachineDowntimeRepository{db: db}
+}
+
+// Create stores a downtime window
+func (r *MachineDowntimeRepository) Create(downtime *models.MachineDowntime) error {
+	return r.db.Create(downtime).Error
+}
+
+// GetByID retrieves a downtime window by ID
+func (r *MachineDowntimeRepository) GetByID(id int64) (*models.MachineDowntime, error) {
+	var downtime models.MachineDowntime
+	if err := r.db.First(&downtime, id).Error; err != nil {
+		return nil, err
+	}
+	return &downtime, nil
+}
+
+// GetAll retrieves downtime windows, optionally of one machine and type, overlapping [from, to).
+// A zero from or to leaves that side open
+func (r *MachineDowntimeRepository) GetAll(machineID int64, downtimeType string, from, to time.Time) ([]models.MachineDowntime, error) {
+	var downtimes []models.MachineDowntime
+	query := r.db.Order("start_time ASC, machine_id ASC")
+	if machineID > 0 {
+		query = query.Where("machine_id = ?", machineID)
+	}
+	if downtimeType != "" {
+		query = query.Where("type = ?", downtimeType)
+	}
+	if !from.IsZero() {
+		query = query.Where("end_time > ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("start_time < ?", to)
+	}
+	err := query.Find(&downtimes).Error
+	return downtimes, err
+}
+
+// GetByMachine retrieves every downtime window of a machine, in start order
+func (r *MachineDowntimeRepository) GetByMachine(machineID int64) ([]models.MachineDowntime, error) {
+	var downtimes []models.MachineDowntime
+	err := r.db.Where("machine_id = ?", machineID).Order("start_time ASC").Find(&downtimes).Error
+	return downtimes, err
+}
+
+// Update saves changes to a downtime window
+func (r *MachineDowntimeRepository) Update(downtime *models.MachineDowntime) error {
+	return r.db.Save(downtime).Error
+}
+
+// Delete deletes a downtime window
+func (r *MachineDowntimeRepository) Delete(id int64) error {
+	result := r.db.Delete(&models.MachineDowntime{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
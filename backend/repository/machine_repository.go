@@ -213,3 +213,38 @@ func (r *MachineRepository) GetByCode(code string) (*models.Machine, error) {
 
 	return &m, nil
 }
+
+// GetRunIntervals retrieves the periods a machine actually ran that end after since (or are still
+// running): actual windows of machine assignments on the live board and 'proses' stages of its job orders
+func (r *MachineRepository) GetRunIntervals(machineID int64, since time.Time) ([]models.MachineRunInterval, error) {
+	query := `
+		SELECT ma.actual_start, ma.actual_end
+		FROM machine_assignments ma
+		JOIN ppic_schedules ps ON ps.id = ma.schedule_id AND ps.deleted_at IS NULL AND ps.scenario_id IS NULL
+		WHERE ma.machine_id = $1 AND ma.actual_start IS NOT NULL
+		  AND (ma.actual_end IS NULL OR ma.actual_end > $2)
+		UNION ALL
+		SELECT st.start_time, st.finish_time
+		FROM process_stages st
+		JOIN job_orders jo ON jo.id = st.job_order_id AND jo.deleted_at IS NULL
+		WHERE jo.machine_id = $1 AND st.stage_name = 'proses' AND st.start_time IS NOT NULL
+		  AND (st.finish_time IS NULL OR st.finish_time > $2)
+	`
+
+	rows, err := r.db.Query(query, machineID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var intervals []models.MachineRunInterval
+	for rows.Next() {
+		var iv models.MachineRunInterval
+		if err := rows.Scan(&iv.Start, &iv.End); err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, iv)
+	}
+
+	return intervals, rows.Err()
+}
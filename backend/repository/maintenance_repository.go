@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MaintenanceRepository struct {
+	db *gorm.DB
+}
+
+func NewMaintenanceRepository(db *gorm.DB) *MaintenanceRepository {
+	return &MaintenanceRepository{db: db}
+}
+
+// ========== Plans ==========
+
+// CreatePlan stores a maintenance plan
+func (r *MaintenanceRepository) CreatePlan(plan *models.MaintenancePlan) error {
+	return r.db.Create(plan).Error
+}
+
+// GetPlanByID retrieves a plan by ID
+func (r *MaintenanceRepository) GetPlanByID(id int64) (*models.MaintenancePlan, error) {
+	var plan models.MaintenancePlan
+	if err := r.db.First(&plan, id).Error; err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// GetPlans retrieves plans, optionally of one machine and only the active ones
+func (r *MaintenanceRepository) GetPlans(machineID int64, activeOnly bool) ([]models.MaintenancePlan, error) {
+	var plans []models.MaintenancePlan
+	query := r.db.Order("machine_id ASC, name ASC")
+	if machineID > 0 {
+		query = query.Where("machine_id = ?", machineID)
+	}
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Find(&plans).Error
+	return plans, err
+}
+
+// UpdatePlan saves changes to a plan
+func (r *MaintenanceRepository) UpdatePlan(plan *models.MaintenancePlan) error {
+	return r.db.Save(plan).Error
+}
+
+// DeletePlan deletes a plan. Its work orders are kept as history
+func (r *MaintenanceRepository) DeletePlan(id int64) error {
+	result := r.db.Delete(&models.MaintenancePlan{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// WithLockedPlan runs fn in a transaction holding the plan's row lock (SELECT ... FOR UPDATE), with
+// a repository bound to that transaction; plan is nil if it no longer exists. Callers locking the
+// same plan take turns, so a check followed by a write in fn can't interleave with another one
+func (r *MaintenanceRepository) WithLockedPlan(planID int64, fn func(repo *MaintenanceRepository, plan *models.MaintenancePlan) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var plan models.MaintenancePlan
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, planID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fn(&MaintenanceRepository{db: tx}, nil)
+		}
+		if err != nil {
+			return err
+		}
+		return fn(&MaintenanceRepository{db: tx}, &plan)
+	})
+}
+
+// ========== Work Orders ==========
+
+// CreateWorkOrder stores a work order
+func (r *MaintenanceRepository) CreateWorkOrder(order *models.MaintenanceWorkOrder) error {
+	return r.db.Create(order).Error
+}
+
+// GetWorkOrderByID retrieves a work order by ID
+func (r *MaintenanceRepository) GetWorkOrderByID(id int64) (*models.MaintenanceWorkOrder, error) {
+	var order models.MaintenanceWorkOrder
+	if err := r.db.First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetWorkOrders retrieves work orders, newest first
+func (r *MaintenanceRepository) GetWorkOrders(filter models.MaintenanceWorkOrderFilterRequest) ([]models.MaintenanceWorkOrder, error) {
+	var orders []models.MaintenanceWorkOrder
+	query := r.db.Order("created_at DESC, id DESC")
+	if filter.MachineID > 0 {
+		query = query.Where("machine_id = ?", filter.MachineID)
+	}
+	if filter.PlanID > 0 {
+		query = query.Where("plan_id = ?", filter.PlanID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	err := query.Find(&orders).Error
+	return orders, err
+}
+
+// GetOpenWorkOrder retrieves the open or in-progress work order of a plan, or nil if there is none
+func (r *MaintenanceRepository) GetOpenWorkOrder(planID int64) (*models.MaintenanceWorkOrder, error) {
+	var order models.MaintenanceWorkOrder
+	err := r.db.Where("plan_id = ? AND status IN ?", planID, []string{models.WorkOrderStatusOpen, models.WorkOrderStatusInProgress}).
+		Order("id ASC").First(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// HasCancelledWorkOrderSince reports whether a work order of the plan created at or after since was cancelled
+func (r *MaintenanceRepository) HasCancelledWorkOrderSince(planID int64, since time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.MaintenanceWorkOrder{}).
+		Where("plan_id = ? AND status = ? AND created_at >= ?", planID, models.WorkOrderStatusCancelled, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateWorkOrder saves changes to a work order
+func (r *MaintenanceRepository) UpdateWorkOrder(order *models.MaintenanceWorkOrder) error {
+	return r.db.Save(order).Error
+}
+
+// CompleteWorkOrder saves a completed work order and restarts its plan's interval in one transaction
+func (r *MaintenanceRepository) CompleteWorkOrder(order *models.MaintenanceWorkOrder, completedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(order).Error; err != nil {
+			return err
+		}
+		// A completion recorded late doesn't move the interval back
+		return tx.Model(&models.MaintenancePlan{}).
+			Where("id = ? AND last_completed_at < ?", order.PlanID, completedAt).
+			Update("last_completed_at", completedAt).Error
+	})
+}
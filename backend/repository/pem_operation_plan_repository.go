@@ -131,16 +131,44 @@ func (r *PEMOperationPlanRepository) FindAll(filters map[string]interface{}) ([]
 	return plans, err
 }
 
-// Update updates a PEM operation plan
-func (r *PEMOperationPlanRepository) Update(plan *models.PEMOperationPlan) error {
-	return r.db.Save(plan).Error
+// Update writes the editable fields of a PEM operation plan, provided it is still at version,
+// and bumps the version. Returns models.ErrStaleVersion if it changed in the meantime
+func (r *PEMOperationPlanRepository) Update(plan *models.PEMOperationPlan, version int) error {
+	result := r.db.Model(&models.PEMOperationPlan{}).
+		Where("id = ? AND version = ?", plan.ID, version).
+		Updates(map[string]interface{}{
+			"part_name": plan.PartName,
+			"material":  plan.Material,
+			"dial_size": plan.DialSize,
+			"quantity":  plan.Quantity,
+			"revision":  plan.Revision,
+			"no_wp":     plan.NoWP,
+			"page":      plan.Page,
+			"version":   gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrStaleVersion
+	}
+	plan.Version = version + 1
+	return nil
 }
 
 // UpdateStatus updates the status of a PEM operation plan
 func (r *PEMOperationPlanRepository) UpdateStatus(id int64, status string) error {
 	return r.db.Model(&models.PEMOperationPlan{}).
 		Where("id = ?", id).
-		Update("status", status).Error
+		Updates(map[string]interface{}{"status": status, "version": gorm.Expr("version + 1")}).Error
+}
+
+// bumpPlanVersion marks a plan as changed when its steps change, so an update of the plan
+// read before the change is rejected as stale
+func bumpPlanVersion(tx *gorm.DB, planID int64) error {
+	return tx.Model(&models.PEMOperationPlan{}).
+		Where("id = ?", planID).
+		Update("version", gorm.Expr("version + 1")).Error
 }
 
 // Delete soft deletes a PEM operation plan
@@ -169,7 +197,12 @@ func (r *PEMOperationPlanRepository) Delete(id int64) error {
 
 // CreateStep creates a new operation plan step
 func (r *PEMOperationPlanRepository) CreateStep(step *models.OperationPlanStep) error {
-	return r.db.Create(step).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(step).Error; err != nil {
+			return err
+		}
+		return bumpPlanVersion(tx, step.OperationPlanID)
+	})
 }
 
 // FindStepByID finds a step by ID
@@ -184,12 +217,26 @@ func (r *PEMOperationPlanRepository) FindStepByID(stepID int64) (*models.Operati
 
 // UpdateStep updates an operation plan step
 func (r *PEMOperationPlanRepository) UpdateStep(step *models.OperationPlanStep) error {
-	return r.db.Save(step).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(step).Error; err != nil {
+			return err
+		}
+		return bumpPlanVersion(tx, step.OperationPlanID)
+	})
 }
 
 // DeleteStep deletes an operation plan step
 func (r *PEMOperationPlanRepository) DeleteStep(stepID int64) error {
-	return r.db.Delete(&models.OperationPlanStep{}, stepID).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var step models.OperationPlanStep
+		if err := tx.First(&step, stepID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&step).Error; err != nil {
+			return err
+		}
+		return bumpPlanVersion(tx, step.OperationPlanID)
+	})
 }
 
 // Approval Management Methods
@@ -221,7 +268,7 @@ func (r *PEMOperationPlanRepository) AssignApprovers(planID int64, approvers map
 func (r *PEMOperationPlanRepository) SubmitForApproval(id int64) error {
 	return r.db.Model(&models.PEMOperationPlan{}).
 		Where("id = ? AND status = ?", id, models.PEMStatusDraft).
-		Update("status", models.PEMStatusPendingApproval).Error
+		Updates(map[string]interface{}{"status": models.PEMStatusPendingApproval, "version": gorm.Expr("version + 1")}).Error
 }
 
 // ApprovePlan approves the operation plan by a specific role
@@ -261,7 +308,7 @@ func (r *PEMOperationPlanRepository) ApprovePlan(planID int64, approverID int64,
 		if pendingCount == 0 {
 			err = tx.Model(&models.PEMOperationPlan{}).
 				Where("id = ?", planID).
-				Update("status", models.PEMStatusApproved).Error
+				Updates(map[string]interface{}{"status": models.PEMStatusApproved, "version": gorm.Expr("version + 1")}).Error
 
 			if err != nil {
 				return err
@@ -298,7 +345,7 @@ func (r *PEMOperationPlanRepository) RejectPlan(planID int64, approverID int64,
 		// Update operation plan status to rejected
 		err := tx.Model(&models.PEMOperationPlan{}).
 			Where("id = ?", planID).
-			Update("status", models.PEMStatusRejected).Error
+			Updates(map[string]interface{}{"status": models.PEMStatusRejected, "version": gorm.Expr("version + 1")}).Error
 
 		if err != nil {
 			return err
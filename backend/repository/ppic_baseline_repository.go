@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"ganttpro-backend/models"
+)
+
+type PPICBaselineRepository struct {
+	db *sql.DB
+}
+
+func NewPPICBaselineRepository(db *sql.DB) *PPICBaselineRepository {
+	return &PPICBaselineRepository{db: db}
+}
+
+// Create snapshots the live schedule and machine assignment dates as a new baseline
+func (r *PPICBaselineRepository) Create(req *models.CreatePPICBaselineRequest, createdBy int64) (*models.PPICBaseline, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	baseline := &models.PPICBaseline{
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedBy:   createdBy,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO ppic_baselines (name, description, created_by, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at
+	`, req.Name, req.Description, createdBy).Scan(&baseline.ID, &baseline.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create baseline: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO ppic_baseline_schedules (baseline_id, schedule_id, njo, part_name, start_date, finish_date)
+		SELECT $1, id, njo, part_name, start_date, finish_date
+		FROM ppic_schedules
+		WHERE scenario_id IS NULL AND deleted_at IS NULL
+	`, baseline.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot schedules: %w", err)
+	}
+	captured, _ := result.RowsAffected()
+	baseline.ScheduleCount = int(captured)
+
+	_, err = tx.Exec(`
+		INSERT INTO ppic_baseline_assignments (baseline_id, schedule_id, machine_id, sequence, target_hours, scheduled_start, scheduled_end)
+		SELECT $1, ma.schedule_id, ma.machine_id, ma.sequence, ma.target_hours, ma.scheduled_start, ma.scheduled_end
+		FROM machine_assignments ma
+		JOIN ppic_baseline_schedules bs ON bs.schedule_id = ma.schedule_id AND bs.baseline_id = $1
+	`, baseline.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot machine assignments: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return baseline, nil
+}
+
+// GetAll returns all baselines, newest first
+func (r *PPICBaselineRepository) GetAll() ([]models.PPICBaseline, error) {
+	rows, err := r.db.Query(`
+		SELECT b.id, b.name, COALESCE(b.description, ''), COALESCE(b.created_by, 0),
+		       (SELECT COUNT(*) FROM ppic_baseline_schedules bs WHERE bs.baseline_id = b.id),
+		       b.created_at
+		FROM ppic_baselines b
+		ORDER BY b.created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baselines := []models.PPICBaseline{}
+	for rows.Next() {
+		var b models.PPICBaseline
+		if err := rows.Scan(&b.ID, &b.Name, &b.Description, &b.CreatedBy, &b.ScheduleCount, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		baselines = append(baselines, b)
+	}
+
+	return baselines, nil
+}
+
+// GetByID returns a baseline, or nil if it doesn't exist
+func (r *PPICBaselineRepository) GetByID(id int64) (*models.PPICBaseline, error) {
+	var b models.PPICBaseline
+	err := r.db.QueryRow(`
+		SELECT b.id, b.name, COALESCE(b.description, ''), COALESCE(b.created_by, 0),
+		       (SELECT COUNT(*) FROM ppic_baseline_schedules bs WHERE bs.baseline_id = b.id),
+		       b.created_at
+		FROM ppic_baselines b
+		WHERE b.id = $1
+	`, id).Scan(&b.ID, &b.Name, &b.Description, &b.CreatedBy, &b.ScheduleCount, &b.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetSchedules returns the schedule dates and machine windows captured in a baseline
+func (r *PPICBaselineRepository) GetSchedules(baselineID int64) ([]models.BaselineSchedule, error) {
+	rows, err := r.db.Query(`
+		SELECT schedule_id, njo, part_name, start_date, finish_date
+		FROM ppic_baseline_schedules
+		WHERE baseline_id = $1
+		ORDER BY start_date, njo
+	`, baselineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.BaselineSchedule{}
+	index := make(map[int64]int)
+	for rows.Next() {
+		var s models.BaselineSchedule
+		if err := rows.Scan(&s.ScheduleID, &s.NJO, &s.PartName, &s.StartDate, &s.FinishDate); err != nil {
+			return nil, err
+		}
+		s.Assignments = []models.BaselineAssignment{}
+		index[s.ScheduleID] = len(schedules)
+		schedules = append(schedules, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	assignmentRows, err := r.db.Query(`
+		SELECT schedule_id, machine_id, sequence, target_hours, scheduled_start, scheduled_end
+		FROM ppic_baseline_assignments
+		WHERE baseline_id = $1
+		ORDER BY schedule_id, sequence
+	`, baselineID)
+	if err != nil {
+		return nil, err
+	}
+	defer assignmentRows.Close()
+
+	for assignmentRows.Next() {
+		var a models.BaselineAssignment
+		if err := assignmentRows.Scan(&a.ScheduleID, &a.MachineID, &a.Sequence, &a.TargetHours, &a.ScheduledStart, &a.ScheduledEnd); err != nil {
+			return nil, err
+		}
+		if i, ok := index[a.ScheduleID]; ok {
+			schedules[i].Assignments = append(schedules[i].Assignments, a)
+		}
+	}
+
+	return schedules, nil
+}
+
+// Delete removes a baseline and its snapshot rows
+func (r *PPICBaselineRepository) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM ppic_baselines WHERE id = $1", id)
+	return err
+}
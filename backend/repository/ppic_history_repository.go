@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql"
+	"ganttpro-backend/models"
+)
+
+type PPICHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewPPICHistoryRepository(db *sql.DB) *PPICHistoryRepository {
+	return &PPICHistoryRepository{db: db}
+}
+
+// Record saves field changes in one transaction
+func (r *PPICHistoryRepository) Record(changes []models.PPICScheduleChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO ppic_schedule_history (schedule_id, entity, assignment_id, sequence, field, old_value, new_value, cause, changed_by, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, change := range changes {
+		var changedBy interface{}
+		if change.ChangedBy > 0 {
+			changedBy = change.ChangedBy
+		}
+		if _, err := stmt.Exec(change.ScheduleID, change.Entity, change.AssignmentID, change.Sequence, change.Field,
+			change.OldValue, change.NewValue, change.Cause, changedBy, change.ChangedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBySchedule returns the change history of a schedule, newest first
+func (r *PPICHistoryRepository) GetBySchedule(scheduleID int64) ([]models.PPICScheduleChange, error) {
+	rows, err := r.db.Query(`
+		SELECT h.id, h.schedule_id, h.entity, h.assignment_id, h.sequence, h.field,
+		       COALESCE(h.old_value, ''), COALESCE(h.new_value, ''), h.cause,
+		       COALESCE(h.changed_by, 0), COALESCE(u.username, ''), h.changed_at
+		FROM ppic_schedule_history h
+		LEFT JOIN users u ON u.id = h.changed_by
+		WHERE h.schedule_id = $1
+		ORDER BY h.changed_at DESC, h.id DESC
+	`, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.PPICScheduleChange{}
+	for rows.Next() {
+		var c models.PPICScheduleChange
+		if err := rows.Scan(&c.ID, &c.ScheduleID, &c.Entity, &c.AssignmentID, &c.Sequence, &c.Field,
+			&c.OldValue, &c.NewValue, &c.Cause, &c.ChangedBy, &c.ChangedByName, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
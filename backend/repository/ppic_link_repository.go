@@ -7,19 +7,38 @@ import (
 )
 
 type PPICLinkRepository struct {
-	db *gorm.DB
+	db         *gorm.DB
+	scenarioID int64 // 0 = live board
 }
 
 func NewPPICLinkRepository(db *gorm.DB) *PPICLinkRepository {
 	return &PPICLinkRepository{db: db}
 }
 
+// ForScenario returns a repository working on a what-if scenario's copy of the links
+func (r *PPICLinkRepository) ForScenario(scenarioID int64) *PPICLinkRepository {
+	return &PPICLinkRepository{db: r.db, scenarioID: scenarioID}
+}
+
+// scoped limits queries to the repository's scenario
+func (r *PPICLinkRepository) scoped() *gorm.DB {
+	if r.scenarioID == 0 {
+		return r.db.Where("scenario_id IS NULL")
+	}
+	return r.db.Where("scenario_id = ?", r.scenarioID)
+}
+
 // Create creates a new PPIC link
 func (r *PPICLinkRepository) Create(req *models.CreatePPICLinkRequest) (*models.PPICLink, error) {
 	link := &models.PPICLink{
 		SourceScheduleID: req.SourceScheduleID,
 		TargetScheduleID: req.TargetScheduleID,
 		LinkType:         req.LinkType,
+		LagDays:          req.LagDays,
+	}
+	if r.scenarioID != 0 {
+		scenarioID := r.scenarioID
+		link.ScenarioID = &scenarioID
 	}
 
 	if err := r.db.Create(link).Error; err != nil {
@@ -32,21 +51,50 @@ func (r *PPICLinkRepository) Create(req *models.CreatePPICLinkRequest) (*models.
 // GetAll returns all PPIC links
 func (r *PPICLinkRepository) GetAll() ([]models.PPICLink, error) {
 	var links []models.PPICLink
-	if err := r.db.Find(&links).Error; err != nil {
+	if err := r.scoped().Find(&links).Error; err != nil {
 		return nil, err
 	}
 	return links, nil
 }
 
+// linkLookupBatchSize caps the schedule IDs per query in GetTouching
+const linkLookupBatchSize = 1000
+
+// GetTouching returns the links whose source or target is one of the given schedules
+func (r *PPICLinkRepository) GetTouching(scheduleIDs []int64) ([]models.PPICLink, error) {
+	links := []models.PPICLink{}
+	seen := make(map[int64]bool)
+	for from := 0; from < len(scheduleIDs); from += linkLookupBatchSize {
+		to := from + linkLookupBatchSize
+		if to > len(scheduleIDs) {
+			to = len(scheduleIDs)
+		}
+		batch := scheduleIDs[from:to]
+
+		var found []models.PPICLink
+		if err := r.scoped().Where("(source_schedule_id IN ? OR target_schedule_id IN ?)", batch, batch).
+			Order("id").Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, link := range found {
+			if !seen[link.ID] {
+				seen[link.ID] = true
+				links = append(links, link)
+			}
+		}
+	}
+	return links, nil
+}
+
 // Delete deletes a PPIC link by ID
 func (r *PPICLinkRepository) Delete(id int64) error {
-	return r.db.Delete(&models.PPICLink{}, id).Error
+	return r.scoped().Delete(&models.PPICLink{}, id).Error
 }
 
 // GetBySourceScheduleID returns all links where the given schedule is the source
 func (r *PPICLinkRepository) GetBySourceScheduleID(scheduleID int64) ([]models.PPICLink, error) {
 	var links []models.PPICLink
-	if err := r.db.Where("source_schedule_id = ?", scheduleID).Find(&links).Error; err != nil {
+	if err := r.scoped().Where("source_schedule_id = ?", scheduleID).Find(&links).Error; err != nil {
 		return nil, err
 	}
 	return links, nil
@@ -55,7 +103,7 @@ func (r *PPICLinkRepository) GetBySourceScheduleID(scheduleID int64) ([]models.P
 // GetByTargetScheduleID returns all links where the given schedule is the target
 func (r *PPICLinkRepository) GetByTargetScheduleID(scheduleID int64) ([]models.PPICLink, error) {
 	var links []models.PPICLink
-	if err := r.db.Where("target_schedule_id = ?", scheduleID).Find(&links).Error; err != nil {
+	if err := r.scoped().Where("target_schedule_id = ?", scheduleID).Find(&links).Error; err != nil {
 		return nil, err
 	}
 	return links, nil
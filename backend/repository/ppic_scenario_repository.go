@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"ganttpro-backend/models"
+	"strings"
+	"time"
+)
+
+type PPICScenarioRepository struct {
+	db *sql.DB
+}
+
+func NewPPICScenarioRepository(db *sql.DB) *PPICScenarioRepository {
+	return &PPICScenarioRepository{db: db}
+}
+
+// Create creates a scenario and copies the live schedules, machine assignments and links into it
+func (r *PPICScenarioRepository) Create(req *models.CreatePPICScenarioRequest, createdBy int64) (*models.PPICScenario, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	scenario := &models.PPICScenario{
+		Name:        req.Name,
+		Description: req.Description,
+		Status:      models.ScenarioStatusDraft,
+		CreatedBy:   createdBy,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO ppic_scenarios (name, description, status, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING id, created_at, updated_at
+	`, req.Name, req.Description, models.ScenarioStatusDraft, createdBy).Scan(&scenario.ID, &scenario.CreatedAt, &scenario.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scenario: %w", err)
+	}
+
+	// Copy live schedules, remembering where each copy came from
+	result, err := tx.Exec(`
+		INSERT INTO ppic_schedules (njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		                            start_date, finish_date, ppic_notes, quantity, parent_schedule_id, lot_number,
+		                            created_by, scenario_id, source_schedule_id, created_at, updated_at)
+		SELECT njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		       start_date, finish_date, ppic_notes, quantity, parent_schedule_id, lot_number,
+		       created_by, $1, id, NOW(), NOW()
+		FROM ppic_schedules
+		WHERE scenario_id IS NULL AND deleted_at IS NULL
+	`, scenario.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy schedules: %w", err)
+	}
+	copied, _ := result.RowsAffected()
+	scenario.ScheduleCount = int(copied)
+
+	// Lots still point at the live parent; point them at its copy
+	_, err = tx.Exec(`
+		UPDATE ppic_schedules c SET parent_schedule_id = p.id
+		FROM ppic_schedules p
+		WHERE c.scenario_id = $1 AND c.parent_schedule_id IS NOT NULL
+		  AND p.scenario_id = $1 AND p.source_schedule_id = c.parent_schedule_id
+	`, scenario.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy lots: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO machine_assignments (schedule_id, machine_id, sequence, target_hours, scheduled_start, scheduled_end,
+		                                 actual_start, actual_end, status, created_at, updated_at)
+		SELECT c.id, ma.machine_id, ma.sequence, ma.target_hours, ma.scheduled_start, ma.scheduled_end,
+		       ma.actual_start, ma.actual_end, ma.status, NOW(), NOW()
+		FROM machine_assignments ma
+		JOIN ppic_schedules c ON c.source_schedule_id = ma.schedule_id AND c.scenario_id = $1
+	`, scenario.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy machine assignments: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO ppic_links (source_schedule_id, target_schedule_id, link_type, lag_days, scenario_id, created_at, updated_at)
+		SELECT s.id, t.id, l.link_type, l.lag_days, $1, NOW(), NOW()
+		FROM ppic_links l
+		JOIN ppic_schedules s ON s.source_schedule_id = l.source_schedule_id AND s.scenario_id = $1
+		JOIN ppic_schedules t ON t.source_schedule_id = l.target_schedule_id AND t.scenario_id = $1
+		WHERE l.scenario_id IS NULL
+	`, scenario.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy links: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return scenario, nil
+}
+
+// GetAll returns all scenarios, newest first
+func (r *PPICScenarioRepository) GetAll() ([]models.PPICScenario, error) {
+	rows, err := r.db.Query(`
+		SELECT sc.id, sc.name, COALESCE(sc.description, ''), sc.status, COALESCE(sc.created_by, 0), sc.promoted_at,
+		       (SELECT COUNT(*) FROM ppic_schedules ps WHERE ps.scenario_id = sc.id AND ps.deleted_at IS NULL),
+		       sc.created_at, sc.updated_at
+		FROM ppic_scenarios sc
+		ORDER BY sc.created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scenarios := []models.PPICScenario{}
+	for rows.Next() {
+		var s models.PPICScenario
+		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.Status, &s.CreatedBy, &s.PromotedAt, &s.ScheduleCount, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, s)
+	}
+
+	return scenarios, nil
+}
+
+// GetByID returns a scenario, or nil if it doesn't exist
+func (r *PPICScenarioRepository) GetByID(id int64) (*models.PPICScenario, error) {
+	var s models.PPICScenario
+	err := r.db.QueryRow(`
+		SELECT sc.id, sc.name, COALESCE(sc.description, ''), sc.status, COALESCE(sc.created_by, 0), sc.promoted_at,
+		       (SELECT COUNT(*) FROM ppic_schedules ps WHERE ps.scenario_id = sc.id AND ps.deleted_at IS NULL),
+		       sc.created_at, sc.updated_at
+		FROM ppic_scenarios sc
+		WHERE sc.id = $1
+	`, id).Scan(&s.ID, &s.Name, &s.Description, &s.Status, &s.CreatedBy, &s.PromotedAt, &s.ScheduleCount, &s.CreatedAt, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Delete removes a scenario; its schedules, machine assignments and links are removed by cascade
+func (r *PPICScenarioRepository) Delete(id int64) error {
+	_, err := r.db.Exec("DELETE FROM ppic_scenarios WHERE id = $1", id)
+	return err
+}
+
+// GetSources returns every schedule copy in the scenario, including ones deleted in the scenario
+func (r *PPICScenarioRepository) GetSources(scenarioID int64) ([]models.ScenarioScheduleSource, error) {
+	rows, err := r.db.Query(`
+		SELECT id, source_schedule_id, deleted_at IS NOT NULL
+		FROM ppic_schedules
+		WHERE scenario_id = $1
+	`, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []models.ScenarioScheduleSource
+	for rows.Next() {
+		var s models.ScenarioScheduleSource
+		if err := rows.Scan(&s.ScheduleID, &s.SourceScheduleID, &s.Deleted); err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+
+	return sources, nil
+}
+
+// Promote copies a scenario onto the live board in one transaction. Live schedules keep their IDs;
+// schedules added in the scenario are created live. The live schedules the scenario was copied from
+// are locked first. Unless force is set, promotion is refused when one of them was changed or deleted
+// after the scenario was created, or a live link touching them was added since
+func (r *PPICScenarioRepository) Promote(scenarioID int64, force bool) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var createdAt time.Time
+	err = tx.QueryRow("SELECT status, created_at FROM ppic_scenarios WHERE id = $1 FOR UPDATE", scenarioID).Scan(&status, &createdAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("scenario not found")
+	}
+	if err != nil {
+		return err
+	}
+	if status != models.ScenarioStatusDraft {
+		return fmt.Errorf("scenario is already %s", status)
+	}
+
+	live, err := lockScenarioSources(tx, scenarioID)
+	if err != nil {
+		return err
+	}
+	if !force {
+		links, err := scenarioLiveLinks(tx, scenarioID)
+		if err != nil {
+			return err
+		}
+		if conflicts := models.ScenarioPromoteConflicts(createdAt, live, links); len(conflicts) > 0 {
+			return fmt.Errorf("live board changed after the scenario was created: %s. Promote with force=true to overwrite them", strings.Join(conflicts, ", "))
+		}
+	}
+
+	// Schedules added in the scenario become new live schedules
+	_, err = tx.Exec(`
+		INSERT INTO ppic_schedules (njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		                            start_date, finish_date, ppic_notes, quantity, created_by, created_at, updated_at)
+		SELECT njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		       start_date, finish_date, ppic_notes, quantity, created_by, NOW(), NOW()
+		FROM ppic_schedules
+		WHERE scenario_id = $1 AND source_schedule_id IS NULL AND parent_schedule_id IS NULL AND deleted_at IS NULL
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to create added schedules: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE ppic_schedules c SET source_schedule_id = l.id
+		FROM ppic_schedules l
+		WHERE c.scenario_id = $1 AND c.source_schedule_id IS NULL AND c.parent_schedule_id IS NULL AND c.deleted_at IS NULL
+		  AND l.scenario_id IS NULL AND l.parent_schedule_id IS NULL AND l.deleted_at IS NULL AND l.njo = c.njo
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to map added schedules: %w", err)
+	}
+
+	// Lots split off in the scenario become new live lots of the live parent
+	_, err = tx.Exec(`
+		INSERT INTO ppic_schedules (njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		                            start_date, finish_date, ppic_notes, quantity, parent_schedule_id, lot_number, created_by, created_at, updated_at)
+		SELECT c.njo, c.part_name, c.priority, c.priority_alpha, c.material_status, c.status, c.progress, c.progress_override,
+		       c.start_date, c.finish_date, c.ppic_notes, c.quantity, p.source_schedule_id, c.lot_number, c.created_by, NOW(), NOW()
+		FROM ppic_schedules c
+		JOIN ppic_schedules p ON p.id = c.parent_schedule_id
+		WHERE c.scenario_id = $1 AND c.source_schedule_id IS NULL AND c.deleted_at IS NULL
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to create added lots: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE ppic_schedules c SET source_schedule_id = l.id
+		FROM ppic_schedules p, ppic_schedules l
+		WHERE c.scenario_id = $1 AND c.source_schedule_id IS NULL AND c.deleted_at IS NULL
+		  AND p.id = c.parent_schedule_id
+		  AND l.scenario_id IS NULL AND l.deleted_at IS NULL
+		  AND l.parent_schedule_id = p.source_schedule_id AND l.lot_number = c.lot_number
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to map added lots: %w", err)
+	}
+
+	// Copy fields back onto the live schedules (deleted copies soft delete the live schedule)
+	_, err = tx.Exec(`
+		UPDATE ppic_schedules l SET
+			part_name = c.part_name, priority = c.priority, priority_alpha = c.priority_alpha,
+			material_status = c.material_status, status = c.status, progress = c.progress, progress_override = c.progress_override,
+			start_date = c.start_date, finish_date = c.finish_date, ppic_notes = c.ppic_notes, quantity = c.quantity,
+			deleted_at = c.deleted_at, updated_at = NOW(), version = l.version + 1
+		FROM ppic_schedules c
+		WHERE c.scenario_id = $1 AND c.source_schedule_id = l.id AND l.scenario_id IS NULL
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to update live schedules: %w", err)
+	}
+
+	// Replace machine assignments of the promoted schedules
+	_, err = tx.Exec(`
+		DELETE FROM machine_assignments
+		WHERE schedule_id IN (SELECT source_schedule_id FROM ppic_schedules WHERE scenario_id = $1 AND source_schedule_id IS NOT NULL)
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to clear live machine assignments: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO machine_assignments (schedule_id, machine_id, sequence, target_hours, scheduled_start, scheduled_end,
+		                                 actual_start, actual_end, status, created_at, updated_at)
+		SELECT c.source_schedule_id, ma.machine_id, ma.sequence, ma.target_hours, ma.scheduled_start, ma.scheduled_end,
+		       ma.actual_start, ma.actual_end, ma.status, NOW(), NOW()
+		FROM machine_assignments ma
+		JOIN ppic_schedules c ON c.id = ma.schedule_id
+		WHERE c.scenario_id = $1 AND c.source_schedule_id IS NOT NULL AND c.deleted_at IS NULL
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to copy machine assignments: %w", err)
+	}
+
+	// Replace links touching the promoted schedules
+	_, err = tx.Exec(`
+		DELETE FROM ppic_links
+		WHERE scenario_id IS NULL AND (
+			source_schedule_id IN (SELECT source_schedule_id FROM ppic_schedules WHERE scenario_id = $1 AND source_schedule_id IS NOT NULL)
+			OR target_schedule_id IN (SELECT source_schedule_id FROM ppic_schedules WHERE scenario_id = $1 AND source_schedule_id IS NOT NULL)
+		)
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to clear live links: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO ppic_links (source_schedule_id, target_schedule_id, link_type, lag_days, created_at, updated_at)
+		SELECT s.source_schedule_id, t.source_schedule_id, l.link_type, l.lag_days, NOW(), NOW()
+		FROM ppic_links l
+		JOIN ppic_schedules s ON s.id = l.source_schedule_id AND s.deleted_at IS NULL
+		JOIN ppic_schedules t ON t.id = l.target_schedule_id AND t.deleted_at IS NULL
+		WHERE l.scenario_id = $1
+	`, scenarioID)
+	if err != nil {
+		return fmt.Errorf("failed to copy links: %w", err)
+	}
+
+	_, err = tx.Exec(
+		"UPDATE ppic_scenarios SET status = $1, promoted_at = NOW(), updated_at = NOW() WHERE id = $2",
+		models.ScenarioStatusPromoted, scenarioID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockScenarioSources locks the live schedules a scenario was copied from, deleted ones included,
+// in ID order and returns their state
+func lockScenarioSources(tx *sql.Tx, scenarioID int64) ([]models.ScenarioLiveState, error) {
+	rows, err := tx.Query(`
+		SELECT l.njo, l.updated_at, l.deleted_at IS NOT NULL
+		FROM ppic_schedules l
+		WHERE l.scenario_id IS NULL
+		  AND l.id IN (SELECT source_schedule_id FROM ppic_schedules WHERE scenario_id = $1 AND source_schedule_id IS NOT NULL)
+		ORDER BY l.id
+		FOR UPDATE
+	`, scenarioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock live schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var live []models.ScenarioLiveState
+	for rows.Next() {
+		var l models.ScenarioLiveState
+		if err := rows.Scan(&l.NJO, &l.UpdatedAt, &l.Deleted); err != nil {
+			return nil, err
+		}
+		live = append(live, l)
+	}
+	return live, rows.Err()
+}
+
+// scenarioLiveLinks returns the live links touching the live schedules a scenario was copied from
+func scenarioLiveLinks(tx *sql.Tx, scenarioID int64) ([]models.ScenarioLiveLink, error) {
+	rows, err := tx.Query(`
+		SELECT s.njo, t.njo, pl.created_at
+		FROM ppic_links pl
+		JOIN ppic_schedules s ON s.id = pl.source_schedule_id
+		JOIN ppic_schedules t ON t.id = pl.target_schedule_id
+		WHERE pl.scenario_id IS NULL AND (
+			pl.source_schedule_id IN (SELECT source_schedule_id FROM ppic_schedules WHERE scenario_id = $1 AND source_schedule_id IS NOT NULL)
+			OR pl.target_schedule_id IN (SELECT source_schedule_id FROM ppic_schedules WHERE scenario_id = $1 AND source_schedule_id IS NOT NULL)
+		)
+		ORDER BY s.njo, t.njo
+	`, scenarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.ScenarioLiveLink
+	for rows.Next() {
+		var l models.ScenarioLiveLink
+		if err := rows.Scan(&l.SourceNJO, &l.TargetNJO, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
@@ -4,34 +4,56 @@ import (
 	"database/sql"
 	"fmt"
 	"ganttpro-backend/models"
+	"strings"
 	"time"
 )
 
 type PPICScheduleRepository struct {
-	db *sql.DB
+	db         *sql.DB
+	scenarioID int64 // 0 = live board
 }
 
 func NewPPICScheduleRepository(db *sql.DB) *PPICScheduleRepository {
 	return &PPICScheduleRepository{db: db}
 }
 
-// Create creates a new PPIC schedule with machine assignments
-func (r *PPICScheduleRepository) Create(req *models.CreatePPICScheduleRequest, createdBy int64, startDate, finishDate time.Time) (*models.PPICSchedule, error) {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return nil, err
+// ForScenario returns a repository working on a what-if scenario's copy of the board
+func (r *PPICScheduleRepository) ForScenario(scenarioID int64) *PPICScheduleRepository {
+	return &PPICScheduleRepository{db: r.db, scenarioID: scenarioID}
+}
+
+// ScenarioID returns the scenario the repository works on (0 = live board)
+func (r *PPICScheduleRepository) ScenarioID() int64 {
+	return r.scenarioID
+}
+
+// scenarioScope returns the condition selecting rows of the repository's scenario.
+// The ID is an int64, so it is safe to format into the query
+func (r *PPICScheduleRepository) scenarioScope(column string) string {
+	if r.scenarioID == 0 {
+		return column + " IS NULL"
 	}
-	defer tx.Rollback()
+	return fmt.Sprintf("%s = %d", column, r.scenarioID)
+}
+
+// scenarioValue is the scenario_id column value for new rows
+func (r *PPICScheduleRepository) scenarioValue() interface{} {
+	if r.scenarioID == 0 {
+		return nil
+	}
+	return r.scenarioID
+}
 
+func (r *PPICScheduleRepository) createSchedule(tx *sql.Tx, req *models.CreatePPICScheduleRequest, createdBy int64, startDate, finishDate time.Time) (*models.PPICSchedule, error) {
 	// Insert schedule
 	query := `
-		INSERT INTO ppic_schedules (njo, part_name, priority, priority_alpha, material_status, start_date, finish_date, ppic_notes, created_by, status, progress, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'pending', 0, NOW(), NOW())
+		INSERT INTO ppic_schedules (njo, part_name, priority, priority_alpha, material_status, start_date, finish_date, ppic_notes, quantity, created_by, scenario_id, status, progress, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 'pending', 0, NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
 
 	var schedule models.PPICSchedule
-	err = tx.QueryRow(query, req.NJO, req.PartName, req.Priority, req.PriorityAlpha, req.MaterialStatus, startDate, finishDate, req.PPICNotes, createdBy).
+	err := tx.QueryRow(query, req.NJO, req.PartName, req.Priority, req.PriorityAlpha, req.MaterialStatus, startDate, finishDate, req.PPICNotes, req.Quantity, createdBy, r.scenarioValue()).
 		Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create schedule: %w", err)
@@ -46,9 +68,11 @@ func (r *PPICScheduleRepository) Create(req *models.CreatePPICScheduleRequest, c
 	schedule.StartDate = startDate
 	schedule.FinishDate = finishDate
 	schedule.PPICNotes = req.PPICNotes
+	schedule.Quantity = req.Quantity
 	schedule.CreatedBy = createdBy
 	schedule.Status = "pending"
 	schedule.Progress = 0
+	schedule.Version = 1
 
 	// Insert machine assignments
 	for _, ma := range req.MachineAssignments {
@@ -59,10 +83,6 @@ func (r *PPICScheduleRepository) Create(req *models.CreatePPICScheduleRequest, c
 		schedule.MachineAssignments = append(schedule.MachineAssignments, *assignment)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
-	}
-
 	return &schedule, nil
 }
 
@@ -74,14 +94,19 @@ func (r *PPICScheduleRepository) createMachineAssignment(tx *sql.Tx, scheduleID
 		return nil, fmt.Errorf("machine not found: %w", err)
 	}
 
+	scheduledStart, scheduledEnd, err := req.ParseScheduledWindow()
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO machine_assignments (schedule_id, machine_id, sequence, target_hours, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, 'pending', NOW(), NOW())
+		INSERT INTO machine_assignments (schedule_id, machine_id, sequence, target_hours, scheduled_start, scheduled_end, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'pending', NOW(), NOW())
 		RETURNING id, created_at, updated_at
 	`
 
 	var assignment models.MachineAssignment
-	err = tx.QueryRow(query, scheduleID, req.MachineID, req.Sequence, req.TargetHours).
+	err = tx.QueryRow(query, scheduleID, req.MachineID, req.Sequence, req.TargetHours, scheduledStart, scheduledEnd).
 		Scan(&assignment.ID, &assignment.CreatedAt, &assignment.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create machine assignment: %w", err)
@@ -93,26 +118,36 @@ func (r *PPICScheduleRepository) createMachineAssignment(tx *sql.Tx, scheduleID
 	assignment.MachineCode = machineCode
 	assignment.Sequence = req.Sequence
 	assignment.TargetHours = req.TargetHours
+	assignment.ScheduledStart = scheduledStart
+	assignment.ScheduledEnd = scheduledEnd
 	assignment.Status = "pending"
+	assignment.Version = 1
 
 	return &assignment, nil
 }
 
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // GetByID retrieves a schedule by ID with its machine assignments
 func (r *PPICScheduleRepository) GetByID(id int64) (*models.PPICSchedule, error) {
+	return r.getByID(r.db, id)
+}
+
+func (r *PPICScheduleRepository) getByID(q queryer, id int64) (*models.PPICSchedule, error) {
 	query := `
-		SELECT id, njo, part_name, priority, priority_alpha, material_status, status, progress, 
-		       start_date, finish_date, ppic_notes, created_by, created_at, updated_at
+		SELECT id, njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		       start_date, finish_date, ppic_notes, version, quantity, parent_schedule_id, lot_number, created_by, created_at, updated_at
 		FROM ppic_schedules
-		WHERE id = $1 AND deleted_at IS NULL
+		WHERE id = $1 AND deleted_at IS NULL AND ` + r.scenarioScope("scenario_id") + `
 	`
 
 	var schedule models.PPICSchedule
-	err := r.db.QueryRow(query, id).Scan(
-		&schedule.ID, &schedule.NJO, &schedule.PartName, &schedule.Priority, &schedule.PriorityAlpha,
-		&schedule.MaterialStatus, &schedule.Status, &schedule.Progress, &schedule.StartDate,
-		&schedule.FinishDate, &schedule.PPICNotes, &schedule.CreatedBy, &schedule.CreatedAt, &schedule.UpdatedAt,
-	)
+	err := q.QueryRow(query, id).Scan(scheduleScanDest(&schedule)...)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -121,7 +156,7 @@ func (r *PPICScheduleRepository) GetByID(id int64) (*models.PPICSchedule, error)
 	}
 
 	// Get machine assignments
-	assignments, err := r.getMachineAssignments(schedule.ID)
+	assignments, err := r.queryMachineAssignments(q, "ma.schedule_id = $1", schedule.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -130,10 +165,21 @@ func (r *PPICScheduleRepository) GetByID(id int64) (*models.PPICSchedule, error)
 	return &schedule, nil
 }
 
-// GetByNJO retrieves a schedule by NJO
+// scheduleScanDest returns the scan destinations for the schedule columns in the order the queries
+// select them: id through ppic_notes, version, quantity, parent_schedule_id, lot_number, created_by, created_at, updated_at
+func scheduleScanDest(s *models.PPICSchedule) []interface{} {
+	return []interface{}{
+		&s.ID, &s.NJO, &s.PartName, &s.Priority, &s.PriorityAlpha,
+		&s.MaterialStatus, &s.Status, &s.Progress, &s.ProgressOverride, &s.StartDate,
+		&s.FinishDate, &s.PPICNotes, &s.Version, &s.Quantity, &s.ParentScheduleID, &s.LotNumber,
+		&s.CreatedBy, &s.CreatedAt, &s.UpdatedAt,
+	}
+}
+
+// GetByNJO retrieves a schedule by NJO. Lots share the NJO of their parent and are not returned
 func (r *PPICScheduleRepository) GetByNJO(njo string) (*models.PPICSchedule, error) {
 	query := `
-		SELECT id FROM ppic_schedules WHERE njo = $1 AND deleted_at IS NULL
+		SELECT id FROM ppic_schedules WHERE njo = $1 AND parent_schedule_id IS NULL AND deleted_at IS NULL AND ` + r.scenarioScope("scenario_id") + `
 	`
 	var id int64
 	err := r.db.QueryRow(query, njo).Scan(&id)
@@ -149,10 +195,10 @@ func (r *PPICScheduleRepository) GetByNJO(njo string) (*models.PPICSchedule, err
 // GetAll retrieves all schedules
 func (r *PPICScheduleRepository) GetAll() ([]models.PPICSchedule, error) {
 	query := `
-		SELECT id, njo, part_name, priority, priority_alpha, material_status, status, progress, 
-		       start_date, finish_date, ppic_notes, created_by, created_at, updated_at
+		SELECT id, njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		       start_date, finish_date, ppic_notes, version, quantity, parent_schedule_id, lot_number, created_by, created_at, updated_at
 		FROM ppic_schedules
-		WHERE deleted_at IS NULL
+		WHERE deleted_at IS NULL AND ` + r.scenarioScope("scenario_id") + `
 		ORDER BY 
 			CASE priority 
 				WHEN 'Top Urgent' THEN 1 
@@ -172,74 +218,39 @@ func (r *PPICScheduleRepository) GetAll() ([]models.PPICSchedule, error) {
 	var schedules []models.PPICSchedule
 	for rows.Next() {
 		var s models.PPICSchedule
-		err := rows.Scan(
-			&s.ID, &s.NJO, &s.PartName, &s.Priority, &s.PriorityAlpha,
-			&s.MaterialStatus, &s.Status, &s.Progress, &s.StartDate,
-			&s.FinishDate, &s.PPICNotes, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt,
-		)
-		if err != nil {
+		if err := rows.Scan(scheduleScanDest(&s)...); err != nil {
 			return nil, err
 		}
 
-		assignments, err := r.getMachineAssignments(s.ID)
-		if err != nil {
-			return nil, err
-		}
-		s.MachineAssignments = assignments
 		schedules = append(schedules, s)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	if err := r.attachMachineAssignments(schedules); err != nil {
+		return nil, err
+	}
 	return schedules, nil
 }
 
-// GetWithFilters retrieves schedules with filters
-// GetWithFilters retrieves schedules with filters
+// GetWithFilters retrieves schedules with filters, with their machine assignments
 func (r *PPICScheduleRepository) GetWithFilters(filter models.GanttFilterRequest) ([]models.PPICSchedule, error) {
+	conditions, args := r.scheduleFilterConditions(filter, true)
 	query := `
 		SELECT ps.id, ps.njo, ps.part_name, ps.priority, ps.priority_alpha, ps.material_status, 
-		       ps.status, ps.progress, ps.start_date, ps.finish_date, ps.ppic_notes, ps.created_by, 
+		       ps.status, ps.progress, ps.progress_override, ps.start_date, ps.finish_date, ps.ppic_notes, ps.version, ps.quantity, ps.parent_schedule_id, ps.lot_number, ps.created_by, 
 		       ps.created_at, ps.updated_at
 		FROM ppic_schedules ps
-		WHERE ps.deleted_at IS NULL
-	`
-
-	var args []interface{}
-	argNum := 1
-
-	if filter.StartDate != "" {
-		query += fmt.Sprintf(" AND ps.start_date >= $%d", argNum)
-		args = append(args, filter.StartDate)
-		argNum++
-	}
-	if filter.EndDate != "" {
-		query += fmt.Sprintf(" AND ps.finish_date <= $%d", argNum)
-		args = append(args, filter.EndDate)
-		argNum++
-	}
-	if filter.Priority != "" {
-		query += fmt.Sprintf(" AND ps.priority = $%d", argNum)
-		args = append(args, filter.Priority)
-		argNum++
-	}
-	if filter.Status != "" {
-		query += fmt.Sprintf(" AND ps.status = $%d", argNum)
-		args = append(args, filter.Status)
-		argNum++
-	}
-	if filter.MachineID > 0 {
-		query += fmt.Sprintf(" AND ps.id IN (SELECT schedule_id FROM machine_assignments WHERE machine_id = $%d)", argNum)
-		args = append(args, filter.MachineID)
-		argNum++
-	}
-
-	query += ` ORDER BY 
-		CASE ps.priority 
-			WHEN 'Top Urgent' THEN 1 
-			WHEN 'Urgent' THEN 2 
-			WHEN 'Medium' THEN 3 
-			WHEN 'Low' THEN 4 
-		END,
-		ps.start_date ASC`
+		WHERE ` + conditions + `
+		ORDER BY 
+			CASE ps.priority 
+				WHEN 'Top Urgent' THEN 1 
+				WHEN 'Urgent' THEN 2 
+				WHEN 'Medium' THEN 3 
+				WHEN 'Low' THEN 4 
+			END,
+			ps.start_date ASC, ps.id ASC`
 
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
@@ -250,26 +261,77 @@ func (r *PPICScheduleRepository) GetWithFilters(filter models.GanttFilterRequest
 	var schedules []models.PPICSchedule
 	for rows.Next() {
 		var s models.PPICSchedule
-		err := rows.Scan(
-			&s.ID, &s.NJO, &s.PartName, &s.Priority, &s.PriorityAlpha,
-			&s.MaterialStatus, &s.Status, &s.Progress, &s.StartDate,
-			&s.FinishDate, &s.PPICNotes, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt,
-		)
-		if err != nil {
+		if err := rows.Scan(scheduleScanDest(&s)...); err != nil {
 			return nil, err
 		}
 
-		assignments, err := r.getMachineAssignments(s.ID)
-		if err != nil {
-			return nil, err
-		}
-		s.MachineAssignments = assignments
 		schedules = append(schedules, s)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	if err := r.attachMachineAssignments(schedules); err != nil {
+		return nil, err
+	}
 	return schedules, nil
 }
 
+// CountOutsideWindow counts the schedules matching the filter that lie entirely before or
+// after its date window
+func (r *PPICScheduleRepository) CountOutsideWindow(filter models.GanttFilterRequest) (int, int, error) {
+	conditions, args := r.scheduleFilterConditions(filter, false)
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) FILTER (WHERE ps.finish_date < CAST($%d AS DATE)),
+		       COUNT(*) FILTER (WHERE ps.start_date >= CAST($%d AS DATE) + CAST($%d AS INTEGER))
+		FROM ppic_schedules ps
+		WHERE %s`, len(args)+1, len(args)+1, len(args)+2, conditions)
+	args = append(args, filter.WindowStart, filter.WindowDays)
+
+	var earlier, later int
+	if err := r.db.QueryRow(query, args...).Scan(&earlier, &later); err != nil {
+		return 0, 0, err
+	}
+	return earlier, later, nil
+}
+
+// scheduleFilterConditions builds the WHERE clause of a Gantt filter. The date window
+// (window_start + window_days) keeps schedules overlapping it and is only applied with withWindow
+func (r *PPICScheduleRepository) scheduleFilterConditions(filter models.GanttFilterRequest, withWindow bool) (string, []interface{}) {
+	conditions := []string{"ps.deleted_at IS NULL", r.scenarioScope("ps.scenario_id")}
+	var args []interface{}
+	add := func(condition string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i := range values {
+			placeholders[i] = len(args) + i + 1
+		}
+		conditions = append(conditions, fmt.Sprintf(condition, placeholders...))
+		args = append(args, values...)
+	}
+
+	if filter.StartDate != "" {
+		add("ps.start_date >= $%d", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		add("ps.finish_date <= $%d", filter.EndDate)
+	}
+	if filter.Priority != "" {
+		add("ps.priority = $%d", filter.Priority)
+	}
+	if filter.Status != "" {
+		add("ps.status = $%d", filter.Status)
+	}
+	if filter.MachineID > 0 {
+		add("ps.id IN (SELECT schedule_id FROM machine_assignments WHERE machine_id = $%d)", filter.MachineID)
+	}
+	if withWindow && filter.WindowStart != "" {
+		add("ps.finish_date >= CAST($%d AS DATE) AND ps.start_date < CAST($%d AS DATE) + CAST($%d AS INTEGER)",
+			filter.WindowStart, filter.WindowStart, filter.WindowDays)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
 // Update updates a schedule
 func (r *PPICScheduleRepository) Update(id int64, req *models.UpdatePPICScheduleRequest, startDate, finishDate *time.Time) (*models.PPICSchedule, error) {
 	tx, err := r.db.Begin()
@@ -278,7 +340,21 @@ func (r *PPICScheduleRepository) Update(id int64, req *models.UpdatePPICSchedule
 	}
 	defer tx.Rollback()
 
-	query := "UPDATE ppic_schedules SET updated_at = NOW()"
+	if err := r.updateSchedule(tx, id, req, startDate, finishDate); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return r.GetByID(id)
+}
+
+// updateSchedule applies an update and bumps the version. When req.Version is set, the update
+// only applies to that version and returns models.ErrStaleVersion otherwise
+func (r *PPICScheduleRepository) updateSchedule(tx *sql.Tx, id int64, req *models.UpdatePPICScheduleRequest, startDate, finishDate *time.Time) error {
+	query := "UPDATE ppic_schedules SET updated_at = NOW(), version = version + 1"
 	var args []interface{}
 	argNum := 1
 
@@ -312,6 +388,11 @@ func (r *PPICScheduleRepository) Update(id int64, req *models.UpdatePPICSchedule
 		args = append(args, *req.Progress)
 		argNum++
 	}
+	if req.ProgressOverride != nil {
+		query += fmt.Sprintf(", progress_override = $%d", argNum)
+		args = append(args, *req.ProgressOverride)
+		argNum++
+	}
 	if startDate != nil {
 		query += fmt.Sprintf(", start_date = $%d", argNum)
 		args = append(args, *startDate)
@@ -327,52 +408,140 @@ func (r *PPICScheduleRepository) Update(id int64, req *models.UpdatePPICSchedule
 		args = append(args, req.PPICNotes)
 		argNum++
 	}
+	if req.Quantity != nil {
+		query += fmt.Sprintf(", quantity = $%d", argNum)
+		args = append(args, *req.Quantity)
+		argNum++
+	}
 
-	query += fmt.Sprintf(" WHERE id = $%d AND deleted_at IS NULL", argNum)
+	query += fmt.Sprintf(" WHERE id = $%d AND deleted_at IS NULL AND %s", argNum, r.scenarioScope("scenario_id"))
 	args = append(args, id)
+	argNum++
+	if req.Version != nil {
+		query += fmt.Sprintf(" AND version = $%d", argNum)
+		args = append(args, *req.Version)
+	}
 
-	_, err = tx.Exec(query, args...)
+	result, err := tx.Exec(query, args...)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if req.Version != nil {
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return models.ErrStaleVersion
+		}
+	}
+
+	// Lots describe the same job as their parent: keep the job fields in step
+	if req.PartName != "" || req.Priority != "" || req.PriorityAlpha != "" || req.MaterialStatus != "" {
+		_, err := tx.Exec(`
+			UPDATE ppic_schedules l SET part_name = p.part_name, priority = p.priority, priority_alpha = p.priority_alpha,
+			       material_status = p.material_status, updated_at = NOW(), version = l.version + 1
+			FROM ppic_schedules p
+			WHERE p.id = $1 AND l.parent_schedule_id = p.id AND l.deleted_at IS NULL
+			  AND (l.part_name, l.priority, l.priority_alpha, l.material_status) IS DISTINCT FROM (p.part_name, p.priority, p.priority_alpha, p.material_status)
+		`, id)
+		if err != nil {
+			return fmt.Errorf("failed to update lots: %w", err)
+		}
 	}
 
 	// Handle machine assignments if provided
 	if len(req.MachineAssignments) > 0 {
-		// Delete all existing machine assignments for this schedule
-		_, err = tx.Exec("DELETE FROM machine_assignments WHERE schedule_id = $1", id)
+		if err := r.updateMachineAssignments(tx, id, req.MachineAssignments); err != nil {
+			return err
+		}
+	}
+	// New assignments or a lifted override change the derived progress
+	if len(req.MachineAssignments) > 0 || req.ProgressOverride != nil {
+		schedule, err := r.getByID(tx, id)
 		if err != nil {
-			return nil, fmt.Errorf("failed to delete old machine assignments: %w", err)
+			return err
+		}
+		if schedule != nil && !schedule.ProgressOverride {
+			if progress, status, ok := models.DeriveScheduleProgress(schedule.MachineAssignments); ok {
+				if err := r.setDerivedProgress(tx, id, progress, status); err != nil {
+					return fmt.Errorf("failed to derive schedule progress: %w", err)
+				}
+			}
 		}
+	}
+
+	return nil
+}
+
+// updateMachineAssignments brings a schedule's machine assignments in line with the requested ones,
+// matched by ID or, without one, by sequence. A matched assignment is updated in place and keeps
+// its status and actual times; unmatched requests are added and the assignments no request matched
+// are removed. An assignment that has started can't move to another machine or be removed
+func (r *PPICScheduleRepository) updateMachineAssignments(tx *sql.Tx, scheduleID int64, reqs []models.UpdateMachineAssignmentRequest) error {
+	current, err := r.queryMachineAssignments(tx, "ma.schedule_id = $1", scheduleID)
+	if err != nil {
+		return fmt.Errorf("failed to get machine assignments: %w", err)
+	}
+	byID := make(map[int64]*models.MachineAssignment, len(current))
+	bySequence := make(map[int]*models.MachineAssignment, len(current))
+	for i := range current {
+		byID[current[i].ID] = &current[i]
+		bySequence[current[i].Sequence] = &current[i]
+	}
 
-		// Create new machine assignments
-		for _, ma := range req.MachineAssignments {
-			// Get machine info
-			var machineName, machineCode string
-			err := tx.QueryRow("SELECT machine_name, machine_code FROM machines WHERE id = $1", ma.MachineID).Scan(&machineName, &machineCode)
-			if err != nil {
-				return nil, fmt.Errorf("machine not found: %w", err)
+	matched := make(map[int64]bool, len(reqs))
+	for i := range reqs {
+		req := reqs[i].CreateRequest()
+		existing := bySequence[req.Sequence]
+		if reqs[i].ID > 0 {
+			if existing = byID[reqs[i].ID]; existing == nil {
+				return fmt.Errorf("machine assignment %d is not part of this schedule", reqs[i].ID)
+			}
+		}
+		if existing == nil || matched[existing.ID] {
+			if _, err := r.createMachineAssignment(tx, scheduleID, &req); err != nil {
+				return err
 			}
+			continue
+		}
+		matched[existing.ID] = true
 
-			insertQuery := `
-				INSERT INTO machine_assignments (schedule_id, machine_id, sequence, target_hours, status, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, 'pending', NOW(), NOW())
-			`
-			_, err = tx.Exec(insertQuery, id, ma.MachineID, ma.Sequence, ma.TargetHours)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create machine assignment: %w", err)
+		if existing.MachineID != req.MachineID {
+			if existing.HasStarted() {
+				return fmt.Errorf("sequence %d has already started on %s and can't move to another machine", existing.Sequence, existing.MachineName)
+			}
+			var machineName string
+			if err := tx.QueryRow("SELECT machine_name FROM machines WHERE id = $1", req.MachineID).Scan(&machineName); err != nil {
+				return fmt.Errorf("machine not found: %w", err)
 			}
 		}
+		scheduledStart, scheduledEnd, err := req.ParseScheduledWindow()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE machine_assignments SET machine_id = $1, sequence = $2, target_hours = $3, scheduled_start = $4, scheduled_end = $5,
+			       version = version + 1, updated_at = NOW()
+			WHERE id = $6
+		`, req.MachineID, req.Sequence, req.TargetHours, scheduledStart, scheduledEnd, existing.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update machine assignment: %w", err)
+		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	for _, ma := range current {
+		if matched[ma.ID] {
+			continue
+		}
+		if ma.HasStarted() {
+			return fmt.Errorf("sequence %d has already started on %s and can't be removed", ma.Sequence, ma.MachineName)
+		}
+		if _, err := tx.Exec("DELETE FROM machine_assignments WHERE id = $1", ma.ID); err != nil {
+			return fmt.Errorf("failed to delete machine assignment: %w", err)
+		}
 	}
-
-	return r.GetByID(id)
+	return nil
 }
 
 func (r *PPICScheduleRepository) updateMachineAssignment(req *models.UpdateMachineAssignmentRequest) error {
-	query := "UPDATE machine_assignments SET updated_at = NOW()"
+	query := "UPDATE machine_assignments SET updated_at = NOW(), version = version + 1"
 	var args []interface{}
 	argNum := 1
 
@@ -386,6 +555,16 @@ func (r *PPICScheduleRepository) updateMachineAssignment(req *models.UpdateMachi
 		args = append(args, req.TargetHours)
 		argNum++
 	}
+	if req.ScheduledStart != nil {
+		query += fmt.Sprintf(", scheduled_start = $%d", argNum)
+		args = append(args, req.ScheduledStart)
+		argNum++
+	}
+	if req.ScheduledEnd != nil {
+		query += fmt.Sprintf(", scheduled_end = $%d", argNum)
+		args = append(args, req.ScheduledEnd)
+		argNum++
+	}
 	if req.Status != "" {
 		query += fmt.Sprintf(", status = $%d", argNum)
 		args = append(args, req.Status)
@@ -409,12 +588,55 @@ func (r *PPICScheduleRepository) updateMachineAssignment(req *models.UpdateMachi
 	return err
 }
 
-// Delete soft deletes a schedule
+// Delete soft deletes a schedule and its lots. It bumps their version, so scenarios copied from
+// them see the change
 func (r *PPICScheduleRepository) Delete(id int64) error {
-	_, err := r.db.Exec("UPDATE ppic_schedules SET deleted_at = NOW() WHERE id = $1", id)
+	_, err := r.db.Exec("UPDATE ppic_schedules SET deleted_at = NOW(), updated_at = NOW(), version = version + 1 WHERE (id = $1 OR parent_schedule_id = $1) AND deleted_at IS NULL AND "+r.scenarioScope("scenario_id"), id)
 	return err
 }
 
+// GetLots returns the lots split from a schedule, by lot number, with their machine assignments
+func (r *PPICScheduleRepository) GetLots(parentID int64) ([]models.PPICSchedule, error) {
+	return r.getLots(r.db, parentID)
+}
+
+func (r *PPICScheduleRepository) getLots(q queryer, parentID int64) ([]models.PPICSchedule, error) {
+	query := `
+		SELECT id, njo, part_name, priority, priority_alpha, material_status, status, progress, progress_override,
+		       start_date, finish_date, ppic_notes, version, quantity, parent_schedule_id, lot_number, created_by, created_at, updated_at
+		FROM ppic_schedules
+		WHERE parent_schedule_id = $1 AND deleted_at IS NULL AND ` + r.scenarioScope("scenario_id") + `
+		ORDER BY lot_number
+	`
+
+	rows, err := q.Query(query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []models.PPICSchedule
+	for rows.Next() {
+		var s models.PPICSchedule
+		if err := rows.Scan(scheduleScanDest(&s)...); err != nil {
+			return nil, err
+		}
+		lots = append(lots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range lots {
+		assignments, err := r.queryMachineAssignments(q, "ma.schedule_id = $1", lots[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		lots[i].MachineAssignments = assignments
+	}
+	return lots, nil
+}
+
 // GetAllMachines returns all machines
 func (r *PPICScheduleRepository) GetAllMachines() ([]models.Machine, error) {
 	query := `SELECT id, machine_code, machine_name, machine_type, location, status, created_at, updated_at 
@@ -438,7 +660,39 @@ func (r *PPICScheduleRepository) GetAllMachines() ([]models.Machine, error) {
 	return machines, nil
 }
 
-// GetSummary returns summary statistics
+// GetMachinesByIDs returns the given machines
+func (r *PPICScheduleRepository) GetMachinesByIDs(ids []int64) ([]models.Machine, error) {
+	machines := []models.Machine{}
+	if len(ids) == 0 {
+		return machines, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := `SELECT id, machine_code, machine_name, machine_type, location, status, created_at, updated_at 
+	          FROM machines WHERE deleted_at IS NULL AND id IN (` + strings.Join(placeholders, ", ") + `) ORDER BY machine_name`
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.Machine
+		if err := rows.Scan(&m.ID, &m.MachineCode, &m.MachineName, &m.MachineType, &m.Location, &m.Status, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		machines = append(machines, m)
+	}
+	return machines, rows.Err()
+}
+
+// GetSummary returns summary statistics. Lots are counted through their parent
 func (r *PPICScheduleRepository) GetSummary() (*models.GanttSummary, error) {
 	summary := &models.GanttSummary{}
 
@@ -449,7 +703,7 @@ func (r *PPICScheduleRepository) GetSummary() (*models.GanttSummary, error) {
 			COUNT(*) FILTER (WHERE status = 'completed') as completed,
 			COUNT(*) FILTER (WHERE status = 'in_progress') as in_progress,
 			COUNT(*) FILTER (WHERE status = 'pending') as pending
-		FROM ppic_schedules WHERE deleted_at IS NULL
+		FROM ppic_schedules WHERE deleted_at IS NULL AND parent_schedule_id IS NULL AND `+r.scenarioScope("scenario_id")+`
 	`).Scan(&summary.TotalTasks, &summary.CompletedTasks, &summary.InProgressTasks, &summary.PendingTasks)
 	if err != nil {
 		return nil, err
@@ -462,7 +716,7 @@ func (r *PPICScheduleRepository) GetSummary() (*models.GanttSummary, error) {
 			COUNT(*) FILTER (WHERE priority = 'Urgent') as urgent,
 			COUNT(*) FILTER (WHERE priority = 'Medium') as medium,
 			COUNT(*) FILTER (WHERE priority = 'Low') as low
-		FROM ppic_schedules WHERE deleted_at IS NULL
+		FROM ppic_schedules WHERE deleted_at IS NULL AND parent_schedule_id IS NULL AND `+r.scenarioScope("scenario_id")+`
 	`).Scan(&summary.TopUrgentCount, &summary.UrgentCount, &summary.MediumCount, &summary.LowCount)
 	if err != nil {
 		return nil, err
@@ -473,7 +727,7 @@ func (r *PPICScheduleRepository) GetSummary() (*models.GanttSummary, error) {
 		SELECT 
 			COUNT(*) FILTER (WHERE material_status = 'Ready') as ready,
 			COUNT(*) FILTER (WHERE material_status != 'Ready') as not_ready
-		FROM ppic_schedules WHERE deleted_at IS NULL
+		FROM ppic_schedules WHERE deleted_at IS NULL AND parent_schedule_id IS NULL AND `+r.scenarioScope("scenario_id")+`
 	`).Scan(&summary.MaterialReady, &summary.MaterialNotReady)
 	if err != nil {
 		return nil, err
@@ -486,11 +740,11 @@ func (r *PPICScheduleRepository) GetSummary() (*models.GanttSummary, error) {
 func (r *PPICScheduleRepository) GetSchedulesByMachine(machineID int64) ([]models.PPICSchedule, error) {
 	query := `
 		SELECT DISTINCT ps.id, ps.njo, ps.part_name, ps.priority, ps.priority_alpha, ps.material_status, 
-		       ps.status, ps.progress, ps.start_date, ps.finish_date, ps.ppic_notes, ps.created_by, 
+		       ps.status, ps.progress, ps.progress_override, ps.start_date, ps.finish_date, ps.ppic_notes, ps.version, ps.quantity, ps.parent_schedule_id, ps.lot_number, ps.created_by, 
 		       ps.created_at, ps.updated_at
 		FROM ppic_schedules ps
 		JOIN machine_assignments ma ON ps.id = ma.schedule_id
-		WHERE ma.machine_id = $1 AND ps.deleted_at IS NULL
+		WHERE ma.machine_id = $1 AND ps.deleted_at IS NULL AND ` + r.scenarioScope("ps.scenario_id") + `
 		ORDER BY ps.start_date
 	`
 
@@ -503,65 +757,368 @@ func (r *PPICScheduleRepository) GetSchedulesByMachine(machineID int64) ([]model
 	var schedules []models.PPICSchedule
 	for rows.Next() {
 		var s models.PPICSchedule
+		if err := rows.Scan(scheduleScanDest(&s)...); err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachMachineAssignments(schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// GetActiveIDs returns which of the given schedule IDs exist and are not soft deleted
+func (r *PPICScheduleRepository) GetActiveIDs(ids []int64) (map[int64]bool, error) {
+	active := make(map[int64]bool)
+	if len(ids) == 0 {
+		return active, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf("SELECT id FROM ppic_schedules WHERE deleted_at IS NULL AND %s AND id IN (%s)", r.scenarioScope("scenario_id"), strings.Join(placeholders, ", "))
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		active[id] = true
+	}
+
+	return active, nil
+}
+
+// GetMachineBookings returns scheduled windows on a machine that overlap the given window.
+// Assignments of excludeScheduleID are skipped (use 0 to include all)
+func (r *PPICScheduleRepository) GetMachineBookings(machineID int64, start, end time.Time, excludeScheduleID int64) ([]models.MachineAssignmentWindow, error) {
+	return r.getMachineBookings(r.db, machineID, start, end, excludeScheduleID)
+}
+
+func (r *PPICScheduleRepository) getMachineBookings(q queryer, machineID int64, start, end time.Time, excludeScheduleID int64) ([]models.MachineAssignmentWindow, error) {
+	query := `
+		SELECT ma.id, ma.schedule_id, ps.njo, ps.part_name, ma.sequence, ma.scheduled_start, ma.scheduled_end
+		FROM machine_assignments ma
+		JOIN ppic_schedules ps ON ps.id = ma.schedule_id AND ps.deleted_at IS NULL AND ` + r.scenarioScope("ps.scenario_id") + `
+		WHERE ma.machine_id = $1
+		  AND ma.scheduled_start IS NOT NULL AND ma.scheduled_end IS NOT NULL
+		  AND ma.scheduled_start < $3 AND ma.scheduled_end > $2
+		  AND ma.schedule_id <> $4
+		ORDER BY ma.scheduled_start
+	`
+
+	rows, err := q.Query(query, machineID, start, end, excludeScheduleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []models.MachineAssignmentWindow
+	for rows.Next() {
+		var w models.MachineAssignmentWindow
+		if err := rows.Scan(&w.AssignmentID, &w.ScheduleID, &w.NJO, &w.PartName, &w.Sequence, &w.ScheduledStart, &w.ScheduledEnd); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+
+	return windows, nil
+}
+
+// GetBookedWindows returns every scheduled machine window ending after from, grouped by machine
+func (r *PPICScheduleRepository) GetBookedWindows(from time.Time) (map[int64][]models.MachineAssignmentWindow, error) {
+	query := `
+		SELECT ma.machine_id, ma.id, ma.schedule_id, ps.njo, ps.part_name, ma.sequence, ma.scheduled_start, ma.scheduled_end
+		FROM machine_assignments ma
+		JOIN ppic_schedules ps ON ps.id = ma.schedule_id AND ps.deleted_at IS NULL AND ` + r.scenarioScope("ps.scenario_id") + `
+		WHERE ma.scheduled_start IS NOT NULL AND ma.scheduled_end IS NOT NULL
+		  AND ma.scheduled_end > $1
+		ORDER BY ma.machine_id, ma.scheduled_start
+	`
+
+	rows, err := r.db.Query(query, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := make(map[int64][]models.MachineAssignmentWindow)
+	for rows.Next() {
+		var machineID int64
+		var w models.MachineAssignmentWindow
+		if err := rows.Scan(&machineID, &w.AssignmentID, &w.ScheduleID, &w.NJO, &w.PartName, &w.Sequence, &w.ScheduledStart, &w.ScheduledEnd); err != nil {
+			return nil, err
+		}
+		windows[machineID] = append(windows[machineID], w)
+	}
+
+	return windows, nil
+}
+
+// GetAssignmentsInRange returns the machine assignments whose scheduled or actual window overlaps [start, end)
+func (r *PPICScheduleRepository) GetAssignmentsInRange(start, end time.Time) ([]models.MachineAssignment, error) {
+	query := `
+		SELECT ma.id, ma.schedule_id, ma.machine_id, ma.sequence, ma.target_hours, ma.scheduled_start, ma.scheduled_end,
+		       ma.actual_start, ma.actual_end, ma.status
+		FROM machine_assignments ma
+		JOIN ppic_schedules ps ON ps.id = ma.schedule_id AND ps.deleted_at IS NULL AND ` + r.scenarioScope("ps.scenario_id") + `
+		WHERE (ma.scheduled_start IS NOT NULL AND ma.scheduled_end IS NOT NULL AND ma.scheduled_start < $2 AND ma.scheduled_end > $1)
+		   OR (ma.actual_start IS NOT NULL AND ma.actual_start < $2 AND (ma.actual_end IS NULL OR ma.actual_end > $1))
+		ORDER BY ma.machine_id, ma.scheduled_start
+	`
+
+	rows, err := r.db.Query(query, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []models.MachineAssignment
+	for rows.Next() {
+		var ma models.MachineAssignment
+		if err := rows.Scan(&ma.ID, &ma.ScheduleID, &ma.MachineID, &ma.Sequence, &ma.TargetHours, &ma.ScheduledStart, &ma.ScheduledEnd,
+			&ma.ActualStart, &ma.ActualEnd, &ma.Status); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, ma)
+	}
+
+	return assignments, nil
+}
+
+// GetMachineConflicts returns every pair of assignments on the same machine with overlapping scheduled windows
+func (r *PPICScheduleRepository) GetMachineConflicts(filter models.MachineConflictFilterRequest) ([]models.MachineConflictGroup, error) {
+	query := `
+		SELECT m.id, m.machine_name, m.machine_code,
+		       a.id, a.schedule_id, pa.njo, pa.part_name, a.sequence, a.scheduled_start, a.scheduled_end,
+		       b.id, b.schedule_id, pb.njo, pb.part_name, b.sequence, b.scheduled_start, b.scheduled_end
+		FROM machine_assignments a
+		JOIN machine_assignments b ON b.machine_id = a.machine_id AND b.id > a.id
+		     AND a.scheduled_start < b.scheduled_end AND b.scheduled_start < a.scheduled_end
+		JOIN ppic_schedules pa ON pa.id = a.schedule_id AND pa.deleted_at IS NULL AND ` + r.scenarioScope("pa.scenario_id") + `
+		JOIN ppic_schedules pb ON pb.id = b.schedule_id AND pb.deleted_at IS NULL AND ` + r.scenarioScope("pb.scenario_id") + `
+		JOIN machines m ON m.id = a.machine_id
+		WHERE a.scheduled_start IS NOT NULL AND a.scheduled_end IS NOT NULL
+		  AND b.scheduled_start IS NOT NULL AND b.scheduled_end IS NOT NULL
+	`
+
+	var args []interface{}
+	argNum := 1
+
+	if filter.StartDate != "" {
+		query += fmt.Sprintf(" AND LEAST(a.scheduled_end, b.scheduled_end) > $%d", argNum)
+		args = append(args, filter.StartDate)
+		argNum++
+	}
+	if filter.EndDate != "" {
+		// Include the whole end day
+		query += fmt.Sprintf(" AND GREATEST(a.scheduled_start, b.scheduled_start) < ($%d::date + INTERVAL '1 day')", argNum)
+		args = append(args, filter.EndDate)
+		argNum++
+	}
+	if filter.MachineID > 0 {
+		query += fmt.Sprintf(" AND a.machine_id = $%d", argNum)
+		args = append(args, filter.MachineID)
+		argNum++
+	}
+
+	query += " ORDER BY m.machine_name, a.scheduled_start, b.scheduled_start"
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []models.MachineConflictGroup
+	groupIndex := make(map[int64]int)
+	for rows.Next() {
+		var machineID int64
+		var machineName, machineCode string
+		var c models.MachineConflict
 		err := rows.Scan(
-			&s.ID, &s.NJO, &s.PartName, &s.Priority, &s.PriorityAlpha,
-			&s.MaterialStatus, &s.Status, &s.Progress, &s.StartDate,
-			&s.FinishDate, &s.PPICNotes, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt,
+			&machineID, &machineName, &machineCode,
+			&c.First.AssignmentID, &c.First.ScheduleID, &c.First.NJO, &c.First.PartName, &c.First.Sequence, &c.First.ScheduledStart, &c.First.ScheduledEnd,
+			&c.Second.AssignmentID, &c.Second.ScheduleID, &c.Second.NJO, &c.Second.PartName, &c.Second.Sequence, &c.Second.ScheduledStart, &c.Second.ScheduledEnd,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		assignments, err := r.getMachineAssignments(s.ID)
-		if err != nil {
-			return nil, err
+		idx, ok := groupIndex[machineID]
+		if !ok {
+			groups = append(groups, models.MachineConflictGroup{
+				MachineID:   machineID,
+				MachineName: machineName,
+				MachineCode: machineCode,
+			})
+			idx = len(groups) - 1
+			groupIndex[machineID] = idx
 		}
-		s.MachineAssignments = assignments
-		schedules = append(schedules, s)
+		groups[idx].Conflicts = append(groups[idx].Conflicts, c)
 	}
 
-	return schedules, nil
+	return groups, nil
 }
 
-// AddMachineAssignment adds a machine assignment to a schedule
-func (r *PPICScheduleRepository) AddMachineAssignment(req *models.CreateMachineAssignmentRequest, scheduleID int64) (*models.MachineAssignment, error) {
+// DeleteMachineAssignment removes a machine assignment from a schedule
+func (r *PPICScheduleRepository) DeleteMachineAssignment(scheduleID, assignmentID int64) error {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback()
 
-	assignment, err := r.createMachineAssignment(tx, scheduleID, req)
+	_, err = tx.Exec(
+		"DELETE FROM machine_assignments WHERE id = $1 AND schedule_id = $2 AND schedule_id IN (SELECT id FROM ppic_schedules WHERE "+r.scenarioScope("scenario_id")+")",
+		assignmentID, scheduleID,
+	)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := r.touchSchedule(tx, scheduleID); err != nil {
+		return err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, err
+	return tx.Commit()
+}
+
+// UpdateMachineAssignmentStatus sets the status and actual times of one machine assignment,
+// provided it is still at version. Returns models.ErrStaleVersion otherwise
+func (r *PPICScheduleRepository) UpdateMachineAssignmentStatus(scheduleID, assignmentID int64, version int, status string, actualStart, actualEnd *time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	return assignment, nil
+	result, err := tx.Exec(`
+		UPDATE machine_assignments
+		SET status = $1, actual_start = $2, actual_end = $3, updated_at = NOW(), version = version + 1
+		WHERE id = $4 AND schedule_id = $5 AND version = $6
+	`, status, actualStart, actualEnd, assignmentID, scheduleID, version)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return models.ErrStaleVersion
+	}
+	if err := r.touchSchedule(tx, scheduleID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// DeleteMachineAssignment removes a machine assignment
-func (r *PPICScheduleRepository) DeleteMachineAssignment(assignmentID int64) error {
-	_, err := r.db.Exec("DELETE FROM machine_assignments WHERE id = $1", assignmentID)
+// touchSchedule bumps the version of a schedule whose machine assignments changed, so an
+// update still carrying the old assignments is rejected as stale
+func (r *PPICScheduleRepository) touchSchedule(q queryer, id int64) error {
+	_, err := q.Exec("UPDATE ppic_schedules SET updated_at = NOW(), version = version + 1 WHERE id = $1 AND "+r.scenarioScope("scenario_id"), id)
 	return err
 }
 
-// Helper function to get machine assignments for a schedule
-func (r *PPICScheduleRepository) getMachineAssignments(scheduleID int64) ([]models.MachineAssignment, error) {
+// syncLotParent sets the dates of the parent of a lot to the span of its lots and its quantity
+// to their total. It does nothing when the schedule is not a lot
+func (r *PPICScheduleRepository) syncLotParent(q queryer, lotID int64) error {
+	_, err := q.Exec(`
+		UPDATE ppic_schedules p SET start_date = lots.start_date, finish_date = lots.finish_date, quantity = lots.quantity,
+		       updated_at = NOW(), version = p.version + 1
+		FROM (
+			SELECT l.parent_schedule_id, MIN(l.start_date) AS start_date, MAX(l.finish_date) AS finish_date, SUM(l.quantity) AS quantity
+			FROM ppic_schedules l
+			WHERE l.parent_schedule_id = (SELECT parent_schedule_id FROM ppic_schedules WHERE id = $1) AND l.deleted_at IS NULL
+			GROUP BY l.parent_schedule_id
+		) lots
+		WHERE p.id = lots.parent_schedule_id AND `+r.scenarioScope("p.scenario_id")+`
+		  AND (p.start_date, p.finish_date, p.quantity) IS DISTINCT FROM (lots.start_date, lots.finish_date, lots.quantity)
+	`, lotID)
+	if err != nil {
+		return fmt.Errorf("failed to update the split schedule: %w", err)
+	}
+	return nil
+}
+
+// SetDerivedProgress stores the progress and status derived from the machine assignments.
+// Schedules with a manual override are left alone. The version is not bumped: derived values
+// follow a change that already bumped it
+func (r *PPICScheduleRepository) SetDerivedProgress(id int64, progress int, status string) error {
+	return r.setDerivedProgress(r.db, id, progress, status)
+}
+
+func (r *PPICScheduleRepository) setDerivedProgress(q queryer, id int64, progress int, status string) error {
+	_, err := q.Exec(`
+		UPDATE ppic_schedules SET progress = $1, status = $2, updated_at = NOW()
+		WHERE id = $3 AND progress_override = FALSE AND deleted_at IS NULL AND `+r.scenarioScope("scenario_id"),
+		progress, status, id)
+	return err
+}
+
+// machineAssignmentBatchSize caps the schedule IDs per assignment query (Postgres allows 65535 parameters)
+const machineAssignmentBatchSize = 1000
+
+// attachMachineAssignments loads the machine assignments of all schedules with one query per
+// machineAssignmentBatchSize schedules, instead of one query per schedule
+func (r *PPICScheduleRepository) attachMachineAssignments(schedules []models.PPICSchedule) error {
+	index := make(map[int64]int, len(schedules))
+	for i := range schedules {
+		index[schedules[i].ID] = i
+	}
+
+	for from := 0; from < len(schedules); from += machineAssignmentBatchSize {
+		to := from + machineAssignmentBatchSize
+		if to > len(schedules) {
+			to = len(schedules)
+		}
+
+		placeholders := make([]string, 0, to-from)
+		args := make([]interface{}, 0, to-from)
+		for i := from; i < to; i++ {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)+1))
+			args = append(args, schedules[i].ID)
+		}
+
+		assignments, err := r.queryMachineAssignments(r.db, "ma.schedule_id IN ("+strings.Join(placeholders, ", ")+")", args...)
+		if err != nil {
+			return err
+		}
+		for _, a := range assignments {
+			if i, ok := index[a.ScheduleID]; ok {
+				schedules[i].MachineAssignments = append(schedules[i].MachineAssignments, a)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *PPICScheduleRepository) queryMachineAssignments(q queryer, condition string, args ...interface{}) ([]models.MachineAssignment, error) {
 	query := `
 		SELECT ma.id, ma.schedule_id, ma.machine_id, m.machine_name, m.machine_code,
 		       ma.sequence, ma.target_hours, ma.scheduled_start, ma.scheduled_end,
-		       ma.actual_start, ma.actual_end, ma.status, ma.created_at, ma.updated_at
+		       ma.actual_start, ma.actual_end, ma.status, ma.version, ma.created_at, ma.updated_at
 		FROM machine_assignments ma
 		JOIN machines m ON ma.machine_id = m.id
-		WHERE ma.schedule_id = $1
-		ORDER BY ma.sequence
+		WHERE ` + condition + `
+		ORDER BY ma.schedule_id, ma.sequence
 	`
 
-	rows, err := r.db.Query(query, scheduleID)
+	rows, err := q.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -573,7 +1130,7 @@ func (r *PPICScheduleRepository) getMachineAssignments(scheduleID int64) ([]mode
 		err := rows.Scan(
 			&a.ID, &a.ScheduleID, &a.MachineID, &a.MachineName, &a.MachineCode,
 			&a.Sequence, &a.TargetHours, &a.ScheduledStart, &a.ScheduledEnd,
-			&a.ActualStart, &a.ActualEnd, &a.Status, &a.CreatedAt, &a.UpdatedAt,
+			&a.ActualStart, &a.ActualEnd, &a.Status, &a.Version, &a.CreatedAt, &a.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -581,5 +1138,5 @@ func (r *PPICScheduleRepository) getMachineAssignments(scheduleID int64) ([]mode
 		assignments = append(assignments, a)
 	}
 
-	return assignments, nil
+	return assignments, rows.Err()
 }
@@ -0,0 +1,329 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"ganttpro-backend/models"
+	"strings"
+	"time"
+)
+
+// ScheduleTx is a transaction on the schedule board of the repository's scenario.
+// Nothing is visible to other connections until Commit
+type ScheduleTx struct {
+	tx   *sql.Tx
+	repo *PPICScheduleRepository
+}
+
+// Begin starts a transaction on the schedule board
+func (r *PPICScheduleRepository) Begin() (*ScheduleTx, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &ScheduleTx{tx: tx, repo: r}, nil
+}
+
+// Lock locks the schedules (SELECT ... FOR UPDATE) until the transaction ends. The rows of one call
+// are locked in ID order, so two transactions each locking their whole set in one call wait for each
+// other instead of deadlocking. Locks taken over several calls carry no such ordering
+func (t *ScheduleTx) Lock(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	rows, err := t.tx.Query(`
+		SELECT id FROM ppic_schedules
+		WHERE id IN (`+strings.Join(placeholders, ", ")+`) AND deleted_at IS NULL AND `+t.repo.scenarioScope("scenario_id")+`
+		ORDER BY id
+		FOR UPDATE
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to lock schedules: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		// Rows are locked as they are read
+	}
+	return rows.Err()
+}
+
+// LockMachines locks the machines (SELECT ... FOR UPDATE) until the transaction ends, so writers
+// booking the same machines check and write their windows one at a time. Like Lock, the rows of one
+// call are locked in ID order. Schedules are locked before machines, never the other way round
+func (t *ScheduleTx) LockMachines(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	rows, err := t.tx.Query(`
+		SELECT id FROM machines
+		WHERE id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY id
+		FOR UPDATE
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to lock machines: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		// Rows are locked as they are read
+	}
+	return rows.Err()
+}
+
+// GetLinks reads the links of the board inside the transaction
+func (t *ScheduleTx) GetLinks() ([]models.PPICLink, error) {
+	rows, err := t.tx.Query(`
+		SELECT id, source_schedule_id, target_schedule_id, link_type, lag_days, scenario_id, created_at, updated_at
+		FROM ppic_links
+		WHERE ` + t.repo.scenarioScope("scenario_id") + `
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.PPICLink
+	for rows.Next() {
+		var link models.PPICLink
+		if err := rows.Scan(&link.ID, &link.SourceScheduleID, &link.TargetScheduleID, &link.LinkType, &link.LagDays,
+			&link.ScenarioID, &link.CreatedAt, &link.UpdatedAt); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// CreateLink stores a link inside the transaction
+func (t *ScheduleTx) CreateLink(req *models.CreatePPICLinkRequest) (*models.PPICLink, error) {
+	link := &models.PPICLink{
+		SourceScheduleID: req.SourceScheduleID,
+		TargetScheduleID: req.TargetScheduleID,
+		LinkType:         req.LinkType,
+		LagDays:          req.LagDays,
+	}
+	err := t.tx.QueryRow(`
+		INSERT INTO ppic_links (source_schedule_id, target_schedule_id, link_type, lag_days, scenario_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, scenario_id, created_at, updated_at
+	`, link.SourceScheduleID, link.TargetScheduleID, link.LinkType, link.LagDays, t.repo.scenarioValue()).
+		Scan(&link.ID, &link.ScenarioID, &link.CreatedAt, &link.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create link: %w", err)
+	}
+	return link, nil
+}
+
+// Create inserts a schedule with its machine assignments inside the transaction
+func (t *ScheduleTx) Create(req *models.CreatePPICScheduleRequest, createdBy int64, startDate, finishDate time.Time) (*models.PPICSchedule, error) {
+	return t.repo.createSchedule(t.tx, req, createdBy, startDate, finishDate)
+}
+
+// AddMachineAssignment adds a machine assignment to a schedule inside the transaction and bumps its version
+func (t *ScheduleTx) AddMachineAssignment(req *models.CreateMachineAssignmentRequest, scheduleID int64) (*models.MachineAssignment, error) {
+	assignment, err := t.repo.createMachineAssignment(t.tx, scheduleID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.repo.touchSchedule(t.tx, scheduleID); err != nil {
+		return nil, err
+	}
+	return assignment, nil
+}
+
+// GetByID reads a schedule with its machine assignments inside the transaction
+func (t *ScheduleTx) GetByID(id int64) (*models.PPICSchedule, error) {
+	return t.repo.getByID(t.tx, id)
+}
+
+// Update updates a schedule inside the transaction. When req.Version is set and no longer
+// matches, it returns models.ErrStaleVersion
+func (t *ScheduleTx) Update(id int64, req *models.UpdatePPICScheduleRequest, startDate, finishDate *time.Time) error {
+	return t.repo.updateSchedule(t.tx, id, req, startDate, finishDate)
+}
+
+// ApplyImport writes validated import rows inside the transaction: new schedules are inserted,
+// update rows overwrite the schedule in ScheduleID. An update row fails with models.ErrStaleVersion
+// when its schedule changed after the row was validated. ScheduleID is filled in for inserted rows
+func (t *ScheduleTx) ApplyImport(rows []models.PPICImportRow, createdBy int64) error {
+	for i := range rows {
+		row := &rows[i]
+		switch row.Action {
+		case models.ImportActionCreate:
+			schedule, err := t.repo.createSchedule(t.tx, row.Create, createdBy, row.StartDate, row.FinishDate)
+			if err != nil {
+				return fmt.Errorf("row %d (NJO %s): %w", row.Row, row.NJO, err)
+			}
+			row.ScheduleID = &schedule.ID
+		case models.ImportActionUpdate:
+			req := row.UpdateRequest()
+			req.Version = &row.Existing.Version
+			if err := t.repo.updateSchedule(t.tx, *row.ScheduleID, req, &row.StartDate, &row.FinishDate); err != nil {
+				return fmt.Errorf("row %d (NJO %s): %w", row.Row, row.NJO, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyAutoScheduledTask writes the dates and machine windows an auto-schedule plan gives a schedule
+func (t *ScheduleTx) ApplyAutoScheduledTask(task *models.AutoScheduledTask) error {
+	if err := t.SetDates(task.ScheduleID, task.ProposedStartDate, task.ProposedFinishDate); err != nil {
+		return fmt.Errorf("failed to update schedule %s: %w", task.NJO, err)
+	}
+	for _, op := range task.Operations {
+		result, err := t.tx.Exec(
+			"UPDATE machine_assignments SET scheduled_start = $1, scheduled_end = $2, updated_at = NOW(), version = version + 1 WHERE id = $3 AND schedule_id = $4",
+			op.ScheduledStart, op.ScheduledEnd, op.AssignmentID, task.ScheduleID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update machine assignment %d: %w", op.AssignmentID, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return fmt.Errorf("machine assignment %d of %s not found", op.AssignmentID, task.NJO)
+		}
+	}
+	return t.repo.syncLotParent(t.tx, task.ScheduleID)
+}
+
+// SetDates moves a schedule to new dates inside the transaction
+func (t *ScheduleTx) SetDates(id int64, startDate, finishDate time.Time) error {
+	result, err := t.tx.Exec(
+		"UPDATE ppic_schedules SET start_date = $1, finish_date = $2, updated_at = NOW(), version = version + 1 WHERE id = $3 AND deleted_at IS NULL AND "+t.repo.scenarioScope("scenario_id"),
+		startDate, finishDate, id,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	return nil
+}
+
+// GetLots reads the lots of a schedule inside the transaction
+func (t *ScheduleTx) GetLots(parentID int64) ([]models.PPICSchedule, error) {
+	return t.repo.getLots(t.tx, parentID)
+}
+
+// CreateLot splits a lot off the parent: it copies the parent's job fields and gets the next
+// lot number. Numbers of merged lots are not reused
+func (t *ScheduleTx) CreateLot(parent *models.PPICSchedule, lot *models.SplitLotRequest, startDate, finishDate time.Time, createdBy int64) (*models.PPICSchedule, error) {
+	var lotNumber int
+	err := t.tx.QueryRow("SELECT COALESCE(MAX(lot_number), 0) + 1 FROM ppic_schedules WHERE parent_schedule_id = $1", parent.ID).Scan(&lotNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.CreatePPICScheduleRequest{
+		NJO:                parent.NJO,
+		PartName:           parent.PartName,
+		Priority:           parent.Priority,
+		PriorityAlpha:      parent.PriorityAlpha,
+		MaterialStatus:     parent.MaterialStatus,
+		PPICNotes:          lot.PPICNotes,
+		Quantity:           lot.Quantity,
+		MachineAssignments: lot.MachineAssignments,
+	}
+	schedule, err := t.repo.createSchedule(t.tx, req, createdBy, startDate, finishDate)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = t.tx.Exec("UPDATE ppic_schedules SET parent_schedule_id = $1, lot_number = $2 WHERE id = $3", parent.ID, lotNumber, schedule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lot: %w", err)
+	}
+	schedule.ParentScheduleID = &parent.ID
+	schedule.LotNumber = &lotNumber
+	return schedule, nil
+}
+
+// SetMachineAssignments replaces the machine assignments of a schedule and bumps its version
+func (t *ScheduleTx) SetMachineAssignments(id int64, assignments []models.CreateMachineAssignmentRequest) error {
+	if _, err := t.tx.Exec("DELETE FROM machine_assignments WHERE schedule_id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete old machine assignments: %w", err)
+	}
+	for i := range assignments {
+		if _, err := t.repo.createMachineAssignment(t.tx, id, &assignments[i]); err != nil {
+			return err
+		}
+	}
+	return t.repo.touchSchedule(t.tx, id)
+}
+
+// DeleteLots soft deletes lots of the parent
+func (t *ScheduleTx) DeleteLots(parentID int64, ids []int64) error {
+	for _, id := range ids {
+		_, err := t.tx.Exec(
+			"UPDATE ppic_schedules SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND parent_schedule_id = $2 AND deleted_at IS NULL",
+			id, parentID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete lot %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// MoveLot writes the dates and scheduled machine windows of a moved lot
+func (t *ScheduleTx) MoveLot(lot *models.PPICSchedule) error {
+	if err := t.SetDates(lot.ID, lot.StartDate, lot.FinishDate); err != nil {
+		return fmt.Errorf("failed to move lot %d: %w", lot.ID, err)
+	}
+	for _, ma := range lot.MachineAssignments {
+		result, err := t.tx.Exec(
+			"UPDATE machine_assignments SET scheduled_start = $1, scheduled_end = $2, updated_at = NOW(), version = version + 1 WHERE id = $3 AND schedule_id = $4",
+			ma.ScheduledStart, ma.ScheduledEnd, ma.ID, lot.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to move machine assignment %d: %w", ma.ID, err)
+		}
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return fmt.Errorf("machine assignment %d of lot %d not found", ma.ID, lot.ID)
+		}
+	}
+	return nil
+}
+
+// GetMachineBookings reads, inside the transaction, the scheduled windows on a machine overlapping
+// [start, end), leaving out those of excludeScheduleID
+func (t *ScheduleTx) GetMachineBookings(machineID int64, start, end time.Time, excludeScheduleID int64) ([]models.MachineAssignmentWindow, error) {
+	return t.repo.getMachineBookings(t.tx, machineID, start, end, excludeScheduleID)
+}
+
+// SyncLotParent sets the dates of the lot's parent to the span of its lots and its quantity to their total
+func (t *ScheduleTx) SyncLotParent(lotID int64) error {
+	return t.repo.syncLotParent(t.tx, lotID)
+}
+
+// Commit makes the changes visible and releases the locks
+func (t *ScheduleTx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback undoes the changes and releases the locks. It is a no-op after Commit
+func (t *ScheduleTx) Rollback() error {
+	err := t.tx.Rollback()
+	if err == sql.ErrTxDone {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type ProductionReportRepository struct {
+	db *gorm.DB
+}
+
+func NewProductionReportRepository(db *gorm.DB) *ProductionReportRepository {
+	return &ProductionReportRepository{db: db}
+}
+
+// GetAll retrieves reports, newest first, produced in [from, to). A zero from or to leaves that side open
+func (r *ProductionReportRepository) GetAll(filter models.ProductionReportFilterRequest, from, to time.Time) ([]models.ProductionReport, error) {
+	var reports []models.ProductionReport
+	query := r.db.Order("produced_at DESC, id DESC")
+	if filter.MachineID > 0 {
+		query = query.Where("machine_id = ?", filter.MachineID)
+	}
+	if filter.ScheduleID > 0 {
+		query = query.Where("schedule_id = ?", filter.ScheduleID)
+	}
+	if filter.AssignmentID > 0 {
+		query = query.Where("assignment_id = ?", filter.AssignmentID)
+	}
+	if !from.IsZero() {
+		query = query.Where("produced_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("produced_at < ?", to)
+	}
+	err := query.Find(&reports).Error
+	return reports, err
+}
+
+// GetByID retrieves a report by ID
+func (r *ProductionReportRepository) GetByID(id int64) (*models.ProductionReport, error) {
+	var report models.ProductionReport
+	if err := r.db.First(&report, id).Error; err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// LatestIdealCycle returns the ideal cycle time of the newest report of an assignment or, without one,
+// of a part on a machine. It returns 0 when there is no such report
+func (r *ProductionReportRepository) LatestIdealCycle(assignmentID *int64, machineID int64, partName string) (float64, error) {
+	var reports []models.ProductionReport
+	query := r.db.Order("produced_at DESC, id DESC").Limit(1)
+	if assignmentID != nil {
+		query = query.Where("assignment_id = ?", *assignmentID)
+	} else {
+		query = query.Where("machine_id = ? AND part_name = ?", machineID, partName)
+	}
+	if err := query.Find(&reports).Error; err != nil {
+		return 0, err
+	}
+	if len(reports) == 0 {
+		return 0, nil
+	}
+	return reports[0].IdealCycleSeconds, nil
+}
+
+// Create stores a report
+func (r *ProductionReportRepository) Create(report *models.ProductionReport) error {
+	return r.db.Create(report).Error
+}
+
+// Update saves changes to a report
+func (r *ProductionReportRepository) Update(report *models.ProductionReport) error {
+	return r.db.Save(report).Error
+}
+
+// Delete deletes a report
+func (r *ProductionReportRepository) Delete(id int64) error {
+	result := r.db.Delete(&models.ProductionReport{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"errors"
+
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+)
+
+type RoutingTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewRoutingTemplateRepository(db *gorm.DB) *RoutingTemplateRepository {
+	return &RoutingTemplateRepository{db: db}
+}
+
+// withSteps loads the steps of templates in sequence order, with their alternatives
+func withSteps(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Steps", func(q *gorm.DB) *gorm.DB { return q.Order("sequence ASC") }).
+		Preload("Steps.Alternatives", func(q *gorm.DB) *gorm.DB { return q.Order("preference ASC") })
+}
+
+// Create stores a template with its steps
+func (r *RoutingTemplateRepository) Create(template *models.RoutingTemplate) error {
+	return r.db.Create(template).Error
+}
+
+// GetAll retrieves templates with their steps, optionally of one part family
+func (r *RoutingTemplateRepository) GetAll(partFamily string) ([]models.RoutingTemplate, error) {
+	var templates []models.RoutingTemplate
+	query := withSteps(r.db).Order("part_family ASC, name ASC")
+	if partFamily != "" {
+		query = query.Where("part_family = ?", partFamily)
+	}
+	err := query.Find(&templates).Error
+	return templates, err
+}
+
+// GetByID retrieves a template with its steps, or nil if it doesn't exist
+func (r *RoutingTemplateRepository) GetByID(id int64) (*models.RoutingTemplate, error) {
+	var template models.RoutingTemplate
+	err := withSteps(r.db).First(&template, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetByName retrieves a template by name, or nil if there is none
+func (r *RoutingTemplateRepository) GetByName(name string) (*models.RoutingTemplate, error) {
+	var template models.RoutingTemplate
+	err := r.db.Where("name = ?", name).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// Update saves a template's fields. When steps is not nil, they replace the template's steps
+func (r *RoutingTemplateRepository) Update(template *models.RoutingTemplate, steps []models.RoutingTemplateStep) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Steps").Save(template).Error; err != nil {
+			return err
+		}
+		if steps == nil {
+			return nil
+		}
+		if err := deleteSteps(tx, template.ID); err != nil {
+			return err
+		}
+		for i := range steps {
+			steps[i].TemplateID = template.ID
+		}
+		if err := tx.Create(&steps).Error; err != nil {
+			return err
+		}
+		template.Steps = steps
+		return nil
+	})
+}
+
+// Delete deletes a template and its steps
+func (r *RoutingTemplateRepository) Delete(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteSteps(tx, id); err != nil {
+			return err
+		}
+		result := tx.Delete(&models.RoutingTemplate{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func deleteSteps(tx *gorm.DB, templateID int64) error {
+	stepIDs := tx.Model(&models.RoutingTemplateStep{}).Select("id").Where("template_id = ?", templateID)
+	if err := tx.Where("step_id IN (?)", stepIDs).Delete(&models.RoutingStepAlternative{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("template_id = ?", templateID).Delete(&models.RoutingTemplateStep{}).Error
+}
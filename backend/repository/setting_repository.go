@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"ganttpro-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SettingRepository struct {
+	db *gorm.DB
+}
+
+func NewSettingRepository(db *gorm.DB) *SettingRepository {
+	return &SettingRepository{db: db}
+}
+
+// GetAll returns every stored setting, keyed by setting key
+func (r *SettingRepository) GetAll() (map[string]string, error) {
+	var settings []models.Setting
+	if err := r.db.Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(settings))
+	for _, s := range settings {
+		values[s.Key] = s.Value
+	}
+	return values, nil
+}
+
+// Set stores a setting, replacing its current value
+func (r *SettingRepository) Set(key, value string, updatedBy int64) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&models.Setting{Key: key, Value: value, UpdatedBy: updatedBy}).Error
+}
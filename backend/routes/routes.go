@@ -38,6 +38,16 @@ func SetupRoutes(
 	googleSheetsHandler *handlers.GoogleSheetsHandler,
 	pemPlanHandler *handlers.PEMOperationPlanHandler,
 	toolpatherFileHandler *handlers.ToolpatherFileHandler,
+	calendarHandler *handlers.CalendarHandler,
+	ppicScenarioHandler *handlers.PPICScenarioHandler,
+	ppicBaselineHandler *handlers.PPICBaselineHandler,
+	calendarFeedHandler *handlers.CalendarFeedHandler,
+	routingTemplateHandler *handlers.RoutingTemplateHandler,
+	settingHandler *handlers.SettingHandler,
+	machineDowntimeHandler *handlers.MachineDowntimeHandler,
+	maintenanceHandler *handlers.MaintenanceHandler,
+	andonHandler *handlers.AndonHandler,
+	productionReportHandler *handlers.ProductionReportHandler,
 	authService *services.AuthService,
 ) *RateLimiters {
 	// Initialize rate limiters
@@ -57,6 +67,14 @@ func SetupRoutes(
 		auth.GET("/profile", middleware.AuthMiddleware(authService), apiRateLimiter.RateLimit(), authHandler.GetProfile)
 	}
 
+	// iCalendar feeds, authenticated by a per-user feed token (calendar apps can't send the JWT header)
+	feeds := api.Group("/feeds")
+	feeds.Use(apiRateLimiter.RateLimit())
+	{
+		feeds.GET("/machines/:machine_id", calendarFeedHandler.GetMachineFeed) // Scheduled windows of a machine
+		feeds.GET("/me", calendarFeedHandler.GetUserFeed)                      // Pending approvals and job orders of the token owner
+	}
+
 	// Protected routes with authentication and rate limiting
 	protected := api.Group("")
 	protected.Use(middleware.AuthMiddleware(authService))
@@ -66,9 +84,63 @@ func SetupRoutes(
 		machines := protected.Group("/machines")
 		{
 			machines.GET("", machineHandler.GetAllMachines)
+			machines.GET("/utilization", ganttHandler.GetMachineUtilization)
+			machines.GET("/oee", productionReportHandler.GetOEE)
 			machines.GET("/:id", machineHandler.GetMachine)
 		}
 
+		// Machine downtime (maintenance, breakdown and calibration windows that block scheduling)
+		machineDowntimes := protected.Group("/machine-downtimes")
+		{
+			machineDowntimes.GET("", machineDowntimeHandler.GetAllDowntimes)       // Get downtime windows
+			machineDowntimes.GET("/:id", machineDowntimeHandler.GetDowntime)       // Get single window
+			machineDowntimes.POST("", machineDowntimeHandler.CreateDowntime)       // Create window (lists affected assignments)
+			machineDowntimes.PUT("/:id", machineDowntimeHandler.UpdateDowntime)    // Update window
+			machineDowntimes.DELETE("/:id", machineDowntimeHandler.DeleteDowntime) // Delete window
+		}
+
+		// Preventive maintenance (PM plans by calendar days or run hours, and their work orders)
+		maintenance := protected.Group("/maintenance")
+		{
+			maintenance.GET("/plans", maintenanceHandler.GetAllPlans)                           // Get plans with due status
+			maintenance.GET("/plans/:id", maintenanceHandler.GetPlan)                           // Get single plan
+			maintenance.POST("/plans", maintenanceHandler.CreatePlan)                           // Create plan
+			maintenance.PUT("/plans/:id", maintenanceHandler.UpdatePlan)                        // Update plan
+			maintenance.DELETE("/plans/:id", maintenanceHandler.DeletePlan)                     // Delete plan
+			maintenance.POST("/plans/:id/work-orders", maintenanceHandler.CreateWorkOrder)      // Raise a manual work order
+			maintenance.GET("/overdue", maintenanceHandler.GetOverdue)                          // Overdue plans, most overdue first
+			maintenance.GET("/work-orders", maintenanceHandler.GetAllWorkOrders)                // Get work orders
+			maintenance.POST("/work-orders/generate", maintenanceHandler.GenerateWorkOrders)    // Generate work orders of due plans
+			maintenance.GET("/work-orders/:id", maintenanceHandler.GetWorkOrder)                // Get single work order
+			maintenance.POST("/work-orders/:id/start", maintenanceHandler.StartWorkOrder)       // Start work
+			maintenance.POST("/work-orders/:id/complete", maintenanceHandler.CompleteWorkOrder) // Record completion (technician, notes)
+			maintenance.POST("/work-orders/:id/cancel", maintenanceHandler.CancelWorkOrder)     // Cancel work order
+		}
+
+		// Andon (machine stops reported from the shop floor)
+		andon := protected.Group("/andon")
+		{
+			andon.GET("/reason-codes", andonHandler.GetReasonCodes)  // Reason code hierarchy
+			andon.GET("/board", andonHandler.GetBoard)               // Open events
+			andon.GET("/pareto", andonHandler.GetPareto)             // Downtime Pareto by reason or category
+			andon.GET("/events", andonHandler.GetAllEvents)          // Get events
+			andon.GET("/events/:id", andonHandler.GetEvent)          // Get single event
+			andon.POST("/events", andonHandler.CreateEvent)          // Raise andon (machine goes down)
+			andon.PUT("/events/:id", andonHandler.UpdateEvent)       // Classify or correct event
+			andon.POST("/events/:id/close", andonHandler.CloseEvent) // Close event (machine back up)
+			andon.DELETE("/events/:id", andonHandler.DeleteEvent)    // Delete event raised by mistake
+		}
+
+		// Production reports (good and scrap parts per machine, for OEE)
+		productionReports := protected.Group("/production-reports")
+		{
+			productionReports.GET("", productionReportHandler.GetAllReports)       // Get reports
+			productionReports.GET("/:id", productionReportHandler.GetReport)       // Get single report
+			productionReports.POST("", productionReportHandler.CreateReport)       // Report good/scrap parts
+			productionReports.PUT("/:id", productionReportHandler.UpdateReport)    // Correct report
+			productionReports.DELETE("/:id", productionReportHandler.DeleteReport) // Delete report
+		}
+
 		// Job Order routes
 		jobOrders := protected.Group("/job-orders")
 		{
@@ -119,7 +191,8 @@ func SetupRoutes(
 		// Gantt Chart routes
 		gantt := protected.Group("/gantt-chart")
 		{
-			gantt.GET("", ganttHandler.GetGanttChart) // Get Gantt chart data with filters
+			gantt.GET("", ganttHandler.GetGanttChart)                 // Get Gantt chart data with filters
+			gantt.GET("/critical-path", ganttHandler.GetCriticalPath) // Get critical path analysis
 		}
 
 		// PPIC Schedule routes (for Gantt chart data management)
@@ -127,21 +200,61 @@ func SetupRoutes(
 		{
 			ppic.GET("", ganttHandler.GetAllPPICSchedules)                                              // Get all schedules
 			ppic.GET("/:id", ganttHandler.GetPPICSchedule)                                              // Get single schedule
+			ppic.GET("/:id/history", ganttHandler.GetPPICScheduleHistory)                               // Field change history
 			ppic.POST("", ganttHandler.CreatePPICSchedule)                                              // Create schedule
+			ppic.POST("/import", ganttHandler.ImportPPICSchedules)                                      // Bulk import from CSV/XLSX
 			ppic.PUT("/:id", ganttHandler.UpdatePPICSchedule)                                           // Update schedule
 			ppic.DELETE("/:id", ganttHandler.DeletePPICSchedule)                                        // Delete schedule
+			ppic.POST("/:id/split", ganttHandler.SplitPPICSchedule)                                     // Split into lots
+			ppic.POST("/:id/merge", ganttHandler.MergePPICLots)                                         // Merge lots back
 			ppic.GET("/machine/:machine_id", ganttHandler.GetSchedulesByMachine)                        // Get by machine
+			ppic.GET("/conflicts", ganttHandler.GetMachineConflicts)                                    // Machine double-bookings
+			ppic.POST("/auto-schedule/preview", ganttHandler.PreviewAutoSchedule)                       // Propose machine windows
+			ppic.POST("/auto-schedule/commit", ganttHandler.CommitAutoSchedule)                         // Save proposed machine windows
 			ppic.POST("/:id/machines", ganttHandler.AddMachineAssignment)                               // Add machine
 			ppic.DELETE("/:id/machines/:assignment_id", ganttHandler.RemoveMachineAssignment)           // Remove machine
 			ppic.PUT("/:id/machines/:assignment_id/status", ganttHandler.UpdateMachineAssignmentStatus) // Update status
 		}
 
+		// Routing templates (reusable machine routings per part family)
+		routingTemplates := protected.Group("/routing-templates")
+		{
+			routingTemplates.GET("", routingTemplateHandler.GetAllTemplates)          // Get all templates
+			routingTemplates.GET("/:id", routingTemplateHandler.GetTemplate)          // Get single template
+			routingTemplates.POST("", routingTemplateHandler.CreateTemplate)          // Create template
+			routingTemplates.PUT("/:id", routingTemplateHandler.UpdateTemplate)       // Update template
+			routingTemplates.DELETE("/:id", routingTemplateHandler.DeleteTemplate)    // Delete template
+			routingTemplates.POST("/:id/apply", routingTemplateHandler.ApplyTemplate) // Preview machine assignments for a schedule
+		}
+
 		// PPIC Links routes (for Gantt chart dependencies/arrows)
 		ppicLinks := protected.Group("/ppic-links")
 		{
-			ppicLinks.GET("", ppicLinkHandler.GetAllPPICLinks)      // Get all links
-			ppicLinks.POST("", ppicLinkHandler.CreatePPICLink)      // Create link
-			ppicLinks.DELETE("/:id", ppicLinkHandler.DeletePPICLink) // Delete link
+			ppicLinks.GET("", ppicLinkHandler.GetAllPPICLinks)                  // Get all links
+			ppicLinks.GET("/integrity", ppicLinkHandler.CheckPPICLinkIntegrity) // Check link graph integrity
+			ppicLinks.POST("", ppicLinkHandler.CreatePPICLink)                  // Create link
+			ppicLinks.DELETE("/:id", ppicLinkHandler.DeletePPICLink)            // Delete link
+		}
+
+		// PPIC what-if scenarios (edit copies with ?scenario_id= on the ppic-schedules/ppic-links routes)
+		ppicScenarios := protected.Group("/ppic-scenarios")
+		{
+			ppicScenarios.GET("", ppicScenarioHandler.GetAllScenarios)              // Get all scenarios
+			ppicScenarios.GET("/:id", ppicScenarioHandler.GetScenario)              // Get single scenario
+			ppicScenarios.POST("", ppicScenarioHandler.CreateScenario)              // Copy live board into a scenario
+			ppicScenarios.DELETE("/:id", ppicScenarioHandler.DeleteScenario)        // Discard scenario
+			ppicScenarios.GET("/:id/compare", ppicScenarioHandler.CompareScenario)  // Compare with live
+			ppicScenarios.POST("/:id/promote", ppicScenarioHandler.PromoteScenario) // Make scenario live
+		}
+
+		// PPIC baselines (frozen plans for variance reporting)
+		ppicBaselines := protected.Group("/ppic-baselines")
+		{
+			ppicBaselines.GET("", ppicBaselineHandler.GetAllBaselines)          // Get all baselines
+			ppicBaselines.GET("/:id", ppicBaselineHandler.GetBaseline)          // Get baseline with captured dates
+			ppicBaselines.POST("", ppicBaselineHandler.CreateBaseline)          // Snapshot the live plan
+			ppicBaselines.DELETE("/:id", ppicBaselineHandler.DeleteBaseline)    // Delete baseline
+			ppicBaselines.GET("/:id/variance", ppicBaselineHandler.GetVariance) // Baseline vs current and actual
 		}
 
 		// Google Sheets routes
@@ -195,6 +308,20 @@ func SetupRoutes(
 			toolpatherFiles.DELETE("/:id", toolpatherFileHandler.DeleteFile)                         // Delete file
 		}
 
+		// Working calendar (working days/hours per plant or machine)
+		protected.GET("/calendar", calendarHandler.GetCalendar)
+
+		// Planning settings (changed under /admin/settings)
+		protected.GET("/settings/planning", settingHandler.GetPlanningSettings)
+
+		// Calendar feed token of the current user
+		calendarFeeds := protected.Group("/calendar-feeds")
+		{
+			calendarFeeds.GET("", calendarFeedHandler.GetFeedStatus)            // Token status
+			calendarFeeds.POST("/token", calendarFeedHandler.IssueFeedToken)    // Issue a new token (revokes the old one)
+			calendarFeeds.DELETE("/token", calendarFeedHandler.RevokeFeedToken) // Revoke token
+		}
+
 		// Admin routes
 		admin := protected.Group("/admin")
 		admin.Use(middleware.RequireRole("Admin"))
@@ -208,6 +335,23 @@ func SetupRoutes(
 			admin.POST("/machines", machineHandler.CreateMachine)
 			admin.PUT("/machines/:id", machineHandler.UpdateMachine)
 			admin.DELETE("/machines/:id", machineHandler.DeleteMachine)
+
+			// Working calendar management
+			admin.GET("/calendar/shifts", calendarHandler.GetShifts)
+			admin.POST("/calendar/shifts", calendarHandler.CreateShift)
+			admin.PUT("/calendar/shifts/:id", calendarHandler.UpdateShift)
+			admin.DELETE("/calendar/shifts/:id", calendarHandler.DeleteShift)
+			admin.GET("/calendar/exceptions", calendarHandler.GetExceptions)
+			admin.POST("/calendar/exceptions", calendarHandler.CreateException)
+			admin.DELETE("/calendar/exceptions/:id", calendarHandler.DeleteException)
+
+			// Planning settings
+			admin.PUT("/settings/planning", settingHandler.UpdatePlanningSettings)
+
+			// Andon reason codes
+			admin.POST("/andon/reason-codes", andonHandler.CreateReasonCode)
+			admin.PUT("/andon/reason-codes/:id", andonHandler.UpdateReasonCode)
+			admin.DELETE("/andon/reason-codes/:id", andonHandler.DeleteReasonCode)
 		}
 	}
 
@@ -0,0 +1,570 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+)
+
+type AndonService struct {
+	repo         *repository.AndonRepository
+	machineRepo  *repository.MachineRepository
+	jobOrderRepo *repository.JobOrderRepository
+	userRepo     *repository.UserRepository
+}
+
+func NewAndonService(repo *repository.AndonRepository, machineRepo *repository.MachineRepository, jobOrderRepo *repository.JobOrderRepository, userRepo *repository.UserRepository) *AndonService {
+	return &AndonService{repo: repo, machineRepo: machineRepo, jobOrderRepo: jobOrderRepo, userRepo: userRepo}
+}
+
+// ========== Reason Codes ==========
+
+// GetReasonCodes returns the reason code hierarchy, by default only the active codes
+func (s *AndonService) GetReasonCodes(includeInactive bool) ([]models.AndonReasonNode, error) {
+	codes, err := s.repo.GetReasonCodes(!includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reason codes: %w", err)
+	}
+	return models.BuildAndonReasonTree(codes), nil
+}
+
+// CreateReasonCode adds a reason code, at the top level or under a parent
+func (s *AndonService) CreateReasonCode(req *models.CreateAndonReasonCodeRequest) (*models.AndonReasonCode, error) {
+	code := &models.AndonReasonCode{
+		Code:        strings.ToUpper(strings.TrimSpace(req.Code)),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		SortOrder:   req.SortOrder,
+		Active:      true,
+	}
+	if req.ParentID != nil && *req.ParentID > 0 {
+		codes, err := s.reasonCodes()
+		if err != nil {
+			return nil, err
+		}
+		if err := models.CheckAndonReasonParent(codes, 0, *req.ParentID); err != nil {
+			return nil, err
+		}
+		code.ParentID = req.ParentID
+	}
+
+	if err := s.repo.CreateReasonCode(code); err != nil {
+		return nil, fmt.Errorf("failed to create reason code (code %s may already exist): %w", code.Code, err)
+	}
+	return code, nil
+}
+
+// UpdateReasonCode changes a reason code; omitted fields are kept. Codes are deactivated rather
+// than deleted once events use them
+func (s *AndonService) UpdateReasonCode(id int64, req *models.UpdateAndonReasonCodeRequest) (*models.AndonReasonCode, error) {
+	code, err := s.repo.GetReasonCodeByID(id)
+	if err != nil {
+		return nil, errors.New("reason code not found")
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		code.Name = name
+	}
+	if req.Description != nil {
+		code.Description = *req.Description
+	}
+	if req.SortOrder != nil {
+		code.SortOrder = *req.SortOrder
+	}
+	if req.Active != nil {
+		code.Active = *req.Active
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			code.ParentID = nil
+		} else {
+			codes, err := s.reasonCodes()
+			if err != nil {
+				return nil, err
+			}
+			if err := models.CheckAndonReasonParent(codes, id, *req.ParentID); err != nil {
+				return nil, err
+			}
+			code.ParentID = req.ParentID
+		}
+	}
+
+	if err := s.repo.UpdateReasonCode(code); err != nil {
+		return nil, fmt.Errorf("failed to update reason code: %w", err)
+	}
+	return code, nil
+}
+
+// DeleteReasonCode removes a reason code that has no sub-codes and was never recorded on an event
+func (s *AndonService) DeleteReasonCode(id int64) error {
+	if _, err := s.repo.GetReasonCodeByID(id); err != nil {
+		return errors.New("reason code not found")
+	}
+	inUse, err := s.repo.ReasonCodeInUse(id)
+	if err != nil {
+		return fmt.Errorf("failed to check reason code: %w", err)
+	}
+	if inUse {
+		return errors.New("reason code has sub-codes or recorded events; deactivate it instead")
+	}
+	if err := s.repo.DeleteReasonCode(id); err != nil {
+		return errors.New("reason code not found")
+	}
+	return nil
+}
+
+// ========== Events ==========
+
+// GetEvents returns andon events, newest first, overlapping a date range
+func (s *AndonService) GetEvents(filter models.AndonEventFilterRequest) ([]models.AndonEventView, error) {
+	if filter.Status != "" && !models.ValidateAndonEventStatus(filter.Status) {
+		return nil, errors.New("invalid status. Must be: open or closed")
+	}
+	var from, to time.Time
+	if filter.StartDate != "" {
+		t, err := time.Parse("2006-01-02", filter.StartDate)
+		if err != nil {
+			return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+		}
+		from = t
+	}
+	if filter.EndDate != "" {
+		t, err := time.Parse("2006-01-02", filter.EndDate)
+		if err != nil {
+			return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+
+	events, err := s.repo.GetEvents(filter, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get andon events: %w", err)
+	}
+	return s.views(events, time.Now())
+}
+
+// GetEvent returns a single andon event
+func (s *AndonService) GetEvent(id int64) (*models.AndonEventView, error) {
+	event, err := s.repo.GetEventByID(id)
+	if err != nil {
+		return nil, errors.New("andon event not found")
+	}
+	return s.view(event)
+}
+
+// GetBoard returns the open events, longest-running first
+func (s *AndonService) GetBoard() ([]models.AndonEventView, error) {
+	events, err := s.repo.GetOpenEvents(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open andon events: %w", err)
+	}
+	return s.views(events, time.Now())
+}
+
+// CreateEvent raises an andon on a machine. An open event sets the machine's status to down until
+// it is closed; an event given with end_time logs a stop that is already over
+func (s *AndonService) CreateEvent(req *models.CreateAndonEventRequest, reportedBy int64) (*models.AndonEventView, error) {
+	var err error
+	now := time.Now()
+	event := &models.AndonEvent{
+		MachineID:  req.MachineID,
+		StartTime:  now,
+		Notes:      req.Notes,
+		ReportedBy: reportedBy,
+	}
+	if req.StartTime != "" {
+		if event.StartTime, err = models.ParseDowntimeTime("start_time", req.StartTime); err != nil {
+			return nil, err
+		}
+	}
+	if req.EndTime != "" {
+		end, err := models.ParseDowntimeTime("end_time", req.EndTime)
+		if err != nil {
+			return nil, err
+		}
+		event.EndTime = &end
+	}
+	if err := checkAndonTimes(event, now); err != nil {
+		return nil, err
+	}
+	if req.ReasonCodeID != nil && *req.ReasonCodeID > 0 {
+		if err := s.checkReasonCode(*req.ReasonCodeID); err != nil {
+			return nil, err
+		}
+		event.ReasonCodeID = req.ReasonCodeID
+	}
+	if !event.IsOpen() && event.ReasonCodeID == nil {
+		return nil, errors.New("reason_code_id is required for a closed event")
+	}
+	if req.JobOrderID != nil && *req.JobOrderID > 0 {
+		if err := s.checkJobOrder(*req.JobOrderID); err != nil {
+			return nil, err
+		}
+		event.JobOrderID = req.JobOrderID
+	}
+
+	// Under the machine's lock a concurrent andon can't open between the overlap check and the
+	// insert, nor change the status the event restores on close
+	err = s.repo.WithLockedMachine(event.MachineID, func(repo *repository.AndonRepository, machine *models.Machine) error {
+		if machine == nil {
+			return fmt.Errorf("machine %d not found", event.MachineID)
+		}
+		if err := checkAndonOverlap(repo, event); err != nil {
+			return err
+		}
+
+		machineStatus := ""
+		if event.IsOpen() {
+			event.PreviousStatus = machine.Status
+			machineStatus = models.MachineStatusDown
+		}
+		if err := repo.CreateEvent(event, machineStatus); err != nil {
+			return fmt.Errorf("failed to create andon event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.view(event)
+}
+
+// UpdateEvent classifies or corrects an event: reason code, linked job order, start time and notes
+func (s *AndonService) UpdateEvent(id int64, req *models.UpdateAndonEventRequest) (*models.AndonEventView, error) {
+	var updated *models.AndonEvent
+	err := s.withLockedEvent(id, func(repo *repository.AndonRepository, event *models.AndonEvent) error {
+		if req.ReasonCodeID != nil {
+			if *req.ReasonCodeID == 0 {
+				if !event.IsOpen() {
+					return errors.New("a closed event must keep a reason code")
+				}
+				event.ReasonCodeID = nil
+			} else {
+				if err := s.checkReasonCode(*req.ReasonCodeID); err != nil {
+					return err
+				}
+				event.ReasonCodeID = req.ReasonCodeID
+			}
+		}
+		if req.JobOrderID != nil {
+			if *req.JobOrderID == 0 {
+				event.JobOrderID = nil
+			} else {
+				if err := s.checkJobOrder(*req.JobOrderID); err != nil {
+					return err
+				}
+				event.JobOrderID = req.JobOrderID
+			}
+		}
+		if req.Notes != nil {
+			event.Notes = *req.Notes
+		}
+		if req.StartTime != "" {
+			var err error
+			if event.StartTime, err = models.ParseDowntimeTime("start_time", req.StartTime); err != nil {
+				return err
+			}
+			if err := checkAndonTimes(event, time.Now()); err != nil {
+				return err
+			}
+			if err := checkAndonOverlap(repo, event); err != nil {
+				return err
+			}
+		}
+
+		if err := repo.UpdateEvent(event); err != nil {
+			return fmt.Errorf("failed to update andon event: %w", err)
+		}
+		updated = event
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.view(updated)
+}
+
+// CloseEvent ends an open event and puts the machine back to the status it had before the stop
+func (s *AndonService) CloseEvent(id int64, req *models.CloseAndonEventRequest, userID int64) (*models.AndonEventView, error) {
+	var closed *models.AndonEvent
+	err := s.withLockedEvent(id, func(repo *repository.AndonRepository, event *models.AndonEvent) error {
+		if !event.IsOpen() {
+			return models.ErrAndonEventClosed
+		}
+
+		now := time.Now()
+		end := now
+		if req.EndTime != "" {
+			var err error
+			if end, err = models.ParseDowntimeTime("end_time", req.EndTime); err != nil {
+				return err
+			}
+		}
+		event.EndTime = &end
+		if err := checkAndonTimes(event, now); err != nil {
+			return err
+		}
+		if req.ReasonCodeID != nil && *req.ReasonCodeID > 0 {
+			if err := s.checkReasonCode(*req.ReasonCodeID); err != nil {
+				return err
+			}
+			event.ReasonCodeID = req.ReasonCodeID
+		}
+		if event.ReasonCodeID == nil {
+			return errors.New("reason_code_id is required to close the event")
+		}
+		if err := checkAndonOverlap(repo, event); err != nil {
+			return err
+		}
+		if notes := strings.TrimSpace(req.Notes); notes != "" {
+			if event.Notes != "" {
+				event.Notes += "\n"
+			}
+			event.Notes += notes
+		}
+		closedBy := userID
+		event.ClosedBy = &closedBy
+
+		if err := repo.CloseEvent(event, restoredMachineStatus(event)); err != nil {
+			if errors.Is(err, models.ErrAndonEventClosed) {
+				return err
+			}
+			return fmt.Errorf("failed to close andon event: %w", err)
+		}
+		closed = event
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.view(closed)
+}
+
+// DeleteEvent removes an event raised by mistake. Deleting an open event brings the machine back up
+func (s *AndonService) DeleteEvent(id int64) error {
+	return s.withLockedEvent(id, func(repo *repository.AndonRepository, event *models.AndonEvent) error {
+		restore := ""
+		if event.IsOpen() {
+			restore = restoredMachineStatus(event)
+		}
+		if err := repo.DeleteEvent(event, restore); err != nil {
+			return fmt.Errorf("failed to delete andon event: %w", err)
+		}
+		return nil
+	})
+}
+
+// withLockedEvent runs fn under the row lock of the event's machine (see WithLockedMachine), with the
+// event read again under that lock. Changes to the events of one machine so run one at a time: a
+// close can't interleave with an update of the same event, and only one of two closes succeeds
+func (s *AndonService) withLockedEvent(id int64, fn func(repo *repository.AndonRepository, event *models.AndonEvent) error) error {
+	event, err := s.repo.GetEventByID(id)
+	if err != nil {
+		return errors.New("andon event not found")
+	}
+	return s.repo.WithLockedMachine(event.MachineID, func(repo *repository.AndonRepository, _ *models.Machine) error {
+		event, err := repo.GetEventByID(id)
+		if err != nil {
+			return errors.New("andon event not found")
+		}
+		return fn(repo, event)
+	})
+}
+
+// GetPareto ranks stop reasons by downtime between start_date and end_date (inclusive)
+func (s *AndonService) GetPareto(req models.AndonParetoRequest) (*models.AndonParetoReport, error) {
+	groupBy := req.GroupBy
+	if groupBy == "" {
+		groupBy = models.AndonParetoByReason
+	}
+	if !models.ValidateAndonParetoGroup(groupBy) {
+		return nil, errors.New("invalid group_by. Must be: reason or category")
+	}
+
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if req.EndDate != "" {
+		t, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+		}
+		endDate = t
+	}
+	startDate := endDate.AddDate(0, 0, -29)
+	if req.StartDate != "" {
+		t, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+		}
+		startDate = t
+	}
+	if endDate.Before(startDate) {
+		return nil, errors.New("end_date must not be before start_date")
+	}
+	from, to := startDate, endDate.AddDate(0, 0, 1)
+
+	events, err := s.repo.GetEvents(models.AndonEventFilterRequest{MachineID: req.MachineID}, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get andon events: %w", err)
+	}
+	codes, err := s.reasonCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.BuildAndonPareto(events, codes, groupBy, from, to, now)
+	report.MachineID = req.MachineID
+	return &report, nil
+}
+
+// reasonCodes returns every reason code, inactive ones included, by ID
+func (s *AndonService) reasonCodes() (map[int64]models.AndonReasonCode, error) {
+	codes, err := s.repo.GetReasonCodes(false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reason codes: %w", err)
+	}
+	byID := make(map[int64]models.AndonReasonCode, len(codes))
+	for _, code := range codes {
+		byID[code.ID] = code
+	}
+	return byID, nil
+}
+
+func (s *AndonService) checkReasonCode(id int64) error {
+	code, err := s.repo.GetReasonCodeByID(id)
+	if err != nil {
+		return fmt.Errorf("reason code %d not found", id)
+	}
+	if !code.Active {
+		return fmt.Errorf("reason code %s is inactive", code.Code)
+	}
+	return nil
+}
+
+func (s *AndonService) checkJobOrder(id int64) error {
+	jobOrder, err := s.jobOrderRepo.GetByID(id)
+	if err != nil || jobOrder == nil {
+		return fmt.Errorf("job order %d not found", id)
+	}
+	return nil
+}
+
+// checkAndonOverlap refuses an event overlapping another event of the same machine, so a stop isn't
+// counted twice. An open event runs up to now
+func checkAndonOverlap(repo *repository.AndonRepository, event *models.AndonEvent) error {
+	end := time.Time{}
+	if event.EndTime != nil {
+		end = *event.EndTime
+	}
+	others, err := repo.GetEvents(models.AndonEventFilterRequest{MachineID: event.MachineID}, event.StartTime, end)
+	if err != nil {
+		return fmt.Errorf("failed to check andon events: %w", err)
+	}
+	for _, other := range others {
+		if other.ID == event.ID {
+			continue
+		}
+		if other.IsOpen() {
+			return fmt.Errorf("machine already has open andon event #%d; close or update it instead", other.ID)
+		}
+		return fmt.Errorf("overlaps andon event #%d (%s - %s)", other.ID,
+			other.StartTime.Format("2006-01-02 15:04"), other.EndTime.Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// view adds the names the board shows to one event
+func (s *AndonService) view(event *models.AndonEvent) (*models.AndonEventView, error) {
+	views, err := s.views([]models.AndonEvent{*event}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &views[0], nil
+}
+
+// views adds machine, reason, job order and reporter names to events
+func (s *AndonService) views(events []models.AndonEvent, now time.Time) ([]models.AndonEventView, error) {
+	views := make([]models.AndonEventView, 0, len(events))
+	if len(events) == 0 {
+		return views, nil
+	}
+
+	machines, err := s.machineRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machines: %w", err)
+	}
+	machineNames := make(map[int64]string, len(machines))
+	for _, m := range machines {
+		machineNames[m.ID] = m.MachineName
+	}
+	codes, err := s.reasonCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	njos := make(map[int64]string)
+	users := make(map[int64]string)
+	for _, event := range events {
+		view := models.AndonEventView{
+			AndonEvent:      event,
+			MachineName:     machineNames[event.MachineID],
+			DurationMinutes: event.DurationMinutes(now),
+		}
+		if event.ReasonCodeID != nil {
+			if code, ok := codes[*event.ReasonCodeID]; ok {
+				view.ReasonCode = code.Code
+				view.ReasonPath = models.AndonReasonPath(codes, code.ID)
+			}
+		}
+		if event.JobOrderID != nil {
+			njo, ok := njos[*event.JobOrderID]
+			if !ok {
+				if jobOrder, err := s.jobOrderRepo.GetByID(*event.JobOrderID); err == nil && jobOrder != nil {
+					njo = jobOrder.NJO
+				}
+				njos[*event.JobOrderID] = njo
+			}
+			view.NJO = njo
+		}
+		if event.ReportedBy > 0 {
+			name, ok := users[event.ReportedBy]
+			if !ok {
+				if user, err := s.userRepo.FindByID(uint(event.ReportedBy)); err == nil {
+					name = user.Username
+				}
+				users[event.ReportedBy] = name
+			}
+			view.ReportedByName = name
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+// checkAndonTimes requires a start that isn't in the future and an end after it, also not in the future
+func checkAndonTimes(event *models.AndonEvent, now time.Time) error {
+	if event.StartTime.After(now) {
+		return errors.New("start_time can't be in the future")
+	}
+	if event.EndTime != nil {
+		if !event.EndTime.After(event.StartTime) {
+			return errors.New("end_time must be after start_time")
+		}
+		if event.EndTime.After(now) {
+			return errors.New("end_time can't be in the future")
+		}
+	}
+	return nil
+}
+
+// restoredMachineStatus is the status a machine goes back to when its andon event ends
+func restoredMachineStatus(event *models.AndonEvent) string {
+	if event.PreviousStatus == "" || event.PreviousStatus == models.MachineStatusDown {
+		return models.MachineStatusActive
+	}
+	return event.PreviousStatus
+}
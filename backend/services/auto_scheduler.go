@@ -0,0 +1,481 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"sort"
+	"time"
+)
+
+// maxFinishConstraintAttempts bounds how often a schedule is pushed later to meet a finish-side link
+const maxFinishConstraintAttempts = 10
+
+// AutoScheduleContext is the fixed state the auto-scheduler plans around
+type AutoScheduleContext struct {
+	StartFrom        time.Time
+	PlantCalendar    *models.WorkingCalendar                    // Used for link constraints and machines without their own calendar
+	MachineCalendars map[int64]*models.WorkingCalendar          // Per machine working time
+	Bookings         map[int64][]models.MachineAssignmentWindow // Existing machine bookings that must not be overlapped
+	Downtime         map[int64][]models.MachineDowntime         // Machine downtime, booked around like bookings
+	Links            []models.PPICLink
+	FixedSchedules   map[int64]models.PPICSchedule // Predecessors that are not part of the plan
+}
+
+// PlanAutoSchedule forward-schedules the given schedules onto their machines with finite capacity.
+// Schedules are taken in dependency order, ties broken by priority (Top Urgent first), start date and ID.
+// Each machine assignment is placed in routing sequence at the earliest free working time on its machine
+func PlanAutoSchedule(schedules []models.PPICSchedule, ctx AutoScheduleContext) *models.AutoSchedulePlan {
+	plan := &models.AutoSchedulePlan{
+		StartFrom: ctx.StartFrom,
+		Scheduled: []models.AutoScheduledTask{},
+		Skipped:   []models.AutoScheduleSkipped{},
+	}
+
+	plantCalendar := ctx.PlantCalendar
+	if plantCalendar == nil {
+		plantCalendar = models.AllDaysCalendar()
+	}
+	calendarFor := func(machineID int64) *models.WorkingCalendar {
+		if cal, ok := ctx.MachineCalendars[machineID]; ok && cal != nil {
+			return cal
+		}
+		return plantCalendar
+	}
+
+	// Copy bookings so placing operations doesn't modify the caller's map
+	bookings := make(map[int64][]models.MachineAssignmentWindow, len(ctx.Bookings))
+	for machineID, windows := range ctx.Bookings {
+		bookings[machineID] = append([]models.MachineAssignmentWindow{}, windows...)
+	}
+	for machineID, downtimes := range ctx.Downtime {
+		bookings[machineID] = append(bookings[machineID], models.DowntimeWindows(downtimes)...)
+	}
+	for machineID := range bookings {
+		sortBookings(bookings[machineID])
+	}
+
+	byID := make(map[int64]*models.PPICSchedule, len(schedules))
+	for i := range schedules {
+		byID[schedules[i].ID] = &schedules[i]
+	}
+
+	predecessors := make(map[int64][]models.PPICLink)
+	successors := make(map[int64][]int64)
+	inDegree := make(map[int64]int)
+	for _, link := range ctx.Links {
+		if byID[link.TargetScheduleID] == nil {
+			continue
+		}
+		predecessors[link.TargetScheduleID] = append(predecessors[link.TargetScheduleID], link)
+		if byID[link.SourceScheduleID] != nil {
+			successors[link.SourceScheduleID] = append(successors[link.SourceScheduleID], link.TargetScheduleID)
+			inDegree[link.TargetScheduleID]++
+		}
+	}
+
+	planned := make(map[int64]*models.AutoScheduledTask)
+	done := make(map[int64]bool)
+	skip := func(schedule *models.PPICSchedule, reason string) {
+		plan.Skipped = append(plan.Skipped, models.AutoScheduleSkipped{ScheduleID: schedule.ID, NJO: schedule.NJO, Reason: reason})
+	}
+
+	for len(done) < len(schedules) {
+		// Pick the highest priority schedule whose predecessors are all handled
+		var next *models.PPICSchedule
+		for i := range schedules {
+			candidate := &schedules[i]
+			if done[candidate.ID] || inDegree[candidate.ID] > 0 {
+				continue
+			}
+			if next == nil || scheduleRanksBefore(candidate, next) {
+				next = candidate
+			}
+		}
+
+		if next == nil {
+			// Everything left waits on a dependency loop
+			for i := range schedules {
+				if !done[schedules[i].ID] {
+					done[schedules[i].ID] = true
+					skip(&schedules[i], "dependency cycle between pending schedules")
+				}
+			}
+			break
+		}
+
+		done[next.ID] = true
+		for _, targetID := range successors[next.ID] {
+			inDegree[targetID]--
+		}
+
+		task, reason := placeSchedule(next, predecessors[next.ID], planned, byID, ctx.FixedSchedules, ctx.StartFrom, plantCalendar, calendarFor, bookings)
+		if task == nil {
+			skip(next, reason)
+			continue
+		}
+
+		planned[next.ID] = task
+		for _, op := range task.Operations {
+			bookings[op.MachineID] = append(bookings[op.MachineID], models.MachineAssignmentWindow{
+				AssignmentID:   op.AssignmentID,
+				ScheduleID:     next.ID,
+				NJO:            next.NJO,
+				PartName:       next.PartName,
+				Sequence:       op.Sequence,
+				ScheduledStart: op.ScheduledStart,
+				ScheduledEnd:   op.ScheduledEnd,
+			})
+			sortBookings(bookings[op.MachineID])
+
+			if plan.PlanFinish == nil || op.ScheduledEnd.After(*plan.PlanFinish) {
+				end := op.ScheduledEnd
+				plan.PlanFinish = &end
+			}
+		}
+		plan.Scheduled = append(plan.Scheduled, *task)
+	}
+
+	return plan
+}
+
+// placeSchedule places one schedule's machine assignments, or returns the reason it can't be placed
+func placeSchedule(
+	schedule *models.PPICSchedule,
+	links []models.PPICLink,
+	planned map[int64]*models.AutoScheduledTask,
+	inPlan map[int64]*models.PPICSchedule,
+	fixed map[int64]models.PPICSchedule,
+	startFrom time.Time,
+	plantCalendar *models.WorkingCalendar,
+	calendarFor func(machineID int64) *models.WorkingCalendar,
+	bookings map[int64][]models.MachineAssignmentWindow,
+) (*models.AutoScheduledTask, string) {
+	if len(schedule.MachineAssignments) == 0 {
+		return nil, "no machine assignments"
+	}
+	assignments := append([]models.MachineAssignment{}, schedule.MachineAssignments...)
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].Sequence < assignments[j].Sequence })
+	for _, ma := range assignments {
+		if ma.TargetHours <= 0 {
+			return nil, fmt.Sprintf("machine sequence %d has no target_hours", ma.Sequence)
+		}
+	}
+
+	// Link constraints from predecessors, planned in this run or already fixed on the board
+	earliest := startFrom
+	var finishConstraint time.Time
+	for _, link := range links {
+		var sourceStart, sourceFinish time.Time
+		if p, ok := planned[link.SourceScheduleID]; ok {
+			sourceStart, sourceFinish = p.ProposedStartDate, p.ProposedFinishDate
+		} else if source, ok := inPlan[link.SourceScheduleID]; ok {
+			return nil, fmt.Sprintf("predecessor NJO %s could not be scheduled", source.NJO)
+		} else if source, ok := fixed[link.SourceScheduleID]; ok {
+			sourceStart, sourceFinish = source.StartDate, source.FinishDate
+		} else {
+			continue
+		}
+
+		constraint, onStart := plantCalendar.LinkConstraintDate(link.LinkType, link.LagDays, sourceStart, sourceFinish)
+		if onStart {
+			if constraint.After(earliest) {
+				earliest = constraint
+			}
+		} else if constraint.After(finishConstraint) {
+			finishConstraint = constraint
+		}
+	}
+
+	for attempt := 0; attempt < maxFinishConstraintAttempts; attempt++ {
+		operations := make([]models.AutoScheduleOperation, 0, len(assignments))
+		cursor := earliest
+		for _, ma := range assignments {
+			start, end := findMachineSlot(calendarFor(ma.MachineID), bookings[ma.MachineID], cursor, ma.TargetHours)
+			operations = append(operations, models.AutoScheduleOperation{
+				AssignmentID:   ma.ID,
+				MachineID:      ma.MachineID,
+				MachineName:    ma.MachineName,
+				MachineCode:    ma.MachineCode,
+				Sequence:       ma.Sequence,
+				TargetHours:    ma.TargetHours,
+				ScheduledStart: start,
+				ScheduledEnd:   end,
+			})
+			cursor = end
+		}
+
+		startDate := truncateToDate(operations[0].ScheduledStart)
+		// An end exactly at midnight belongs to the previous day
+		finishDate := truncateToDate(operations[len(operations)-1].ScheduledEnd.Add(-time.Nanosecond))
+
+		if !finishConstraint.IsZero() && finishDate.Before(finishConstraint) {
+			// Finish-side link not met yet: push the whole schedule later by the shortfall
+			earliest = earliest.Add(finishConstraint.Sub(finishDate))
+			continue
+		}
+
+		return &models.AutoScheduledTask{
+			ScheduleID:         schedule.ID,
+			NJO:                schedule.NJO,
+			PartName:           schedule.PartName,
+			Priority:           schedule.Priority,
+			CurrentStartDate:   schedule.StartDate,
+			CurrentFinishDate:  schedule.FinishDate,
+			ProposedStartDate:  startDate,
+			ProposedFinishDate: finishDate,
+			Operations:         operations,
+			Version:            schedule.Version,
+		}, ""
+	}
+
+	return nil, "could not satisfy finish constraint of a dependency link"
+}
+
+// findMachineSlot returns the earliest working window of the given length on a machine,
+// starting no earlier than earliest and not overlapping any booking (sorted by start)
+func findMachineSlot(calendar *models.WorkingCalendar, bookings []models.MachineAssignmentWindow, earliest time.Time, hours float64) (time.Time, time.Time) {
+	start := earliest
+	// Each conflict moves the start past one booking, so len(bookings)+1 tries is enough
+	for i := 0; i <= len(bookings); i++ {
+		start = calendar.NextWorkingTime(start)
+		end := calendar.AddWorkingHours(start, hours)
+
+		conflict := false
+		for _, b := range bookings {
+			if models.WindowsOverlap(start, end, b.ScheduledStart, b.ScheduledEnd) {
+				start = b.ScheduledEnd
+				conflict = true
+				break
+			}
+		}
+		if !conflict {
+			return start, end
+		}
+	}
+
+	start = calendar.NextWorkingTime(start)
+	return start, calendar.AddWorkingHours(start, hours)
+}
+
+// scheduleRanksBefore orders schedules like GetWithFilters: priority, then start date, then ID
+func scheduleRanksBefore(a, b *models.PPICSchedule) bool {
+	if ra, rb := models.PriorityRank(a.Priority), models.PriorityRank(b.Priority); ra != rb {
+		return ra < rb
+	}
+	if !a.StartDate.Equal(b.StartDate) {
+		return a.StartDate.Before(b.StartDate)
+	}
+	return a.ID < b.ID
+}
+
+func sortBookings(windows []models.MachineAssignmentWindow) {
+	sort.Slice(windows, func(i, j int) bool { return windows[i].ScheduledStart.Before(windows[j].ScheduledStart) })
+}
+
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// PreviewAutoSchedule builds an auto-schedule plan for pending schedules without saving it
+func (s *GanttService) PreviewAutoSchedule(req *models.AutoScheduleRequest) (*models.AutoSchedulePlan, error) {
+	startFrom := time.Now().Truncate(time.Minute)
+	if req.StartFrom != "" {
+		parsed, err := parseAutoScheduleStart(req.StartFrom)
+		if err != nil {
+			return nil, err
+		}
+		startFrom = parsed
+	}
+
+	pending, err := s.ppicRepo.GetWithFilters(models.GanttFilterRequest{Status: models.ScheduleStatusPending})
+	if err != nil {
+		return nil, err
+	}
+
+	var skipped []models.AutoScheduleSkipped
+	if len(req.ScheduleIDs) > 0 {
+		pendingByID := make(map[int64]models.PPICSchedule, len(pending))
+		for _, schedule := range pending {
+			pendingByID[schedule.ID] = schedule
+		}
+		var selected []models.PPICSchedule
+		for _, id := range req.ScheduleIDs {
+			schedule, ok := pendingByID[id]
+			if !ok {
+				skipped = append(skipped, models.AutoScheduleSkipped{ScheduleID: id, Reason: "schedule not found or not pending"})
+				continue
+			}
+			selected = append(selected, schedule)
+			delete(pendingByID, id)
+		}
+		pending = selected
+	}
+
+	inPlan := make(map[int64]bool, len(pending))
+	for _, schedule := range pending {
+		inPlan[schedule.ID] = true
+	}
+
+	// Bookings of the schedules being planned are replaced by the plan
+	booked, err := s.ppicRepo.GetBookedWindows(startFrom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load machine bookings: %w", err)
+	}
+	bookings := make(map[int64][]models.MachineAssignmentWindow)
+	for machineID, windows := range booked {
+		for _, w := range windows {
+			if !inPlan[w.ScheduleID] {
+				bookings[machineID] = append(bookings[machineID], w)
+			}
+		}
+	}
+
+	links, err := s.ppicLinkRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links: %w", err)
+	}
+
+	fixed := make(map[int64]models.PPICSchedule)
+	for _, link := range links {
+		if !inPlan[link.TargetScheduleID] || inPlan[link.SourceScheduleID] {
+			continue
+		}
+		if _, ok := fixed[link.SourceScheduleID]; ok {
+			continue
+		}
+		source, err := s.ppicRepo.GetByID(link.SourceScheduleID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get predecessor schedule %d: %w", link.SourceScheduleID, err)
+		}
+		if source != nil {
+			fixed[source.ID] = *source
+		}
+	}
+
+	plantCalendar, err := s.calendarService.GetPlantCalendar()
+	if err != nil {
+		return nil, err
+	}
+	machineCalendars := make(map[int64]*models.WorkingCalendar)
+	for _, schedule := range pending {
+		for _, ma := range schedule.MachineAssignments {
+			if _, ok := machineCalendars[ma.MachineID]; ok {
+				continue
+			}
+			cal, err := s.calendarService.GetMachineCalendar(ma.MachineID)
+			if err != nil {
+				return nil, err
+			}
+			machineCalendars[ma.MachineID] = cal
+		}
+	}
+
+	downtime, err := s.downtimeService.DowntimesBetween(startFrom, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := PlanAutoSchedule(pending, AutoScheduleContext{
+		StartFrom:        startFrom,
+		PlantCalendar:    plantCalendar,
+		MachineCalendars: machineCalendars,
+		Bookings:         bookings,
+		Downtime:         downtime,
+		Links:            links,
+		FixedSchedules:   fixed,
+	})
+	plan.Skipped = append(skipped, plan.Skipped...)
+	if plan.Skipped == nil {
+		plan.Skipped = []models.AutoScheduleSkipped{}
+	}
+
+	return plan, nil
+}
+
+// CommitAutoSchedule rebuilds the plan for the same request and saves it, with the cascade to
+// dependents outside the plan, in one transaction. The planned schedules must still have the
+// versions the preview returned (req.Versions); they and their chains are locked while the plan
+// is checked against them and written
+func (s *GanttService) CommitAutoSchedule(req *models.AutoScheduleRequest, userID int64) (*models.AutoSchedulePlan, error) {
+	plan, err := s.PreviewAutoSchedule(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Scheduled) == 0 {
+		return nil, errors.New("nothing to schedule")
+	}
+	if err := models.CheckAutoScheduleVersions(plan.Scheduled, req.Versions); err != nil {
+		return nil, err
+	}
+	calendar, err := s.calendarService.GetPlantCalendar()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.ppicRepo.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, len(plan.Scheduled))
+	for i, task := range plan.Scheduled {
+		ids[i] = task.ScheduleID
+	}
+	locked := make(map[int64]bool)
+	if _, err := lockCascade(tx, ids, locked); err != nil {
+		return nil, err
+	}
+	// The plan was built from these versions; anything written since invalidates it
+	before := make(map[int64]*models.PPICSchedule, len(plan.Scheduled))
+	for _, task := range plan.Scheduled {
+		schedule, err := tx.GetByID(task.ScheduleID)
+		if err != nil {
+			return nil, err
+		}
+		if schedule == nil || schedule.Version != task.Version {
+			return nil, models.ErrStaleVersion
+		}
+		before[task.ScheduleID] = schedule
+	}
+
+	for i := range plan.Scheduled {
+		if err := tx.ApplyAutoScheduledTask(&plan.Scheduled[i]); err != nil {
+			return nil, fmt.Errorf("failed to save auto-schedule: %w", err)
+		}
+	}
+	// Dependents inside the plan are already placed after their predecessors; the cascade moves those outside it
+	cascades := make(map[int64]*scheduleCascade, len(plan.Scheduled))
+	for _, task := range plan.Scheduled {
+		cascade, err := s.cascadeInTx(tx, calendar, task.ScheduleID, before[task.ScheduleID], models.ChangeCauseAutoSchedule, locked)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cascade auto-schedule of %s: %w", task.NJO, err)
+		}
+		cascades[task.ScheduleID] = cascade
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	plan.Committed = true
+
+	for _, task := range plan.Scheduled {
+		if after, err := s.ppicRepo.GetByID(task.ScheduleID); err == nil {
+			recordScheduleChanges(s.historyRepo, before[task.ScheduleID], after, userID, models.ChangeCauseAutoSchedule)
+		}
+	}
+	for id, cascade := range cascades {
+		s.recordCascadeHistory(cascade, id, userID)
+	}
+
+	return plan, nil
+}
+
+func parseAutoScheduleStart(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid start_from format. Use RFC3339 or YYYY-MM-DD")
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+	"ganttpro-backend/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feedHistoryDays is how far back feeds keep past events
+const feedHistoryDays = 30
+
+var (
+	ErrInvalidFeedToken    = errors.New("invalid or revoked calendar feed token")
+	ErrFeedMachineNotFound = errors.New("machine not found")
+)
+
+type CalendarFeedService struct {
+	repo         *repository.CalendarFeedRepository
+	userRepo     *repository.UserRepository
+	machineRepo  *repository.MachineRepository
+	jobOrderRepo *repository.JobOrderRepository
+	pemPlanRepo  *repository.PEMOperationPlanRepository
+	ganttService *GanttService
+}
+
+func NewCalendarFeedService(
+	repo *repository.CalendarFeedRepository,
+	userRepo *repository.UserRepository,
+	machineRepo *repository.MachineRepository,
+	jobOrderRepo *repository.JobOrderRepository,
+	pemPlanRepo *repository.PEMOperationPlanRepository,
+	ganttService *GanttService,
+) *CalendarFeedService {
+	return &CalendarFeedService{
+		repo:         repo,
+		userRepo:     userRepo,
+		machineRepo:  machineRepo,
+		jobOrderRepo: jobOrderRepo,
+		pemPlanRepo:  pemPlanRepo,
+		ganttService: ganttService,
+	}
+}
+
+// GetFeedStatus tells whether the user has an active feed token
+func (s *CalendarFeedService) GetFeedStatus(userID int64) (*models.CalendarFeedStatus, error) {
+	token, err := s.repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return &models.CalendarFeedStatus{}, nil
+	}
+	return &models.CalendarFeedStatus{Active: true, CreatedAt: &token.CreatedAt, LastUsedAt: token.LastUsedAt}, nil
+}
+
+// IssueFeedToken creates a new feed token for the user; any previous token stops working
+func (s *CalendarFeedService) IssueFeedToken(userID int64) (*models.CalendarFeedTokenResponse, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	plain := hex.EncodeToString(secret)
+
+	token := &models.CalendarFeedToken{UserID: userID, TokenHash: hashFeedToken(plain)}
+	if err := s.repo.ReplaceToken(token); err != nil {
+		return nil, err
+	}
+
+	return &models.CalendarFeedTokenResponse{
+		Token:           plain,
+		UserFeedPath:    "/api/v1/feeds/me?token=" + plain,
+		MachineFeedPath: "/api/v1/feeds/machines/{machine_id}?token=" + plain,
+		CreatedAt:       token.CreatedAt,
+	}, nil
+}
+
+// RevokeFeedToken disables the user's feeds until a new token is issued
+func (s *CalendarFeedService) RevokeFeedToken(userID int64) error {
+	return s.repo.DeleteByUser(userID)
+}
+
+// Authenticate returns the active user owning a feed token
+func (s *CalendarFeedService) Authenticate(plain string) (*models.User, error) {
+	if plain == "" {
+		return nil, ErrInvalidFeedToken
+	}
+	token, err := s.repo.GetByTokenHash(hashFeedToken(plain))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidFeedToken
+	}
+	user, err := s.userRepo.FindByID(uint(token.UserID))
+	if err != nil || !user.IsActive {
+		return nil, ErrInvalidFeedToken
+	}
+
+	if err := s.repo.TouchLastUsed(token.ID, time.Now()); err != nil {
+		fmt.Printf("Warning: failed to update feed token usage: %v\n", err)
+	}
+	return user, nil
+}
+
+// MachineFeed renders the scheduled windows of a machine as an iCalendar feed
+func (s *CalendarFeedService) MachineFeed(machineID int64) ([]byte, error) {
+	machine, err := s.machineRepo.GetByID(machineID)
+	if err != nil {
+		return nil, err
+	}
+	if machine == nil {
+		return nil, ErrFeedMachineNotFound
+	}
+
+	schedules, err := s.ganttService.GetSchedulesByMachine(machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	events := MachineFeedEvents(machine, schedules, now.AddDate(0, 0, -feedHistoryDays))
+	return utils.WriteICalendar(fmt.Sprintf("%s (%s)", machine.MachineName, machine.MachineCode), events, now), nil
+}
+
+// UserFeed renders a user's pending PEM approvals and assigned job orders as an iCalendar feed
+func (s *CalendarFeedService) UserFeed(user *models.User) ([]byte, error) {
+	userID := int64(user.ID)
+	plans, err := s.pemPlanRepo.GetPendingApprovalsByApprover(userID)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := s.jobOrderRepo.GetByOperatorID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	events := UserFeedEvents(userID, plans, jobs, now.AddDate(0, 0, -feedHistoryDays))
+	return utils.WriteICalendar("GanttPro - "+user.Username, events, now), nil
+}
+
+// MachineFeedEvents turns the scheduled windows on a machine into timed events.
+// Windows without both ends, or that ended before since, are left out
+func MachineFeedEvents(machine *models.Machine, schedules []models.PPICSchedule, since time.Time) []utils.ICalEvent {
+	var events []utils.ICalEvent
+	for _, schedule := range schedules {
+		for _, ma := range schedule.MachineAssignments {
+			if ma.MachineID != machine.ID || ma.ScheduledStart == nil || ma.ScheduledEnd == nil || ma.ScheduledEnd.Before(since) {
+				continue
+			}
+
+			description := []string{
+				"NJO: " + schedule.NJO,
+				"Part: " + schedule.PartName,
+				"Priority: " + schedule.Priority,
+				fmt.Sprintf("Sequence: %d", ma.Sequence),
+				fmt.Sprintf("Target hours: %s", formatFeedHours(ma.TargetHours)),
+				"Status: " + ma.Status,
+			}
+			if schedule.PPICNotes != "" {
+				description = append(description, "Notes: "+schedule.PPICNotes)
+			}
+
+			events = append(events, utils.ICalEvent{
+				UID:         fmt.Sprintf("machine-assignment-%d@ganttpro", ma.ID),
+				Summary:     fmt.Sprintf("%s - %s (seq %d)", schedule.NJO, schedule.PartName, ma.Sequence),
+				Description: strings.Join(description, "\n"),
+				Location:    machine.MachineName,
+				Start:       *ma.ScheduledStart,
+				End:         *ma.ScheduledEnd,
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
+}
+
+// UserFeedEvents builds all-day events for a user: one per PEM approval waiting on them (on the
+// linked schedule's start date, since the plan must be approved before production starts, or the
+// day it was assigned) and one per open job order on its deadline. Pending approvals are always
+// listed; job orders due before since are left out
+func UserFeedEvents(userID int64, pendingPlans []models.PEMOperationPlan, jobs []models.JobOrder, since time.Time) []utils.ICalEvent {
+	var events []utils.ICalEvent
+	sinceDay := feedDay(since)
+
+	for _, plan := range pendingPlans {
+		if plan.Status != models.PEMStatusPendingApproval {
+			continue
+		}
+		for _, approval := range plan.Approvals {
+			if approval.ApproverID == nil || *approval.ApproverID != userID || approval.Status != models.ApprovalStatusPending {
+				continue
+			}
+
+			day := feedDay(approval.CreatedAt)
+			description := []string{"Form: " + plan.FormNumber, "Part: " + plan.PartName, "Role: " + approval.ApproverRole}
+			if plan.PPICSchedule != nil && !plan.PPICSchedule.StartDate.IsZero() {
+				day = feedDay(plan.PPICSchedule.StartDate)
+				description = append(description, "NJO: "+plan.PPICSchedule.NJO, "Production start: "+day.Format("2006-01-02"))
+			}
+
+			events = append(events, utils.ICalEvent{
+				UID:         fmt.Sprintf("pem-approval-%d@ganttpro", approval.ID),
+				Summary:     fmt.Sprintf("Approve %s (%s) - %s", plan.FormNumber, approval.ApproverRole, plan.PartName),
+				Description: strings.Join(description, "\n"),
+				Start:       day,
+				End:         day.AddDate(0, 0, 1),
+				AllDay:      true,
+			})
+		}
+	}
+
+	for _, job := range jobs {
+		if job.Status == models.ScheduleStatusCompleted || job.CompletedAt != nil {
+			continue
+		}
+		deadline, ok := parseFeedDeadline(job.Deadline)
+		if !ok || deadline.Before(sinceDay) {
+			continue
+		}
+
+		description := []string{"NJO: " + job.NJO, "Project: " + job.Project, "Item: " + job.Item, "Status: " + job.Status}
+		if job.Note != "" {
+			description = append(description, "Note: "+job.Note)
+		}
+		events = append(events, utils.ICalEvent{
+			UID:         fmt.Sprintf("job-order-%d@ganttpro", job.ID),
+			Summary:     fmt.Sprintf("Job order %s - %s", job.NJO, job.Item),
+			Description: strings.Join(description, "\n"),
+			Location:    job.MachineName,
+			Start:       deadline,
+			End:         deadline.AddDate(0, 0, 1),
+			AllDay:      true,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
+}
+
+func hashFeedToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func feedDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseFeedDeadline reads a job order deadline; it is free text, so unknown formats are skipped
+func parseFeedDeadline(deadline string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05", "02/01/2006"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(deadline)); err == nil {
+			return feedDay(t), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func formatFeedHours(hours float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", hours), "0"), ".")
+}
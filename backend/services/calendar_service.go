@@ -0,0 +1,229 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+	"time"
+)
+
+type CalendarService struct {
+	repo *repository.CalendarRepository
+}
+
+func NewCalendarService(repo *repository.CalendarRepository) *CalendarService {
+	return &CalendarService{repo: repo}
+}
+
+// ========== Shifts ==========
+
+// GetShifts returns all configured shifts, plant-wide and per machine
+func (s *CalendarService) GetShifts() ([]models.PlantShift, error) {
+	return s.repo.GetAllShifts()
+}
+
+// CreateShift validates and stores a shift pattern entry
+func (s *CalendarService) CreateShift(req *models.CreatePlantShiftRequest) (*models.PlantShift, error) {
+	if _, err := models.ParseShiftClock(req.StartTime); err != nil {
+		return nil, err
+	}
+	if _, err := models.ParseShiftClock(req.EndTime); err != nil {
+		return nil, err
+	}
+	if req.StartTime == req.EndTime {
+		return nil, errors.New("shift start and end time must be different")
+	}
+
+	shift := &models.PlantShift{
+		MachineID: req.MachineID,
+		Name:      req.Name,
+		DayOfWeek: *req.DayOfWeek,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+	if err := s.repo.CreateShift(shift); err != nil {
+		return nil, fmt.Errorf("failed to create shift: %w", err)
+	}
+	return shift, nil
+}
+
+// UpdateShift updates an existing shift pattern entry
+func (s *CalendarService) UpdateShift(id int64, req *models.UpdatePlantShiftRequest) (*models.PlantShift, error) {
+	shift, err := s.repo.GetShiftByID(id)
+	if err != nil {
+		return nil, errors.New("shift not found")
+	}
+
+	if req.Name != "" {
+		shift.Name = req.Name
+	}
+	if req.DayOfWeek != nil {
+		shift.DayOfWeek = *req.DayOfWeek
+	}
+	if req.StartTime != "" {
+		if _, err := models.ParseShiftClock(req.StartTime); err != nil {
+			return nil, err
+		}
+		shift.StartTime = req.StartTime
+	}
+	if req.EndTime != "" {
+		if _, err := models.ParseShiftClock(req.EndTime); err != nil {
+			return nil, err
+		}
+		shift.EndTime = req.EndTime
+	}
+	if shift.StartTime == shift.EndTime {
+		return nil, errors.New("shift start and end time must be different")
+	}
+
+	if err := s.repo.UpdateShift(shift); err != nil {
+		return nil, fmt.Errorf("failed to update shift: %w", err)
+	}
+	return shift, nil
+}
+
+// DeleteShift removes a shift pattern entry
+func (s *CalendarService) DeleteShift(id int64) error {
+	if err := s.repo.DeleteShift(id); err != nil {
+		return errors.New("shift not found")
+	}
+	return nil
+}
+
+// ========== Exceptions ==========
+
+// GetExceptions returns holidays and special working days, optionally within a date range
+func (s *CalendarService) GetExceptions(startDate, endDate string) ([]models.CalendarException, error) {
+	var start, end *time.Time
+	if startDate != "" {
+		t, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+		}
+		start = &t
+	}
+	if endDate != "" {
+		t, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+		}
+		end = &t
+	}
+	return s.repo.GetExceptions(start, end, nil)
+}
+
+// CreateException validates and stores a holiday or special working day
+func (s *CalendarService) CreateException(req *models.CreateCalendarExceptionRequest) (*models.CalendarException, error) {
+	if !models.ValidateCalendarExceptionType(req.Type) {
+		return nil, errors.New("invalid type. Must be: holiday or working_day")
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, errors.New("invalid date format. Use YYYY-MM-DD")
+	}
+
+	exception := &models.CalendarException{
+		MachineID: req.MachineID,
+		Date:      date,
+		Type:      req.Type,
+		Name:      req.Name,
+	}
+
+	if req.Type == models.CalendarExceptionWorkingDay {
+		if req.StartTime == "" || req.EndTime == "" {
+			return nil, errors.New("start_time and end_time are required for a working_day")
+		}
+		if _, err := models.ParseShiftClock(req.StartTime); err != nil {
+			return nil, err
+		}
+		if _, err := models.ParseShiftClock(req.EndTime); err != nil {
+			return nil, err
+		}
+		exception.StartTime = req.StartTime
+		exception.EndTime = req.EndTime
+	}
+
+	if err := s.repo.CreateException(exception); err != nil {
+		return nil, fmt.Errorf("failed to create calendar exception: %w", err)
+	}
+	return exception, nil
+}
+
+// DeleteException removes a holiday or special working day
+func (s *CalendarService) DeleteException(id int64) error {
+	if err := s.repo.DeleteException(id); err != nil {
+		return errors.New("calendar exception not found")
+	}
+	return nil
+}
+
+// ========== Working Calendar ==========
+
+// GetPlantCalendar builds the plant-wide working calendar
+func (s *CalendarService) GetPlantCalendar() (*models.WorkingCalendar, error) {
+	shifts, err := s.repo.GetShifts(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shifts: %w", err)
+	}
+	exceptions, err := s.repo.GetExceptions(nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar exceptions: %w", err)
+	}
+	return models.NewWorkingCalendar(shifts, exceptions), nil
+}
+
+// GetMachineCalendar builds a machine's working calendar. A machine with its own shifts uses
+// them instead of the plant shifts; plant and machine exceptions both apply
+func (s *CalendarService) GetMachineCalendar(machineID int64) (*models.WorkingCalendar, error) {
+	shifts, err := s.repo.GetShifts(&machineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shifts: %w", err)
+	}
+	if len(shifts) == 0 {
+		shifts, err = s.repo.GetShifts(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load shifts: %w", err)
+		}
+	}
+	exceptions, err := s.repo.GetExceptions(nil, nil, &machineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar exceptions: %w", err)
+	}
+	return models.NewWorkingCalendar(shifts, exceptions), nil
+}
+
+// GetCalendarDays lists each day in the range with its working hours
+func (s *CalendarService) GetCalendarDays(filter models.CalendarFilterRequest) ([]models.CalendarDay, error) {
+	start, err := time.Parse("2006-01-02", filter.StartDate)
+	if err != nil {
+		return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+	}
+	end, err := time.Parse("2006-01-02", filter.EndDate)
+	if err != nil {
+		return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end_date must be after start_date")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, errors.New("date range cannot exceed one year")
+	}
+
+	var calendar *models.WorkingCalendar
+	if filter.MachineID > 0 {
+		calendar, err = s.GetMachineCalendar(filter.MachineID)
+	} else {
+		calendar, err = s.GetPlantCalendar()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	days := []models.CalendarDay{}
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, calendar.Day(d))
+	}
+	return days, nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"sort"
+	"time"
+)
+
+// ComputeCriticalPath runs a forward and backward pass over the schedules and the links between them.
+// Tasks without predecessors keep their scheduled start; the project finish is the latest early finish.
+// Durations, lags and float are counted in working days of the calendar, as links are validated and
+// cascaded. Links to schedules outside the given set are ignored
+func ComputeCriticalPath(calendar *models.WorkingCalendar, schedules []models.PPICSchedule, links []models.PPICLink) (*models.CriticalPathResponse, error) {
+	response := &models.CriticalPathResponse{
+		Tasks:         []models.CriticalPathTask{},
+		CriticalChain: []models.CriticalPathTask{},
+	}
+	if len(schedules) == 0 {
+		return response, nil
+	}
+
+	byID := make(map[int64]*models.PPICSchedule, len(schedules))
+	for i := range schedules {
+		byID[schedules[i].ID] = &schedules[i]
+	}
+
+	predecessors := make(map[int64][]models.PPICLink)
+	successors := make(map[int64][]models.PPICLink)
+	inDegree := make(map[int64]int)
+	for _, link := range links {
+		if byID[link.SourceScheduleID] == nil || byID[link.TargetScheduleID] == nil {
+			continue
+		}
+		predecessors[link.TargetScheduleID] = append(predecessors[link.TargetScheduleID], link)
+		successors[link.SourceScheduleID] = append(successors[link.SourceScheduleID], link)
+		inDegree[link.TargetScheduleID]++
+	}
+
+	// Topological order (Kahn), keeping the input order for ties
+	var queue, order []int64
+	for _, schedule := range schedules {
+		if inDegree[schedule.ID] == 0 {
+			queue = append(queue, schedule.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+		for _, link := range successors[id] {
+			inDegree[link.TargetScheduleID]--
+			if inDegree[link.TargetScheduleID] == 0 {
+				queue = append(queue, link.TargetScheduleID)
+			}
+		}
+	}
+	if len(order) != len(schedules) {
+		return nil, errors.New("cannot compute critical path: dependency links contain a cycle")
+	}
+
+	// Working days a task spans, counting its first day
+	workingDays := make(map[int64]int, len(schedules))
+	for id, schedule := range byID {
+		workingDays[id] = calendar.WorkingDaysBetween(schedule.StartDate, schedule.FinishDate)
+	}
+	finishFrom := func(id int64, start time.Time) time.Time {
+		return calendar.AddWorkingDays(start, workingDays[id]-1)
+	}
+	startFrom := func(id int64, finish time.Time) time.Time {
+		return calendar.AddWorkingDays(finish, -(workingDays[id] - 1))
+	}
+
+	// Forward pass
+	earlyStart := make(map[int64]time.Time)
+	earlyFinish := make(map[int64]time.Time)
+	var projectStart, projectFinish time.Time
+	for _, id := range order {
+		es := byID[id].StartDate
+		for i, link := range predecessors[id] {
+			constraint, onStart := calendar.LinkConstraintDate(link.LinkType, link.LagDays,
+				earlyStart[link.SourceScheduleID], earlyFinish[link.SourceScheduleID])
+			constraint = calendar.NextWorkingDay(constraint)
+			if !onStart {
+				constraint = startFrom(id, constraint)
+			}
+			if i == 0 || constraint.After(es) {
+				es = constraint
+			}
+		}
+		earlyStart[id] = es
+		earlyFinish[id] = finishFrom(id, es)
+
+		if projectStart.IsZero() || es.Before(projectStart) {
+			projectStart = es
+		}
+		if earlyFinish[id].After(projectFinish) {
+			projectFinish = earlyFinish[id]
+		}
+	}
+
+	// Backward pass
+	lateStart := make(map[int64]time.Time)
+	lateFinish := make(map[int64]time.Time)
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		lf := projectFinish
+		for _, link := range successors[id] {
+			candidate := latestSourceFinish(calendar, link, workingDays[id], lateStart[link.TargetScheduleID], lateFinish[link.TargetScheduleID])
+			if candidate.Before(lf) {
+				lf = candidate
+			}
+		}
+		lateFinish[id] = lf
+		lateStart[id] = startFrom(id, lf)
+	}
+
+	response.ProjectStart = projectStart
+	response.ProjectFinish = projectFinish
+	for _, id := range order {
+		schedule := byID[id]
+		floatDays := calendar.WorkingDaysOffset(earlyStart[id], lateStart[id])
+		task := models.CriticalPathTask{
+			ScheduleID:      id,
+			NJO:             schedule.NJO,
+			PartName:        schedule.PartName,
+			ScheduledStart:  schedule.StartDate,
+			ScheduledFinish: schedule.FinishDate,
+			EarlyStart:      earlyStart[id],
+			EarlyFinish:     earlyFinish[id],
+			LateStart:       lateStart[id],
+			LateFinish:      lateFinish[id],
+			TotalFloatDays:  floatDays,
+			IsCritical:      floatDays <= 0,
+		}
+		response.Tasks = append(response.Tasks, task)
+		if task.IsCritical {
+			response.CriticalChain = append(response.CriticalChain, task)
+		}
+	}
+
+	sort.SliceStable(response.CriticalChain, func(i, j int) bool {
+		return response.CriticalChain[i].EarlyStart.Before(response.CriticalChain[j].EarlyStart)
+	})
+
+	return response, nil
+}
+
+// latestSourceFinish returns the latest finish of a link's source that still lets the target
+// keep its late dates. It is the inverse of WorkingCalendar.LinkConstraintDate
+func latestSourceFinish(calendar *models.WorkingCalendar, link models.PPICLink, sourceWorkingDays int, targetLateStart, targetLateFinish time.Time) time.Time {
+	switch link.LinkType {
+	case models.LinkTypeStartToStart:
+		return calendar.AddWorkingDays(calendar.AddWorkingDays(targetLateStart, -link.LagDays), sourceWorkingDays-1)
+	case models.LinkTypeFinishToFinish:
+		return calendar.AddWorkingDays(targetLateFinish, -link.LagDays)
+	case models.LinkTypeStartToFinish:
+		return calendar.AddWorkingDays(calendar.AddWorkingDays(targetLateFinish, -link.LagDays), sourceWorkingDays-1)
+	default:
+		return calendar.AddWorkingDays(targetLateStart, -1-link.LagDays)
+	}
+}
+
+// GetCriticalPath computes the critical path over the schedules matching the Gantt filter
+func (s *GanttService) GetCriticalPath(filter models.GanttFilterRequest) (*models.CriticalPathResponse, error) {
+	if _, err := ganttDateWindow(&filter); err != nil {
+		return nil, err
+	}
+	schedules, err := s.ppicRepo.GetWithFilters(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.ppicLinkRepo.GetTouching(scheduleIDs(schedules))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links: %w", err)
+	}
+
+	calendar, err := s.calendarService.GetPlantCalendar()
+	if err != nil {
+		return nil, err
+	}
+	return ComputeCriticalPath(calendar, schedules, links)
+}
+
+// applyCriticalPath marks Gantt tasks with their float and critical flag
+func (s *GanttService) applyCriticalPath(sections []models.GanttSection, result *models.CriticalPathResponse) {
+	byTaskID := make(map[string]models.CriticalPathTask, len(result.Tasks))
+	for _, task := range result.Tasks {
+		byTaskID[fmt.Sprintf("task-%d", task.ScheduleID)] = task
+	}
+
+	for i := range sections {
+		for j := range sections[i].Tasks {
+			cp, ok := byTaskID[sections[i].Tasks[j].TaskID]
+			if !ok {
+				continue
+			}
+			floatDays := cp.TotalFloatDays
+			sections[i].Tasks[j].IsCritical = cp.IsCritical
+			sections[i].Tasks[j].FloatDays = &floatDays
+		}
+	}
+}
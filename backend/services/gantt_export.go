@@ -0,0 +1,367 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportGanttChart renders the Gantt chart for the filter (same filters and group_by sections
+// as the JSON response) as a CSV, XLSX or PDF file
+func (s *GanttService) ExportGanttChart(filter models.GanttFilterRequest) (*models.GanttExport, error) {
+	if !models.IsGanttExportFormat(filter.Format) {
+		return nil, errors.New("invalid format. Must be: json, csv, xlsx, or pdf")
+	}
+
+	chart, err := s.GetGanttChartData(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	export := &models.GanttExport{Filename: fmt.Sprintf("gantt-chart-%s.%s", now.Format("20060102-1504"), filter.Format)}
+	switch filter.Format {
+	case models.GanttFormatCSV:
+		export.ContentType = "text/csv; charset=utf-8"
+		export.Data, err = RenderGanttCSV(chart)
+	case models.GanttFormatXLSX:
+		export.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		export.Data, err = RenderGanttXLSX(chart)
+	case models.GanttFormatPDF:
+		export.ContentType = "application/pdf"
+		export.Data, err = RenderGanttPDF(chart, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// ganttExportTable lays out one row per task (per section) with numbered machine columns.
+// Column names match the schedule import, so a CSV export can be edited and imported again
+func ganttExportTable(chart *models.GanttChartResponse) [][]utils.XLSXCell {
+	maxSequence, hasFloat, hasBaseline := 0, false, false
+	for _, section := range chart.Sections {
+		for _, task := range section.Tasks {
+			for _, m := range task.Machines {
+				if m.Sequence > maxSequence {
+					maxSequence = m.Sequence
+				}
+			}
+			hasFloat = hasFloat || task.FloatDays != nil
+			hasBaseline = hasBaseline || task.BaselineStart != nil
+		}
+	}
+
+	header := []string{"Section", "NJO", "Part Name", "Priority", "Priority Alpha", "Material Status", "Status", "Progress", "Start Date", "Finish Date", "PPIC Notes"}
+	if hasFloat {
+		header = append(header, "Critical", "Float Days")
+	}
+	if hasBaseline {
+		header = append(header, "Baseline Start", "Baseline Finish", "Slip Days")
+	}
+	for seq := 1; seq <= maxSequence; seq++ {
+		n := strconv.Itoa(seq)
+		header = append(header, "Machine "+n, "Hours "+n, "Machine "+n+" Start", "Machine "+n+" End", "Machine "+n+" Status")
+	}
+
+	var rows [][]utils.XLSXCell
+	headerRow := make([]utils.XLSXCell, len(header))
+	for i, name := range header {
+		headerRow[i] = utils.XLSXCell{Value: name, Bold: true, Fill: "#E9ECEF"}
+	}
+	rows = append(rows, headerRow)
+
+	for _, section := range chart.Sections {
+		for _, task := range section.Tasks {
+			row := []utils.XLSXCell{
+				{Value: section.SectionName},
+				{Value: task.NJO},
+				{Value: task.PartName},
+				{Value: task.Priority, Fill: task.Color},
+				{Value: task.PriorityAlpha},
+				{Value: task.MaterialStatus},
+				{Value: task.Status},
+				{Value: task.Progress},
+				{Value: task.Start},
+				{Value: task.End},
+				{Value: task.PPICNotes},
+			}
+			if hasFloat {
+				critical := "No"
+				if task.IsCritical {
+					critical = "Yes"
+				}
+				row = append(row, utils.XLSXCell{Value: critical}, exportIntCell(task.FloatDays))
+			}
+			if hasBaseline {
+				row = append(row, exportTimeCell(task.BaselineStart), exportTimeCell(task.BaselineEnd), exportIntCell(task.SlipDays))
+			}
+
+			bySequence := make(map[int]models.GanttMachineInfo, len(task.Machines))
+			for _, m := range task.Machines {
+				bySequence[m.Sequence] = m
+			}
+			for seq := 1; seq <= maxSequence; seq++ {
+				m, ok := bySequence[seq]
+				if !ok {
+					row = append(row, utils.XLSXCell{}, utils.XLSXCell{}, utils.XLSXCell{}, utils.XLSXCell{}, utils.XLSXCell{})
+					continue
+				}
+				row = append(row,
+					utils.XLSXCell{Value: m.MachineCode},
+					utils.XLSXCell{Value: m.DurationHours},
+					exportTimeCell(m.ScheduledStart),
+					exportTimeCell(m.ScheduledEnd),
+					utils.XLSXCell{Value: m.Status},
+				)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+func exportTimeCell(t *time.Time) utils.XLSXCell {
+	if t == nil {
+		return utils.XLSXCell{}
+	}
+	return utils.XLSXCell{Value: *t}
+}
+
+func exportIntCell(v *int) utils.XLSXCell {
+	if v == nil {
+		return utils.XLSXCell{}
+	}
+	return utils.XLSXCell{Value: *v}
+}
+
+// RenderGanttCSV writes the export table as CSV. Dates are YYYY-MM-DD, times YYYY-MM-DD HH:MM.
+// Text a spreadsheet would run as a formula is escaped (see escapeCSVFormula)
+func RenderGanttCSV(chart *models.GanttChartResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	for _, row := range ganttExportTable(chart) {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			switch v := cell.Value.(type) {
+			case nil:
+			case string:
+				record[i] = escapeCSVFormula(v)
+			case int:
+				record[i] = strconv.Itoa(v)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case time.Time:
+				record[i] = formatExportTime(v)
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// escapeCSVFormula prefixes text starting with =, +, - or @ with a quote, so a spreadsheet opening
+// the CSV shows it as text instead of running it as a formula. The import strips the quote again
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// RenderGanttXLSX writes the export table as a formatted worksheet: bold frozen header,
+// priority cells in the priority color and real Excel dates
+func RenderGanttXLSX(chart *models.GanttChartResponse) ([]byte, error) {
+	rows := ganttExportTable(chart)
+	widths := make([]float64, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			width := 12.0
+			switch v := cell.Value.(type) {
+			case string:
+				width = math.Min(float64(len(v))+2, 50)
+			case time.Time:
+				width = float64(len(formatExportTime(v))) + 2
+			}
+			widths[i] = math.Max(widths[i], width)
+		}
+	}
+
+	return utils.WriteXLSX(utils.XLSXSheet{
+		Name:         "Gantt Chart",
+		ColumnWidths: widths,
+		FreezeHeader: true,
+		Rows:         rows,
+	})
+}
+
+func formatExportTime(t time.Time) string {
+	if t.Hour() == 0 && t.Minute() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// PDF timeline layout, in points
+const (
+	pdfMargin      = 30.0
+	pdfLabelWidth  = 220.0
+	pdfRowHeight   = 14.0
+	pdfHeaderTop   = 62.0
+	pdfTimelineTop = 80.0
+	pdfFooterSpace = 30.0
+)
+
+// RenderGanttPDF draws a printable timeline on A4 landscape pages: one bar per task in its
+// priority color with a progress strip, section bands, date ticks, a today marker and a legend
+func RenderGanttPDF(chart *models.GanttChartResponse, generatedAt time.Time) ([]byte, error) {
+	doc := utils.NewPDFDocument(utils.PDFA4LandscapeWidth, utils.PDFA4LandscapeHeight)
+	width, height := utils.PDFA4LandscapeWidth, utils.PDFA4LandscapeHeight
+
+	rangeStart, rangeEnd, ok := ganttExportRange(chart)
+	timelineLeft := pdfMargin + pdfLabelWidth
+	timelineWidth := width - pdfMargin - timelineLeft
+	days := rangeEnd.Sub(rangeStart).Hours() / 24
+	dayWidth := timelineWidth / math.Max(days, 1)
+	xOf := func(t time.Time) float64 {
+		x := timelineLeft + t.Sub(rangeStart).Hours()/24*dayWidth
+		return math.Max(timelineLeft, math.Min(x, timelineLeft+timelineWidth))
+	}
+
+	title := fmt.Sprintf("Gantt Chart - %d tasks", chart.Summary.TotalTasks)
+	subtitle := "Generated " + generatedAt.Format("2006-01-02 15:04")
+	if ok {
+		subtitle += fmt.Sprintf(" | %s to %s", rangeStart.Format("2006-01-02"), rangeEnd.AddDate(0, 0, -1).Format("2006-01-02"))
+	}
+
+	newPage := func() float64 {
+		doc.AddPage()
+		doc.Text(pdfMargin, 36, 14, true, "#212529", title)
+		doc.Text(pdfMargin, 50, 8, false, "#6c757d", subtitle)
+		doc.Text(pdfMargin, height-12, 7, false, "#6c757d", fmt.Sprintf("Page %d", doc.PageCount()))
+
+		// Legend
+		x := width - pdfMargin - 4*80
+		for _, priority := range []string{models.PriorityTopUrgent, models.PriorityUrgent, models.PriorityMedium, models.PriorityLow} {
+			doc.FillRect(x, height-19, 8, 8, models.GetPriorityColor(priority))
+			doc.Text(x+11, height-12, 7, false, "#212529", priority)
+			x += 80
+		}
+
+		if !ok {
+			return pdfTimelineTop
+		}
+
+		// Date ticks, at least 36pt apart
+		step := int(math.Ceil(36 / dayWidth))
+		bottom := height - pdfFooterSpace
+		for day := 0; float64(day) < days; day++ {
+			date := rangeStart.AddDate(0, 0, day)
+			x := xOf(date)
+			if date.Weekday() == time.Sunday && dayWidth >= 3 {
+				doc.FillRect(x, pdfTimelineTop-4, dayWidth, bottom-pdfTimelineTop+4, "#f8f9fa")
+			}
+			if day%step == 0 {
+				doc.Line(x, pdfTimelineTop-4, x, bottom, "#dee2e6", 0.5)
+				doc.Text(x+2, pdfHeaderTop+10, 7, false, "#495057", date.Format("02 Jan"))
+			}
+		}
+		doc.Line(pdfMargin, pdfTimelineTop-4, width-pdfMargin, pdfTimelineTop-4, "#adb5bd", 0.8)
+		doc.Text(pdfMargin, pdfHeaderTop+10, 7, true, "#495057", "NJO / Part")
+
+		today := time.Date(generatedAt.Year(), generatedAt.Month(), generatedAt.Day(), 0, 0, 0, 0, rangeStart.Location())
+		if !today.Before(rangeStart) && today.Before(rangeEnd) {
+			doc.Line(xOf(today), pdfTimelineTop-4, xOf(today), bottom, "#007bff", 1)
+		}
+		return pdfTimelineTop
+	}
+
+	y := newPage()
+	if !ok {
+		doc.Text(pdfMargin, y+10, 10, false, "#6c757d", "No tasks match the filters")
+		return doc.Bytes()
+	}
+
+	row := func() {
+		if y+pdfRowHeight > height-pdfFooterSpace {
+			y = newPage()
+		}
+	}
+	for _, section := range chart.Sections {
+		if len(section.Tasks) == 0 {
+			continue
+		}
+		row()
+		doc.FillRect(pdfMargin, y, width-2*pdfMargin, pdfRowHeight, "#e9ecef")
+		doc.Text(pdfMargin+3, y+10, 8, true, "#212529", fmt.Sprintf("%s (%d)", section.SectionName, len(section.Tasks)))
+		y += pdfRowHeight
+
+		for _, task := range section.Tasks {
+			row()
+			label := utils.PDFFitText(task.NJO, 7, 80)
+			doc.Text(pdfMargin+3, y+10, 7, true, "#212529", label)
+			doc.Text(pdfMargin+88, y+10, 7, false, "#212529", utils.PDFFitText(task.PartName, 7, pdfLabelWidth-120))
+			doc.Text(pdfMargin+pdfLabelWidth-28, y+10, 7, false, "#6c757d", fmt.Sprintf("%d%%", task.Progress))
+
+			// Finish dates are inclusive, so bars end at the end of the finish day
+			x1, x2 := xOf(task.Start), xOf(task.End.AddDate(0, 0, 1))
+			barWidth := math.Max(x2-x1, 1)
+			if task.BaselineStart != nil && task.BaselineEnd != nil {
+				bx1, bx2 := xOf(*task.BaselineStart), xOf(task.BaselineEnd.AddDate(0, 0, 1))
+				doc.FillRect(bx1, y+11, math.Max(bx2-bx1, 1), 2, "#adb5bd")
+			}
+			doc.FillRect(x1, y+2, barWidth, 8, task.Color)
+			if task.Progress > 0 {
+				doc.FillRect(x1, y+8, barWidth*float64(task.Progress)/100, 2, "#343a40")
+			}
+			if task.IsCritical {
+				doc.StrokeRect(x1, y+2, barWidth, 8, "#000000", 1)
+			}
+			doc.Line(pdfMargin, y+pdfRowHeight, width-pdfMargin, y+pdfRowHeight, "#f1f3f5", 0.3)
+			y += pdfRowHeight
+		}
+	}
+
+	return doc.Bytes()
+}
+
+// ganttExportRange returns the days covered by the tasks, or the filter range if one was given.
+// The end is exclusive
+func ganttExportRange(chart *models.GanttChartResponse) (time.Time, time.Time, bool) {
+	var start, end time.Time
+	for _, section := range chart.Sections {
+		for _, task := range section.Tasks {
+			if start.IsZero() || task.Start.Before(start) {
+				start = task.Start
+			}
+			if finish := task.End.AddDate(0, 0, 1); finish.After(end) {
+				end = finish
+			}
+		}
+	}
+	if chart.Filters.StartDate != nil {
+		start = *chart.Filters.StartDate
+	}
+	if chart.Filters.EndDate != nil {
+		end = chart.Filters.EndDate.AddDate(0, 0, 1)
+	}
+	if start.IsZero() || end.IsZero() || !end.After(start) {
+		return start, end, false
+	}
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	return start, end, true
+}
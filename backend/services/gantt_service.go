@@ -5,108 +5,262 @@ import (
 	"fmt"
 	"ganttpro-backend/models"
 	"ganttpro-backend/repository"
+	"sort"
 	"time"
 )
 
 type GanttService struct {
-	ppicRepo     *repository.PPICScheduleRepository
-	ppicLinkRepo *repository.PPICLinkRepository
+	ppicRepo        *repository.PPICScheduleRepository
+	ppicLinkRepo    *repository.PPICLinkRepository
+	baselineRepo    *repository.PPICBaselineRepository
+	historyRepo     *repository.PPICHistoryRepository
+	calendarService *CalendarService
+	settingService  *SettingService
+	routingService  *RoutingTemplateService
+	downtimeService *MachineDowntimeService
 }
 
-func NewGanttService(ppicRepo *repository.PPICScheduleRepository, ppicLinkRepo *repository.PPICLinkRepository) *GanttService {
+func NewGanttService(ppicRepo *repository.PPICScheduleRepository, ppicLinkRepo *repository.PPICLinkRepository, baselineRepo *repository.PPICBaselineRepository, historyRepo *repository.PPICHistoryRepository, calendarService *CalendarService, settingService *SettingService, routingService *RoutingTemplateService, downtimeService *MachineDowntimeService) *GanttService {
 	return &GanttService{
-		ppicRepo:     ppicRepo,
-		ppicLinkRepo: ppicLinkRepo,
+		ppicRepo:        ppicRepo,
+		ppicLinkRepo:    ppicLinkRepo,
+		baselineRepo:    baselineRepo,
+		historyRepo:     historyRepo,
+		calendarService: calendarService,
+		settingService:  settingService,
+		routingService:  routingService,
+		downtimeService: downtimeService,
 	}
 }
 
-// CreatePPICSchedule creates a new PPIC schedule entry
+// ForScenario returns a service working on a what-if scenario instead of the live board
+func (s *GanttService) ForScenario(scenarioID int64) *GanttService {
+	return &GanttService{
+		ppicRepo:        s.ppicRepo.ForScenario(scenarioID),
+		ppicLinkRepo:    s.ppicLinkRepo.ForScenario(scenarioID),
+		baselineRepo:    s.baselineRepo,
+		historyRepo:     s.historyRepo,
+		calendarService: s.calendarService,
+		settingService:  s.settingService,
+		routingService:  s.routingService,
+		downtimeService: s.downtimeService,
+	}
+}
+
+// CreatePPICSchedule creates a new PPIC schedule entry. With a routing template, the machine
+// assignments come from the template and those in the request override its steps
 func (s *GanttService) CreatePPICSchedule(req *models.CreatePPICScheduleRequest, createdBy int64) (*models.PPICSchedule, error) {
+	if req.RoutingTemplateID != nil {
+		applied, err := s.routingService.ApplyTemplate(*req.RoutingTemplateID, req.MachineAssignments, req.SkipSequences)
+		if err != nil {
+			return nil, err
+		}
+		req.MachineAssignments = applied.MachineAssignments
+	}
+
+	startDate, finishDate, err := s.validateCreateRequest(req, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if NJO already exists
+	existing, err := s.ppicRepo.GetByNJO(req.NJO)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("NJO already exists in PPIC schedule")
+	}
+
+	tx, err := s.ppicRepo.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Checked again under the machine lock, so a concurrent booking of the same machines is seen
+	if err := s.checkMachineWindowsInTx(tx, createWindows(req.MachineAssignments), 0); err != nil {
+		return nil, err
+	}
+	schedule, err := tx.Create(req, createdBy, startDate, finishDate)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// validateCreateRequest checks a new schedule and returns its parsed dates. Missing scheduled
+// ends are filled in from target hours. Bookings of excludeScheduleID don't count as conflicts
+func (s *GanttService) validateCreateRequest(req *models.CreatePPICScheduleRequest, excludeScheduleID int64) (time.Time, time.Time, error) {
+	var startDate, finishDate time.Time
+
 	// Validate priority
 	if !models.ValidatePriority(req.Priority) {
-		return nil, errors.New("invalid priority value. Must be: Low, Medium, Urgent, or Top Urgent")
+		return startDate, finishDate, errors.New("invalid priority value. Must be: Low, Medium, Urgent, or Top Urgent")
 	}
 
 	// Validate material status
 	if !models.ValidateMaterialStatus(req.MaterialStatus) {
-		return nil, errors.New("invalid material status. Must be: Ready, Pending, Ordered, or Not Ready")
+		return startDate, finishDate, errors.New("invalid material status. Must be: Ready, Pending, Ordered, or Not Ready")
 	}
 
 	// Parse dates
 	startDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
-		return nil, fmt.Errorf("invalid start_date format. Use YYYY-MM-DD: %v", err)
+		return startDate, finishDate, fmt.Errorf("invalid start_date format. Use YYYY-MM-DD: %v", err)
 	}
 
-	finishDate, err := time.Parse("2006-01-02", req.FinishDate)
+	finishDate, err = time.Parse("2006-01-02", req.FinishDate)
 	if err != nil {
-		return nil, fmt.Errorf("invalid finish_date format. Use YYYY-MM-DD: %v", err)
+		return startDate, finishDate, fmt.Errorf("invalid finish_date format. Use YYYY-MM-DD: %v", err)
 	}
 
 	// Validate date range
 	if finishDate.Before(startDate) {
-		return nil, errors.New("finish_date must be after start_date")
+		return startDate, finishDate, errors.New("finish_date must be after start_date")
 	}
 
-	// Check if NJO already exists
-	existing, err := s.ppicRepo.GetByNJO(req.NJO)
-	if err != nil {
-		return nil, err
+	// Validate machine assignments count and unique sequences (1..configured maximum)
+	sequences := make([]int, 0, len(req.MachineAssignments))
+	for _, ma := range req.MachineAssignments {
+		sequences = append(sequences, ma.Sequence)
 	}
-	if existing != nil {
-		return nil, errors.New("NJO already exists in PPIC schedule")
+	if err := s.validateSequences(sequences); err != nil {
+		return startDate, finishDate, err
+	}
+
+	// Validate that the scheduled machine windows don't double-book a machine. The writer checks
+	// them again under the machine lock
+	for i := range req.MachineAssignments {
+		ma := &req.MachineAssignments[i]
+		start, end, err := ma.ParseScheduledWindow()
+		if err != nil {
+			return startDate, finishDate, err
+		}
+		if end, err = s.scheduledEndFromHours(ma.MachineID, start, end, ma.TargetHours); err != nil {
+			return startDate, finishDate, err
+		}
+		if end != nil {
+			ma.ScheduledEnd = end.Format(time.RFC3339)
+		}
 	}
+	windows := createWindows(req.MachineAssignments)
+	if err := s.validateMachineWindows(windows, excludeScheduleID); err != nil {
+		return startDate, finishDate, err
+	}
+
+	return startDate, finishDate, nil
+}
 
-	// Validate machine assignments count (0-5)
-	if len(req.MachineAssignments) > 5 {
-		return nil, errors.New("cannot have more than 5 machine assignments")
+// UpdatePPICSchedule updates an existing PPIC schedule. A date change and its cascade to the
+// dependent schedules are written in one transaction; changes lists every schedule that moved
+func (s *GanttService) UpdatePPICSchedule(id int64, req *models.UpdatePPICScheduleRequest, userID int64) (*models.PPICSchedule, []models.ScheduleDateChange, error) {
+	if req.Version == nil {
+		return nil, nil, models.ErrVersionRequired
+	}
+	existing, startDate, finishDate, calendar, err := s.validateUpdateRequest(id, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Rejected early here; the update itself re-checks the version in its WHERE clause
+	if existing.Version != *req.Version {
+		return nil, nil, models.ErrStaleVersion
 	}
 
-	// Validate unique sequences if there are machine assignments
-	if len(req.MachineAssignments) > 0 {
-		sequences := make(map[int]bool)
-		for _, ma := range req.MachineAssignments {
-			if ma.Sequence < 1 || ma.Sequence > 5 {
-				return nil, errors.New("machine sequence must be between 1 and 5")
+	changes := []models.ScheduleDateChange{}
+	if existing.ParentScheduleID != nil && (startDate != nil || finishDate != nil || req.Quantity != nil) {
+		// Moving or resizing a lot changes its parent, which cascades from there
+		if changes, err = s.updateLot(existing, req, startDate, finishDate, calendar, userID); err != nil {
+			return nil, nil, err
+		}
+	} else if startDate == nil && finishDate == nil {
+		if err := s.updateInPlace(id, req); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		newStart, newFinish := updatedDates(existing, startDate, finishDate)
+		result, err := s.rescheduleWithCascade(calendar, id, models.ChangeCauseManual, userID, func(tx *repository.ScheduleTx) error {
+			// Checked under lock, so a predecessor moved or a machine booked by a concurrent update is seen
+			if err := s.validateNoPredecessorConflict(calendar, tx.GetByID, id, newStart, newFinish); err != nil {
+				return err
 			}
-			if sequences[ma.Sequence] {
-				return nil, errors.New("duplicate sequence numbers found")
+			if err := s.checkMachineWindowsInTx(tx, updateWindows(req.MachineAssignments), id); err != nil {
+				return err
 			}
-			sequences[ma.Sequence] = true
+			return tx.Update(id, req, startDate, finishDate)
+		})
+		if err != nil {
+			return nil, nil, err
 		}
+		existing = result.before
+		changes = result.changes
+	}
+
+	// Get the updated schedule for the history
+	scheduleAfterUpdate, err := s.ppicRepo.GetByID(id)
+	if err != nil {
+		return nil, changes, err
 	}
+	// The update derives the schedule's own progress; a lot's rolls up into its split schedule
+	if err := s.refreshDerivedProgress(scheduleAfterUpdate); err != nil {
+		fmt.Printf("Warning: Failed to derive schedule progress: %v\n", err)
+	}
+	recordScheduleChanges(s.historyRepo, existing, scheduleAfterUpdate, userID, models.ChangeCauseManual)
+
+	return scheduleAfterUpdate, changes, nil
+}
 
-	return s.ppicRepo.Create(req, createdBy, startDate, finishDate)
+// updateInPlace writes an update that doesn't move the schedule. New machine windows are checked
+// under the schedule and machine locks
+func (s *GanttService) updateInPlace(id int64, req *models.UpdatePPICScheduleRequest) error {
+	tx, err := s.ppicRepo.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.Lock([]int64{id}); err != nil {
+		return err
+	}
+	if err := s.checkMachineWindowsInTx(tx, updateWindows(req.MachineAssignments), id); err != nil {
+		return err
+	}
+	if err := tx.Update(id, req, nil, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// UpdatePPICSchedule updates an existing PPIC schedule
-func (s *GanttService) UpdatePPICSchedule(id int64, req *models.UpdatePPICScheduleRequest) (*models.PPICSchedule, error) {
+// validateUpdateRequest checks an update and returns the stored schedule, the parsed new dates
+// (nil when unchanged) and, when dates change, the plant calendar. Link constraints are left to the caller
+func (s *GanttService) validateUpdateRequest(id int64, req *models.UpdatePPICScheduleRequest) (existing *models.PPICSchedule, startDate, finishDate *time.Time, calendar *models.WorkingCalendar, err error) {
 	// Check if exists
-	existing, err := s.ppicRepo.GetByID(id)
+	existing, err = s.ppicRepo.GetByID(id)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, nil, err
 	}
 	if existing == nil {
-		return nil, errors.New("PPIC schedule not found")
+		return nil, nil, nil, nil, errors.New("PPIC schedule not found")
 	}
 
 	// Validate priority if provided
 	if req.Priority != "" && !models.ValidatePriority(req.Priority) {
-		return nil, errors.New("invalid priority value. Must be: Low, Medium, Urgent, or Top Urgent")
+		return nil, nil, nil, nil, errors.New("invalid priority value. Must be: Low, Medium, Urgent, or Top Urgent")
 	}
 
 	// Validate material status if provided
 	if req.MaterialStatus != "" && !models.ValidateMaterialStatus(req.MaterialStatus) {
-		return nil, errors.New("invalid material status. Must be: Ready, Pending, Ordered, or Not Ready")
+		return nil, nil, nil, nil, errors.New("invalid material status. Must be: Ready, Pending, Ordered, or Not Ready")
 	}
 
 	// Parse dates if provided
-	var startDate, finishDate *time.Time
-
 	if req.StartDate != "" {
 		sd, err := time.Parse("2006-01-02", req.StartDate)
 		if err != nil {
-			return nil, fmt.Errorf("invalid start_date format. Use YYYY-MM-DD: %v", err)
+			return nil, nil, nil, nil, fmt.Errorf("invalid start_date format. Use YYYY-MM-DD: %v", err)
 		}
 		startDate = &sd
 	}
@@ -114,54 +268,136 @@ func (s *GanttService) UpdatePPICSchedule(id int64, req *models.UpdatePPICSchedu
 	if req.FinishDate != "" {
 		fd, err := time.Parse("2006-01-02", req.FinishDate)
 		if err != nil {
-			return nil, fmt.Errorf("invalid finish_date format. Use YYYY-MM-DD: %v", err)
+			return nil, nil, nil, nil, fmt.Errorf("invalid finish_date format. Use YYYY-MM-DD: %v", err)
 		}
 		finishDate = &fd
 	}
 
 	// Validate date range if both provided
 	if startDate != nil && finishDate != nil && finishDate.Before(*startDate) {
-		return nil, errors.New("finish_date must be after start_date")
+		return nil, nil, nil, nil, errors.New("finish_date must be after start_date")
+	}
+
+	if err := s.validateLotUpdate(existing, req, startDate, finishDate); err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	// Validate progress if provided
 	if req.Progress != nil && (*req.Progress < 0 || *req.Progress > 100) {
-		return nil, errors.New("progress must be between 0 and 100")
+		return nil, nil, nil, nil, errors.New("progress must be between 0 and 100")
 	}
 
-	// Validate that the new dates don't conflict with predecessor tasks (if this task is a target of any links)
-	if startDate != nil {
-		if err := s.validateNoPredecessorConflict(id, *startDate); err != nil {
-			return nil, err
-		}
+	// Validate status if provided
+	if req.Status != "" && !models.ValidateScheduleStatus(req.Status) {
+		return nil, nil, nil, nil, errors.New("invalid status. Must be: pending, in_progress, completed, or on_hold")
 	}
 
-	// Update the schedule
-	updated, err := s.ppicRepo.Update(id, req, startDate, finishDate)
-	if err != nil {
-		return nil, err
+	// Status and progress follow the machine assignments unless they are overridden by hand
+	override := existing.ProgressOverride
+	if req.ProgressOverride != nil {
+		override = *req.ProgressOverride
+	}
+	if (req.Status != "" || req.Progress != nil) && !override {
+		return nil, nil, nil, nil, errors.New("status and progress are derived from the machine assignments. Set progress_override to true to change them by hand")
 	}
 
-	// If dates were updated, cascade the changes to dependent tasks
+	// Validate that replacement machine windows don't double-book a machine
+	if len(req.MachineAssignments) > 0 {
+		sequences := make([]int, 0, len(req.MachineAssignments))
+		for _, ma := range req.MachineAssignments {
+			sequences = append(sequences, ma.Sequence)
+		}
+		if err := s.validateSequences(sequences); err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		for i := range req.MachineAssignments {
+			ma := &req.MachineAssignments[i]
+			if ma.ScheduledStart != nil && ma.ScheduledEnd != nil && !ma.ScheduledEnd.After(*ma.ScheduledStart) {
+				return nil, nil, nil, nil, errors.New("scheduled_end must be after scheduled_start")
+			}
+			end, err := s.scheduledEndFromHours(ma.MachineID, ma.ScheduledStart, ma.ScheduledEnd, ma.TargetHours)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			ma.ScheduledEnd = end
+		}
+		// Existing assignments of this schedule are replaced, so they don't count as bookings.
+		// The writer checks them again under the machine lock
+		if err := s.validateMachineWindows(updateWindows(req.MachineAssignments), id); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	// Link constraints and cascades are measured in plant working days
 	if startDate != nil || finishDate != nil {
-		// Get the updated schedule to use for cascading
-		scheduleAfterUpdate, err := s.ppicRepo.GetByID(id)
+		calendar, err = s.calendarService.GetPlantCalendar()
 		if err != nil {
-			return updated, nil // Return the update result even if cascade fails
+			return nil, nil, nil, nil, err
 		}
+	}
 
-		// Cascade reschedule to all dependent tasks
-		if err := s.cascadeReschedule(scheduleAfterUpdate); err != nil {
-			// Log error but don't fail the update
-			fmt.Printf("Warning: Failed to cascade reschedule: %v\n", err)
-		}
+	return existing, startDate, finishDate, calendar, nil
+}
+
+// updatedDates returns the dates a schedule has after an update changing startDate and/or finishDate
+func updatedDates(existing *models.PPICSchedule, startDate, finishDate *time.Time) (time.Time, time.Time) {
+	newStart, newFinish := existing.StartDate, existing.FinishDate
+	if startDate != nil {
+		newStart = *startDate
+	}
+	if finishDate != nil {
+		newFinish = *finishDate
+	}
+	return newStart, newFinish
+}
+
+// PreviewUpdatePPICSchedule runs the checks of UpdatePPICSchedule and lists every schedule the
+// update and its cascade would move, without writing anything. A conflict with a predecessor of
+// the schedule is reported and marks the preview blocked instead of failing
+func (s *GanttService) PreviewUpdatePPICSchedule(id int64, req *models.UpdatePPICScheduleRequest) (*models.ScheduleImpact, error) {
+	existing, startDate, finishDate, calendar, err := s.validateUpdateRequest(id, req)
+	if err != nil {
+		return nil, err
+	}
+	if startDate == nil && finishDate == nil {
+		return &models.ScheduleImpact{DryRun: true, Changes: []models.ScheduleDateChange{}, Conflicts: []models.ScheduleLinkConflict{}}, nil
+	}
+	if existing.ParentScheduleID != nil {
+		return s.previewLotUpdate(existing, startDate, finishDate, calendar)
+	}
+
+	links, err := s.ppicLinkRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links: %w", err)
+	}
+	planner := NewScheduleImpactPlanner(calendar, links, s.ppicRepo.GetByID)
+	planner.UseDowntime(s.downtimeService.MachineDowntimes)
+	newStart, newFinish := updatedDates(existing, startDate, finishDate)
+	if err := planner.Move(id, newStart, newFinish, models.ChangeCauseManual); err != nil {
+		return nil, err
+	}
+	if err := planner.Cascade(id); err != nil {
+		return nil, err
 	}
 
-	return updated, nil
+	impact, err := planner.Impact()
+	if err != nil {
+		return nil, err
+	}
+	if impact.Changes, err = withLotChanges(calendar, impact.Changes, id, s.ppicRepo.GetLots); err != nil {
+		return nil, err
+	}
+	for _, conflict := range impact.Conflicts {
+		if conflict.TargetScheduleID == id {
+			impact.Blocked = true
+		}
+	}
+	return impact, nil
 }
 
-// DeletePPICSchedule deletes a PPIC schedule
-func (s *GanttService) DeletePPICSchedule(id int64) error {
+// DeletePPICSchedule deletes a PPIC schedule, with its lots when it is split
+func (s *GanttService) DeletePPICSchedule(id int64, userID int64) error {
 	// Check if exists
 	existing, err := s.ppicRepo.GetByID(id)
 	if err != nil {
@@ -170,11 +406,18 @@ func (s *GanttService) DeletePPICSchedule(id int64) error {
 	if existing == nil {
 		return errors.New("PPIC schedule not found")
 	}
+	if existing.ParentScheduleID != nil {
+		return errors.New("a lot cannot be deleted on its own. Merge it into another lot instead")
+	}
 
-	return s.ppicRepo.Delete(id)
+	if err := s.ppicRepo.Delete(id); err != nil {
+		return err
+	}
+	recordScheduleDeleted(s.historyRepo, existing, userID, models.ChangeCauseManual)
+	return nil
 }
 
-// GetPPICSchedule gets a single PPIC schedule by ID
+// GetPPICSchedule gets a single PPIC schedule by ID, with its lots when it is split
 func (s *GanttService) GetPPICSchedule(id int64) (*models.PPICSchedule, error) {
 	schedule, err := s.ppicRepo.GetByID(id)
 	if err != nil {
@@ -183,6 +426,11 @@ func (s *GanttService) GetPPICSchedule(id int64) (*models.PPICSchedule, error) {
 	if schedule == nil {
 		return nil, errors.New("PPIC schedule not found")
 	}
+	if schedule.ParentScheduleID == nil {
+		if schedule.Lots, err = s.ppicRepo.GetLots(id); err != nil {
+			return nil, err
+		}
+	}
 	return schedule, nil
 }
 
@@ -193,14 +441,19 @@ func (s *GanttService) GetAllPPICSchedules() ([]models.PPICSchedule, error) {
 
 // GetGanttChartData returns formatted data for Gantt chart display
 func (s *GanttService) GetGanttChartData(filter models.GanttFilterRequest) (*models.GanttChartResponse, error) {
-	// Get filtered schedules
+	window, err := ganttDateWindow(&filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get filtered schedules (machine assignments are batch loaded)
 	schedules, err := s.ppicRepo.GetWithFilters(filter)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get all machines
-	machines, err := s.ppicRepo.GetAllMachines()
+	// Only the machines used by the returned tasks
+	machines, err := s.ppicRepo.GetMachinesByIDs(assignedMachineIDs(schedules))
 	if err != nil {
 		return nil, err
 	}
@@ -211,18 +464,26 @@ func (s *GanttService) GetGanttChartData(filter models.GanttFilterRequest) (*mod
 		return nil, err
 	}
 
-	// Get all PPIC links
-	ppicLinks, err := s.ppicLinkRepo.GetAll()
+	// Only the links touching the returned tasks
+	ppicLinks, err := s.ppicLinkRepo.GetTouching(scheduleIDs(schedules))
 	if err != nil {
 		return nil, err
 	}
 
+	if window != nil {
+		window.EarlierTasks, window.LaterTasks, err = s.ppicRepo.CountOutsideWindow(filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Build response
 	response := &models.GanttChartResponse{
 		Machines: machines,
 		Links:    s.convertToGanttLinks(ppicLinks),
 		Summary:  *summary,
 		Filters:  s.buildFiltersApplied(filter),
+		Window:   window,
 	}
 
 	// Group tasks based on groupBy parameter
@@ -230,22 +491,102 @@ func (s *GanttService) GetGanttChartData(filter models.GanttFilterRequest) (*mod
 	case "priority":
 		response.Sections = s.groupByPriority(schedules)
 	case "machine":
-		response.Sections = s.groupByMachine(schedules)
+		response.Sections, response.Machines, err = s.groupByMachine(schedules, machines, filter, window)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		// Default: group by all (single section)
 		response.Sections = s.groupAll(schedules)
 	}
 
+	// Mark critical tasks if requested
+	if filter.CriticalPath {
+		calendar, err := s.calendarService.GetPlantCalendar()
+		if err != nil {
+			return nil, err
+		}
+		criticalPath, err := ComputeCriticalPath(calendar, schedules, ppicLinks)
+		if err != nil {
+			return nil, err
+		}
+		s.applyCriticalPath(response.Sections, criticalPath)
+	}
+
+	// Overlay baseline dates if requested
+	if filter.BaselineID > 0 {
+		baseline, err := s.baselineRepo.GetByID(filter.BaselineID)
+		if err != nil {
+			return nil, err
+		}
+		if baseline == nil {
+			return nil, ErrBaselineNotFound
+		}
+		captured, err := s.baselineRepo.GetSchedules(filter.BaselineID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load baseline: %w", err)
+		}
+		s.applyBaseline(response.Sections, captured)
+	}
+
 	return response, nil
 }
 
+// ganttDateWindow validates the date window of a Gantt filter and fills in the default length.
+// It returns nil when no window_start is given, i.e. the chart isn't paginated
+func ganttDateWindow(filter *models.GanttFilterRequest) (*models.GanttDateWindow, error) {
+	if filter.WindowStart == "" {
+		return nil, nil
+	}
+	start, err := time.Parse("2006-01-02", filter.WindowStart)
+	if err != nil {
+		return nil, errors.New("invalid window_start format. Use YYYY-MM-DD")
+	}
+	if filter.WindowDays == 0 {
+		filter.WindowDays = models.DefaultGanttWindowDays
+	}
+	if filter.WindowDays < 1 || filter.WindowDays > models.MaxGanttWindowDays {
+		return nil, fmt.Errorf("window_days must be between 1 and %d", models.MaxGanttWindowDays)
+	}
+
+	return &models.GanttDateWindow{
+		Start:     start.Format("2006-01-02"),
+		End:       start.AddDate(0, 0, filter.WindowDays-1).Format("2006-01-02"),
+		Days:      filter.WindowDays,
+		PrevStart: start.AddDate(0, 0, -filter.WindowDays).Format("2006-01-02"),
+		NextStart: start.AddDate(0, 0, filter.WindowDays).Format("2006-01-02"),
+	}, nil
+}
+
+func scheduleIDs(schedules []models.PPICSchedule) []int64 {
+	ids := make([]int64, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.ID
+	}
+	return ids
+}
+
+func assignedMachineIDs(schedules []models.PPICSchedule) []int64 {
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, schedule := range schedules {
+		for _, ma := range schedule.MachineAssignments {
+			if !seen[ma.MachineID] {
+				seen[ma.MachineID] = true
+				ids = append(ids, ma.MachineID)
+			}
+		}
+	}
+	return ids
+}
+
 // GetSchedulesByMachine gets all schedules for a specific machine
 func (s *GanttService) GetSchedulesByMachine(machineID int64) ([]models.PPICSchedule, error) {
 	return s.ppicRepo.GetSchedulesByMachine(machineID)
 }
 
 // AddMachineAssignment adds a machine to an existing schedule
-func (s *GanttService) AddMachineAssignment(scheduleID int64, req *models.CreateMachineAssignmentRequest) (*models.MachineAssignment, error) {
+func (s *GanttService) AddMachineAssignment(scheduleID int64, req *models.CreateMachineAssignmentRequest, userID int64) (*models.MachineAssignment, error) {
 	// Check if schedule exists
 	schedule, err := s.ppicRepo.GetByID(scheduleID)
 	if err != nil {
@@ -255,28 +596,290 @@ func (s *GanttService) AddMachineAssignment(scheduleID int64, req *models.Create
 		return nil, errors.New("PPIC schedule not found")
 	}
 
-	// Check max 5 machines
-	if len(schedule.MachineAssignments) >= 5 {
-		return nil, errors.New("maximum 5 machines allowed per schedule")
+	// Machines of a split schedule are on its lots
+	if schedule.ParentScheduleID == nil {
+		lots, err := s.ppicRepo.GetLots(scheduleID)
+		if err != nil {
+			return nil, err
+		}
+		if len(lots) > 0 {
+			return nil, errors.New("schedule is split into lots. Add the machine to a lot instead")
+		}
 	}
 
-	// Check sequence uniqueness
+	// Check the machine maximum and sequence uniqueness
 	for _, ma := range schedule.MachineAssignments {
 		if ma.Sequence == req.Sequence {
 			return nil, errors.New("sequence number already exists")
 		}
 	}
+	maxMachines, err := s.settingService.MaxMachinesPerSchedule()
+	if err != nil {
+		return nil, err
+	}
+	if len(schedule.MachineAssignments) >= maxMachines {
+		return nil, fmt.Errorf("maximum %d machines allowed per schedule", maxMachines)
+	}
+	if req.Sequence < 1 || req.Sequence > maxMachines {
+		return nil, fmt.Errorf("machine sequence must be between 1 and %d", maxMachines)
+	}
+
+	// Check the machine isn't already booked in the scheduled window
+	start, end, err := req.ParseScheduledWindow()
+	if err != nil {
+		return nil, err
+	}
+	if end, err = s.scheduledEndFromHours(req.MachineID, start, end, req.TargetHours); err != nil {
+		return nil, err
+	}
+	if end != nil {
+		req.ScheduledEnd = end.Format(time.RFC3339)
+	}
+	windows := []plannedWindow{{machineID: req.MachineID, sequence: req.Sequence, start: start, end: end}}
+	if err := s.validateMachineWindows(windows, 0); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.ppicRepo.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The schedule is locked before the machine, as in every other writer
+	if err := tx.Lock([]int64{scheduleID}); err != nil {
+		return nil, err
+	}
+	if err := s.checkMachineWindowsInTx(tx, windows, 0); err != nil {
+		return nil, err
+	}
+	assignment, err := tx.AddMachineAssignment(req, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if after, err := s.ppicRepo.GetByID(scheduleID); err == nil {
+		if err := s.refreshDerivedProgress(after); err != nil {
+			fmt.Printf("Warning: Failed to derive schedule progress: %v\n", err)
+		}
+		recordScheduleChanges(s.historyRepo, schedule, after, userID, models.ChangeCauseManual)
+	}
+	return assignment, nil
+}
+
+// validateSequences checks the machine sequences of a schedule: at most the configured maximum of
+// machines, sequences unique and between 1 and the maximum
+func (s *GanttService) validateSequences(sequences []int) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+	maxMachines, err := s.settingService.MaxMachinesPerSchedule()
+	if err != nil {
+		return err
+	}
+	if len(sequences) > maxMachines {
+		return fmt.Errorf("cannot have more than %d machine assignments", maxMachines)
+	}
+	seen := make(map[int]bool, len(sequences))
+	for _, seq := range sequences {
+		if seq < 1 || seq > maxMachines {
+			return fmt.Errorf("machine sequence must be between 1 and %d", maxMachines)
+		}
+		if seen[seq] {
+			return errors.New("duplicate sequence numbers found")
+		}
+		seen[seq] = true
+	}
+	return nil
+}
 
-	return s.ppicRepo.AddMachineAssignment(req, scheduleID)
+// scheduledEndFromHours fills in a missing scheduled end by spending the target hours
+// on the machine's working calendar, starting at the scheduled start
+func (s *GanttService) scheduledEndFromHours(machineID int64, start, end *time.Time, targetHours float64) (*time.Time, error) {
+	if start == nil || end != nil || targetHours <= 0 {
+		return end, nil
+	}
+	calendar, err := s.calendarService.GetMachineCalendar(machineID)
+	if err != nil {
+		return nil, err
+	}
+	computed := calendar.AddWorkingHours(*start, targetHours)
+	return &computed, nil
+}
+
+// plannedWindow is a machine booking about to be written
+type plannedWindow struct {
+	machineID int64
+	sequence  int
+	start     *time.Time
+	end       *time.Time
+}
+
+// createWindows returns the windows of machine assignments about to be created. Scheduled ends
+// must already be filled in
+func createWindows(assignments []models.CreateMachineAssignmentRequest) []plannedWindow {
+	windows := make([]plannedWindow, 0, len(assignments))
+	for _, ma := range assignments {
+		start, end, _ := ma.ParseScheduledWindow()
+		windows = append(windows, plannedWindow{machineID: ma.MachineID, sequence: ma.Sequence, start: start, end: end})
+	}
+	return windows
+}
+
+// updateWindows returns the windows of the machine assignments of an update. Scheduled ends
+// must already be filled in
+func updateWindows(assignments []models.UpdateMachineAssignmentRequest) []plannedWindow {
+	windows := make([]plannedWindow, 0, len(assignments))
+	for _, ma := range assignments {
+		windows = append(windows, plannedWindow{machineID: ma.MachineID, sequence: ma.Sequence, start: ma.ScheduledStart, end: ma.ScheduledEnd})
+	}
+	return windows
+}
+
+// windowMachines returns the machines of the windows that are checked for double booking
+func windowMachines(windows []plannedWindow) []int64 {
+	var ids []int64
+	for _, w := range windows {
+		if w.start != nil && w.end != nil {
+			ids = append(ids, w.machineID)
+		}
+	}
+	return ids
+}
+
+// validateMachineWindows rejects windows that overlap each other, existing bookings or downtime on the same machine.
+// Windows without both start and end are not checked
+func (s *GanttService) validateMachineWindows(windows []plannedWindow, excludeScheduleID int64) error {
+	return s.checkMachineWindows(windows, excludeScheduleID, s.ppicRepo.GetMachineBookings)
+}
+
+// checkMachineWindowsInTx is validateMachineWindows inside a write transaction. The machines are
+// locked first, so two writers booking the same machine can't both pass the check and commit
+// overlapping windows
+func (s *GanttService) checkMachineWindowsInTx(tx *repository.ScheduleTx, windows []plannedWindow, excludeScheduleID int64) error {
+	if err := tx.LockMachines(windowMachines(windows)); err != nil {
+		return err
+	}
+	return s.checkMachineWindows(windows, excludeScheduleID, tx.GetMachineBookings)
+}
+
+// checkMachineWindows is validateMachineWindows with the bookings read by getBookings
+func (s *GanttService) checkMachineWindows(windows []plannedWindow, excludeScheduleID int64, getBookings func(machineID int64, start, end time.Time, excludeScheduleID int64) ([]models.MachineAssignmentWindow, error)) error {
+	for i, w := range windows {
+		if w.start == nil || w.end == nil {
+			continue
+		}
+
+		// Overlap within the same request
+		for _, other := range windows[:i] {
+			if other.start == nil || other.end == nil || other.machineID != w.machineID {
+				continue
+			}
+			if models.WindowsOverlap(*w.start, *w.end, *other.start, *other.end) {
+				return fmt.Errorf("machine assignments sequence %d and %d overlap on the same machine", other.sequence, w.sequence)
+			}
+		}
+
+		// Overlap with other schedules already on the board
+		bookings, err := getBookings(w.machineID, *w.start, *w.end, excludeScheduleID)
+		if err != nil {
+			return fmt.Errorf("failed to check machine bookings: %w", err)
+		}
+		if len(bookings) > 0 {
+			b := bookings[0]
+			return fmt.Errorf("machine double-booked: sequence %d (%s - %s) overlaps NJO %s sequence %d (%s - %s)",
+				w.sequence, w.start.Format("2006-01-02 15:04"), w.end.Format("2006-01-02 15:04"),
+				b.NJO, b.Sequence, b.ScheduledStart.Format("2006-01-02 15:04"), b.ScheduledEnd.Format("2006-01-02 15:04"))
+		}
+
+		// Downtime of the machine
+		if err := s.downtimeService.CheckWindow(w.machineID, w.sequence, *w.start, *w.end); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetMachineConflicts lists every overlapping pair of machine assignments per machine
+func (s *GanttService) GetMachineConflicts(filter models.MachineConflictFilterRequest) (*models.MachineConflictResponse, error) {
+	if filter.StartDate != "" {
+		if _, err := time.Parse("2006-01-02", filter.StartDate); err != nil {
+			return nil, fmt.Errorf("invalid start_date format. Use YYYY-MM-DD: %v", err)
+		}
+	}
+	if filter.EndDate != "" {
+		if _, err := time.Parse("2006-01-02", filter.EndDate); err != nil {
+			return nil, fmt.Errorf("invalid end_date format. Use YYYY-MM-DD: %v", err)
+		}
+	}
+
+	groups, err := s.ppicRepo.GetMachineConflicts(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.MachineConflictResponse{Machines: []models.MachineConflictGroup{}}
+	for _, group := range groups {
+		for i := range group.Conflicts {
+			c := &group.Conflicts[i]
+			c.OverlapStart = c.First.ScheduledStart
+			if c.Second.ScheduledStart.After(c.OverlapStart) {
+				c.OverlapStart = c.Second.ScheduledStart
+			}
+			c.OverlapEnd = c.First.ScheduledEnd
+			if c.Second.ScheduledEnd.Before(c.OverlapEnd) {
+				c.OverlapEnd = c.Second.ScheduledEnd
+			}
+			c.OverlapHours = c.OverlapEnd.Sub(c.OverlapStart).Hours()
+		}
+		response.TotalConflicts += len(group.Conflicts)
+		response.Machines = append(response.Machines, group)
+	}
+
+	return response, nil
 }
 
 // RemoveMachineAssignment removes a machine from a schedule
-func (s *GanttService) RemoveMachineAssignment(assignmentID int64) error {
-	return s.ppicRepo.DeleteMachineAssignment(assignmentID)
+func (s *GanttService) RemoveMachineAssignment(scheduleID, assignmentID int64, userID int64) error {
+	schedule, err := s.ppicRepo.GetByID(scheduleID)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return errors.New("PPIC schedule not found")
+	}
+
+	found := false
+	for _, ma := range schedule.MachineAssignments {
+		if ma.ID == assignmentID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return errors.New("machine assignment not found")
+	}
+
+	if err := s.ppicRepo.DeleteMachineAssignment(scheduleID, assignmentID); err != nil {
+		return err
+	}
+
+	if after, err := s.ppicRepo.GetByID(scheduleID); err == nil {
+		if err := s.refreshDerivedProgress(after); err != nil {
+			fmt.Printf("Warning: Failed to derive schedule progress: %v\n", err)
+		}
+		recordScheduleChanges(s.historyRepo, schedule, after, userID, models.ChangeCauseManual)
+	}
+	return nil
 }
 
-// validateNoPredecessorConflict checks if the new start date conflicts with any predecessor tasks
-func (s *GanttService) validateNoPredecessorConflict(scheduleID int64, newStartDate time.Time) error {
+// validateNoPredecessorConflict checks if the new dates conflict with any predecessor tasks.
+// Predecessors are read with getSchedule
+func (s *GanttService) validateNoPredecessorConflict(calendar *models.WorkingCalendar, getSchedule func(id int64) (*models.PPICSchedule, error), scheduleID int64, newStartDate, newFinishDate time.Time) error {
 	// Get all links where this schedule is the target (predecessor links)
 	predecessorLinks, err := s.ppicLinkRepo.GetByTargetScheduleID(scheduleID)
 	if err != nil {
@@ -290,13 +893,8 @@ func (s *GanttService) validateNoPredecessorConflict(scheduleID int64, newStartD
 
 	// Check each predecessor link
 	for _, link := range predecessorLinks {
-		// Only validate finish-to-start links
-		if link.LinkType != "0" {
-			continue
-		}
-
 		// Get the source (predecessor) schedule
-		sourceSchedule, err := s.ppicRepo.GetByID(link.SourceScheduleID)
+		sourceSchedule, err := getSchedule(link.SourceScheduleID)
 		if err != nil {
 			return fmt.Errorf("failed to get predecessor schedule %d: %w", link.SourceScheduleID, err)
 		}
@@ -304,81 +902,209 @@ func (s *GanttService) validateNoPredecessorConflict(scheduleID int64, newStartD
 			continue
 		}
 
-		// For finish-to-start: target must start after source finishes
-		// newStartDate must be > sourceSchedule.FinishDate
-		if newStartDate.Before(sourceSchedule.FinishDate) || newStartDate.Equal(sourceSchedule.FinishDate) {
+		if calendar.IsLinkSatisfied(link.LinkType, link.LagDays, sourceSchedule.StartDate, sourceSchedule.FinishDate, newStartDate, newFinishDate) {
+			continue
+		}
+
+		constraint, _ := calendar.LinkConstraintDate(link.LinkType, link.LagDays, sourceSchedule.StartDate, sourceSchedule.FinishDate)
+		switch link.LinkType {
+		case models.LinkTypeStartToStart:
+			return fmt.Errorf("task tidak bisa dimajuin ke tanggal %s karena terhubung (start-to-start) dengan task '%s' yang mulai di tanggal %s. Task ini harus mulai minimal tanggal %s",
+				newStartDate.Format("2006-01-02"),
+				sourceSchedule.PartName,
+				sourceSchedule.StartDate.Format("2006-01-02"),
+				constraint.Format("2006-01-02"))
+		case models.LinkTypeFinishToFinish:
+			return fmt.Errorf("task tidak bisa selesai di tanggal %s karena terhubung (finish-to-finish) dengan task '%s' yang selesai di tanggal %s. Task ini harus selesai minimal tanggal %s",
+				newFinishDate.Format("2006-01-02"),
+				sourceSchedule.PartName,
+				sourceSchedule.FinishDate.Format("2006-01-02"),
+				constraint.Format("2006-01-02"))
+		case models.LinkTypeStartToFinish:
+			return fmt.Errorf("task tidak bisa selesai di tanggal %s karena terhubung (start-to-finish) dengan task '%s' yang mulai di tanggal %s. Task ini harus selesai minimal tanggal %s",
+				newFinishDate.Format("2006-01-02"),
+				sourceSchedule.PartName,
+				sourceSchedule.StartDate.Format("2006-01-02"),
+				constraint.Format("2006-01-02"))
+		default:
+			// Finish-to-start: target must start after source finishes
 			return fmt.Errorf("task tidak bisa dimajuin ke tanggal %s karena terhubung dengan task '%s' yang selesai di tanggal %s. Task ini harus mulai minimal tanggal %s",
 				newStartDate.Format("2006-01-02"),
 				sourceSchedule.PartName,
 				sourceSchedule.FinishDate.Format("2006-01-02"),
-				sourceSchedule.FinishDate.AddDate(0, 0, 1).Format("2006-01-02"))
+				constraint.Format("2006-01-02"))
 		}
 	}
 
 	return nil
 }
 
-// cascadeReschedule recursively reschedules all dependent tasks when a source task is updated
-func (s *GanttService) cascadeReschedule(sourceSchedule *models.PPICSchedule) error {
-	// Get all links where this schedule is the source (tasks that depend on this one)
-	dependentLinks, err := s.ppicLinkRepo.GetBySourceScheduleID(sourceSchedule.ID)
+// rescheduleResult is what rescheduleWithCascade changed
+type rescheduleResult struct {
+	before  *models.PPICSchedule        // The schedule as read under lock, before write
+	changes []models.ScheduleDateChange // Every schedule that moved, in cascade order
+}
+
+// rescheduleWithCascade runs write, which changes the schedule, and moves every schedule depending
+// on it onto its link constraints, all in one transaction. The schedule, its predecessors and all
+// its dependents are locked first, so concurrent reschedules of the same chain run one after the
+// other. Any error, including a dependency cycle, rolls everything back. Dates move in working days
+// of the calendar. write may add links; the cascade follows the links as they are after it.
+// History of the dependents is recorded after the commit; the caller records its own
+func (s *GanttService) rescheduleWithCascade(calendar *models.WorkingCalendar, id int64, cause string, userID int64, write func(tx *repository.ScheduleTx) error) (*rescheduleResult, error) {
+	return s.rescheduleWithCascadeLocking(calendar, id, nil, cause, userID, write)
+}
+
+// rescheduleWithCascadeLocking is rescheduleWithCascade that also locks the schedules in also and
+// their chains, together with those of the schedule, before write runs
+func (s *GanttService) rescheduleWithCascadeLocking(calendar *models.WorkingCalendar, id int64, also []int64, cause string, userID int64, write func(tx *repository.ScheduleTx) error) (*rescheduleResult, error) {
+	tx, err := s.ppicRepo.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to get dependent links: %w", err)
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	// If no dependent tasks, nothing to do
-	if len(dependentLinks) == 0 {
-		return nil
+	locked := make(map[int64]bool)
+	if _, err := lockCascade(tx, append([]int64{id}, also...), locked); err != nil {
+		return nil, err
 	}
-
-	// For each dependent task, reschedule it
-	for _, link := range dependentLinks {
-		// Only handle finish-to-start links for now
-		if link.LinkType != "0" {
-			continue
+	before, err := tx.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, errors.New("PPIC schedule not found")
+	}
+	if write != nil {
+		if err := write(tx); err != nil {
+			return nil, err
 		}
+	}
+	cascade, err := s.cascadeInTx(tx, calendar, id, before, cause, locked)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
-		// Get the target schedule
-		targetSchedule, err := s.ppicRepo.GetByID(link.TargetScheduleID)
-		if err != nil {
-			return fmt.Errorf("failed to get target schedule %d: %w", link.TargetScheduleID, err)
-		}
-		if targetSchedule == nil {
-			continue
-		}
+	s.recordCascadeHistory(cascade, id, userID)
+	return &rescheduleResult{before: before, changes: cascade.changes}, nil
+}
 
-		// Calculate new dates for the target task
-		// For finish-to-start: target should start after source finishes
-		newStartDate := sourceSchedule.FinishDate.AddDate(0, 0, 1) // Day after source finishes
+// scheduleCascade is a cascade written by cascadeInTx
+type scheduleCascade struct {
+	planner    *ScheduleImpactPlanner
+	changes    []models.ScheduleDateChange    // Every schedule that moved, in cascade order
+	lotsBefore map[int64]*models.PPICSchedule // Lots of the moved schedules, before they moved
+}
 
-		// Calculate task duration to maintain it
-		duration := targetSchedule.FinishDate.Sub(targetSchedule.StartDate)
-		newFinishDate := newStartDate.Add(duration)
+// cascadeInTx moves every schedule depending on a schedule, whose own change is already written in
+// tx, onto its link constraints. before is the schedule as it was before that change, so it is
+// listed among the changes when its dates changed. The cascade set is locked first (see lockCascade)
+func (s *GanttService) cascadeInTx(tx *repository.ScheduleTx, calendar *models.WorkingCalendar, id int64, before *models.PPICSchedule, cause string, locked map[int64]bool) (*scheduleCascade, error) {
+	links, err := lockCascade(tx, []int64{id}, locked)
+	if err != nil {
+		return nil, err
+	}
+	after, err := tx.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if after == nil {
+		return nil, errors.New("PPIC schedule not found")
+	}
 
-		// Update the target schedule
-		updateReq := &models.UpdatePPICScheduleRequest{
-			StartDate:  newStartDate.Format("2006-01-02"),
-			FinishDate: newFinishDate.Format("2006-01-02"),
+	// Plan from the version before the change, so the schedule itself is listed when its dates changed
+	planner := NewScheduleImpactPlanner(calendar, links, func(scheduleID int64) (*models.PPICSchedule, error) {
+		if scheduleID == id {
+			return before, nil
 		}
+		return tx.GetByID(scheduleID)
+	})
+	planner.UseDowntime(s.downtimeService.MachineDowntimes)
+	if err := planner.Move(id, after.StartDate, after.FinishDate, cause); err != nil {
+		return nil, err
+	}
+	if err := planner.Cascade(id); err != nil {
+		return nil, err
+	}
 
-		_, err = s.ppicRepo.Update(targetSchedule.ID, updateReq, &newStartDate, &newFinishDate)
-		if err != nil {
-			return fmt.Errorf("failed to update dependent schedule %d: %w", targetSchedule.ID, err)
+	// Lots of a moved split schedule follow it; read them before they move
+	cascade := &scheduleCascade{planner: planner, lotsBefore: make(map[int64]*models.PPICSchedule)}
+	cascade.changes, err = withLotChanges(calendar, planner.Changes(), id, func(parentID int64) ([]models.PPICSchedule, error) {
+		lots, err := tx.GetLots(parentID)
+		for i := range lots {
+			cascade.lotsBefore[lots[i].ID] = &lots[i]
 		}
+		return lots, err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Get the updated target schedule
-		updatedTarget, err := s.ppicRepo.GetByID(targetSchedule.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get updated target schedule %d: %w", targetSchedule.ID, err)
+	for _, schedule := range planner.Moved() {
+		if schedule.ID == id {
+			continue
+		}
+		if err := tx.SetDates(schedule.ID, schedule.StartDate, schedule.FinishDate); err != nil {
+			return nil, fmt.Errorf("failed to update dependent schedule %s: %w", schedule.NJO, err)
 		}
+		if err := s.shiftLotsInTx(tx, calendar, schedule.ID, planner.Stored(schedule.ID).StartDate, schedule.StartDate); err != nil {
+			return nil, fmt.Errorf("failed to update dependent schedule %s: %w", schedule.NJO, err)
+		}
+	}
+	return cascade, nil
+}
 
-		// Recursively cascade to tasks that depend on this target
-		if err := s.cascadeReschedule(updatedTarget); err != nil {
-			return fmt.Errorf("failed to cascade reschedule for schedule %d: %w", updatedTarget.ID, err)
+// recordCascadeHistory records the history of the schedules a cascade moved, after the commit.
+// The schedule it started from is left to the caller
+func (s *GanttService) recordCascadeHistory(c *scheduleCascade, id int64, userID int64) {
+	for _, change := range c.changes {
+		if change.ScheduleID == id {
+			continue
+		}
+		stored := c.planner.Stored(change.ScheduleID)
+		if stored == nil {
+			stored = c.lotsBefore[change.ScheduleID]
+		}
+		if updated, err := s.ppicRepo.GetByID(change.ScheduleID); err == nil {
+			recordScheduleChanges(s.historyRepo, stored, updated, userID, change.Cause)
 		}
 	}
+}
 
-	return nil
+// lockCascade locks schedules, their predecessors and their dependents, and returns the links read
+// under those locks. The sets of all the schedules are locked in one ID-ordered call, so two
+// transactions starting from the same schedules wait for each other instead of deadlocking. A link
+// added by a transaction that committed before the locks were taken can widen the set, so the links
+// are read again until every schedule of the set is locked
+func lockCascade(tx *repository.ScheduleTx, ids []int64, locked map[int64]bool) ([]models.PPICLink, error) {
+	for {
+		links, err := tx.GetLinks()
+		if err != nil {
+			return nil, err
+		}
+		var missing []int64
+		seen := make(map[int64]bool)
+		for _, id := range ids {
+			for _, scheduleID := range models.CascadeScheduleIDs(links, id) {
+				if !locked[scheduleID] && !seen[scheduleID] {
+					seen[scheduleID] = true
+					missing = append(missing, scheduleID)
+				}
+			}
+		}
+		if len(missing) == 0 {
+			return links, nil
+		}
+		if err := tx.Lock(missing); err != nil {
+			return nil, err
+		}
+		for _, scheduleID := range missing {
+			locked[scheduleID] = true
+		}
+	}
 }
 
 // Helper functions
@@ -400,6 +1126,9 @@ func (s *GanttService) buildFiltersApplied(filter models.GanttFilterRequest) mod
 	if filter.MachineID > 0 {
 		applied.MachineID = &filter.MachineID
 	}
+	if filter.BaselineID > 0 {
+		applied.BaselineID = &filter.BaselineID
+	}
 
 	return applied
 }
@@ -441,14 +1170,10 @@ func (s *GanttService) groupByPriority(schedules []models.PPICSchedule) []models
 	return sections
 }
 
-func (s *GanttService) groupByMachine(schedules []models.PPICSchedule) []models.GanttSection {
-	// Get all machines first
-	machines, _ := s.ppicRepo.GetAllMachines()
-	machineMap := make(map[int64]string)
-	for _, m := range machines {
-		machineMap[m.ID] = m.MachineName
-	}
-
+// groupByMachine puts each machine's tasks in a section, with the machine's downtime in the
+// chart range as blocked bars. Machines that are down in the range are listed even without tasks.
+// machines are those of the tasks; the machines of the sections, in name order, are returned
+func (s *GanttService) groupByMachine(schedules []models.PPICSchedule, machines []models.Machine, filter models.GanttFilterRequest, window *models.GanttDateWindow) ([]models.GanttSection, []models.Machine, error) {
 	// Group schedules by machine
 	machineSchedules := make(map[int64][]models.PPICSchedule)
 
@@ -458,48 +1183,174 @@ func (s *GanttService) groupByMachine(schedules []models.PPICSchedule) []models.
 		}
 	}
 
-	var sections []models.GanttSection
-	for machineID, scheds := range machineSchedules {
-		machineName := machineMap[machineID]
-		if machineName == "" {
-			machineName = fmt.Sprintf("Machine %d", machineID)
+	downtimes, err := s.ganttDowntimes(schedules, filter, window)
+	if err != nil {
+		return nil, nil, err
+	}
+	for machineID := range downtimes {
+		if _, ok := machineSchedules[machineID]; !ok {
+			machineSchedules[machineID] = nil
+		}
+	}
+
+	// Only the machines that are down without tasks still have to be loaded
+	known := make(map[int64]bool, len(machines))
+	for _, m := range machines {
+		known[m.ID] = true
+	}
+	var downOnly []int64
+	for machineID := range machineSchedules {
+		if !known[machineID] {
+			downOnly = append(downOnly, machineID)
+		}
+	}
+	if len(downOnly) > 0 {
+		sort.Slice(downOnly, func(i, j int) bool { return downOnly[i] < downOnly[j] })
+		more, err := s.ppicRepo.GetMachinesByIDs(downOnly)
+		if err != nil {
+			return nil, nil, err
 		}
-		sections = append(sections, models.GanttSection{
+		machines = append(append([]models.Machine{}, machines...), more...)
+		sort.SliceStable(machines, func(i, j int) bool { return machines[i].MachineName < machines[j].MachineName })
+	}
+
+	var sections []models.GanttSection
+	newSection := func(machineID int64, machineName string) models.GanttSection {
+		section := models.GanttSection{
 			SectionID:   fmt.Sprintf("machine-%d", machineID),
 			SectionName: machineName,
-			Tasks:       s.convertToGanttTasks(scheds),
-		})
+			Tasks:       s.convertToGanttTasks(machineSchedules[machineID]),
+		}
+		for _, d := range downtimes[machineID] {
+			section.Downtime = append(section.Downtime, models.NewGanttDowntimeBar(d))
+		}
+		return section
+	}
+	listed := make(map[int64]bool, len(machines))
+	for _, m := range machines {
+		listed[m.ID] = true
+		sections = append(sections, newSection(m.ID, m.MachineName))
+	}
+	// Machines deleted since their tasks were assigned keep a section under their ID
+	var unknown []int64
+	for machineID := range machineSchedules {
+		if !listed[machineID] {
+			unknown = append(unknown, machineID)
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i] < unknown[j] })
+	for _, machineID := range unknown {
+		sections = append(sections, newSection(machineID, fmt.Sprintf("Machine %d", machineID)))
 	}
 
-	return sections
+	return sections, machines, nil
 }
 
+// ganttDowntimes loads the machine downtime in the chart range: the date window, else the start_date
+// and end_date filters, else the span of the listed tasks
+func (s *GanttService) ganttDowntimes(schedules []models.PPICSchedule, filter models.GanttFilterRequest, window *models.GanttDateWindow) (map[int64][]models.MachineDowntime, error) {
+	var from, to time.Time
+	switch {
+	case window != nil:
+		from, _ = time.Parse("2006-01-02", window.Start)
+		to, _ = time.Parse("2006-01-02", window.End)
+	case filter.StartDate != "" || filter.EndDate != "":
+		from, _ = time.Parse("2006-01-02", filter.StartDate)
+		to, _ = time.Parse("2006-01-02", filter.EndDate)
+	default:
+		if len(schedules) == 0 {
+			return nil, nil
+		}
+		for _, schedule := range schedules {
+			if from.IsZero() || schedule.StartDate.Before(from) {
+				from = schedule.StartDate
+			}
+			if schedule.FinishDate.After(to) {
+				to = schedule.FinishDate
+			}
+		}
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	downtimes, err := s.downtimeService.DowntimesBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if filter.MachineID > 0 {
+		machineDowntimes := make(map[int64][]models.MachineDowntime)
+		if list := downtimes[filter.MachineID]; len(list) > 0 {
+			machineDowntimes[filter.MachineID] = list
+		}
+		return machineDowntimes, nil
+	}
+	return downtimes, nil
+}
+
+// convertToGanttTasks converts schedules to tasks. Lots are listed right after their split
+// schedule, by lot number, as its children; lots whose split schedule isn't listed stand alone
 func (s *GanttService) convertToGanttTasks(schedules []models.PPICSchedule) []models.GanttTask {
 	var tasks []models.GanttTask
 
+	listed := make(map[int64]bool, len(schedules))
+	for _, schedule := range schedules {
+		listed[schedule.ID] = true
+	}
+	lotsOf := make(map[int64][]models.PPICSchedule)
+	var top []models.PPICSchedule
 	for _, schedule := range schedules {
-		task := models.GanttTask{
-			TaskID:         fmt.Sprintf("task-%d", schedule.ID),
-			TaskName:       schedule.PartName,
-			NJO:            schedule.NJO,
-			PartName:       schedule.PartName,
-			Start:          schedule.StartDate,
-			End:            schedule.FinishDate,
-			Priority:       schedule.Priority,
-			PriorityAlpha:  schedule.PriorityAlpha,
-			MaterialStatus: schedule.MaterialStatus,
-			Status:         schedule.Status,
-			Progress:       schedule.Progress,
-			PPICNotes:      schedule.PPICNotes,
-			Color:          models.GetPriorityColor(schedule.Priority),
-			Machines:       s.convertToGanttMachines(schedule.MachineAssignments),
+		if schedule.ParentScheduleID != nil && listed[*schedule.ParentScheduleID] {
+			lotsOf[*schedule.ParentScheduleID] = append(lotsOf[*schedule.ParentScheduleID], schedule)
+			continue
 		}
+		top = append(top, schedule)
+	}
+
+	for _, schedule := range top {
+		task := s.convertToGanttTask(schedule)
+		lots := lotsOf[schedule.ID]
+		task.IsSplit = len(lots) > 0
 		tasks = append(tasks, task)
+
+		sort.SliceStable(lots, func(i, j int) bool { return *lots[i].LotNumber < *lots[j].LotNumber })
+		for _, lot := range lots {
+			lotTask := s.convertToGanttTask(lot)
+			lotTask.Parent = task.TaskID
+			tasks = append(tasks, lotTask)
+		}
 	}
 
 	return tasks
 }
 
+func (s *GanttService) convertToGanttTask(schedule models.PPICSchedule) models.GanttTask {
+	taskName := schedule.PartName
+	if schedule.LotNumber != nil {
+		taskName = models.LotTaskName(&schedule)
+	}
+	return models.GanttTask{
+		TaskID:           fmt.Sprintf("task-%d", schedule.ID),
+		TaskName:         taskName,
+		NJO:              schedule.NJO,
+		PartName:         schedule.PartName,
+		Start:            schedule.StartDate,
+		End:              schedule.FinishDate,
+		Priority:         schedule.Priority,
+		PriorityAlpha:    schedule.PriorityAlpha,
+		MaterialStatus:   schedule.MaterialStatus,
+		Status:           schedule.Status,
+		Progress:         schedule.Progress,
+		ProgressOverride: schedule.ProgressOverride,
+		PPICNotes:        schedule.PPICNotes,
+		Version:          schedule.Version,
+		Quantity:         schedule.Quantity,
+		LotNumber:        schedule.LotNumber,
+		Color:            models.GetPriorityColor(schedule.Priority),
+		Machines:         s.convertToGanttMachines(schedule.MachineAssignments),
+	}
+}
+
 func (s *GanttService) convertToGanttMachines(assignments []models.MachineAssignment) []models.GanttMachineInfo {
 	var machines []models.GanttMachineInfo
 
@@ -529,6 +1380,7 @@ func (s *GanttService) convertToGanttLinks(ppicLinks []models.PPICLink) []models
 			Source: fmt.Sprintf("task-%d", link.SourceScheduleID),
 			Target: fmt.Sprintf("task-%d", link.TargetScheduleID),
 			Type:   link.LinkType,
+			Lag:    link.LagDays,
 		}
 		ganttLinks = append(ganttLinks, ganttLink)
 	}
@@ -536,40 +1388,97 @@ func (s *GanttService) convertToGanttLinks(ppicLinks []models.PPICLink) []models
 	return ganttLinks
 }
 
-// UpdateMachineAssignmentStatus updates the status of a machine assignment
-func (s *GanttService) UpdateMachineAssignmentStatus(scheduleID int64, assignmentID int64, status string, actualStart, actualEnd *time.Time) error {
+// UpdateMachineAssignmentStatus updates the status of a machine assignment last read at version
+// and re-derives the schedule's progress and status from it. Returns the updated schedule
+func (s *GanttService) UpdateMachineAssignmentStatus(scheduleID int64, assignmentID int64, version *int, status string, actualStart, actualEnd *time.Time, userID int64) (*models.PPICSchedule, error) {
+	if !models.ValidateAssignmentStatus(status) {
+		return nil, errors.New("invalid status. Must be: pending, in_progress, or completed")
+	}
+	if version == nil {
+		return nil, models.ErrVersionRequired
+	}
+
 	schedule, err := s.ppicRepo.GetByID(scheduleID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if schedule == nil {
-		return errors.New("PPIC schedule not found")
+		return nil, errors.New("PPIC schedule not found")
 	}
 
 	// Find the assignment
-	found := false
-	for _, ma := range schedule.MachineAssignments {
-		if ma.ID == assignmentID {
-			found = true
+	var assignment *models.MachineAssignment
+	for i := range schedule.MachineAssignments {
+		if schedule.MachineAssignments[i].ID == assignmentID {
+			assignment = &schedule.MachineAssignments[i]
 			break
 		}
 	}
-	if !found {
-		return errors.New("machine assignment not found")
+	if assignment == nil {
+		return nil, errors.New("machine assignment not found")
+	}
+	if assignment.Version != *version {
+		return nil, models.ErrStaleVersion
 	}
 
-	// Update via repository
-	updateReq := &models.UpdatePPICScheduleRequest{
-		MachineAssignments: []models.UpdateMachineAssignmentRequest{
-			{
-				ID:          assignmentID,
-				Status:      status,
-				ActualStart: actualStart,
-				ActualEnd:   actualEnd,
-			},
-		},
+	// Keep recorded actual times and stamp the missing ones, so progress is based on real starts and ends
+	now := time.Now()
+	if actualStart == nil {
+		actualStart = assignment.ActualStart
+	}
+	if actualStart == nil && status != models.AssignmentStatusPending {
+		actualStart = &now
+	}
+	if actualEnd == nil && status == models.AssignmentStatusCompleted {
+		actualEnd = assignment.ActualEnd
+		if actualEnd == nil {
+			actualEnd = &now
+		}
+	}
+	if status == models.AssignmentStatusPending {
+		// Back to pending means the work hasn't happened yet
+		actualStart, actualEnd = nil, nil
+	}
+	if actualStart != nil && actualEnd != nil && actualEnd.Before(*actualStart) {
+		return nil, errors.New("actual_end must be after actual_start")
 	}
 
-	_, err = s.ppicRepo.Update(scheduleID, updateReq, nil, nil)
-	return err
+	// Update only this assignment; the other assignments are left as they are
+	if err := s.ppicRepo.UpdateMachineAssignmentStatus(scheduleID, assignmentID, *version, status, actualStart, actualEnd); err != nil {
+		return nil, err
+	}
+
+	after, err := s.ppicRepo.GetByID(scheduleID)
+	if err != nil || after == nil {
+		return nil, err
+	}
+	if err := s.refreshDerivedProgress(after); err != nil {
+		return nil, err
+	}
+	recordScheduleChanges(s.historyRepo, schedule, after, userID, models.ChangeCauseManual)
+	return after, nil
+}
+
+// refreshDerivedProgress stores the progress and status derived from the schedule's
+// machine assignments and updates schedule to match. Overridden schedules are left alone
+func (s *GanttService) refreshDerivedProgress(schedule *models.PPICSchedule) error {
+	if !schedule.ProgressOverride {
+		progress, status, ok := models.DeriveScheduleProgress(schedule.MachineAssignments)
+		if ok && (progress != schedule.Progress || status != schedule.Status) {
+			if err := s.ppicRepo.SetDerivedProgress(schedule.ID, progress, status); err != nil {
+				return err
+			}
+			schedule.Progress = progress
+			schedule.Status = status
+		}
+	}
+
+	// The progress of a lot rolls up into its split schedule
+	if schedule.ParentScheduleID != nil {
+		return s.refreshSplitProgress(*schedule.ParentScheduleID)
+	}
+	if len(schedule.MachineAssignments) == 0 {
+		return s.refreshSplitProgress(schedule.ID)
+	}
+	return nil
 }
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+)
+
+type MachineDowntimeService struct {
+	repo        *repository.MachineDowntimeRepository
+	machineRepo *repository.MachineRepository
+	ppicRepo    *repository.PPICScheduleRepository
+}
+
+func NewMachineDowntimeService(repo *repository.MachineDowntimeRepository, machineRepo *repository.MachineRepository, ppicRepo *repository.PPICScheduleRepository) *MachineDowntimeService {
+	return &MachineDowntimeService{repo: repo, machineRepo: machineRepo, ppicRepo: ppicRepo}
+}
+
+// GetDowntimes returns downtime windows, optionally of one machine and type, overlapping a date range
+func (s *MachineDowntimeService) GetDowntimes(filter models.MachineDowntimeFilterRequest) ([]models.MachineDowntime, error) {
+	if filter.Type != "" && !models.ValidateDowntimeType(filter.Type) {
+		return nil, errors.New("invalid type. Must be: planned_maintenance, breakdown or calibration")
+	}
+	var from, to time.Time
+	if filter.StartDate != "" {
+		t, err := time.Parse("2006-01-02", filter.StartDate)
+		if err != nil {
+			return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+		}
+		from = t
+	}
+	if filter.EndDate != "" {
+		t, err := time.Parse("2006-01-02", filter.EndDate)
+		if err != nil {
+			return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+	return s.repo.GetAll(filter.MachineID, filter.Type, from, to)
+}
+
+// GetDowntime returns a single downtime window
+func (s *MachineDowntimeService) GetDowntime(id int64) (*models.MachineDowntime, error) {
+	downtime, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("machine downtime not found")
+	}
+	return downtime, nil
+}
+
+// CreateDowntime validates and stores a downtime window. Machine assignments already scheduled in
+// it are not moved; they are returned so the planner can reschedule them
+func (s *MachineDowntimeService) CreateDowntime(req *models.CreateMachineDowntimeRequest, createdBy int64) (*models.MachineDowntimeResult, error) {
+	if !models.ValidateDowntimeType(req.Type) {
+		return nil, errors.New("invalid type. Must be: planned_maintenance, breakdown or calibration")
+	}
+	if machine, err := s.machineRepo.GetByID(req.MachineID); err != nil || machine == nil {
+		return nil, fmt.Errorf("machine %d not found", req.MachineID)
+	}
+	start, err := models.ParseDowntimeTime("start_time", req.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := models.ParseDowntimeTime("end_time", req.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.ValidateDowntimeWindow(start, end); err != nil {
+		return nil, err
+	}
+
+	downtime := &models.MachineDowntime{
+		MachineID: req.MachineID,
+		Type:      req.Type,
+		Reason:    req.Reason,
+		StartTime: start,
+		EndTime:   end,
+		CreatedBy: createdBy,
+	}
+	if err := s.repo.Create(downtime); err != nil {
+		return nil, fmt.Errorf("failed to create machine downtime: %w", err)
+	}
+	return s.withAffected(downtime)
+}
+
+// UpdateDowntime changes a downtime window; omitted fields are kept
+func (s *MachineDowntimeService) UpdateDowntime(id int64, req *models.UpdateMachineDowntimeRequest) (*models.MachineDowntimeResult, error) {
+	downtime, err := s.GetDowntime(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Type != "" {
+		if !models.ValidateDowntimeType(req.Type) {
+			return nil, errors.New("invalid type. Must be: planned_maintenance, breakdown or calibration")
+		}
+		downtime.Type = req.Type
+	}
+	if req.Reason != nil {
+		downtime.Reason = *req.Reason
+	}
+	if req.StartTime != "" {
+		if downtime.StartTime, err = models.ParseDowntimeTime("start_time", req.StartTime); err != nil {
+			return nil, err
+		}
+	}
+	if req.EndTime != "" {
+		if downtime.EndTime, err = models.ParseDowntimeTime("end_time", req.EndTime); err != nil {
+			return nil, err
+		}
+	}
+	if err := models.ValidateDowntimeWindow(downtime.StartTime, downtime.EndTime); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(downtime); err != nil {
+		return nil, fmt.Errorf("failed to update machine downtime: %w", err)
+	}
+	return s.withAffected(downtime)
+}
+
+// DeleteDowntime removes a downtime window
+func (s *MachineDowntimeService) DeleteDowntime(id int64) error {
+	if err := s.repo.Delete(id); err != nil {
+		return errors.New("machine downtime not found")
+	}
+	return nil
+}
+
+// MachineDowntimes returns every downtime window of a machine
+func (s *MachineDowntimeService) MachineDowntimes(machineID int64) ([]models.MachineDowntime, error) {
+	downtimes, err := s.repo.GetByMachine(machineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load machine downtime: %w", err)
+	}
+	return downtimes, nil
+}
+
+// ScheduleDowntimes returns the downtime windows of the machines a schedule is assigned to
+func (s *MachineDowntimeService) ScheduleDowntimes(schedule *models.PPICSchedule) ([]models.MachineDowntime, error) {
+	var downtimes []models.MachineDowntime
+	seen := make(map[int64]bool)
+	for _, ma := range schedule.MachineAssignments {
+		if seen[ma.MachineID] {
+			continue
+		}
+		seen[ma.MachineID] = true
+		machineDowntimes, err := s.MachineDowntimes(ma.MachineID)
+		if err != nil {
+			return nil, err
+		}
+		downtimes = append(downtimes, machineDowntimes...)
+	}
+	return downtimes, nil
+}
+
+// DowntimesBetween returns downtime windows overlapping [from, to) grouped by machine. A zero to leaves the range open
+func (s *MachineDowntimeService) DowntimesBetween(from, to time.Time) (map[int64][]models.MachineDowntime, error) {
+	downtimes, err := s.repo.GetAll(0, "", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load machine downtime: %w", err)
+	}
+	byMachine := make(map[int64][]models.MachineDowntime)
+	for _, d := range downtimes {
+		byMachine[d.MachineID] = append(byMachine[d.MachineID], d)
+	}
+	return byMachine, nil
+}
+
+// CheckWindow returns an error when a machine window overlaps a downtime of the machine
+func (s *MachineDowntimeService) CheckWindow(machineID int64, sequence int, start, end time.Time) error {
+	downtimes, err := s.repo.GetAll(machineID, "", start, end)
+	if err != nil {
+		return fmt.Errorf("failed to check machine downtime: %w", err)
+	}
+	if d := models.OverlappingDowntime(downtimes, start, end); d != nil {
+		return fmt.Errorf("machine unavailable: sequence %d (%s - %s) overlaps %s",
+			sequence, start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"), d.Describe())
+	}
+	return nil
+}
+
+// withAffected pairs a downtime with the scheduled machine assignments it overlaps on the live board
+func (s *MachineDowntimeService) withAffected(downtime *models.MachineDowntime) (*models.MachineDowntimeResult, error) {
+	affected, err := s.ppicRepo.GetMachineBookings(downtime.MachineID, downtime.StartTime, downtime.EndTime, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check machine bookings: %w", err)
+	}
+	if affected == nil {
+		affected = []models.MachineAssignmentWindow{}
+	}
+	return &models.MachineDowntimeResult{Downtime: *downtime, AffectedAssignments: affected}, nil
+}
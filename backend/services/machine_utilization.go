@@ -0,0 +1,223 @@
+package services
+
+import (
+	"errors"
+	"ganttpro-backend/models"
+	"math"
+	"strings"
+	"time"
+)
+
+// GetMachineUtilization reports planned, actual and available hours per machine and day or week
+func (s *GanttService) GetMachineUtilization(filter models.MachineUtilizationFilterRequest) (*models.MachineUtilizationReport, error) {
+	start, err := time.Parse("2006-01-02", filter.StartDate)
+	if err != nil {
+		return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+	}
+	end, err := time.Parse("2006-01-02", filter.EndDate)
+	if err != nil {
+		return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end_date must be after start_date")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, errors.New("date range cannot exceed one year")
+	}
+	if filter.Period == "" {
+		filter.Period = models.UtilizationPeriodDay
+	}
+	if filter.Period != models.UtilizationPeriodDay && filter.Period != models.UtilizationPeriodWeek {
+		return nil, errors.New("invalid period. Must be: day or week")
+	}
+
+	allMachines, err := s.ppicRepo.GetAllMachines()
+	if err != nil {
+		return nil, err
+	}
+	var machines []models.Machine
+	calendars := make(map[int64]*models.WorkingCalendar)
+	for _, m := range allMachines {
+		if filter.MachineID > 0 && m.ID != filter.MachineID {
+			continue
+		}
+		if filter.MachineType != "" && !strings.EqualFold(m.MachineType, filter.MachineType) {
+			continue
+		}
+		if filter.Location != "" && !strings.EqualFold(m.Location, filter.Location) {
+			continue
+		}
+		calendar, err := s.calendarService.GetMachineCalendar(m.ID)
+		if err != nil {
+			return nil, err
+		}
+		machines = append(machines, m)
+		calendars[m.ID] = calendar
+	}
+
+	assignments, err := s.ppicRepo.GetAssignmentsInRange(start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildMachineUtilization(machines, calendars, assignments, start, end, filter.Period, time.Now()), nil
+}
+
+// BuildMachineUtilization computes the utilization report for the days start..end (inclusive).
+// Each assignment's target hours are spread over the working time of its scheduled window, so a
+// window too short for its target hours shows up as overload. Assignments without target hours
+// count the working time of their window. Still running assignments count actual hours up to now
+func BuildMachineUtilization(machines []models.Machine, calendars map[int64]*models.WorkingCalendar, assignments []models.MachineAssignment, start, end time.Time, period string, now time.Time) *models.MachineUtilizationReport {
+	var days []time.Time
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	rangeEnd := end.AddDate(0, 0, 1)
+
+	byMachine := make(map[int64][]models.MachineAssignment)
+	for _, ma := range assignments {
+		byMachine[ma.MachineID] = append(byMachine[ma.MachineID], ma)
+	}
+
+	report := &models.MachineUtilizationReport{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Period:    period,
+		Machines:  []models.MachineUtilization{},
+	}
+
+	for _, machine := range machines {
+		calendar := calendars[machine.ID]
+		if calendar == nil {
+			calendar = models.NewWorkingCalendar(nil, nil)
+		}
+
+		planned := make([]float64, len(days))
+		actual := make([]float64, len(days))
+		available := make([]float64, len(days))
+		if machine.IsAvailable() {
+			for i, day := range days {
+				available[i] = calendar.WorkingHoursBetween(day, day.AddDate(0, 0, 1))
+			}
+		}
+
+		for _, ma := range byMachine[machine.ID] {
+			if ma.ScheduledStart != nil && ma.ScheduledEnd != nil && ma.ScheduledEnd.After(*ma.ScheduledStart) {
+				windowStart, windowEnd := *ma.ScheduledStart, *ma.ScheduledEnd
+				windowWork := calendar.WorkingHoursBetween(windowStart, windowEnd)
+				windowWall := windowEnd.Sub(windowStart).Hours()
+				for i, day := range days {
+					from, to := clipWindow(windowStart, windowEnd, day, day.AddDate(0, 0, 1))
+					if !to.After(from) {
+						continue
+					}
+					dayWork := calendar.WorkingHoursBetween(from, to)
+					switch {
+					case ma.TargetHours <= 0:
+						planned[i] += dayWork
+					case windowWork > 0:
+						planned[i] += ma.TargetHours * dayWork / windowWork
+					default:
+						// Window lies entirely outside working time: spread over the clock
+						planned[i] += ma.TargetHours * to.Sub(from).Hours() / windowWall
+					}
+				}
+			}
+
+			if ma.ActualStart != nil {
+				actualEnd := now
+				if ma.ActualEnd != nil {
+					actualEnd = *ma.ActualEnd
+				}
+				if actualEnd.After(rangeEnd) {
+					actualEnd = rangeEnd
+				}
+				for i, day := range days {
+					from, to := clipWindow(*ma.ActualStart, actualEnd, day, day.AddDate(0, 0, 1))
+					if to.After(from) {
+						actual[i] += to.Sub(from).Hours()
+					}
+				}
+			}
+		}
+
+		result := models.MachineUtilization{
+			MachineID:   machine.ID,
+			MachineCode: machine.MachineCode,
+			MachineName: machine.MachineName,
+			MachineType: machine.MachineType,
+			Location:    machine.Location,
+			Status:      machine.Status,
+			Periods:     []models.MachineUtilizationPeriod{},
+		}
+		var totalPlanned, totalActual, totalAvailable float64
+		for i := 0; i < len(days); {
+			j := i + 1
+			if period == models.UtilizationPeriodWeek {
+				for j < len(days) && days[j].Weekday() != time.Monday {
+					j++
+				}
+			}
+
+			var p, a, av float64
+			for k := i; k < j; k++ {
+				p += planned[k]
+				a += actual[k]
+				av += available[k]
+			}
+			bucket := utilizationPeriod(days[i], days[j-1], p, a, av)
+			if bucket.Overloaded {
+				result.OverloadedPeriods++
+			}
+			result.Periods = append(result.Periods, bucket)
+
+			totalPlanned += p
+			totalActual += a
+			totalAvailable += av
+			i = j
+		}
+		result.Total = utilizationPeriod(start, end, totalPlanned, totalActual, totalAvailable)
+
+		if result.OverloadedPeriods > 0 {
+			report.OverloadedMachines++
+		}
+		report.TotalPlannedHours += result.Total.PlannedHours
+		report.TotalAvailableHours += result.Total.AvailableHours
+		report.Machines = append(report.Machines, result)
+	}
+
+	report.TotalPlannedHours = roundHours(report.TotalPlannedHours)
+	report.TotalAvailableHours = roundHours(report.TotalAvailableHours)
+	return report
+}
+
+func utilizationPeriod(first, last time.Time, planned, actual, available float64) models.MachineUtilizationPeriod {
+	bucket := models.MachineUtilizationPeriod{
+		PeriodStart:    first.Format("2006-01-02"),
+		PeriodEnd:      last.Format("2006-01-02"),
+		PlannedHours:   roundHours(planned),
+		ActualHours:    roundHours(actual),
+		AvailableHours: roundHours(available),
+	}
+	if bucket.AvailableHours > 0 {
+		bucket.LoadPercent = math.Round(bucket.PlannedHours/bucket.AvailableHours*1000) / 10
+		bucket.UtilizationPercent = math.Round(bucket.ActualHours/bucket.AvailableHours*1000) / 10
+	}
+	bucket.Overloaded = bucket.PlannedHours > bucket.AvailableHours
+	return bucket
+}
+
+// clipWindow limits the window [start, end) to [from, to)
+func clipWindow(start, end, from, to time.Time) (time.Time, time.Time) {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	return start, end
+}
+
+func roundHours(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
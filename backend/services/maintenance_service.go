@@ -0,0 +1,410 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+)
+
+type MaintenanceService struct {
+	repo        *repository.MaintenanceRepository
+	machineRepo *repository.MachineRepository
+}
+
+func NewMaintenanceService(repo *repository.MaintenanceRepository, machineRepo *repository.MachineRepository) *MaintenanceService {
+	return &MaintenanceService{repo: repo, machineRepo: machineRepo}
+}
+
+// ========== Plans ==========
+
+// GetPlans returns plans with their due state, optionally of one machine.
+// Due plans get their work orders generated first
+func (s *MaintenanceService) GetPlans(machineID int64) ([]models.MaintenancePlanStatus, error) {
+	now := time.Now()
+	if _, err := s.GenerateDueWorkOrders(now); err != nil {
+		return nil, err
+	}
+	plans, err := s.repo.GetPlans(machineID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance plans: %w", err)
+	}
+	return s.evaluate(plans, now)
+}
+
+// GetPlan returns a plan with its due state
+func (s *MaintenanceService) GetPlan(id int64) (*models.MaintenancePlanStatus, error) {
+	plan, err := s.repo.GetPlanByID(id)
+	if err != nil {
+		return nil, errors.New("maintenance plan not found")
+	}
+	statuses, err := s.evaluate([]models.MaintenancePlan{*plan}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &statuses[0], nil
+}
+
+// CreatePlan validates and stores a plan. Its first interval starts at last_completed_at, or now
+func (s *MaintenanceService) CreatePlan(req *models.CreateMaintenancePlanRequest, createdBy int64) (*models.MaintenancePlanStatus, error) {
+	if machine, err := s.machineRepo.GetByID(req.MachineID); err != nil || machine == nil {
+		return nil, fmt.Errorf("machine %d not found", req.MachineID)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if err := models.ValidateMaintenanceIntervals(req.IntervalDays, req.IntervalRunHours); err != nil {
+		return nil, err
+	}
+
+	lastCompleted := time.Now()
+	if req.LastCompletedAt != "" {
+		parsed, err := parseMaintenanceTime("last_completed_at", req.LastCompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		lastCompleted = parsed
+	}
+
+	plan := &models.MaintenancePlan{
+		MachineID:        req.MachineID,
+		Name:             name,
+		Description:      req.Description,
+		IntervalDays:     req.IntervalDays,
+		IntervalRunHours: req.IntervalRunHours,
+		EstimatedHours:   req.EstimatedHours,
+		Active:           true,
+		LastCompletedAt:  lastCompleted,
+		CreatedBy:        createdBy,
+	}
+	if err := s.repo.CreatePlan(plan); err != nil {
+		return nil, fmt.Errorf("failed to create maintenance plan: %w", err)
+	}
+	return s.GetPlan(plan.ID)
+}
+
+// UpdatePlan changes a plan; omitted fields are kept and an interval of 0 is removed
+func (s *MaintenanceService) UpdatePlan(id int64, req *models.UpdateMaintenancePlanRequest) (*models.MaintenancePlanStatus, error) {
+	plan, err := s.repo.GetPlanByID(id)
+	if err != nil {
+		return nil, errors.New("maintenance plan not found")
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		plan.Name = name
+	}
+	if req.Description != nil {
+		plan.Description = *req.Description
+	}
+	if req.IntervalDays != nil {
+		plan.IntervalDays = req.IntervalDays
+		if *req.IntervalDays == 0 {
+			plan.IntervalDays = nil
+		}
+	}
+	if req.IntervalRunHours != nil {
+		plan.IntervalRunHours = req.IntervalRunHours
+		if *req.IntervalRunHours == 0 {
+			plan.IntervalRunHours = nil
+		}
+	}
+	if err := models.ValidateMaintenanceIntervals(plan.IntervalDays, plan.IntervalRunHours); err != nil {
+		return nil, err
+	}
+	if req.EstimatedHours != nil {
+		plan.EstimatedHours = *req.EstimatedHours
+	}
+	if req.Active != nil {
+		plan.Active = *req.Active
+	}
+
+	if err := s.repo.UpdatePlan(plan); err != nil {
+		return nil, fmt.Errorf("failed to update maintenance plan: %w", err)
+	}
+	return s.GetPlan(id)
+}
+
+// DeletePlan removes a plan; its work orders are kept
+func (s *MaintenanceService) DeletePlan(id int64) error {
+	if err := s.repo.DeletePlan(id); err != nil {
+		return errors.New("maintenance plan not found")
+	}
+	return nil
+}
+
+// GetOverdue returns the active plans past due, most days overdue first
+func (s *MaintenanceService) GetOverdue() ([]models.MaintenancePlanStatus, error) {
+	now := time.Now()
+	if _, err := s.GenerateDueWorkOrders(now); err != nil {
+		return nil, err
+	}
+	plans, err := s.repo.GetPlans(0, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance plans: %w", err)
+	}
+	statuses, err := s.evaluate(plans, now)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := []models.MaintenancePlanStatus{}
+	for _, status := range statuses {
+		if status.Overdue {
+			overdue = append(overdue, status)
+		}
+	}
+	models.SortOverdueMaintenance(overdue)
+	return overdue, nil
+}
+
+// evaluate adds the run hours, due state, machine name and open work order to plans
+func (s *MaintenanceService) evaluate(plans []models.MaintenancePlan, now time.Time) ([]models.MaintenancePlanStatus, error) {
+	machines, err := s.machineRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machines: %w", err)
+	}
+	machineNames := make(map[int64]string, len(machines))
+	for _, m := range machines {
+		machineNames[m.ID] = m.MachineName
+	}
+
+	statuses := make([]models.MaintenancePlanStatus, 0, len(plans))
+	for _, plan := range plans {
+		status, err := s.planStatus(plan, now)
+		if err != nil {
+			return nil, err
+		}
+		status.MachineName = machineNames[plan.MachineID]
+
+		open, err := s.repo.GetOpenWorkOrder(plan.ID)
+		if err != nil {
+			return nil, err
+		}
+		if open != nil {
+			status.OpenWorkOrderID = &open.ID
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *MaintenanceService) planStatus(plan models.MaintenancePlan, now time.Time) (models.MaintenancePlanStatus, error) {
+	var runHours float64
+	if plan.IntervalRunHours != nil {
+		intervals, err := s.machineRepo.GetRunIntervals(plan.MachineID, plan.LastCompletedAt)
+		if err != nil {
+			return models.MaintenancePlanStatus{}, fmt.Errorf("failed to get machine run time: %w", err)
+		}
+		runHours = models.RunHoursBetween(intervals, plan.LastCompletedAt, now)
+	}
+	return models.EvaluateMaintenancePlan(plan, runHours, now), nil
+}
+
+// ========== Work Orders ==========
+
+// GenerateDueWorkOrders creates a work order for each active plan that is due. A plan gets one work
+// order per interval: none while one is open, nor after one was cancelled in the same interval.
+// The check and the insert hold the plan's row lock, so concurrent runs create one work order at most
+func (s *MaintenanceService) GenerateDueWorkOrders(now time.Time) ([]models.MaintenanceWorkOrder, error) {
+	plans, err := s.repo.GetPlans(0, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance plans: %w", err)
+	}
+
+	generated := []models.MaintenanceWorkOrder{}
+	for _, plan := range plans {
+		status, err := s.planStatus(plan, now)
+		if err != nil {
+			return nil, err
+		}
+		if !status.Due {
+			continue
+		}
+
+		var created *models.MaintenanceWorkOrder
+		err = s.repo.WithLockedPlan(plan.ID, func(repo *repository.MaintenanceRepository, locked *models.MaintenancePlan) error {
+			// Completed, paused or deleted since its status was evaluated: the next run decides
+			if locked == nil || !locked.Active || !locked.LastCompletedAt.Equal(plan.LastCompletedAt) {
+				return nil
+			}
+			open, err := repo.GetOpenWorkOrder(plan.ID)
+			if err != nil || open != nil {
+				return err
+			}
+			cancelled, err := repo.HasCancelledWorkOrderSince(plan.ID, plan.LastCompletedAt)
+			if err != nil || cancelled {
+				return err
+			}
+
+			order := models.NewMaintenanceWorkOrder(status)
+			if err := repo.CreateWorkOrder(order); err != nil {
+				return fmt.Errorf("failed to create maintenance work order: %w", err)
+			}
+			created = order
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if created != nil {
+			generated = append(generated, *created)
+		}
+	}
+	return generated, nil
+}
+
+// GetWorkOrders returns work orders, newest first. Due plans get their work orders generated first
+func (s *MaintenanceService) GetWorkOrders(filter models.MaintenanceWorkOrderFilterRequest) ([]models.MaintenanceWorkOrder, error) {
+	if filter.Status != "" && !models.ValidateWorkOrderStatus(filter.Status) {
+		return nil, errors.New("invalid status. Must be: open, in_progress, completed or cancelled")
+	}
+	if _, err := s.GenerateDueWorkOrders(time.Now()); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWorkOrders(filter)
+}
+
+// GetWorkOrder returns a single work order
+func (s *MaintenanceService) GetWorkOrder(id int64) (*models.MaintenanceWorkOrder, error) {
+	order, err := s.repo.GetWorkOrderByID(id)
+	if err != nil {
+		return nil, errors.New("maintenance work order not found")
+	}
+	return order, nil
+}
+
+// CreateWorkOrder raises a work order for a plan by hand, e.g. to do the maintenance early
+func (s *MaintenanceService) CreateWorkOrder(planID int64, req *models.CreateMaintenanceWorkOrderRequest, createdBy int64) (*models.MaintenanceWorkOrder, error) {
+	plan, err := s.repo.GetPlanByID(planID)
+	if err != nil {
+		return nil, errors.New("maintenance plan not found")
+	}
+	status, err := s.planStatus(*plan, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	order := models.NewMaintenanceWorkOrder(status)
+	order.Trigger = models.WorkOrderTriggerManual
+	order.Notes = req.Notes
+	order.CreatedBy = &createdBy
+	if title := strings.TrimSpace(req.Title); title != "" {
+		order.Title = title
+	}
+
+	// Checked and stored under the plan's lock, so a generation run can't add a second open work order
+	err = s.repo.WithLockedPlan(planID, func(repo *repository.MaintenanceRepository, locked *models.MaintenancePlan) error {
+		if locked == nil {
+			return errors.New("maintenance plan not found")
+		}
+		open, err := repo.GetOpenWorkOrder(planID)
+		if err != nil {
+			return err
+		}
+		if open != nil {
+			return fmt.Errorf("plan already has open work order %d", open.ID)
+		}
+		if err := repo.CreateWorkOrder(order); err != nil {
+			return fmt.Errorf("failed to create maintenance work order: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// StartWorkOrder marks a work order in progress
+func (s *MaintenanceService) StartWorkOrder(id int64) (*models.MaintenanceWorkOrder, error) {
+	order, err := s.GetWorkOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.CheckWorkOrderTransition(order.Status, models.WorkOrderStatusInProgress); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	order.Status = models.WorkOrderStatusInProgress
+	order.StartedAt = &now
+	if err := s.repo.UpdateWorkOrder(order); err != nil {
+		return nil, fmt.Errorf("failed to update maintenance work order: %w", err)
+	}
+	return order, nil
+}
+
+// CompleteWorkOrder records who did the work and restarts the plan's interval from the completion
+func (s *MaintenanceService) CompleteWorkOrder(id int64, req *models.CompleteMaintenanceWorkOrderRequest, userID int64) (*models.MaintenanceWorkOrder, error) {
+	order, err := s.GetWorkOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.CheckWorkOrderTransition(order.Status, models.WorkOrderStatusCompleted); err != nil {
+		return nil, err
+	}
+	technician := strings.TrimSpace(req.Technician)
+	if technician == "" {
+		return nil, errors.New("technician is required")
+	}
+
+	completedAt := time.Now()
+	if req.CompletedAt != "" {
+		parsed, err := parseMaintenanceTime("completed_at", req.CompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.After(completedAt) {
+			return nil, errors.New("completed_at cannot be in the future")
+		}
+		completedAt = parsed
+	}
+	if order.StartedAt != nil && completedAt.Before(*order.StartedAt) {
+		return nil, errors.New("completed_at must be after the work was started")
+	}
+
+	order.Status = models.WorkOrderStatusCompleted
+	order.Technician = technician
+	if req.Notes != "" {
+		order.Notes = req.Notes
+	}
+	order.CompletedAt = &completedAt
+	order.CompletedBy = &userID
+	if err := s.repo.CompleteWorkOrder(order, completedAt); err != nil {
+		return nil, fmt.Errorf("failed to complete maintenance work order: %w", err)
+	}
+	return order, nil
+}
+
+// CancelWorkOrder cancels a work order. The plan stays due and overdue, but no new work order is
+// generated for it until its interval restarts; one can still be raised by hand
+func (s *MaintenanceService) CancelWorkOrder(id int64, notes string) (*models.MaintenanceWorkOrder, error) {
+	order, err := s.GetWorkOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.CheckWorkOrderTransition(order.Status, models.WorkOrderStatusCancelled); err != nil {
+		return nil, err
+	}
+
+	order.Status = models.WorkOrderStatusCancelled
+	if notes != "" {
+		order.Notes = notes
+	}
+	if err := s.repo.UpdateWorkOrder(order); err != nil {
+		return nil, fmt.Errorf("failed to update maintenance work order: %w", err)
+	}
+	return order, nil
+}
+
+func parseMaintenanceTime(field, value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid %s format. Use RFC3339 or YYYY-MM-DD", field)
+}
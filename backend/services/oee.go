@@ -0,0 +1,272 @@
+package services
+
+import (
+	"ganttpro-backend/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// oeeBucket is the time one shift, day or week of the report covers
+type oeeBucket struct {
+	from, to    time.Time
+	periodStart string
+	periodEnd   string
+	shift       string
+}
+
+// oeeTotals are the sums the OEE rates of a bucket, machine or the plant are computed from
+type oeeTotals struct {
+	planned, run, ideal float64
+	good, scrap         int
+}
+
+func (t *oeeTotals) add(other oeeTotals) {
+	t.planned += other.planned
+	t.run += other.run
+	t.ideal += other.ideal
+	t.good += other.good
+	t.scrap += other.scrap
+}
+
+type timeSpan struct {
+	start, end time.Time
+}
+
+// BuildOEEReport computes the OEE per machine and shift, day or week for the days start..end (inclusive).
+// A shift also takes the time up to the next shift, so overtime after it and parts reported after the
+// bell count for it. Only elapsed time is planned: a period still running counts up to now
+func BuildOEEReport(machines []models.Machine, calendars map[int64]*models.WorkingCalendar, data map[int64]models.OEEMachineData, start, end time.Time, period string, now time.Time) *models.OEEReport {
+	report := &models.OEEReport{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Period:    period,
+		Machines:  []models.MachineOEE{},
+	}
+	whole := oeeBucket{periodStart: report.StartDate, periodEnd: report.EndDate}
+
+	var plant oeeTotals
+	for _, machine := range machines {
+		calendar := calendars[machine.ID]
+		if calendar == nil {
+			calendar = models.NewWorkingCalendar(nil, nil)
+		}
+
+		result := models.MachineOEE{
+			MachineID:   machine.ID,
+			MachineCode: machine.MachineCode,
+			MachineName: machine.MachineName,
+			MachineType: machine.MachineType,
+			Location:    machine.Location,
+			Periods:     []models.OEEPeriod{},
+		}
+		var total oeeTotals
+		for _, bucket := range oeeBuckets(calendar, start, end, period) {
+			totals := measureOEE(calendar, data[machine.ID], bucket.from, bucket.to, now)
+			result.Periods = append(result.Periods, oeePeriod(bucket, totals))
+			total.add(totals)
+		}
+		result.Total = oeePeriod(whole, total)
+
+		plant.add(total)
+		report.Machines = append(report.Machines, result)
+	}
+	report.Total = oeePeriod(whole, plant)
+	return report
+}
+
+// oeeBuckets splits the days start..end into shifts, days or weeks (Monday to Sunday)
+func oeeBuckets(calendar *models.WorkingCalendar, start, end time.Time, period string) []oeeBucket {
+	rangeEnd := end.AddDate(0, 0, 1)
+	var buckets []oeeBucket
+
+	if period == models.OEEPeriodShift {
+		var shifts []models.ShiftPeriod
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			shifts = append(shifts, calendar.ShiftsOn(d)...)
+		}
+		for i, shift := range shifts {
+			to := rangeEnd
+			if shift.End.After(to) {
+				to = shift.End
+			}
+			if i+1 < len(shifts) {
+				to = shifts[i+1].Start
+			}
+			buckets = append(buckets, oeeBucket{
+				from:        shift.Start,
+				to:          to,
+				periodStart: shift.Start.Format(time.RFC3339),
+				periodEnd:   shift.End.Format(time.RFC3339),
+				shift:       shift.Name,
+			})
+		}
+		return buckets
+	}
+
+	for d := start; d.Before(rangeEnd); {
+		next := d.AddDate(0, 0, 1)
+		if period == models.UtilizationPeriodWeek {
+			for next.Before(rangeEnd) && next.Weekday() != time.Monday {
+				next = next.AddDate(0, 0, 1)
+			}
+		}
+		buckets = append(buckets, oeeBucket{
+			from:        d,
+			to:          next,
+			periodStart: d.Format("2006-01-02"),
+			periodEnd:   next.AddDate(0, 0, -1).Format("2006-01-02"),
+		})
+		d = next
+	}
+	return buckets
+}
+
+// measureOEE sums planned time, run time and reported parts of one machine in [from, to).
+// Run time is the union of the machine's runs less andon stops and breakdowns. Planned time is the
+// working time less planned maintenance and calibration, plus any run time outside of it
+func measureOEE(calendar *models.WorkingCalendar, data models.OEEMachineData, from, to, now time.Time) oeeTotals {
+	var totals oeeTotals
+	for _, r := range data.Reports {
+		if !r.ProducedAt.Before(from) && r.ProducedAt.Before(to) {
+			totals.good += r.GoodQuantity
+			totals.scrap += r.ScrapQuantity
+			totals.ideal += r.IdealHours()
+		}
+	}
+
+	if to.After(now) {
+		to = now
+	}
+	if !to.After(from) {
+		return totals
+	}
+
+	var runs, stops, plannedOff []timeSpan
+	for _, run := range data.Runs {
+		runs = append(runs, timeSpan{run.Start, endOrNow(run.End, now)})
+	}
+	for _, event := range data.Stops {
+		stops = append(stops, timeSpan{event.StartTime, endOrNow(event.EndTime, now)})
+	}
+	for _, d := range data.Downtime {
+		if d.Type == models.DowntimeBreakdown {
+			stops = append(stops, timeSpan{d.StartTime, d.EndTime})
+		} else {
+			plannedOff = append(plannedOff, timeSpan{d.StartTime, d.EndTime})
+		}
+	}
+	runs = subtractSpans(clipSpans(mergeSpans(runs), from, to), mergeSpans(stops))
+	plannedOff = clipSpans(mergeSpans(plannedOff), from, to)
+
+	totals.planned = calendar.WorkingHoursBetween(from, to)
+	for _, s := range plannedOff {
+		totals.planned -= calendar.WorkingHoursBetween(s.start, s.end)
+	}
+	for _, s := range runs {
+		totals.run += s.end.Sub(s.start).Hours()
+	}
+	// Run time counts as planned wherever it falls outside the planned working time
+	totals.planned += totals.run
+	for _, s := range subtractSpans(runs, plannedOff) {
+		totals.planned -= calendar.WorkingHoursBetween(s.start, s.end)
+	}
+	return totals
+}
+
+func oeePeriod(bucket oeeBucket, totals oeeTotals) models.OEEPeriod {
+	result := models.OEEPeriod{
+		PeriodStart:   bucket.periodStart,
+		PeriodEnd:     bucket.periodEnd,
+		Shift:         bucket.shift,
+		PlannedHours:  roundHours(totals.planned),
+		RunHours:      roundHours(totals.run),
+		DowntimeHours: roundHours(math.Max(totals.planned-totals.run, 0)),
+		IdealHours:    roundHours(totals.ideal),
+		GoodQuantity:  totals.good,
+		ScrapQuantity: totals.scrap,
+	}
+
+	var availability, performance, quality float64
+	if totals.planned > 0 {
+		availability = totals.run / totals.planned
+	}
+	if totals.run > 0 {
+		performance = totals.ideal / totals.run
+	}
+	if parts := totals.good + totals.scrap; parts > 0 {
+		quality = float64(totals.good) / float64(parts)
+	}
+	result.Availability = oeePercent(availability)
+	result.Performance = oeePercent(performance)
+	result.Quality = oeePercent(quality)
+	result.OEE = oeePercent(availability * performance * quality)
+	return result
+}
+
+func oeePercent(rate float64) float64 {
+	return math.Round(rate*1000) / 10
+}
+
+func endOrNow(end *time.Time, now time.Time) time.Time {
+	if end == nil {
+		return now
+	}
+	return *end
+}
+
+// mergeSpans sorts spans and joins the overlapping ones, dropping empty spans
+func mergeSpans(spans []timeSpan) []timeSpan {
+	sorted := make([]timeSpan, 0, len(spans))
+	for _, s := range spans {
+		if s.end.After(s.start) {
+			sorted = append(sorted, s)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start.Before(sorted[j].start) })
+
+	var merged []timeSpan
+	for _, s := range sorted {
+		if n := len(merged); n > 0 && !s.start.After(merged[n-1].end) {
+			if s.end.After(merged[n-1].end) {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// clipSpans limits spans to [from, to), dropping those outside it
+func clipSpans(spans []timeSpan, from, to time.Time) []timeSpan {
+	var clipped []timeSpan
+	for _, s := range spans {
+		start, end := clipWindow(s.start, s.end, from, to)
+		if end.After(start) {
+			clipped = append(clipped, timeSpan{start, end})
+		}
+	}
+	return clipped
+}
+
+// subtractSpans removes the merged spans cut from spans
+func subtractSpans(spans, cut []timeSpan) []timeSpan {
+	var result []timeSpan
+	for _, s := range spans {
+		start := s.start
+		for _, c := range cut {
+			if !c.end.After(start) || !c.start.Before(s.end) {
+				continue
+			}
+			if c.start.After(start) {
+				result = append(result, timeSpan{start, c.start})
+			}
+			start = c.end
+		}
+		if s.end.After(start) {
+			result = append(result, timeSpan{start, s.end})
+		}
+	}
+	return result
+}
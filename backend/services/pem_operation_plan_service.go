@@ -17,6 +17,7 @@ type PEMOperationPlanService struct {
 	repo             *repository.PEMOperationPlanRepository
 	userRepo         *repository.UserRepository
 	ppicScheduleRepo *repository.PPICScheduleRepository
+	historyRepo      *repository.PPICHistoryRepository
 	emailService     *EmailService
 	uploadDir        string
 }
@@ -25,6 +26,7 @@ func NewPEMOperationPlanService(
 	repo *repository.PEMOperationPlanRepository,
 	userRepo *repository.UserRepository,
 	ppicScheduleRepo *repository.PPICScheduleRepository,
+	historyRepo *repository.PPICHistoryRepository,
 	emailService *EmailService,
 	uploadDir string,
 ) *PEMOperationPlanService {
@@ -37,6 +39,7 @@ func NewPEMOperationPlanService(
 		repo:             repo,
 		userRepo:         userRepo,
 		ppicScheduleRepo: ppicScheduleRepo,
+		historyRepo:      historyRepo,
 		emailService:     emailService,
 		uploadDir:        uploadDir,
 	}
@@ -106,8 +109,12 @@ func (s *PEMOperationPlanService) GetPlansByPPICSchedule(scheduleID int64) ([]mo
 	return s.repo.FindAll(filters)
 }
 
-// UpdatePlan updates an existing plan
+// UpdatePlan updates an existing plan last read at request.Version
 func (s *PEMOperationPlanService) UpdatePlan(id int64, request models.UpdatePEMPlanRequest, userID int64) error {
+	if request.Version == nil {
+		return models.ErrVersionRequired
+	}
+
 	plan, err := s.repo.FindByID(id)
 	if err != nil {
 		return fmt.Errorf("plan not found: %w", err)
@@ -145,7 +152,8 @@ func (s *PEMOperationPlanService) UpdatePlan(id int64, request models.UpdatePEMP
 		plan.Page = request.Page
 	}
 
-	return s.repo.Update(plan)
+	// Steps added or changed since the plan was read also count: they bump the plan's version
+	return s.repo.Update(plan, *request.Version)
 }
 
 // DeletePlan deletes a plan (only drafts can be deleted)
@@ -514,6 +522,7 @@ func (s *PEMOperationPlanService) ApprovePlan(planID int64, approverID int64, ro
 
 		// Auto-update PPIC schedule status to "in_progress"
 		if plan.PPICScheduleID != nil {
+			before, _ := s.ppicScheduleRepo.GetByID(*plan.PPICScheduleID)
 			updateReq := &models.UpdatePPICScheduleRequest{
 				Status: "in_progress",
 			}
@@ -523,6 +532,9 @@ func (s *PEMOperationPlanService) ApprovePlan(planID int64, approverID int64, ro
 				fmt.Printf("Warning: failed to update PPIC schedule status: %v\n", err)
 			} else {
 				fmt.Printf("PPIC schedule %d status updated to 'in_progress'\n", *plan.PPICScheduleID)
+				if after, err := s.ppicScheduleRepo.GetByID(*plan.PPICScheduleID); err == nil {
+					recordScheduleChanges(s.historyRepo, before, after, approverID, models.ChangeCausePEMApproved)
+				}
 			}
 		}
 	}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrBaselineNotFound is returned when a baseline ID does not exist
+var ErrBaselineNotFound = errors.New("baseline not found")
+
+type PPICBaselineService struct {
+	repo     *repository.PPICBaselineRepository
+	ppicRepo *repository.PPICScheduleRepository
+}
+
+func NewPPICBaselineService(repo *repository.PPICBaselineRepository, ppicRepo *repository.PPICScheduleRepository) *PPICBaselineService {
+	return &PPICBaselineService{
+		repo:     repo,
+		ppicRepo: ppicRepo,
+	}
+}
+
+// CreateBaseline freezes the current live plan as a named baseline
+func (s *PPICBaselineService) CreateBaseline(req *models.CreatePPICBaselineRequest, createdBy int64) (*models.PPICBaseline, error) {
+	return s.repo.Create(req, createdBy)
+}
+
+// GetAllBaselines returns all baselines
+func (s *PPICBaselineService) GetAllBaselines() ([]models.PPICBaseline, error) {
+	return s.repo.GetAll()
+}
+
+// GetBaseline returns a single baseline
+func (s *PPICBaselineService) GetBaseline(id int64) (*models.PPICBaseline, error) {
+	baseline, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if baseline == nil {
+		return nil, ErrBaselineNotFound
+	}
+	return baseline, nil
+}
+
+// GetBaselineSchedules returns the dates captured in a baseline
+func (s *PPICBaselineService) GetBaselineSchedules(id int64) ([]models.BaselineSchedule, error) {
+	if _, err := s.GetBaseline(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSchedules(id)
+}
+
+// DeleteBaseline removes a baseline
+func (s *PPICBaselineService) DeleteBaseline(id int64) error {
+	if _, err := s.GetBaseline(id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// GetVariance compares the live plan and actuals against a baseline
+func (s *PPICBaselineService) GetVariance(id int64) (*models.BaselineVarianceResponse, error) {
+	baseline, err := s.GetBaseline(id)
+	if err != nil {
+		return nil, err
+	}
+
+	captured, err := s.repo.GetSchedules(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline schedules: %w", err)
+	}
+	current, err := s.ppicRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schedules: %w", err)
+	}
+
+	response := ComputeBaselineVariance(captured, current)
+	response.Baseline = baseline
+	return response, nil
+}
+
+// ComputeBaselineVariance compares current schedules and machine assignments against baseline dates.
+// Slip is in days; positive means later than planned
+func ComputeBaselineVariance(baseline []models.BaselineSchedule, current []models.PPICSchedule) *models.BaselineVarianceResponse {
+	response := &models.BaselineVarianceResponse{Schedules: []models.ScheduleVariance{}}
+
+	byScheduleID := make(map[int64]*models.BaselineSchedule, len(baseline))
+	for i := range baseline {
+		byScheduleID[baseline[i].ScheduleID] = &baseline[i]
+	}
+
+	seen := make(map[int64]bool)
+	totalSlip := 0
+	for _, schedule := range current {
+		variance := models.ScheduleVariance{
+			ScheduleID:        schedule.ID,
+			NJO:               schedule.NJO,
+			PartName:          schedule.PartName,
+			Status:            schedule.Status,
+			CurrentStartDate:  schedule.StartDate,
+			CurrentFinishDate: schedule.FinishDate,
+			Assignments:       []models.AssignmentVariance{},
+		}
+
+		captured := byScheduleID[schedule.ID]
+		if captured != nil {
+			seen[schedule.ID] = true
+			start, finish := captured.StartDate, captured.FinishDate
+			startSlip := slipDays(start, schedule.StartDate)
+			finishSlip := slipDays(finish, schedule.FinishDate)
+			variance.InBaseline = true
+			variance.BaselineStartDate, variance.BaselineFinishDate = &start, &finish
+			variance.StartSlipDays, variance.FinishSlipDays = &startSlip, &finishSlip
+
+			switch {
+			case finishSlip > 0:
+				response.Summary.LateCount++
+			case finishSlip < 0:
+				response.Summary.EarlyCount++
+			default:
+				response.Summary.OnTrackCount++
+			}
+			totalSlip += finishSlip
+			if finishSlip > response.Summary.MaxFinishSlipDays {
+				response.Summary.MaxFinishSlipDays = finishSlip
+			}
+		} else {
+			response.Summary.NotInBaselineCount++
+		}
+
+		for _, ma := range schedule.MachineAssignments {
+			variance.Assignments = append(variance.Assignments, assignmentVariance(ma, captured))
+		}
+		response.Schedules = append(response.Schedules, variance)
+	}
+
+	for _, captured := range baseline {
+		if !seen[captured.ScheduleID] {
+			response.Summary.RemovedCount++
+		}
+	}
+
+	response.Summary.TotalSchedules = len(response.Schedules)
+	if compared := len(response.Schedules) - response.Summary.NotInBaselineCount; compared > 0 {
+		response.Summary.AverageFinishSlipDays = math.Round(float64(totalSlip)/float64(compared)*100) / 100
+	}
+
+	// Worst slip first
+	sort.SliceStable(response.Schedules, func(i, j int) bool {
+		return finishSlipOf(response.Schedules[i]) > finishSlipOf(response.Schedules[j])
+	})
+
+	return response
+}
+
+func assignmentVariance(ma models.MachineAssignment, captured *models.BaselineSchedule) models.AssignmentVariance {
+	variance := models.AssignmentVariance{
+		AssignmentID:   ma.ID,
+		MachineID:      ma.MachineID,
+		MachineName:    ma.MachineName,
+		Sequence:       ma.Sequence,
+		Status:         ma.Status,
+		ScheduledStart: ma.ScheduledStart,
+		ScheduledEnd:   ma.ScheduledEnd,
+		ActualStart:    ma.ActualStart,
+		ActualEnd:      ma.ActualEnd,
+	}
+
+	if captured != nil {
+		for _, a := range captured.Assignments {
+			if a.Sequence == ma.Sequence {
+				variance.BaselineStart, variance.BaselineEnd = a.ScheduledStart, a.ScheduledEnd
+				break
+			}
+		}
+	}
+
+	variance.BaselineSlipDays = fractionalSlipDays(variance.BaselineEnd, ma.ScheduledEnd)
+	variance.ActualStartSlipDays = fractionalSlipDays(ma.ScheduledStart, ma.ActualStart)
+	variance.ActualEndSlipDays = fractionalSlipDays(ma.ScheduledEnd, ma.ActualEnd)
+	return variance
+}
+
+// slipDays returns whole days between two schedule dates
+func slipDays(planned, current time.Time) int {
+	return int(math.Round(current.Sub(planned).Hours() / 24))
+}
+
+// fractionalSlipDays returns days between two timestamps rounded to 0.01, or nil if either is missing
+func fractionalSlipDays(planned, current *time.Time) *float64 {
+	if planned == nil || current == nil {
+		return nil
+	}
+	days := math.Round(current.Sub(*planned).Hours()/24*100) / 100
+	return &days
+}
+
+func finishSlipOf(variance models.ScheduleVariance) int {
+	if variance.FinishSlipDays == nil {
+		return math.MinInt
+	}
+	return *variance.FinishSlipDays
+}
+
+// applyBaseline adds baseline dates and finish slip to Gantt tasks and their machines
+func (s *GanttService) applyBaseline(sections []models.GanttSection, baseline []models.BaselineSchedule) {
+	byTaskID := make(map[string]*models.BaselineSchedule, len(baseline))
+	for i := range baseline {
+		byTaskID[fmt.Sprintf("task-%d", baseline[i].ScheduleID)] = &baseline[i]
+	}
+
+	for i := range sections {
+		for j := range sections[i].Tasks {
+			task := &sections[i].Tasks[j]
+			captured, ok := byTaskID[task.TaskID]
+			if !ok {
+				continue
+			}
+			start, finish := captured.StartDate, captured.FinishDate
+			slip := slipDays(finish, task.End)
+			task.BaselineStart, task.BaselineEnd, task.SlipDays = &start, &finish, &slip
+
+			for k := range task.Machines {
+				for _, a := range captured.Assignments {
+					if a.Sequence == task.Machines[k].Sequence {
+						task.Machines[k].BaselineStart, task.Machines[k].BaselineEnd = a.ScheduledStart, a.ScheduledEnd
+						break
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+	"time"
+)
+
+// recordScheduleChanges saves the field changes between two versions of a schedule.
+// History is best effort: a failure is logged and doesn't undo the change itself
+func recordScheduleChanges(historyRepo *repository.PPICHistoryRepository, before, after *models.PPICSchedule, userID int64, cause string) {
+	if historyRepo == nil || before == nil || after == nil {
+		return
+	}
+	saveScheduleChanges(historyRepo, models.DiffPPICSchedule(before, after), userID, cause)
+}
+
+// recordScheduleDeleted saves the deletion of a schedule
+func recordScheduleDeleted(historyRepo *repository.PPICHistoryRepository, schedule *models.PPICSchedule, userID int64, cause string) {
+	if historyRepo == nil || schedule == nil {
+		return
+	}
+	saveScheduleChanges(historyRepo, []models.PPICScheduleChange{{
+		ScheduleID: schedule.ID,
+		Entity:     models.ChangeEntitySchedule,
+		Field:      "deleted",
+		OldValue:   "false",
+		NewValue:   "true",
+	}}, userID, cause)
+}
+
+func saveScheduleChanges(historyRepo *repository.PPICHistoryRepository, changes []models.PPICScheduleChange, userID int64, cause string) {
+	if len(changes) == 0 {
+		return
+	}
+	now := time.Now()
+	for i := range changes {
+		changes[i].Cause = cause
+		changes[i].ChangedBy = userID
+		changes[i].ChangedAt = now
+	}
+	if err := historyRepo.Record(changes); err != nil {
+		fmt.Printf("Warning: failed to record schedule history: %v\n", err)
+	}
+}
+
+// GetPPICScheduleHistory returns the change history of a schedule, newest first
+func (s *GanttService) GetPPICScheduleHistory(id int64) ([]models.PPICScheduleChange, error) {
+	if _, err := s.GetPPICSchedule(id); err != nil {
+		return nil, err
+	}
+	return s.historyRepo.GetBySchedule(id)
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"fmt"
+	"ganttpro-backend/models"
+	"time"
+)
+
+// ScheduleImpactPlanner works out which schedules a change moves without writing anything.
+// Planned dates are kept in memory, so a schedule reached through several links sees the dates
+// it would already have. Dry runs report the plan; rescheduleWithCascade writes it
+type ScheduleImpactPlanner struct {
+	calendar    *models.WorkingCalendar
+	lookup      func(id int64) (*models.PPICSchedule, error)
+	sourceLinks map[int64][]models.PPICLink
+	targetLinks map[int64][]models.PPICLink
+
+	original map[int64]*models.PPICSchedule // As stored
+	planned  map[int64]*models.PPICSchedule // Copies carrying the planned dates
+	causes   map[int64]string
+	order    []int64 // Moved schedules in the order they were first moved
+
+	downtime     func(machineID int64) ([]models.MachineDowntime, error) // Optional, see UseDowntime
+	downtimeByID map[int64][]models.MachineDowntime
+}
+
+// NewScheduleImpactPlanner plans over the given links; lookup loads a schedule and returns nil if it doesn't exist
+func NewScheduleImpactPlanner(calendar *models.WorkingCalendar, links []models.PPICLink, lookup func(id int64) (*models.PPICSchedule, error)) *ScheduleImpactPlanner {
+	p := &ScheduleImpactPlanner{
+		calendar:    calendar,
+		lookup:      lookup,
+		sourceLinks: make(map[int64][]models.PPICLink),
+		targetLinks: make(map[int64][]models.PPICLink),
+		original:    make(map[int64]*models.PPICSchedule),
+		planned:     make(map[int64]*models.PPICSchedule),
+		causes:      make(map[int64]string),
+	}
+	for _, link := range links {
+		p.sourceLinks[link.SourceScheduleID] = append(p.sourceLinks[link.SourceScheduleID], link)
+		p.targetLinks[link.TargetScheduleID] = append(p.targetLinks[link.TargetScheduleID], link)
+	}
+	return p
+}
+
+// UseDowntime makes cascaded schedules skip the days their machines are down; lookup loads a machine's downtime
+func (p *ScheduleImpactPlanner) UseDowntime(lookup func(machineID int64) ([]models.MachineDowntime, error)) {
+	p.downtime = lookup
+	p.downtimeByID = make(map[int64][]models.MachineDowntime)
+}
+
+// scheduleDowntime returns the downtime of the machines a schedule is assigned to
+func (p *ScheduleImpactPlanner) scheduleDowntime(schedule *models.PPICSchedule) ([]models.MachineDowntime, error) {
+	if p.downtime == nil {
+		return nil, nil
+	}
+	var downtimes []models.MachineDowntime
+	for _, ma := range schedule.MachineAssignments {
+		machineDowntime, ok := p.downtimeByID[ma.MachineID]
+		if !ok {
+			var err error
+			if machineDowntime, err = p.downtime(ma.MachineID); err != nil {
+				return nil, err
+			}
+			p.downtimeByID[ma.MachineID] = machineDowntime
+		}
+		downtimes = append(downtimes, machineDowntime...)
+	}
+	return downtimes, nil
+}
+
+// Schedule returns a schedule carrying its planned dates, or nil if it doesn't exist
+func (p *ScheduleImpactPlanner) Schedule(id int64) (*models.PPICSchedule, error) {
+	if schedule, ok := p.planned[id]; ok {
+		return schedule, nil
+	}
+	stored, err := p.lookup(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedule %d: %w", id, err)
+	}
+	if stored == nil {
+		p.planned[id] = nil
+		return nil, nil
+	}
+	schedule := *stored
+	p.original[id] = stored
+	p.planned[id] = &schedule
+	return &schedule, nil
+}
+
+// Move plans new dates for a schedule. The cause of the first move is the one reported
+func (p *ScheduleImpactPlanner) Move(id int64, start, finish time.Time, cause string) error {
+	schedule, err := p.Schedule(id)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+	if _, moved := p.causes[id]; !moved {
+		p.causes[id] = cause
+		p.order = append(p.order, id)
+	}
+	schedule.StartDate, schedule.FinishDate = start, finish
+	return nil
+}
+
+// Stored returns a schedule as it was read, before any planned move
+func (p *ScheduleImpactPlanner) Stored(id int64) *models.PPICSchedule {
+	return p.original[id]
+}
+
+// Moved returns the moved schedules carrying their planned dates, in the order they were first
+// moved. Schedules planned back onto their stored dates are left out
+func (p *ScheduleImpactPlanner) Moved() []*models.PPICSchedule {
+	var moved []*models.PPICSchedule
+	for _, id := range p.order {
+		before, after := p.original[id], p.planned[id]
+		if !before.StartDate.Equal(after.StartDate) || !before.FinishDate.Equal(after.FinishDate) {
+			moved = append(moved, after)
+		}
+	}
+	return moved
+}
+
+// Cascade pushes every schedule depending on a schedule later until all its incoming links hold,
+// recursively. A dependent is never pulled earlier, so one whose constraints are already met stays put
+func (p *ScheduleImpactPlanner) Cascade(id int64) error {
+	source, err := p.Schedule(id)
+	if err != nil || source == nil {
+		return err
+	}
+	return p.cascade(source, map[int64]bool{id: true})
+}
+
+func (p *ScheduleImpactPlanner) cascade(source *models.PPICSchedule, visited map[int64]bool) error {
+	for _, link := range p.sourceLinks[source.ID] {
+		if visited[link.TargetScheduleID] {
+			return fmt.Errorf("dependency cycle detected at schedule %d", link.TargetScheduleID)
+		}
+		target, err := p.Schedule(link.TargetScheduleID)
+		if err != nil {
+			return err
+		}
+		if target == nil {
+			continue
+		}
+
+		newStartDate, newFinishDate, err := p.constrainedDates(target)
+		if err != nil {
+			return err
+		}
+		if newStartDate.Equal(target.StartDate) && newFinishDate.Equal(target.FinishDate) {
+			continue
+		}
+		if err := p.Move(target.ID, newStartDate, newFinishDate, models.CascadeChangeCause(source.NJO)); err != nil {
+			return err
+		}
+
+		visited[target.ID] = true
+		err = p.cascade(target, visited)
+		delete(visited, target.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// constrainedDates returns the planned dates of a schedule pushed onto the latest constraint among
+// its incoming links that it violates, keeping its number of working days, or its dates unchanged
+// when every link holds. Days its machines are down don't count, which only ever pushes it later
+func (p *ScheduleImpactPlanner) constrainedDates(target *models.PPICSchedule) (time.Time, time.Time, error) {
+	newStartDate, newFinishDate := target.StartDate, target.FinishDate
+	var calendar *models.WorkingCalendar
+	for _, link := range p.targetLinks[target.ID] {
+		source, err := p.Schedule(link.SourceScheduleID)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if source == nil || p.calendar.IsLinkSatisfied(link.LinkType, link.LagDays,
+			source.StartDate, source.FinishDate, target.StartDate, target.FinishDate) {
+			continue
+		}
+
+		if calendar == nil {
+			downtimes, err := p.scheduleDowntime(target)
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			calendar = p.calendar.WithDowntime(downtimes)
+		}
+		start, finish := calendar.LinkedTargetDates(link.LinkType, link.LagDays,
+			source.StartDate, source.FinishDate, target.StartDate, target.FinishDate)
+		if start.After(newStartDate) {
+			newStartDate, newFinishDate = start, finish
+		}
+	}
+	return newStartDate, newFinishDate, nil
+}
+
+// Changes lists the planned moves in the order the schedules were first moved
+func (p *ScheduleImpactPlanner) Changes() []models.ScheduleDateChange {
+	changes := []models.ScheduleDateChange{}
+	for _, after := range p.Moved() {
+		changes = append(changes, models.NewScheduleDateChange(p.original[after.ID], after, p.causes[after.ID]))
+	}
+	return changes
+}
+
+// Impact lists the planned moves, and the links the planned dates leave unsatisfied among
+// those touching a moved schedule and the extra links (not stored yet)
+func (p *ScheduleImpactPlanner) Impact(extraLinks ...models.PPICLink) (*models.ScheduleImpact, error) {
+	impact := &models.ScheduleImpact{DryRun: true, Conflicts: []models.ScheduleLinkConflict{}}
+
+	impact.Changes = p.Changes()
+	links := append([]models.PPICLink{}, extraLinks...)
+	for _, change := range impact.Changes {
+		links = append(append(links, p.targetLinks[change.ScheduleID]...), p.sourceLinks[change.ScheduleID]...)
+	}
+
+	seen := make(map[int64]bool)
+	for _, link := range links {
+		if link.ID != 0 {
+			if seen[link.ID] {
+				continue
+			}
+			seen[link.ID] = true
+		}
+		conflict, err := p.linkConflict(link)
+		if err != nil {
+			return nil, err
+		}
+		if conflict != nil {
+			impact.Conflicts = append(impact.Conflicts, *conflict)
+		}
+	}
+	return impact, nil
+}
+
+// linkConflict checks a link against the planned dates; nil means it holds
+func (p *ScheduleImpactPlanner) linkConflict(link models.PPICLink) (*models.ScheduleLinkConflict, error) {
+	source, err := p.Schedule(link.SourceScheduleID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := p.Schedule(link.TargetScheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil || target == nil {
+		return nil, nil
+	}
+	if p.calendar.IsLinkSatisfied(link.LinkType, link.LagDays, source.StartDate, source.FinishDate, target.StartDate, target.FinishDate) {
+		return nil, nil
+	}
+
+	constraint, onStart := p.calendar.LinkConstraintDate(link.LinkType, link.LagDays, source.StartDate, source.FinishDate)
+	end := "finish"
+	if onStart {
+		end = "start"
+	}
+	return &models.ScheduleLinkConflict{
+		LinkID:           link.ID,
+		SourceScheduleID: source.ID,
+		SourceNJO:        source.NJO,
+		TargetScheduleID: target.ID,
+		TargetNJO:        target.NJO,
+		LinkType:         link.LinkType,
+		LagDays:          link.LagDays,
+		RequiredDate:     constraint.Format("2006-01-02"),
+		Message: fmt.Sprintf("%s must %s on or after %s (%s link from %s)",
+			target.NJO, end, constraint.Format("2006-01-02"), models.GetLinkTypeName(link.LinkType), source.NJO),
+	}, nil
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/utils"
+	"strings"
+)
+
+// ImportPPICSchedules validates a CSV or XLSX file of schedules row by row and, unless it is a
+// dry run, writes all rows and the cascades of their date changes in one transaction. Nothing is
+// written if any row has an error.
+// File-level problems (unreadable file, missing columns) are returned as an error
+func (s *GanttService) ImportPPICSchedules(filename string, data []byte, opts models.PPICImportOptions, userID int64) (*models.PPICImportResult, error) {
+	records, err := utils.ReadSpreadsheet(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	columns, err := models.MapPPICImportColumns(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	machines, err := s.ppicRepo.GetAllMachines()
+	if err != nil {
+		return nil, err
+	}
+	machineIDs := make(map[string]int64, len(machines))
+	for _, m := range machines {
+		machineIDs[strings.ToUpper(m.MachineCode)] = m.ID
+	}
+
+	result := &models.PPICImportResult{DryRun: opts.DryRun}
+	var calendar *models.WorkingCalendar
+	seenNJO := make(map[string]int)
+	bookings := models.PPICImportBookings{}
+
+	for i, record := range records[1:] {
+		if models.IsBlankImportRow(record) {
+			continue
+		}
+		req, errs := columns.ParseRow(record, machineIDs)
+		row := models.PPICImportRow{Row: i + 2, NJO: req.NJO, Action: models.ImportActionCreate, Create: req, Errors: errs}
+
+		if req.NJO != "" {
+			if first, dup := seenNJO[req.NJO]; dup {
+				row.Errors = append(row.Errors, fmt.Sprintf("NJO %s is also on row %d", req.NJO, first))
+			} else {
+				seenNJO[req.NJO] = row.Row
+			}
+		}
+
+		if row.Valid() {
+			if err := s.validateImportRow(&row, opts, &calendar); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+		// The board only holds the machine windows of earlier imports, not those of earlier rows
+		if row.Valid() {
+			row.Errors = append(row.Errors, bookings.Check(&row)...)
+		}
+		if row.Valid() {
+			bookings.Add(&row)
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	for _, row := range result.Rows {
+		switch {
+		case !row.Valid():
+			result.ErrorCount++
+		case row.Action == models.ImportActionUpdate:
+			result.UpdateCount++
+		default:
+			result.CreateCount++
+		}
+	}
+	result.TotalRows = len(result.Rows)
+
+	if opts.DryRun || result.ErrorCount > 0 || result.TotalRows == 0 {
+		return result, nil
+	}
+
+	cascades, err := s.applyImport(result.Rows, calendar, userID)
+	if err != nil {
+		return nil, err
+	}
+	result.Committed = true
+
+	// History is recorded after the commit, as for single updates
+	for _, row := range result.Rows {
+		if row.Action != models.ImportActionUpdate {
+			continue
+		}
+		after, err := s.ppicRepo.GetByID(*row.ScheduleID)
+		if err != nil || after == nil {
+			continue
+		}
+		if err := s.refreshDerivedProgress(after); err != nil {
+			fmt.Printf("Warning: Failed to derive schedule progress: %v\n", err)
+		}
+		recordScheduleChanges(s.historyRepo, row.Existing, after, userID, models.ChangeCauseImport)
+	}
+	for id, cascade := range cascades {
+		s.recordCascadeHistory(cascade, id, userID)
+	}
+
+	return result, nil
+}
+
+// applyImport writes the rows and cascades the date changes of updated schedules to their
+// dependents, all in one transaction. The updated schedules and their chains are locked before
+// anything is written; a row whose schedule changed after it was validated fails the import
+func (s *GanttService) applyImport(rows []models.PPICImportRow, calendar *models.WorkingCalendar, userID int64) (map[int64]*scheduleCascade, error) {
+	tx, err := s.ppicRepo.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	for _, row := range rows {
+		if row.Action == models.ImportActionUpdate {
+			ids = append(ids, *row.ScheduleID)
+		}
+	}
+	locked := make(map[int64]bool)
+	if _, err := lockCascade(tx, ids, locked); err != nil {
+		return nil, err
+	}
+	// Machine windows are checked again under the machine lock, with the machines of all rows locked in one call
+	windows := make([][]plannedWindow, len(rows))
+	var machineIDs []int64
+	for i, row := range rows {
+		windows[i] = createWindows(row.Create.MachineAssignments)
+		machineIDs = append(machineIDs, windowMachines(windows[i])...)
+	}
+	if err := tx.LockMachines(machineIDs); err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		var excludeScheduleID int64
+		if row.Action == models.ImportActionUpdate {
+			excludeScheduleID = *row.ScheduleID
+		}
+		if err := s.checkMachineWindows(windows[i], excludeScheduleID, tx.GetMachineBookings); err != nil {
+			return nil, fmt.Errorf("row %d (NJO %s): %w", row.Row, row.NJO, err)
+		}
+	}
+	if err := tx.ApplyImport(rows, userID); err != nil {
+		return nil, err
+	}
+
+	cascades := make(map[int64]*scheduleCascade)
+	for _, row := range rows {
+		if row.Action != models.ImportActionUpdate ||
+			(row.StartDate.Equal(row.Existing.StartDate) && row.FinishDate.Equal(row.Existing.FinishDate)) {
+			continue
+		}
+		cascade, err := s.cascadeInTx(tx, calendar, *row.ScheduleID, row.Existing, models.ChangeCauseImport, locked)
+		if err != nil {
+			return nil, fmt.Errorf("row %d (NJO %s): %w", row.Row, row.NJO, err)
+		}
+		cascades[*row.ScheduleID] = cascade
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return cascades, nil
+}
+
+// validateImportRow runs the CreatePPICSchedule checks on a parsed row and decides whether it
+// creates a schedule or updates the one with the same NJO
+func (s *GanttService) validateImportRow(row *models.PPICImportRow, opts models.PPICImportOptions, calendar **models.WorkingCalendar) error {
+	existing, err := s.ppicRepo.GetByNJO(row.NJO)
+	if err != nil {
+		return err
+	}
+	if existing != nil && !opts.Upsert {
+		return errors.New("NJO already exists in PPIC schedule")
+	}
+	if existing != nil {
+		lots, err := s.ppicRepo.GetLots(existing.ID)
+		if err != nil {
+			return err
+		}
+		if len(lots) > 0 {
+			return errors.New("NJO is split into lots, so its dates and machines follow the lots. Change the lots on the board instead")
+		}
+	}
+
+	var excludeScheduleID int64
+	if existing != nil {
+		excludeScheduleID = existing.ID
+	}
+	row.StartDate, row.FinishDate, err = s.validateCreateRequest(row.Create, excludeScheduleID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+
+	row.Action = models.ImportActionUpdate
+	row.ScheduleID = &existing.ID
+	row.Existing = existing
+
+	if *calendar == nil {
+		if *calendar, err = s.calendarService.GetPlantCalendar(); err != nil {
+			return err
+		}
+	}
+	return s.validateNoPredecessorConflict(*calendar, s.ppicRepo.GetByID, existing.ID, row.StartDate, row.FinishDate)
+}
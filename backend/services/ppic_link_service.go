@@ -5,75 +5,213 @@ import (
 	"fmt"
 	"ganttpro-backend/models"
 	"ganttpro-backend/repository"
+	"strings"
+	"time"
 )
 
 type PPICLinkService struct {
-	linkRepo     *repository.PPICLinkRepository
-	scheduleRepo *repository.PPICScheduleRepository
+	linkRepo        *repository.PPICLinkRepository
+	scheduleRepo    *repository.PPICScheduleRepository
+	historyRepo     *repository.PPICHistoryRepository
+	calendarService *CalendarService
+	downtimeService *MachineDowntimeService
+	ganttService    *GanttService
 }
 
-func NewPPICLinkService(linkRepo *repository.PPICLinkRepository, scheduleRepo *repository.PPICScheduleRepository) *PPICLinkService {
+func NewPPICLinkService(linkRepo *repository.PPICLinkRepository, scheduleRepo *repository.PPICScheduleRepository, historyRepo *repository.PPICHistoryRepository, calendarService *CalendarService, downtimeService *MachineDowntimeService, ganttService *GanttService) *PPICLinkService {
 	return &PPICLinkService{
-		linkRepo:     linkRepo,
-		scheduleRepo: scheduleRepo,
+		linkRepo:        linkRepo,
+		scheduleRepo:    scheduleRepo,
+		historyRepo:     historyRepo,
+		calendarService: calendarService,
+		downtimeService: downtimeService,
+		ganttService:    ganttService,
 	}
 }
 
-// CreateLink creates a new PPIC link
-func (s *PPICLinkService) CreateLink(req *models.CreatePPICLinkRequest) (*models.PPICLink, error) {
+// ForScenario returns a service working on a what-if scenario instead of the live board
+func (s *PPICLinkService) ForScenario(scenarioID int64) *PPICLinkService {
+	return &PPICLinkService{
+		linkRepo:        s.linkRepo.ForScenario(scenarioID),
+		scheduleRepo:    s.scheduleRepo.ForScenario(scenarioID),
+		historyRepo:     s.historyRepo,
+		calendarService: s.calendarService,
+		downtimeService: s.downtimeService,
+		ganttService:    s.ganttService.ForScenario(scenarioID),
+	}
+}
+
+// CreateLink creates a new PPIC link. A target whose dates violate the link (including the lag/lead
+// offset) is moved onto it, and the target's dependents cascade, in the same transaction as the link.
+// Source and target are locked with their chains, and the link is checked again under those locks,
+// so concurrent creates can't add the same link twice or close a loop between them
+func (s *PPICLinkService) CreateLink(req *models.CreatePPICLinkRequest, userID int64) (*models.PPICLink, error) {
+	if _, _, err := s.validateCreateRequest(req); err != nil {
+		return nil, err
+	}
+	calendar, err := s.calendarService.GetPlantCalendar()
+	if err != nil {
+		return nil, err
+	}
+
+	var link *models.PPICLink
+	result, err := s.ganttService.rescheduleWithCascadeLocking(calendar, req.TargetScheduleID, []int64{req.SourceScheduleID}, models.ChangeCauseLinkCreated, userID, func(tx *repository.ScheduleTx) error {
+		// Read under lock, so a source moved by a concurrent update is seen
+		source, err := tx.GetByID(req.SourceScheduleID)
+		if err != nil {
+			return err
+		}
+		if source == nil {
+			return errors.New("source schedule not found")
+		}
+		target, err := tx.GetByID(req.TargetScheduleID)
+		if err != nil {
+			return err
+		}
+		links, err := tx.GetLinks()
+		if err != nil {
+			return err
+		}
+		if err := s.checkLinkGraph(links, source, target); err != nil {
+			return err
+		}
+
+		if !calendar.IsLinkSatisfied(req.LinkType, req.LagDays, source.StartDate, source.FinishDate, target.StartDate, target.FinishDate) {
+			// Move the target onto the constraint, keeping its number of working days
+			newStartDate, newFinishDate, err := s.linkedTargetDates(calendar, req.LinkType, req.LagDays, source, target)
+			if err != nil {
+				return err
+			}
+			if err := tx.SetDates(target.ID, newStartDate, newFinishDate); err != nil {
+				return fmt.Errorf("failed to update target schedule dates: %w", err)
+			}
+			// Lots of a split target follow it
+			if err := s.ganttService.shiftLotsInTx(tx, calendar, target.ID, target.StartDate, newStartDate); err != nil {
+				return err
+			}
+		}
+
+		link, err = tx.CreateLink(req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if after, err := s.scheduleRepo.GetByID(req.TargetScheduleID); err == nil {
+		recordScheduleChanges(s.historyRepo, result.before, after, userID, models.ChangeCauseLinkCreated)
+	}
+	return link, nil
+}
+
+// PreviewLink runs the checks of CreateLink and lists the schedules creating the link would move
+// and the links those moves would break, without writing anything
+func (s *PPICLinkService) PreviewLink(req *models.CreatePPICLinkRequest) (*models.ScheduleImpact, error) {
+	source, target, err := s.validateCreateRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar, err := s.calendarService.GetPlantCalendar()
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.linkRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch links: %w", err)
+	}
+
+	planner := NewScheduleImpactPlanner(calendar, links, s.scheduleRepo.GetByID)
+	planner.UseDowntime(s.downtimeService.MachineDowntimes)
+	if !calendar.IsLinkSatisfied(req.LinkType, req.LagDays, source.StartDate, source.FinishDate, target.StartDate, target.FinishDate) {
+		newStartDate, newFinishDate, err := s.linkedTargetDates(calendar, req.LinkType, req.LagDays, source, target)
+		if err != nil {
+			return nil, err
+		}
+		if err := planner.Move(target.ID, newStartDate, newFinishDate, models.ChangeCauseLinkCreated); err != nil {
+			return nil, err
+		}
+	}
+
+	return planner.Impact(models.PPICLink{
+		SourceScheduleID: req.SourceScheduleID,
+		TargetScheduleID: req.TargetScheduleID,
+		LinkType:         req.LinkType,
+		LagDays:          req.LagDays,
+	})
+}
+
+// validateCreateRequest checks a new link and returns its source and target schedules
+func (s *PPICLinkService) validateCreateRequest(req *models.CreatePPICLinkRequest) (*models.PPICSchedule, *models.PPICSchedule, error) {
 	// Validate that source and target are different
 	if req.SourceScheduleID == req.TargetScheduleID {
-		return nil, errors.New("source and target schedules must be different")
+		return nil, nil, errors.New("source and target schedules must be different")
 	}
 
 	// Default link type to '0' (finish-to-start) if not specified
 	if req.LinkType == "" {
-		req.LinkType = "0"
+		req.LinkType = models.LinkTypeFinishToStart
+	}
+	if !models.ValidateLinkType(req.LinkType) {
+		return nil, nil, errors.New("invalid link type. Must be: 0 (finish-to-start), 1 (start-to-start), 2 (finish-to-finish), or 3 (start-to-finish)")
 	}
 
 	// Fetch source and target schedules
 	sourceSchedule, err := s.scheduleRepo.GetByID(req.SourceScheduleID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch source schedule: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch source schedule: %w", err)
 	}
 	if sourceSchedule == nil {
-		return nil, errors.New("source schedule not found")
+		return nil, nil, errors.New("source schedule not found")
 	}
 
 	targetSchedule, err := s.scheduleRepo.GetByID(req.TargetScheduleID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch target schedule: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch target schedule: %w", err)
 	}
 	if targetSchedule == nil {
-		return nil, errors.New("target schedule not found")
+		return nil, nil, errors.New("target schedule not found")
+	}
+
+	// Lots follow their split schedule, so links go to the split schedule
+	if sourceSchedule.ParentScheduleID != nil || targetSchedule.ParentScheduleID != nil {
+		return nil, nil, errors.New("lots cannot be linked. Link the split schedule instead")
 	}
 
 	// Validate that both schedules have the same machine
 	if err := s.validateSameMachine(sourceSchedule, targetSchedule); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Auto-reschedule target task if there's a date conflict (only for finish-to-start links)
-	if req.LinkType == "0" {
-		if err := s.autoRescheduleIfNeeded(sourceSchedule, targetSchedule); err != nil {
-			return nil, fmt.Errorf("failed to auto-reschedule: %w", err)
-		}
+	// Validate that the link keeps the dependency graph acyclic and unique
+	if err := s.validateLinkGraph(sourceSchedule, targetSchedule); err != nil {
+		return nil, nil, err
 	}
 
-	return s.linkRepo.Create(req)
+	return sourceSchedule, targetSchedule, nil
 }
 
-// validateSameMachine checks if both schedules have at least one common machine
+// validateSameMachine checks if both schedules have at least one common machine.
+// The machines of a split schedule are those of its lots
 func (s *PPICLinkService) validateSameMachine(source, target *models.PPICSchedule) error {
+	sourceAssignments, err := s.machineAssignments(source)
+	if err != nil {
+		return err
+	}
+	targetAssignments, err := s.machineAssignments(target)
+	if err != nil {
+		return err
+	}
+
 	// Get machine IDs from source schedule
 	sourceMachines := make(map[int64]bool)
-	for _, ma := range source.MachineAssignments {
+	for _, ma := range sourceAssignments {
 		sourceMachines[ma.MachineID] = true
 	}
 
 	// Check if target has any common machine
 	hasCommonMachine := false
-	for _, ma := range target.MachineAssignments {
+	for _, ma := range targetAssignments {
 		if sourceMachines[ma.MachineID] {
 			hasCommonMachine = true
 			break
@@ -81,7 +219,7 @@ func (s *PPICLinkService) validateSameMachine(source, target *models.PPICSchedul
 	}
 
 	// If no machine assignments, both must have no machines
-	if len(source.MachineAssignments) == 0 && len(target.MachineAssignments) == 0 {
+	if len(sourceAssignments) == 0 && len(targetAssignments) == 0 {
 		return errors.New("cannot link tasks without machine assignments")
 	}
 
@@ -92,31 +230,163 @@ func (s *PPICLinkService) validateSameMachine(source, target *models.PPICSchedul
 	return nil
 }
 
-// autoRescheduleIfNeeded reschedules the target task if it starts before source finishes
-func (s *PPICLinkService) autoRescheduleIfNeeded(source, target *models.PPICSchedule) error {
-	// For finish-to-start: target should start after source finishes
-	// Check if target starts before or on the same day as source finishes
-	if target.StartDate.Before(source.FinishDate) || target.StartDate.Equal(source.FinishDate) {
-		// Calculate the duration of the target task
-		duration := target.FinishDate.Sub(target.StartDate)
+// machineAssignments returns the machine assignments of a schedule, or of its lots when it is split
+func (s *PPICLinkService) machineAssignments(schedule *models.PPICSchedule) ([]models.MachineAssignment, error) {
+	if len(schedule.MachineAssignments) > 0 {
+		return schedule.MachineAssignments, nil
+	}
+	lots, err := s.scheduleRepo.GetLots(schedule.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch lots: %w", err)
+	}
+	var assignments []models.MachineAssignment
+	for _, lot := range lots {
+		assignments = append(assignments, lot.MachineAssignments...)
+	}
+	return assignments, nil
+}
 
-		// New start date is the day after source finishes
-		newStartDate := source.FinishDate.AddDate(0, 0, 1)
-		newFinishDate := newStartDate.Add(duration)
+// validateLinkGraph rejects duplicate source/target pairs and links that would close a dependency loop
+func (s *PPICLinkService) validateLinkGraph(source, target *models.PPICSchedule) error {
+	links, err := s.linkRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to fetch links: %w", err)
+	}
+	return s.checkLinkGraph(links, source, target)
+}
 
-		// Update the target schedule
-		updateReq := &models.UpdatePPICScheduleRequest{
-			StartDate:  newStartDate.Format("2006-01-02"),
-			FinishDate: newFinishDate.Format("2006-01-02"),
+// checkLinkGraph runs the checks of validateLinkGraph against the given links
+func (s *PPICLinkService) checkLinkGraph(links []models.PPICLink, source, target *models.PPICSchedule) error {
+	for _, link := range links {
+		if link.SourceScheduleID == source.ID && link.TargetScheduleID == target.ID {
+			return fmt.Errorf("link from '%s' to '%s' already exists", source.NJO, target.NJO)
 		}
+	}
 
-		_, err := s.scheduleRepo.Update(target.ID, updateReq, &newStartDate, &newFinishDate)
-		if err != nil {
-			return fmt.Errorf("failed to update target schedule dates: %w", err)
+	// The new link source -> target closes a loop if source is already reachable from target
+	path := models.FindLinkPath(links, target.ID, source.ID)
+	if path == nil {
+		return nil
+	}
+
+	cycle := s.describeCycle(append(path, target.ID))
+	return fmt.Errorf("link would create a dependency cycle: %s", cycle.Path)
+}
+
+// describeCycle resolves schedule IDs in a loop to their NJOs
+func (s *PPICLinkService) describeCycle(scheduleIDs []int64) models.PPICLinkCycle {
+	cycle := models.PPICLinkCycle{ScheduleIDs: scheduleIDs}
+	njoCache := make(map[int64]string)
+
+	for _, id := range scheduleIDs {
+		njo, ok := njoCache[id]
+		if !ok {
+			njo = fmt.Sprintf("#%d", id)
+			if schedule, err := s.scheduleRepo.GetByID(id); err == nil && schedule != nil {
+				njo = schedule.NJO
+			}
+			njoCache[id] = njo
 		}
+		cycle.NJOs = append(cycle.NJOs, njo)
 	}
+	cycle.Path = strings.Join(cycle.NJOs, " → ")
 
-	return nil
+	return cycle
+}
+
+// CheckIntegrity reports cycles, dangling links, duplicates and links between tasks that no longer share a machine
+func (s *PPICLinkService) CheckIntegrity() (*models.PPICLinkIntegrityReport, error) {
+	links, err := s.linkRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch links: %w", err)
+	}
+
+	report := &models.PPICLinkIntegrityReport{
+		TotalLinks:      len(links),
+		Cycles:          []models.PPICLinkCycle{},
+		DanglingLinks:   []models.PPICLinkIssue{},
+		MachineMismatch: []models.PPICLinkIssue{},
+		DuplicateLinks:  []models.PPICLinkIssue{},
+	}
+
+	// Collect schedule IDs referenced by links
+	var ids []int64
+	seenIDs := make(map[int64]bool)
+	for _, link := range links {
+		for _, id := range []int64{link.SourceScheduleID, link.TargetScheduleID} {
+			if !seenIDs[id] {
+				seenIDs[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	active, err := s.scheduleRepo.GetActiveIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedules: %w", err)
+	}
+
+	schedules := make(map[int64]*models.PPICSchedule)
+	pairs := make(map[[2]int64]bool)
+	var liveLinks []models.PPICLink
+
+	for _, link := range links {
+		// Dangling links point at deleted schedules
+		if !active[link.SourceScheduleID] || !active[link.TargetScheduleID] {
+			reason := "target schedule deleted"
+			if !active[link.SourceScheduleID] && !active[link.TargetScheduleID] {
+				reason = "source and target schedules deleted"
+			} else if !active[link.SourceScheduleID] {
+				reason = "source schedule deleted"
+			}
+			report.DanglingLinks = append(report.DanglingLinks, models.PPICLinkIssue{Link: link, Reason: reason})
+			continue
+		}
+		liveLinks = append(liveLinks, link)
+
+		pair := [2]int64{link.SourceScheduleID, link.TargetScheduleID}
+		if pairs[pair] {
+			report.DuplicateLinks = append(report.DuplicateLinks, models.PPICLinkIssue{Link: link, Reason: "duplicate source/target pair"})
+		}
+		pairs[pair] = true
+
+		// Links are only valid between tasks sharing a machine
+		for _, id := range pair {
+			if _, ok := schedules[id]; !ok {
+				schedule, err := s.scheduleRepo.GetByID(id)
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch schedule %d: %w", id, err)
+				}
+				schedules[id] = schedule
+			}
+		}
+		source, target := schedules[link.SourceScheduleID], schedules[link.TargetScheduleID]
+		if source != nil && target != nil {
+			if err := s.validateSameMachine(source, target); err != nil {
+				report.MachineMismatch = append(report.MachineMismatch, models.PPICLinkIssue{Link: link, Reason: err.Error()})
+			}
+		}
+	}
+
+	for _, cycleIDs := range models.FindLinkCycles(liveLinks) {
+		report.Cycles = append(report.Cycles, s.describeCycle(cycleIDs))
+	}
+
+	report.Healthy = len(report.Cycles) == 0 && len(report.DanglingLinks) == 0 &&
+		len(report.MachineMismatch) == 0 && len(report.DuplicateLinks) == 0
+
+	return report, nil
+}
+
+// linkedTargetDates puts the target on the link constraint, skipping the days its machines are down
+func (s *PPICLinkService) linkedTargetDates(calendar *models.WorkingCalendar, linkType string, lagDays int, source, target *models.PPICSchedule) (time.Time, time.Time, error) {
+	downtimes, err := s.downtimeService.ScheduleDowntimes(target)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	newStartDate, newFinishDate := calendar.WithDowntime(downtimes).LinkedTargetDates(linkType, lagDays,
+		source.StartDate, source.FinishDate, target.StartDate, target.FinishDate)
+	return newStartDate, newFinishDate, nil
 }
 
 // GetAllLinks returns all PPIC links
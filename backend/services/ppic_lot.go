@@ -0,0 +1,497 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+	"time"
+)
+
+// SplitPPICSchedule splits a schedule into lots for partial batches. The schedule keeps its NJO
+// and links and becomes the summary of the lots: it spans their dates, its quantity is their total
+// and its machine assignments move to the lots. When the lots change the span, the schedule's
+// dependents cascade as for a date change, in the same transaction
+func (s *GanttService) SplitPPICSchedule(id int64, req *models.SplitPPICScheduleRequest, userID int64) (*models.PPICSchedule, []models.ScheduleDateChange, error) {
+	if req.Version == nil {
+		return nil, nil, models.ErrVersionRequired
+	}
+	schedule, err := s.ppicRepo.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if schedule == nil {
+		return nil, nil, errors.New("PPIC schedule not found")
+	}
+	if schedule.ParentScheduleID != nil {
+		return nil, nil, errors.New("a lot cannot be split again. Merge the lots and split the schedule instead")
+	}
+	if *req.Version != schedule.Version {
+		return nil, nil, models.ErrStaleVersion
+	}
+	if models.WorkRecorded(schedule) {
+		return nil, nil, errors.New("work has started on the machines of this schedule, so it can no longer be split")
+	}
+
+	total, err := req.ValidateLotQuantities(schedule.Quantity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type plannedLot struct {
+		start, finish time.Time
+	}
+	planned := make([]plannedLot, len(req.Lots))
+	var windows []plannedWindow
+	for i := range req.Lots {
+		lot := &req.Lots[i]
+		create := &models.CreatePPICScheduleRequest{
+			NJO:                schedule.NJO,
+			PartName:           schedule.PartName,
+			Priority:           schedule.Priority,
+			PriorityAlpha:      schedule.PriorityAlpha,
+			MaterialStatus:     schedule.MaterialStatus,
+			StartDate:          lot.StartDate,
+			FinishDate:         lot.FinishDate,
+			PPICNotes:          lot.PPICNotes,
+			Quantity:           lot.Quantity,
+			MachineAssignments: lot.MachineAssignments,
+		}
+		if create.StartDate == "" {
+			create.StartDate = schedule.StartDate.Format("2006-01-02")
+		}
+		if create.FinishDate == "" {
+			create.FinishDate = schedule.FinishDate.Format("2006-01-02")
+		}
+		if len(create.MachineAssignments) == 0 {
+			create.MachineAssignments = models.ProrateAssignments(schedule.MachineAssignments, float64(lot.Quantity)/float64(total))
+		}
+
+		// The schedule's own bookings are replaced by the lots, so they don't count as conflicts
+		start, finish, err := s.validateCreateRequest(create, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("lot %d: %w", i+1, err)
+		}
+		lot.MachineAssignments = create.MachineAssignments
+		planned[i] = plannedLot{start: start, finish: finish}
+		windows = append(windows, createWindows(lot.MachineAssignments)...)
+	}
+	// Lots must not double-book a machine among themselves either
+	if err := s.validateMachineWindows(windows, id); err != nil {
+		return nil, nil, err
+	}
+
+	spanStart, spanFinish := planned[0].start, planned[0].finish
+	for _, lot := range planned[1:] {
+		if lot.start.Before(spanStart) {
+			spanStart = lot.start
+		}
+		if lot.finish.After(spanFinish) {
+			spanFinish = lot.finish
+		}
+	}
+
+	calendar, err := s.calendarService.GetPlantCalendar()
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := s.rescheduleWithCascade(calendar, id, models.ChangeCauseSplit, userID, func(tx *repository.ScheduleTx) error {
+		// Checked under lock, so a concurrent split or a moved predecessor is seen
+		lots, err := tx.GetLots(id)
+		if err != nil {
+			return err
+		}
+		if len(lots) > 0 {
+			return errors.New("schedule is already split into lots. Merge them first")
+		}
+		if err := s.validateNoPredecessorConflict(calendar, tx.GetByID, id, spanStart, spanFinish); err != nil {
+			return err
+		}
+		if err := s.checkMachineWindowsInTx(tx, windows, id); err != nil {
+			return err
+		}
+
+		// The version is checked before anything else bumps it
+		if err := tx.Update(id, &models.UpdatePPICScheduleRequest{Quantity: &total, Version: req.Version}, &spanStart, &spanFinish); err != nil {
+			return err
+		}
+		if err := tx.SetMachineAssignments(id, nil); err != nil {
+			return err
+		}
+		parent, err := tx.GetByID(id)
+		if err != nil {
+			return err
+		}
+		for i := range req.Lots {
+			if _, err := tx.CreateLot(parent, &req.Lots[i], planned[i].start, planned[i].finish, userID); err != nil {
+				return fmt.Errorf("lot %d: %w", i+1, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.refreshSplitProgress(id); err != nil {
+		fmt.Printf("Warning: Failed to derive schedule progress: %v\n", err)
+	}
+	after, err := s.GetPPICSchedule(id)
+	if err != nil {
+		return nil, result.changes, err
+	}
+	recordScheduleChanges(s.historyRepo, result.before, after, userID, models.ChangeCauseSplit)
+	return after, result.changes, nil
+}
+
+// MergePPICLots merges lots of a split schedule. Without lot IDs, or with all of them, the lots
+// are folded back into the schedule, which is no longer split. Otherwise the selected lots are
+// merged into the lowest numbered one. Merged lots get one routing with the target hours of all
+// of them and no scheduled windows. Lots with work recorded cannot be merged
+func (s *GanttService) MergePPICLots(id int64, req *models.MergePPICLotsRequest, userID int64) (*models.PPICSchedule, error) {
+	if req.Version == nil {
+		return nil, models.ErrVersionRequired
+	}
+	schedule, err := s.ppicRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, errors.New("PPIC schedule not found")
+	}
+	if *req.Version != schedule.Version {
+		return nil, models.ErrStaleVersion
+	}
+	lots, err := s.ppicRepo.GetLots(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(lots) == 0 {
+		return nil, errors.New("schedule is not split into lots")
+	}
+
+	selected := lots
+	if len(req.LotIDs) > 0 {
+		byID := make(map[int64]models.PPICSchedule, len(lots))
+		for _, lot := range lots {
+			byID[lot.ID] = lot
+		}
+		picked := make(map[int64]bool, len(req.LotIDs))
+		selected = nil
+		for _, lotID := range req.LotIDs {
+			lot, ok := byID[lotID]
+			if !ok {
+				return nil, fmt.Errorf("lot %d is not a lot of this schedule", lotID)
+			}
+			if !picked[lotID] {
+				picked[lotID] = true
+				selected = append(selected, lot)
+			}
+		}
+		if len(selected) < 2 && len(selected) < len(lots) {
+			return nil, errors.New("select at least 2 lots to merge")
+		}
+	}
+	for i := range selected {
+		if models.WorkRecorded(&selected[i]) {
+			return nil, fmt.Errorf("work has started on lot %d, so it can no longer be merged", *selected[i].LotNumber)
+		}
+	}
+
+	foldAll := len(selected) == len(lots)
+	target := &selected[0]
+	for i := range selected {
+		if *selected[i].LotNumber < *target.LotNumber {
+			target = &selected[i]
+		}
+	}
+	var removed []int64
+	for _, lot := range selected {
+		if foldAll || lot.ID != target.ID {
+			removed = append(removed, lot.ID)
+		}
+	}
+	quantity := models.LotQuantity(selected)
+	assignments := models.MergeLotAssignments(selected)
+	spanStart, spanFinish := models.LotSpan(selected)
+
+	calendar, err := s.calendarService.GetPlantCalendar()
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.rescheduleWithCascade(calendar, id, models.ChangeCauseMerge, userID, func(tx *repository.ScheduleTx) error {
+		if err := tx.Update(id, &models.UpdatePPICScheduleRequest{Version: req.Version}, nil, nil); err != nil {
+			return err
+		}
+		current, err := tx.GetLots(id)
+		if err != nil {
+			return err
+		}
+		if len(current) != len(lots) {
+			return models.ErrStaleVersion
+		}
+
+		if foldAll {
+			if err := tx.SetMachineAssignments(id, assignments); err != nil {
+				return err
+			}
+			if err := tx.Update(id, &models.UpdatePPICScheduleRequest{Quantity: &quantity}, &spanStart, &spanFinish); err != nil {
+				return err
+			}
+			return tx.DeleteLots(id, removed)
+		}
+
+		if err := tx.SetMachineAssignments(target.ID, assignments); err != nil {
+			return err
+		}
+		if err := tx.Update(target.ID, &models.UpdatePPICScheduleRequest{Quantity: &quantity}, &spanStart, &spanFinish); err != nil {
+			return err
+		}
+		if err := tx.DeleteLots(id, removed); err != nil {
+			return err
+		}
+		return tx.SyncLotParent(target.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !foldAll {
+		if lot, err := s.ppicRepo.GetByID(target.ID); err == nil && lot != nil {
+			if err := s.refreshDerivedProgress(lot); err != nil {
+				fmt.Printf("Warning: Failed to derive schedule progress: %v\n", err)
+			}
+			recordScheduleChanges(s.historyRepo, target, lot, userID, models.ChangeCauseMerge)
+		}
+	}
+	for _, lot := range selected {
+		if foldAll || lot.ID != target.ID {
+			recordScheduleDeleted(s.historyRepo, &lot, userID, models.ChangeCauseMerge)
+		}
+	}
+
+	after, err := s.GetPPICSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	if foldAll {
+		if err := s.refreshDerivedProgress(after); err != nil {
+			fmt.Printf("Warning: Failed to derive schedule progress: %v\n", err)
+		}
+	}
+	recordScheduleChanges(s.historyRepo, result.before, after, userID, models.ChangeCauseMerge)
+	return after, nil
+}
+
+// validateLotUpdate rejects changes that would break a split: the dates, quantity and machines of
+// a split schedule follow its lots, and the job fields of a lot follow its split schedule. Fields
+// sent with their current value are not changes
+func (s *GanttService) validateLotUpdate(existing *models.PPICSchedule, req *models.UpdatePPICScheduleRequest, startDate, finishDate *time.Time) error {
+	if req.Quantity != nil && *req.Quantity < 0 {
+		return errors.New("quantity cannot be negative")
+	}
+
+	if existing.ParentScheduleID != nil {
+		if (req.PartName != "" && req.PartName != existing.PartName) ||
+			(req.Priority != "" && req.Priority != existing.Priority) ||
+			(req.PriorityAlpha != "" && req.PriorityAlpha != existing.PriorityAlpha) ||
+			(req.MaterialStatus != "" && req.MaterialStatus != existing.MaterialStatus) {
+			return errors.New("part name, priority and material status of a lot follow its split schedule. Change them there")
+		}
+		if req.Quantity != nil && *req.Quantity < 1 {
+			return errors.New("lot quantity must be at least 1")
+		}
+		return nil
+	}
+
+	changesLots := len(req.MachineAssignments) > 0 ||
+		(req.Quantity != nil && *req.Quantity != existing.Quantity) ||
+		(startDate != nil && !startDate.Equal(existing.StartDate)) ||
+		(finishDate != nil && !finishDate.Equal(existing.FinishDate))
+	if !changesLots {
+		return nil
+	}
+	lots, err := s.ppicRepo.GetLots(existing.ID)
+	if err != nil {
+		return err
+	}
+	if len(lots) > 0 {
+		return errors.New("dates, quantity and machines of a split schedule follow its lots. Change the lots, or merge them first")
+	}
+	return nil
+}
+
+// updateLot writes an update moving or resizing a lot. The parent follows the span and total of
+// its lots, so the cascade runs from the parent
+func (s *GanttService) updateLot(lot *models.PPICSchedule, req *models.UpdatePPICScheduleRequest, startDate, finishDate *time.Time, calendar *models.WorkingCalendar, userID int64) ([]models.ScheduleDateChange, error) {
+	parentID := *lot.ParentScheduleID
+	if calendar == nil {
+		var err error
+		if calendar, err = s.calendarService.GetPlantCalendar(); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := s.rescheduleWithCascade(calendar, parentID, models.ChangeCauseManual, userID, func(tx *repository.ScheduleTx) error {
+		if err := s.checkMachineWindowsInTx(tx, updateWindows(req.MachineAssignments), lot.ID); err != nil {
+			return err
+		}
+		if err := tx.Update(lot.ID, req, startDate, finishDate); err != nil {
+			return err
+		}
+		if err := tx.SyncLotParent(lot.ID); err != nil {
+			return err
+		}
+		parent, err := tx.GetByID(parentID)
+		if err != nil {
+			return err
+		}
+		return s.validateNoPredecessorConflict(calendar, tx.GetByID, parentID, parent.StartDate, parent.FinishDate)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if parent, err := s.ppicRepo.GetByID(parentID); err == nil {
+		recordScheduleChanges(s.historyRepo, result.before, parent, userID, models.ChangeCauseManual)
+	}
+	changes := result.changes
+	if startDate != nil || finishDate != nil {
+		newStart, newFinish := updatedDates(lot, startDate, finishDate)
+		moved := *lot
+		moved.StartDate, moved.FinishDate = newStart, newFinish
+		changes = append([]models.ScheduleDateChange{models.NewScheduleDateChange(lot, &moved, models.ChangeCauseManual)}, changes...)
+	}
+	return changes, nil
+}
+
+// previewLotUpdate plans a lot date change: the parent takes the new span of its lots and its
+// dependents cascade from there
+func (s *GanttService) previewLotUpdate(lot *models.PPICSchedule, startDate, finishDate *time.Time, calendar *models.WorkingCalendar) (*models.ScheduleImpact, error) {
+	parentID := *lot.ParentScheduleID
+	lots, err := s.ppicRepo.GetLots(parentID)
+	if err != nil {
+		return nil, err
+	}
+	moved := *lot
+	moved.StartDate, moved.FinishDate = updatedDates(lot, startDate, finishDate)
+	for i := range lots {
+		if lots[i].ID == lot.ID {
+			lots[i] = moved
+		}
+	}
+	spanStart, spanFinish := models.LotSpan(lots)
+
+	links, err := s.ppicLinkRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get links: %w", err)
+	}
+	planner := NewScheduleImpactPlanner(calendar, links, s.ppicRepo.GetByID)
+	planner.UseDowntime(s.downtimeService.MachineDowntimes)
+	if err := planner.Move(parentID, spanStart, spanFinish, models.ChangeCauseManual); err != nil {
+		return nil, err
+	}
+	if err := planner.Cascade(parentID); err != nil {
+		return nil, err
+	}
+
+	impact, err := planner.Impact()
+	if err != nil {
+		return nil, err
+	}
+	if impact.Changes, err = withLotChanges(calendar, impact.Changes, parentID, s.ppicRepo.GetLots); err != nil {
+		return nil, err
+	}
+	if !moved.StartDate.Equal(lot.StartDate) || !moved.FinishDate.Equal(lot.FinishDate) {
+		impact.Changes = append([]models.ScheduleDateChange{models.NewScheduleDateChange(lot, &moved, models.ChangeCauseManual)}, impact.Changes...)
+	}
+	for _, conflict := range impact.Conflicts {
+		if conflict.TargetScheduleID == parentID {
+			impact.Blocked = true
+		}
+	}
+	return impact, nil
+}
+
+// withLotChanges adds, after each moved split schedule other than skipID, the moves of its lots,
+// which follow it by the same number of working days. getLots reads the lots before they move
+func withLotChanges(calendar *models.WorkingCalendar, changes []models.ScheduleDateChange, skipID int64, getLots func(parentID int64) ([]models.PPICSchedule, error)) ([]models.ScheduleDateChange, error) {
+	withLots := make([]models.ScheduleDateChange, 0, len(changes))
+	for _, change := range changes {
+		withLots = append(withLots, change)
+		if change.ScheduleID == skipID || change.ShiftDays == 0 {
+			continue
+		}
+		lots, err := getLots(change.ScheduleID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lots of %s: %w", change.NJO, err)
+		}
+		oldStart, _ := time.Parse("2006-01-02", change.OldStartDate)
+		newStart, _ := time.Parse("2006-01-02", change.NewStartDate)
+		days := calendar.WorkingDaysOffset(oldStart, newStart)
+		for i := range lots {
+			moved := models.ShiftLot(calendar, lots[i], days)
+			withLots = append(withLots, models.NewScheduleDateChange(&lots[i], &moved, change.Cause))
+		}
+	}
+	return withLots, nil
+}
+
+// shiftLotsInTx moves the lots of a split schedule whose start moved from from to to by as many
+// working days, with their machine windows. The moved windows must not overlap other bookings or
+// downtime of their machines
+func (s *GanttService) shiftLotsInTx(tx *repository.ScheduleTx, calendar *models.WorkingCalendar, parentID int64, from, to time.Time) error {
+	days := calendar.WorkingDaysOffset(from, to)
+	if days == 0 {
+		return nil
+	}
+	lots, err := tx.GetLots(parentID)
+	if err != nil {
+		return err
+	}
+
+	moved := make([]models.PPICSchedule, len(lots))
+	for i := range lots {
+		moved[i] = models.ShiftLot(calendar, lots[i], days)
+		if err := tx.MoveLot(&moved[i]); err != nil {
+			return err
+		}
+	}
+	// Checked once all lots moved, so lots of the same schedule don't block each other. The
+	// machines of all lots are locked in one call
+	windows := make([][]plannedWindow, len(moved))
+	var machineIDs []int64
+	for i, lot := range moved {
+		for _, ma := range lot.MachineAssignments {
+			windows[i] = append(windows[i], plannedWindow{machineID: ma.MachineID, sequence: ma.Sequence, start: ma.ScheduledStart, end: ma.ScheduledEnd})
+		}
+		machineIDs = append(machineIDs, windowMachines(windows[i])...)
+	}
+	if err := tx.LockMachines(machineIDs); err != nil {
+		return err
+	}
+	for i, lot := range moved {
+		if err := s.checkMachineWindows(windows[i], lot.ID, tx.GetMachineBookings); err != nil {
+			return fmt.Errorf("lot %s: %w", models.LotTaskName(&lot), err)
+		}
+	}
+	return nil
+}
+
+// refreshSplitProgress stores the progress and status of a split schedule derived from its lots.
+// Overridden schedules and schedules without lots are left alone
+func (s *GanttService) refreshSplitProgress(id int64) error {
+	schedule, err := s.ppicRepo.GetByID(id)
+	if err != nil || schedule == nil || schedule.ProgressOverride {
+		return err
+	}
+	lots, err := s.ppicRepo.GetLots(id)
+	if err != nil {
+		return err
+	}
+	progress, status, ok := models.DeriveSplitProgress(lots)
+	if !ok || (progress == schedule.Progress && status == schedule.Status) {
+		return nil
+	}
+	return s.ppicRepo.SetDerivedProgress(id, progress, status)
+}
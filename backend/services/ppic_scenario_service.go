@@ -0,0 +1,269 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrScenarioNotFound is returned when a scenario ID does not exist
+var ErrScenarioNotFound = errors.New("scenario not found")
+
+type PPICScenarioService struct {
+	repo         *repository.PPICScenarioRepository
+	ganttService *GanttService
+	linkService  *PPICLinkService
+}
+
+func NewPPICScenarioService(repo *repository.PPICScenarioRepository, ganttService *GanttService, linkService *PPICLinkService) *PPICScenarioService {
+	return &PPICScenarioService{
+		repo:         repo,
+		ganttService: ganttService,
+		linkService:  linkService,
+	}
+}
+
+// CreateScenario copies the live board into a new named scenario
+func (s *PPICScenarioService) CreateScenario(req *models.CreatePPICScenarioRequest, createdBy int64) (*models.PPICScenario, error) {
+	return s.repo.Create(req, createdBy)
+}
+
+// GetAllScenarios returns all scenarios
+func (s *PPICScenarioService) GetAllScenarios() ([]models.PPICScenario, error) {
+	return s.repo.GetAll()
+}
+
+// GetScenario returns a single scenario
+func (s *PPICScenarioService) GetScenario(id int64) (*models.PPICScenario, error) {
+	scenario, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if scenario == nil {
+		return nil, ErrScenarioNotFound
+	}
+	return scenario, nil
+}
+
+// DeleteScenario discards a scenario and everything copied into it
+func (s *PPICScenarioService) DeleteScenario(id int64) error {
+	if _, err := s.GetScenario(id); err != nil {
+		return err
+	}
+	return s.repo.Delete(id)
+}
+
+// GanttService returns the schedule service for a scenario. Changes require a draft scenario
+func (s *PPICScenarioService) GanttService(id int64, forWrite bool) (*GanttService, error) {
+	if err := s.checkAccess(id, forWrite); err != nil {
+		return nil, err
+	}
+	return s.ganttService.ForScenario(id), nil
+}
+
+// LinkService returns the link service for a scenario. Changes require a draft scenario
+func (s *PPICScenarioService) LinkService(id int64, forWrite bool) (*PPICLinkService, error) {
+	if err := s.checkAccess(id, forWrite); err != nil {
+		return nil, err
+	}
+	return s.linkService.ForScenario(id), nil
+}
+
+func (s *PPICScenarioService) checkAccess(id int64, forWrite bool) error {
+	scenario, err := s.GetScenario(id)
+	if err != nil {
+		return err
+	}
+	if forWrite && scenario.Status != models.ScenarioStatusDraft {
+		return fmt.Errorf("scenario is %s and can no longer be changed", scenario.Status)
+	}
+	return nil
+}
+
+// CompareScenario compares a scenario's schedule dates and machine load against the live board
+func (s *PPICScenarioService) CompareScenario(id int64) (*models.ScenarioComparison, error) {
+	scenario, err := s.GetScenario(id)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := s.ganttService.ppicRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load live schedules: %w", err)
+	}
+	copies, err := s.ganttService.ppicRepo.ForScenario(id).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scenario schedules: %w", err)
+	}
+	sources, err := s.repo.GetSources(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scenario sources: %w", err)
+	}
+
+	comparison := CompareScenarioSchedules(live, copies, sources)
+	comparison.Scenario = scenario
+	return comparison, nil
+}
+
+// PromoteScenario makes a draft scenario the live board
+func (s *PPICScenarioService) PromoteScenario(id int64, force bool, userID int64) (*models.PPICScenario, error) {
+	scenario, err := s.GetScenario(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Live board before promotion, for the change history
+	before, err := s.ganttService.ppicRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load live schedules: %w", err)
+	}
+
+	if err := s.repo.Promote(id, force); err != nil {
+		return nil, err
+	}
+
+	if after, err := s.ganttService.ppicRepo.GetAll(); err == nil {
+		s.recordPromotion(before, after, userID, models.ScenarioPromotedChangeCause(scenario.Name))
+	}
+	return s.GetScenario(id)
+}
+
+func (s *PPICScenarioService) recordPromotion(before, after []models.PPICSchedule, userID int64, cause string) {
+	afterByID := make(map[int64]*models.PPICSchedule, len(after))
+	for i := range after {
+		afterByID[after[i].ID] = &after[i]
+	}
+	for i := range before {
+		if promoted, ok := afterByID[before[i].ID]; ok {
+			recordScheduleChanges(s.ganttService.historyRepo, &before[i], promoted, userID, cause)
+		} else {
+			recordScheduleDeleted(s.ganttService.historyRepo, &before[i], userID, cause)
+		}
+	}
+}
+
+// CompareScenarioSchedules diffs scenario copies against the live schedules they came from.
+// sources must list every copy in the scenario, including copies deleted in the scenario
+func CompareScenarioSchedules(live, scenario []models.PPICSchedule, sources []models.ScenarioScheduleSource) *models.ScenarioComparison {
+	comparison := &models.ScenarioComparison{
+		Schedules:   []models.ScenarioScheduleDiff{},
+		MachineLoad: []models.ScenarioMachineLoad{},
+	}
+
+	liveByID := make(map[int64]*models.PPICSchedule, len(live))
+	for i := range live {
+		liveByID[live[i].ID] = &live[i]
+	}
+	scenarioByID := make(map[int64]*models.PPICSchedule, len(scenario))
+	for i := range scenario {
+		scenarioByID[scenario[i].ID] = &scenario[i]
+	}
+
+	referenced := make(map[int64]bool)
+	for _, source := range sources {
+		var liveSchedule *models.PPICSchedule
+		if source.SourceScheduleID != nil {
+			referenced[*source.SourceScheduleID] = true
+			liveSchedule = liveByID[*source.SourceScheduleID]
+		}
+		copySchedule := scenarioByID[source.ScheduleID]
+
+		var diff models.ScenarioScheduleDiff
+		switch {
+		case source.Deleted && liveSchedule != nil:
+			diff = scheduleDiff(liveSchedule, nil, models.ScenarioChangeRemoved)
+		case source.Deleted || copySchedule == nil:
+			continue
+		case liveSchedule == nil:
+			diff = scheduleDiff(nil, copySchedule, models.ScenarioChangeAdded)
+		case liveSchedule.StartDate.Equal(copySchedule.StartDate) && liveSchedule.FinishDate.Equal(copySchedule.FinishDate):
+			diff = scheduleDiff(liveSchedule, copySchedule, models.ScenarioChangeUnchanged)
+		default:
+			diff = scheduleDiff(liveSchedule, copySchedule, models.ScenarioChangeMoved)
+		}
+		comparison.Schedules = append(comparison.Schedules, diff)
+		if diff.Change != models.ScenarioChangeUnchanged {
+			comparison.ChangedCount++
+		}
+	}
+
+	for i := range live {
+		if !referenced[live[i].ID] {
+			comparison.Schedules = append(comparison.Schedules, scheduleDiff(&live[i], nil, models.ScenarioChangeLiveOnly))
+		}
+	}
+
+	sort.SliceStable(comparison.Schedules, func(i, j int) bool {
+		return comparison.Schedules[i].NJO < comparison.Schedules[j].NJO
+	})
+
+	// Project finish and machine load on each board
+	loads := make(map[int64]*models.ScenarioMachineLoad)
+	var machineOrder []int64
+	addLoad := func(schedules []models.PPICSchedule, isLive bool) {
+		for _, schedule := range schedules {
+			for _, ma := range schedule.MachineAssignments {
+				load, ok := loads[ma.MachineID]
+				if !ok {
+					load = &models.ScenarioMachineLoad{MachineID: ma.MachineID, MachineName: ma.MachineName, MachineCode: ma.MachineCode}
+					loads[ma.MachineID] = load
+					machineOrder = append(machineOrder, ma.MachineID)
+				}
+				if isLive {
+					load.LiveHours += ma.TargetHours
+				} else {
+					load.ScenarioHours += ma.TargetHours
+				}
+			}
+		}
+	}
+	addLoad(live, true)
+	addLoad(scenario, false)
+
+	for _, machineID := range machineOrder {
+		load := loads[machineID]
+		load.DeltaHours = load.ScenarioHours - load.LiveHours
+		comparison.MachineLoad = append(comparison.MachineLoad, *load)
+	}
+	sort.SliceStable(comparison.MachineLoad, func(i, j int) bool {
+		return comparison.MachineLoad[i].MachineName < comparison.MachineLoad[j].MachineName
+	})
+
+	comparison.LiveFinish = latestFinish(live)
+	comparison.ScenarioFinish = latestFinish(scenario)
+
+	return comparison
+}
+
+func scheduleDiff(live, scenario *models.PPICSchedule, change string) models.ScenarioScheduleDiff {
+	diff := models.ScenarioScheduleDiff{Change: change}
+	if live != nil {
+		id, start, finish := live.ID, live.StartDate, live.FinishDate
+		diff.NJO, diff.PartName = live.NJO, live.PartName
+		diff.LiveScheduleID, diff.LiveStartDate, diff.LiveFinishDate = &id, &start, &finish
+	}
+	if scenario != nil {
+		id, start, finish := scenario.ID, scenario.StartDate, scenario.FinishDate
+		diff.NJO, diff.PartName = scenario.NJO, scenario.PartName
+		diff.ScenarioScheduleID, diff.ScenarioStartDate, diff.ScenarioFinishDate = &id, &start, &finish
+	}
+	if live != nil && scenario != nil {
+		diff.FinishDeltaDays = int(math.Round(scenario.FinishDate.Sub(live.FinishDate).Hours() / 24))
+	}
+	return diff
+}
+
+func latestFinish(schedules []models.PPICSchedule) *time.Time {
+	var latest *time.Time
+	for i := range schedules {
+		if latest == nil || schedules[i].FinishDate.After(*latest) {
+			finish := schedules[i].FinishDate
+			latest = &finish
+		}
+	}
+	return latest
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+)
+
+type ProductionReportService struct {
+	repo            *repository.ProductionReportRepository
+	machineRepo     *repository.MachineRepository
+	ppicRepo        *repository.PPICScheduleRepository
+	jobOrderRepo    *repository.JobOrderRepository
+	andonRepo       *repository.AndonRepository
+	downtimeService *MachineDowntimeService
+	calendarService *CalendarService
+}
+
+func NewProductionReportService(repo *repository.ProductionReportRepository, machineRepo *repository.MachineRepository, ppicRepo *repository.PPICScheduleRepository, jobOrderRepo *repository.JobOrderRepository, andonRepo *repository.AndonRepository, downtimeService *MachineDowntimeService, calendarService *CalendarService) *ProductionReportService {
+	return &ProductionReportService{
+		repo:            repo,
+		machineRepo:     machineRepo,
+		ppicRepo:        ppicRepo,
+		jobOrderRepo:    jobOrderRepo,
+		andonRepo:       andonRepo,
+		downtimeService: downtimeService,
+		calendarService: calendarService,
+	}
+}
+
+// ========== Production Reports ==========
+
+// GetReports returns production reports, newest first, produced in a date range
+func (s *ProductionReportService) GetReports(filter models.ProductionReportFilterRequest) ([]models.ProductionReport, error) {
+	var from, to time.Time
+	if filter.StartDate != "" {
+		t, err := time.Parse("2006-01-02", filter.StartDate)
+		if err != nil {
+			return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+		}
+		from = t
+	}
+	if filter.EndDate != "" {
+		t, err := time.Parse("2006-01-02", filter.EndDate)
+		if err != nil {
+			return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+		}
+		to = t.AddDate(0, 0, 1)
+	}
+
+	reports, err := s.repo.GetAll(filter, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get production reports: %w", err)
+	}
+	return reports, nil
+}
+
+// GetReport returns a single production report
+func (s *ProductionReportService) GetReport(id int64) (*models.ProductionReport, error) {
+	report, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("production report not found")
+	}
+	return report, nil
+}
+
+// CreateReport records good and scrap parts of a machine. Reported against an operation of a
+// schedule, the machine and part come from it. Without ideal_cycle_seconds the ideal cycle time of
+// the previous report of the operation (or of the part on the machine) is used
+func (s *ProductionReportService) CreateReport(req *models.CreateProductionReportRequest, reportedBy int64) (*models.ProductionReport, error) {
+	now := time.Now()
+	report := &models.ProductionReport{
+		MachineID:     req.MachineID,
+		ProducedAt:    now,
+		GoodQuantity:  req.GoodQuantity,
+		ScrapQuantity: req.ScrapQuantity,
+		ScrapReason:   strings.TrimSpace(req.ScrapReason),
+		Notes:         req.Notes,
+		ReportedBy:    reportedBy,
+	}
+
+	if req.ScheduleID != nil && *req.ScheduleID > 0 {
+		schedule, err := s.ppicRepo.GetByID(*req.ScheduleID)
+		if err != nil || schedule == nil {
+			return nil, fmt.Errorf("schedule %d not found", *req.ScheduleID)
+		}
+		report.ScheduleID = req.ScheduleID
+		report.PartName = schedule.PartName
+
+		if req.AssignmentID != nil && *req.AssignmentID > 0 {
+			var assignment *models.MachineAssignment
+			for i := range schedule.MachineAssignments {
+				if schedule.MachineAssignments[i].ID == *req.AssignmentID {
+					assignment = &schedule.MachineAssignments[i]
+				}
+			}
+			if assignment == nil {
+				return nil, fmt.Errorf("assignment %d is not part of schedule %s", *req.AssignmentID, schedule.NJO)
+			}
+			if report.MachineID > 0 && report.MachineID != assignment.MachineID {
+				return nil, fmt.Errorf("machine_id does not match the machine of assignment %d", assignment.ID)
+			}
+			report.MachineID = assignment.MachineID
+			report.AssignmentID = req.AssignmentID
+		}
+	} else if req.AssignmentID != nil && *req.AssignmentID > 0 {
+		return nil, errors.New("schedule_id is required with assignment_id")
+	}
+	if report.MachineID == 0 {
+		return nil, errors.New("machine_id or assignment_id is required")
+	}
+	if machine, err := s.machineRepo.GetByID(report.MachineID); err != nil || machine == nil {
+		return nil, fmt.Errorf("machine %d not found", report.MachineID)
+	}
+	if req.JobOrderID != nil && *req.JobOrderID > 0 {
+		if err := s.checkJobOrder(*req.JobOrderID); err != nil {
+			return nil, err
+		}
+		report.JobOrderID = req.JobOrderID
+	}
+
+	if req.ProducedAt != "" {
+		producedAt, err := models.ParseDowntimeTime("produced_at", req.ProducedAt)
+		if err != nil {
+			return nil, err
+		}
+		report.ProducedAt = producedAt
+	}
+	if err := checkProductionReport(report, now); err != nil {
+		return nil, err
+	}
+
+	if req.IdealCycleSeconds != nil {
+		report.IdealCycleSeconds = *req.IdealCycleSeconds
+	} else {
+		ideal, err := s.repo.LatestIdealCycle(report.AssignmentID, report.MachineID, report.PartName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ideal cycle time: %w", err)
+		}
+		if ideal <= 0 {
+			return nil, errors.New("ideal_cycle_seconds is required for the first report of this operation")
+		}
+		report.IdealCycleSeconds = ideal
+	}
+
+	if err := s.repo.Create(report); err != nil {
+		return nil, fmt.Errorf("failed to create production report: %w", err)
+	}
+	return report, nil
+}
+
+// UpdateReport corrects the counts, time, ideal cycle time or notes of a report; omitted fields are kept
+func (s *ProductionReportService) UpdateReport(id int64, req *models.UpdateProductionReportRequest) (*models.ProductionReport, error) {
+	report, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, errors.New("production report not found")
+	}
+
+	if req.ProducedAt != "" {
+		if report.ProducedAt, err = models.ParseDowntimeTime("produced_at", req.ProducedAt); err != nil {
+			return nil, err
+		}
+	}
+	if req.GoodQuantity != nil {
+		report.GoodQuantity = *req.GoodQuantity
+	}
+	if req.ScrapQuantity != nil {
+		report.ScrapQuantity = *req.ScrapQuantity
+	}
+	if req.ScrapReason != nil {
+		report.ScrapReason = strings.TrimSpace(*req.ScrapReason)
+	}
+	if req.IdealCycleSeconds != nil {
+		report.IdealCycleSeconds = *req.IdealCycleSeconds
+	}
+	if req.Notes != nil {
+		report.Notes = *req.Notes
+	}
+	if err := checkProductionReport(report, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(report); err != nil {
+		return nil, fmt.Errorf("failed to update production report: %w", err)
+	}
+	return report, nil
+}
+
+// DeleteReport removes a production report
+func (s *ProductionReportService) DeleteReport(id int64) error {
+	if err := s.repo.Delete(id); err != nil {
+		return errors.New("production report not found")
+	}
+	return nil
+}
+
+// ========== OEE ==========
+
+// GetOEE reports availability, performance, quality and OEE per machine and shift, day or week
+func (s *ProductionReportService) GetOEE(filter models.OEEFilterRequest) (*models.OEEReport, error) {
+	start, err := time.Parse("2006-01-02", filter.StartDate)
+	if err != nil {
+		return nil, errors.New("invalid start_date format. Use YYYY-MM-DD")
+	}
+	end, err := time.Parse("2006-01-02", filter.EndDate)
+	if err != nil {
+		return nil, errors.New("invalid end_date format. Use YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end_date must be after start_date")
+	}
+	if end.Sub(start) > 366*24*time.Hour {
+		return nil, errors.New("date range cannot exceed one year")
+	}
+	if filter.Period == "" {
+		filter.Period = models.UtilizationPeriodDay
+	}
+	if !models.ValidateOEEPeriod(filter.Period) {
+		return nil, errors.New("invalid period. Must be: shift, day or week")
+	}
+	// The last shift may run into the day after the range
+	from, to := start, end.AddDate(0, 0, 2)
+
+	allMachines, err := s.ppicRepo.GetAllMachines()
+	if err != nil {
+		return nil, err
+	}
+	stops, err := s.andonRepo.GetEvents(models.AndonEventFilterRequest{MachineID: filter.MachineID}, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get andon events: %w", err)
+	}
+	downtimes, err := s.downtimeService.DowntimesBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := s.repo.GetAll(models.ProductionReportFilterRequest{MachineID: filter.MachineID}, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get production reports: %w", err)
+	}
+
+	var machines []models.Machine
+	calendars := make(map[int64]*models.WorkingCalendar)
+	data := make(map[int64]models.OEEMachineData)
+	for _, m := range allMachines {
+		if filter.MachineID > 0 && m.ID != filter.MachineID {
+			continue
+		}
+		if filter.MachineType != "" && !strings.EqualFold(m.MachineType, filter.MachineType) {
+			continue
+		}
+		if filter.Location != "" && !strings.EqualFold(m.Location, filter.Location) {
+			continue
+		}
+		calendar, err := s.calendarService.GetMachineCalendar(m.ID)
+		if err != nil {
+			return nil, err
+		}
+		runs, err := s.machineRepo.GetRunIntervals(m.ID, from)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get run time of machine %d: %w", m.ID, err)
+		}
+		machines = append(machines, m)
+		calendars[m.ID] = calendar
+		data[m.ID] = models.OEEMachineData{Runs: runs, Downtime: downtimes[m.ID]}
+	}
+	for _, event := range stops {
+		if d, ok := data[event.MachineID]; ok {
+			d.Stops = append(d.Stops, event)
+			data[event.MachineID] = d
+		}
+	}
+	for _, report := range reports {
+		if d, ok := data[report.MachineID]; ok {
+			d.Reports = append(d.Reports, report)
+			data[report.MachineID] = d
+		}
+	}
+
+	return BuildOEEReport(machines, calendars, data, start, end, filter.Period, time.Now()), nil
+}
+
+func (s *ProductionReportService) checkJobOrder(id int64) error {
+	jobOrder, err := s.jobOrderRepo.GetByID(id)
+	if err != nil || jobOrder == nil {
+		return fmt.Errorf("job order %d not found", id)
+	}
+	return nil
+}
+
+// checkProductionReport requires parts and a production time that isn't in the future
+func checkProductionReport(report *models.ProductionReport, now time.Time) error {
+	if report.TotalQuantity() == 0 {
+		return errors.New("good_quantity or scrap_quantity must be greater than 0")
+	}
+	if report.ProducedAt.After(now) {
+		return errors.New("produced_at can't be in the future")
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+)
+
+type RoutingTemplateService struct {
+	repo           *repository.RoutingTemplateRepository
+	machineRepo    *repository.MachineRepository
+	settingService *SettingService
+}
+
+func NewRoutingTemplateService(repo *repository.RoutingTemplateRepository, machineRepo *repository.MachineRepository, settingService *SettingService) *RoutingTemplateService {
+	return &RoutingTemplateService{repo: repo, machineRepo: machineRepo, settingService: settingService}
+}
+
+// GetTemplates returns all templates, optionally of one part family
+func (s *RoutingTemplateService) GetTemplates(partFamily string) ([]models.RoutingTemplate, error) {
+	return s.repo.GetAll(strings.TrimSpace(partFamily))
+}
+
+// GetTemplate returns a template with its steps
+func (s *RoutingTemplateService) GetTemplate(id int64) (*models.RoutingTemplate, error) {
+	template, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, errors.New("routing template not found")
+	}
+	return template, nil
+}
+
+// CreateTemplate validates and stores a template
+func (s *RoutingTemplateService) CreateTemplate(req *models.CreateRoutingTemplateRequest, createdBy int64) (*models.RoutingTemplate, error) {
+	name := strings.TrimSpace(req.Name)
+	if err := s.validateName(name, 0); err != nil {
+		return nil, err
+	}
+	if err := s.validateSteps(req.Steps); err != nil {
+		return nil, err
+	}
+
+	template := &models.RoutingTemplate{
+		Name:        name,
+		PartFamily:  strings.TrimSpace(req.PartFamily),
+		Description: req.Description,
+		CreatedBy:   createdBy,
+		Steps:       models.NewRoutingTemplateSteps(req.Steps),
+	}
+	if err := s.repo.Create(template); err != nil {
+		return nil, fmt.Errorf("failed to create routing template: %w", err)
+	}
+	return s.GetTemplate(template.ID)
+}
+
+// UpdateTemplate updates a template; steps, when given, replace all of its steps
+func (s *RoutingTemplateService) UpdateTemplate(id int64, req *models.UpdateRoutingTemplateRequest) (*models.RoutingTemplate, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" && name != template.Name {
+		if err := s.validateName(name, id); err != nil {
+			return nil, err
+		}
+		template.Name = name
+	}
+	if req.PartFamily != nil {
+		template.PartFamily = strings.TrimSpace(*req.PartFamily)
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+
+	var steps []models.RoutingTemplateStep
+	if req.Steps != nil {
+		if err := s.validateSteps(req.Steps); err != nil {
+			return nil, err
+		}
+		steps = models.NewRoutingTemplateSteps(req.Steps)
+	}
+
+	if err := s.repo.Update(template, steps); err != nil {
+		return nil, fmt.Errorf("failed to update routing template: %w", err)
+	}
+	return s.GetTemplate(id)
+}
+
+// DeleteTemplate deletes a template. Schedules created from it keep their machines
+func (s *RoutingTemplateService) DeleteTemplate(id int64) error {
+	if err := s.repo.Delete(id); err != nil {
+		return errors.New("routing template not found")
+	}
+	return nil
+}
+
+// ApplyTemplate resolves a template into the machine assignments of a schedule. Overrides replace
+// the steps with the same sequence and add steps with new sequences; skipped sequences are dropped
+func (s *RoutingTemplateService) ApplyTemplate(id int64, overrides []models.CreateMachineAssignmentRequest, skip []int) (*models.AppliedRouting, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	machines, err := s.machineRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machines: %w", err)
+	}
+	return models.ApplyRoutingTemplate(template, machines, overrides, skip)
+}
+
+func (s *RoutingTemplateService) validateName(name string, excludeID int64) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+	existing, err := s.repo.GetByName(name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != excludeID {
+		return errors.New("a routing template with this name already exists")
+	}
+	return nil
+}
+
+// validateSteps checks the steps against the machine maximum and that their machines exist
+func (s *RoutingTemplateService) validateSteps(steps []models.RoutingTemplateStepRequest) error {
+	maxSteps, err := s.settingService.MaxMachinesPerSchedule()
+	if err != nil {
+		return err
+	}
+	if err := models.ValidateRoutingSteps(steps, maxSteps); err != nil {
+		return err
+	}
+
+	checkMachine := func(id *int64, label string) error {
+		if id == nil {
+			return nil
+		}
+		machine, err := s.machineRepo.GetByID(*id)
+		if err != nil || machine == nil {
+			return fmt.Errorf("%s: machine %d not found", label, *id)
+		}
+		return nil
+	}
+	for i, step := range steps {
+		if err := checkMachine(step.MachineID, fmt.Sprintf("step %d", i+1)); err != nil {
+			return err
+		}
+		for j, alt := range step.Alternatives {
+			if err := checkMachine(alt.MachineID, fmt.Sprintf("step %d, alternative %d", i+1, j+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+)
+
+type SettingService struct {
+	repo *repository.SettingRepository
+}
+
+func NewSettingService(repo *repository.SettingRepository) *SettingService {
+	return &SettingService{repo: repo}
+}
+
+// GetPlanningSettings returns the planning settings, with defaults for those not configured
+func (s *SettingService) GetPlanningSettings() (*models.PlanningSettings, error) {
+	values, err := s.repo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	settings := models.NewPlanningSettings(values)
+	return &settings, nil
+}
+
+// UpdatePlanningSettings stores the given planning settings. A lower machine maximum applies to
+// new and edited schedules; existing schedules keep their machines
+func (s *SettingService) UpdatePlanningSettings(req *models.UpdatePlanningSettingsRequest, userID int64) (*models.PlanningSettings, error) {
+	if req.MaxMachinesPerSchedule != nil {
+		if err := s.repo.Set(models.SettingMaxMachinesPerSchedule, strconv.Itoa(*req.MaxMachinesPerSchedule), userID); err != nil {
+			return nil, fmt.Errorf("failed to update settings: %w", err)
+		}
+	}
+	return s.GetPlanningSettings()
+}
+
+// MaxMachinesPerSchedule returns the configured maximum of machine assignments per schedule
+func (s *SettingService) MaxMachinesPerSchedule() (int, error) {
+	settings, err := s.GetPlanningSettings()
+	if err != nil {
+		return 0, err
+	}
+	return settings.MaxMachinesPerSchedule, nil
+}
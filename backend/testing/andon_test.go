@@ -0,0 +1,114 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Andon Tests
+// =============================================================================
+
+// andonReasonCodes is Mechanical > Spindle, Mechanical > Coolant and Material
+func andonReasonCodes() map[int64]models.AndonReasonCode {
+	return map[int64]models.AndonReasonCode{
+		1: {ID: 1, Code: "MECH", Name: "Mechanical"},
+		2: {ID: 2, Code: "MECH-SPN", Name: "Spindle", ParentID: int64Ptr(1)},
+		3: {ID: 3, Code: "MECH-CLT", Name: "Coolant", ParentID: int64Ptr(1), SortOrder: -1},
+		4: {ID: 4, Code: "MAT", Name: "Material"},
+	}
+}
+
+func andonEvent(reasonID int64, start, end time.Time) models.AndonEvent {
+	event := models.AndonEvent{MachineID: 1, StartTime: start, EndTime: &end}
+	if reasonID > 0 {
+		event.ReasonCodeID = int64Ptr(reasonID)
+	}
+	return event
+}
+
+func TestBuildAndonReasonTree(t *testing.T) {
+	codes := andonReasonCodes()
+	list := []models.AndonReasonCode{codes[4], codes[2], codes[1], codes[3]}
+
+	tree := models.BuildAndonReasonTree(list)
+	require.Len(t, tree, 2)
+	assert.Equal(t, "MAT", tree[0].Code)
+	assert.Equal(t, "MECH", tree[1].Code)
+	require.Len(t, tree[1].Children, 2)
+	// Sort order first, then code
+	assert.Equal(t, "MECH-CLT", tree[1].Children[0].Code)
+	assert.Equal(t, "Mechanical > Spindle", tree[1].Children[1].Path)
+	assert.Empty(t, tree[0].Children)
+}
+
+func TestCheckAndonReasonParent(t *testing.T) {
+	codes := andonReasonCodes()
+
+	assert.NoError(t, models.CheckAndonReasonParent(codes, 4, 2))
+	assert.Error(t, models.CheckAndonReasonParent(codes, 1, 1), "under itself")
+	assert.Error(t, models.CheckAndonReasonParent(codes, 1, 2), "under its own sub-code")
+	assert.Error(t, models.CheckAndonReasonParent(codes, 4, 99), "unknown parent")
+}
+
+func TestBuildAndonPareto_ByReason(t *testing.T) {
+	from, to := at(6, 0), at(8, 0)
+	events := []models.AndonEvent{
+		andonEvent(2, at(6, 8), at(6, 10)),  // 120 min
+		andonEvent(2, at(7, 9), at(7, 10)),  // 60 min
+		andonEvent(4, at(7, 13), at(7, 14)), // 60 min
+		andonEvent(3, at(5, 23), at(6, 1)),  // 60 min inside the range
+		andonEvent(0, at(7, 15), at(7, 15).Add(30*time.Minute)),
+		andonEvent(4, at(9, 8), at(9, 9)), // Outside
+	}
+
+	report := models.BuildAndonPareto(events, andonReasonCodes(), models.AndonParetoByReason, from, to, at(20, 0))
+	assert.Equal(t, "2025-01-06", report.StartDate)
+	assert.Equal(t, "2025-01-07", report.EndDate)
+	assert.Equal(t, 5, report.TotalEvents)
+	assert.Equal(t, 330.0, report.TotalMinutes)
+
+	require.Len(t, report.Items, 4)
+	assert.Equal(t, "MECH-SPN", report.Items[0].Code)
+	assert.Equal(t, 2, report.Items[0].Events)
+	assert.Equal(t, 180.0, report.Items[0].Minutes)
+	assert.Equal(t, 54.5, report.Items[0].Percent)
+	// Ties are ordered by path
+	assert.Equal(t, "Material", report.Items[1].Path)
+	assert.Equal(t, "Mechanical > Coolant", report.Items[2].Path)
+	assert.Equal(t, "Unclassified", report.Items[3].Name)
+	assert.Nil(t, report.Items[3].ReasonCodeID)
+	assert.Equal(t, 100.0, report.Items[3].CumulativePercent)
+}
+
+func TestBuildAndonPareto_ByCategoryCountsOpenEventsToNow(t *testing.T) {
+	events := []models.AndonEvent{
+		andonEvent(2, at(6, 8), at(6, 9)),
+		andonEvent(3, at(6, 10), at(6, 11)),
+		{MachineID: 2, ReasonCodeID: int64Ptr(4), StartTime: at(6, 12)}, // Still open
+	}
+
+	report := models.BuildAndonPareto(events, andonReasonCodes(), models.AndonParetoByCategory, at(6, 0), at(7, 0), at(6, 15))
+	require.Len(t, report.Items, 2)
+	assert.Equal(t, "MAT", report.Items[0].Code)
+	assert.Equal(t, 180.0, report.Items[0].Minutes)
+	assert.Equal(t, "MECH", report.Items[1].Code)
+	assert.Equal(t, 2, report.Items[1].Events)
+	assert.Equal(t, 120.0, report.Items[1].Minutes)
+	assert.Equal(t, 60.0, report.Items[0].CumulativePercent)
+}
+
+func TestAndonEvent_DurationAndMachineAvailability(t *testing.T) {
+	event := models.AndonEvent{StartTime: at(6, 8)}
+	assert.True(t, event.IsOpen())
+	assert.Equal(t, 90.0, event.DurationMinutes(at(6, 8).Add(90*time.Minute)))
+
+	// A machine down on an andon stop keeps its planned capacity
+	machine := models.Machine{Status: models.MachineStatusDown}
+	assert.True(t, machine.IsAvailable())
+}
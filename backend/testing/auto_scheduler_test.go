@@ -0,0 +1,165 @@
+package testing
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Auto-Scheduler Tests
+// =============================================================================
+
+func at(day, hour int) time.Time {
+	return time.Date(2025, 1, day, hour, 0, 0, 0, time.UTC)
+}
+
+func pendingSchedule(t *testing.T, id int64, priority string, assignments ...models.MachineAssignment) models.PPICSchedule {
+	for i := range assignments {
+		assignments[i].ScheduleID = id
+		assignments[i].ID = id*10 + int64(assignments[i].Sequence)
+	}
+	return models.PPICSchedule{
+		ID:                 id,
+		NJO:                fmt.Sprintf("NJO-%d", id),
+		Priority:           priority,
+		Status:             models.ScheduleStatusPending,
+		StartDate:          mustDate(t, "2025-01-06"),
+		FinishDate:         mustDate(t, "2025-01-06"),
+		MachineAssignments: assignments,
+	}
+}
+
+// Monday 2025-01-06, default Mon-Fri 08:00-17:00 calendar
+func autoScheduleContext() services.AutoScheduleContext {
+	return services.AutoScheduleContext{
+		StartFrom:     at(6, 8),
+		PlantCalendar: models.NewWorkingCalendar(nil, nil),
+	}
+}
+
+func TestPlanAutoSchedule_PriorityOrderOnSharedMachine(t *testing.T) {
+	schedules := []models.PPICSchedule{
+		pendingSchedule(t, 1, models.PriorityLow, models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 4}),
+		pendingSchedule(t, 2, models.PriorityTopUrgent, models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 4}),
+	}
+
+	plan := services.PlanAutoSchedule(schedules, autoScheduleContext())
+	require.Len(t, plan.Scheduled, 2)
+	assert.Empty(t, plan.Skipped)
+
+	assert.Equal(t, int64(2), plan.Scheduled[0].ScheduleID, "Top Urgent goes first")
+	assert.Equal(t, at(6, 8), plan.Scheduled[0].Operations[0].ScheduledStart)
+	assert.Equal(t, at(6, 12), plan.Scheduled[0].Operations[0].ScheduledEnd)
+
+	assert.Equal(t, at(6, 12), plan.Scheduled[1].Operations[0].ScheduledStart)
+	assert.Equal(t, at(6, 16), plan.Scheduled[1].Operations[0].ScheduledEnd)
+}
+
+func TestPlanAutoSchedule_RoutingSequenceAndWorkingHours(t *testing.T) {
+	schedules := []models.PPICSchedule{
+		pendingSchedule(t, 1, models.PriorityMedium,
+			models.MachineAssignment{MachineID: 2, Sequence: 2, TargetHours: 3},
+			models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 7},
+		),
+	}
+
+	plan := services.PlanAutoSchedule(schedules, autoScheduleContext())
+	require.Len(t, plan.Scheduled, 1)
+	ops := plan.Scheduled[0].Operations
+	require.Len(t, ops, 2)
+
+	assert.Equal(t, 1, ops[0].Sequence)
+	assert.Equal(t, at(6, 15), ops[0].ScheduledEnd)
+	// Sequence 2 starts when sequence 1 ends and rolls over to Tuesday morning
+	assert.Equal(t, at(6, 15), ops[1].ScheduledStart)
+	assert.Equal(t, at(7, 9), ops[1].ScheduledEnd)
+
+	assert.Equal(t, "2025-01-06", plan.Scheduled[0].ProposedStartDate.Format("2006-01-02"))
+	assert.Equal(t, "2025-01-07", plan.Scheduled[0].ProposedFinishDate.Format("2006-01-02"))
+}
+
+func TestPlanAutoSchedule_AvoidsExistingBookings(t *testing.T) {
+	ctx := autoScheduleContext()
+	ctx.Bookings = map[int64][]models.MachineAssignmentWindow{
+		1: {{ScheduleID: 99, NJO: "NJO-FIXED", ScheduledStart: at(6, 9), ScheduledEnd: at(6, 11)}},
+	}
+
+	schedules := []models.PPICSchedule{
+		pendingSchedule(t, 1, models.PriorityUrgent, models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 2}),
+	}
+
+	plan := services.PlanAutoSchedule(schedules, ctx)
+	require.Len(t, plan.Scheduled, 1)
+	assert.Equal(t, at(6, 11), plan.Scheduled[0].Operations[0].ScheduledStart)
+	assert.Equal(t, at(6, 13), plan.Scheduled[0].Operations[0].ScheduledEnd)
+}
+
+func TestPlanAutoSchedule_LinkConstraintBeatsPriority(t *testing.T) {
+	ctx := autoScheduleContext()
+	ctx.Links = []models.PPICLink{
+		{SourceScheduleID: 1, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart},
+	}
+
+	schedules := []models.PPICSchedule{
+		pendingSchedule(t, 1, models.PriorityLow, models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 2}),
+		pendingSchedule(t, 2, models.PriorityTopUrgent, models.MachineAssignment{MachineID: 2, Sequence: 1, TargetHours: 2}),
+	}
+
+	plan := services.PlanAutoSchedule(schedules, ctx)
+	require.Len(t, plan.Scheduled, 2)
+	assert.Equal(t, int64(1), plan.Scheduled[0].ScheduleID, "Predecessor is planned first")
+	// Finish-to-start: the successor starts the working day after the predecessor finishes
+	assert.Equal(t, at(7, 8), plan.Scheduled[1].Operations[0].ScheduledStart)
+}
+
+func TestPlanAutoSchedule_SkipsInvalidSchedules(t *testing.T) {
+	ctx := autoScheduleContext()
+	ctx.Links = []models.PPICLink{
+		{SourceScheduleID: 1, TargetScheduleID: 3, LinkType: models.LinkTypeFinishToStart},
+	}
+
+	schedules := []models.PPICSchedule{
+		pendingSchedule(t, 1, models.PriorityMedium, models.MachineAssignment{MachineID: 1, Sequence: 1}),
+		pendingSchedule(t, 2, models.PriorityMedium),
+		pendingSchedule(t, 3, models.PriorityMedium, models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 1}),
+	}
+
+	plan := services.PlanAutoSchedule(schedules, ctx)
+	assert.Empty(t, plan.Scheduled)
+	require.Len(t, plan.Skipped, 3)
+
+	reasons := make(map[int64]string)
+	for _, s := range plan.Skipped {
+		reasons[s.ScheduleID] = s.Reason
+	}
+	assert.Contains(t, reasons[1], "target_hours")
+	assert.Equal(t, "no machine assignments", reasons[2])
+	assert.Contains(t, reasons[3], "predecessor")
+}
+
+func TestCheckAutoScheduleVersions(t *testing.T) {
+	first := pendingSchedule(t, 1, models.PriorityLow, models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 4})
+	first.Version = 3
+	plan := services.PlanAutoSchedule([]models.PPICSchedule{first}, autoScheduleContext())
+	require.Len(t, plan.Scheduled, 1)
+	assert.Equal(t, 3, plan.Scheduled[0].Version)
+
+	assert.NoError(t, models.CheckAutoScheduleVersions(plan.Scheduled, map[int64]int{1: 3}))
+	assert.ErrorIs(t, models.CheckAutoScheduleVersions(plan.Scheduled, nil), models.ErrVersionRequired)
+	// Edited after the preview
+	assert.ErrorIs(t, models.CheckAutoScheduleVersions(plan.Scheduled, map[int64]int{1: 2}), models.ErrStaleVersion)
+}
+
+func TestPriorityRank(t *testing.T) {
+	assert.Less(t, models.PriorityRank(models.PriorityTopUrgent), models.PriorityRank(models.PriorityUrgent))
+	assert.Less(t, models.PriorityRank(models.PriorityUrgent), models.PriorityRank(models.PriorityMedium))
+	assert.Less(t, models.PriorityRank(models.PriorityMedium), models.PriorityRank(models.PriorityLow))
+	assert.Less(t, models.PriorityRank(models.PriorityLow), models.PriorityRank("Unknown"))
+}
@@ -0,0 +1,132 @@
+package testing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+	"ganttpro-backend/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Calendar Feed Tests
+// =============================================================================
+
+func TestWriteICalendar_TimedAndAllDayEvents(t *testing.T) {
+	feed := string(utils.WriteICalendar("CNC 01", []utils.ICalEvent{
+		{UID: "a@ganttpro", Summary: "NJO-001, Bracket; rev A", Start: at(6, 8), End: at(6, 16)},
+		{UID: "b@ganttpro", Summary: "Deadline", Description: "line 1\nline 2", Start: mustDate(t, "2025-01-07"), End: mustDate(t, "2025-01-08"), AllDay: true},
+	}, at(1, 0)))
+
+	assert.True(t, strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(feed, "END:VCALENDAR\r\n"))
+	assert.Contains(t, feed, "X-WR-CALNAME:CNC 01\r\n")
+	assert.Contains(t, feed, "DTSTART:20250106T080000Z\r\nDTEND:20250106T160000Z\r\n")
+	assert.Contains(t, feed, `SUMMARY:NJO-001\, Bracket\; rev A`+"\r\n")
+	assert.Contains(t, feed, "DTSTART;VALUE=DATE:20250107\r\nDTEND;VALUE=DATE:20250108\r\n")
+	assert.Contains(t, feed, `DESCRIPTION:line 1\nline 2`+"\r\n")
+	assert.Equal(t, 2, strings.Count(feed, "BEGIN:VEVENT"))
+}
+
+func TestWriteICalendar_FoldsLongLines(t *testing.T) {
+	feed := string(utils.WriteICalendar("Feed", []utils.ICalEvent{
+		{UID: "a@ganttpro", Summary: strings.Repeat("é", 100), Start: at(6, 8), End: at(6, 9)},
+	}, at(1, 0)))
+
+	var unfolded strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(feed, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	assert.Contains(t, unfolded.String(), "SUMMARY:"+strings.Repeat("é", 100))
+}
+
+func TestMachineFeedEvents_OnlyScheduledWindowsOfTheMachine(t *testing.T) {
+	machine := &models.Machine{ID: 1, MachineCode: "CNC-01", MachineName: "CNC 01"}
+	s1, e1 := at(8, 8), at(8, 16)
+	s2, e2 := at(6, 8), at(6, 12)
+	old := at(1, 8)
+	schedules := []models.PPICSchedule{
+		{NJO: "NJO-001", PartName: "Bracket", MachineAssignments: []models.MachineAssignment{
+			{ID: 10, MachineID: 1, Sequence: 1, TargetHours: 8, ScheduledStart: &s1, ScheduledEnd: &e1},
+			{ID: 11, MachineID: 2, Sequence: 2, TargetHours: 2, ScheduledStart: &s2, ScheduledEnd: &e2},
+		}},
+		{NJO: "NJO-002", PartName: "Shaft", MachineAssignments: []models.MachineAssignment{
+			{ID: 20, MachineID: 1, Sequence: 1, TargetHours: 4.5, ScheduledStart: &s2, ScheduledEnd: &e2},
+			{ID: 21, MachineID: 1, Sequence: 2, TargetHours: 1},                           // Not scheduled yet
+			{ID: 22, MachineID: 1, Sequence: 3, ScheduledStart: &old, ScheduledEnd: &old}, // Before the window
+		}},
+	}
+
+	events := services.MachineFeedEvents(machine, schedules, at(3, 0))
+	require.Len(t, events, 2)
+	assert.Equal(t, "machine-assignment-20@ganttpro", events[0].UID)
+	assert.Equal(t, "NJO-002 - Shaft (seq 1)", events[0].Summary)
+	assert.Contains(t, events[0].Description, "Target hours: 4.5")
+	assert.Equal(t, "CNC 01", events[0].Location)
+	assert.Equal(t, "machine-assignment-10@ganttpro", events[1].UID)
+	assert.Equal(t, s1, events[1].Start)
+}
+
+func TestUserFeedEvents_PendingApprovalsAndOpenJobOrders(t *testing.T) {
+	userID := int64(7)
+	other := int64(8)
+	plans := []models.PEMOperationPlan{
+		{
+			FormNumber:   "FRM-20250101-001",
+			PartName:     "Bracket",
+			Status:       models.PEMStatusPendingApproval,
+			PPICSchedule: &models.PPICSchedule{NJO: "NJO-001", StartDate: mustDate(t, "2025-01-09")},
+			Approvals: []models.PEMApproval{
+				{ID: 1, ApproverRole: "PEM", ApproverID: &other, Status: models.ApprovalStatusPending},
+				{ID: 2, ApproverRole: "QC", ApproverID: &userID, Status: models.ApprovalStatusPending},
+			},
+		},
+		{
+			FormNumber: "FRM-20250101-002",
+			PartName:   "Shaft",
+			Status:     models.PEMStatusPendingApproval,
+			Approvals: []models.PEMApproval{
+				{ID: 3, ApproverRole: "QC", ApproverID: &userID, Status: models.ApprovalStatusPending, CreatedAt: at(2, 10)},
+			},
+		},
+	}
+	completedAt := at(5, 0)
+	jobs := []models.JobOrder{
+		{ID: 1, NJO: "NJO-010", Item: "Plate", Deadline: "2025-01-10", Status: models.ScheduleStatusPending},
+		{ID: 2, NJO: "NJO-011", Item: "Pin", Deadline: "2025-01-10", Status: models.ScheduleStatusCompleted, CompletedAt: &completedAt},
+		{ID: 3, NJO: "NJO-012", Item: "Cover", Deadline: "sometime", Status: models.ScheduleStatusPending},
+		{ID: 4, NJO: "NJO-013", Item: "Base", Deadline: "2024-11-01", Status: models.ScheduleStatusPending},
+	}
+
+	events := services.UserFeedEvents(userID, plans, jobs, at(1, 0))
+	require.Len(t, events, 3)
+
+	// The approval without a schedule falls on the day it was assigned, and is kept even though it is old
+	assert.Equal(t, "pem-approval-3@ganttpro", events[0].UID)
+	assert.Equal(t, mustDate(t, "2025-01-02"), events[0].Start)
+	assert.True(t, events[0].AllDay)
+
+	assert.Equal(t, "pem-approval-2@ganttpro", events[1].UID)
+	assert.Equal(t, mustDate(t, "2025-01-09"), events[1].Start)
+	assert.Equal(t, mustDate(t, "2025-01-10"), events[1].End)
+	assert.Contains(t, events[1].Description, "NJO: NJO-001")
+
+	assert.Equal(t, "job-order-1@ganttpro", events[2].UID)
+	assert.Equal(t, mustDate(t, "2025-01-10"), events[2].Start)
+}
+
+func TestUserFeedEvents_NoEvents(t *testing.T) {
+	events := services.UserFeedEvents(1, nil, nil, time.Now())
+	assert.Empty(t, events)
+	assert.Contains(t, string(utils.WriteICalendar("Empty", events, time.Now())), "BEGIN:VCALENDAR\r\n")
+}
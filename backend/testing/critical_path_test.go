@@ -0,0 +1,123 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Critical Path Tests
+// =============================================================================
+
+func TestComputeCriticalPath_Empty(t *testing.T) {
+	result, err := services.ComputeCriticalPath(models.AllDaysCalendar(), nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Tasks)
+	assert.Empty(t, result.CriticalChain)
+}
+
+func TestComputeCriticalPath_ChainWithParallelBranch(t *testing.T) {
+	// 1 (Jan 1-3) -> 2 (Jan 4-8) -> 4 (Jan 9-10)
+	// 1 (Jan 1-3) -> 3 (Jan 4-5) -> 4
+	schedules := []models.PPICSchedule{
+		{ID: 1, NJO: "NJO-1", StartDate: mustDate(t, "2025-01-01"), FinishDate: mustDate(t, "2025-01-03")},
+		{ID: 2, NJO: "NJO-2", StartDate: mustDate(t, "2025-01-04"), FinishDate: mustDate(t, "2025-01-08")},
+		{ID: 3, NJO: "NJO-3", StartDate: mustDate(t, "2025-01-04"), FinishDate: mustDate(t, "2025-01-05")},
+		{ID: 4, NJO: "NJO-4", StartDate: mustDate(t, "2025-01-09"), FinishDate: mustDate(t, "2025-01-10")},
+	}
+	links := []models.PPICLink{
+		{SourceScheduleID: 1, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart},
+		{SourceScheduleID: 1, TargetScheduleID: 3, LinkType: models.LinkTypeFinishToStart},
+		{SourceScheduleID: 2, TargetScheduleID: 4, LinkType: models.LinkTypeFinishToStart},
+		{SourceScheduleID: 3, TargetScheduleID: 4, LinkType: models.LinkTypeFinishToStart},
+	}
+
+	result, err := services.ComputeCriticalPath(models.AllDaysCalendar(), schedules, links)
+	require.NoError(t, err)
+	require.Len(t, result.Tasks, 4)
+
+	assert.Equal(t, "2025-01-01", result.ProjectStart.Format("2006-01-02"))
+	assert.Equal(t, "2025-01-10", result.ProjectFinish.Format("2006-01-02"))
+
+	floats := make(map[int64]int)
+	for _, task := range result.Tasks {
+		floats[task.ScheduleID] = task.TotalFloatDays
+	}
+	assert.Equal(t, 0, floats[1])
+	assert.Equal(t, 0, floats[2])
+	assert.Equal(t, 3, floats[3], "Short branch should have 3 days of float")
+	assert.Equal(t, 0, floats[4])
+
+	require.Len(t, result.CriticalChain, 3)
+	assert.Equal(t, "NJO-1", result.CriticalChain[0].NJO)
+	assert.Equal(t, "NJO-2", result.CriticalChain[1].NJO)
+	assert.Equal(t, "NJO-4", result.CriticalChain[2].NJO)
+}
+
+func TestComputeCriticalPath_LagExtendsChain(t *testing.T) {
+	schedules := []models.PPICSchedule{
+		{ID: 1, StartDate: mustDate(t, "2025-01-01"), FinishDate: mustDate(t, "2025-01-02")},
+		{ID: 2, StartDate: mustDate(t, "2025-01-03"), FinishDate: mustDate(t, "2025-01-04")},
+	}
+	links := []models.PPICLink{
+		{SourceScheduleID: 1, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart, LagDays: 2},
+	}
+
+	result, err := services.ComputeCriticalPath(models.AllDaysCalendar(), schedules, links)
+	require.NoError(t, err)
+
+	assert.Equal(t, "2025-01-05", result.Tasks[1].EarlyStart.Format("2006-01-02"))
+	assert.Equal(t, "2025-01-06", result.ProjectFinish.Format("2006-01-02"))
+	assert.True(t, result.Tasks[0].IsCritical)
+	assert.True(t, result.Tasks[1].IsCritical)
+}
+
+func TestComputeCriticalPath_CountsWorkingDays(t *testing.T) {
+	// Mon-Fri plant: 1 (Thu 2 - Fri 3) -> 2 with 1 day lag; 3 (Thu 2) -> 2
+	schedules := []models.PPICSchedule{
+		{ID: 1, StartDate: mustDate(t, "2025-01-02"), FinishDate: mustDate(t, "2025-01-03")},
+		{ID: 2, StartDate: mustDate(t, "2025-01-07"), FinishDate: mustDate(t, "2025-01-08")},
+		{ID: 3, StartDate: mustDate(t, "2025-01-02"), FinishDate: mustDate(t, "2025-01-02")},
+	}
+	links := []models.PPICLink{
+		{SourceScheduleID: 1, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart, LagDays: 1},
+		{SourceScheduleID: 3, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart},
+	}
+
+	result, err := services.ComputeCriticalPath(models.NewWorkingCalendar(nil, nil), schedules, links)
+	require.NoError(t, err)
+
+	tasks := make(map[int64]models.CriticalPathTask)
+	for _, task := range result.Tasks {
+		tasks[task.ScheduleID] = task
+	}
+	// The lag skips the weekend: Monday is the lag day, Tuesday the first allowed one
+	assert.Equal(t, "2025-01-07", tasks[2].EarlyStart.Format("2006-01-02"))
+	assert.Equal(t, "2025-01-08", result.ProjectFinish.Format("2006-01-02"))
+	assert.Equal(t, 0, tasks[1].TotalFloatDays)
+	assert.Equal(t, 0, tasks[2].TotalFloatDays)
+
+	// Task 3 may slip to Monday: Friday and Monday are its float, not the weekend
+	assert.Equal(t, "2025-01-06", tasks[3].LateStart.Format("2006-01-02"))
+	assert.Equal(t, 2, tasks[3].TotalFloatDays)
+	assert.False(t, tasks[3].IsCritical)
+}
+
+func TestComputeCriticalPath_CycleReturnsError(t *testing.T) {
+	schedules := []models.PPICSchedule{
+		{ID: 1, StartDate: mustDate(t, "2025-01-01"), FinishDate: mustDate(t, "2025-01-02")},
+		{ID: 2, StartDate: mustDate(t, "2025-01-03"), FinishDate: mustDate(t, "2025-01-04")},
+	}
+	links := []models.PPICLink{
+		{SourceScheduleID: 1, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart},
+		{SourceScheduleID: 2, TargetScheduleID: 1, LinkType: models.LinkTypeFinishToStart},
+	}
+
+	_, err := services.ComputeCriticalPath(models.AllDaysCalendar(), schedules, links)
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+package testing
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+	"ganttpro-backend/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Gantt Export Tests
+// =============================================================================
+
+func exportChart(t *testing.T, tasks int) *models.GanttChartResponse {
+	start := at(6, 8)
+	section := models.GanttSection{SectionID: "all", SectionName: "All Tasks"}
+	for i := 0; i < tasks; i++ {
+		end := at(6, 16)
+		section.Tasks = append(section.Tasks, models.GanttTask{
+			TaskID:         fmt.Sprintf("task-%d", i+1),
+			NJO:            fmt.Sprintf("NJO-%03d", i+1),
+			PartName:       "Bracket",
+			Start:          mustDate(t, "2025-01-06"),
+			End:            mustDate(t, "2025-01-08"),
+			Priority:       models.PriorityUrgent,
+			MaterialStatus: models.MaterialReady,
+			Status:         models.ScheduleStatusPending,
+			Progress:       25,
+			Color:          models.GetPriorityColor(models.PriorityUrgent),
+			Machines: []models.GanttMachineInfo{
+				{MachineID: 2, MachineCode: "MILL-01", Sequence: 2, DurationHours: 1.5},
+				{MachineID: 1, MachineCode: "CNC-01", Sequence: 1, DurationHours: 8, ScheduledStart: &start, ScheduledEnd: &end},
+			},
+		})
+	}
+	return &models.GanttChartResponse{Sections: []models.GanttSection{section}, Summary: models.GanttSummary{TotalTasks: tasks}}
+}
+
+func unzipPart(t *testing.T, data []byte, name string) []byte {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	f, err := archive.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return content
+}
+
+func TestRenderGanttCSV_RowPerTaskWithMachineColumns(t *testing.T) {
+	data, err := services.RenderGanttCSV(exportChart(t, 1))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "Section,NJO,Part Name,Priority,Priority Alpha,Material Status,Status,Progress,Start Date,Finish Date,PPIC Notes,"+
+		"Machine 1,Hours 1,Machine 1 Start,Machine 1 End,Machine 1 Status,"+
+		"Machine 2,Hours 2,Machine 2 Start,Machine 2 End,Machine 2 Status", lines[0])
+	assert.Equal(t, "All Tasks,NJO-001,Bracket,Urgent,,Ready,pending,25,2025-01-06,2025-01-08,,"+
+		"CNC-01,8,2025-01-06 08:00,2025-01-06 16:00,,"+
+		"MILL-01,1.5,,,", lines[1])
+}
+
+func TestRenderGanttCSV_CanBeImported(t *testing.T) {
+	data, err := services.RenderGanttCSV(exportChart(t, 1))
+	require.NoError(t, err)
+	records, err := utils.ReadSpreadsheet("gantt.csv", data)
+	require.NoError(t, err)
+
+	columns, err := models.MapPPICImportColumns(records[0])
+	require.NoError(t, err)
+	req, errs := columns.ParseRow(records[1], importMachineIDs)
+	require.Empty(t, errs)
+	assert.Equal(t, "NJO-001", req.NJO)
+	assert.Equal(t, "2025-01-08", req.FinishDate)
+	require.Len(t, req.MachineAssignments, 2)
+	assert.Equal(t, 1.5, req.MachineAssignments[1].TargetHours)
+}
+
+func TestRenderGanttCSV_EscapesFormulas(t *testing.T) {
+	chart := exportChart(t, 1)
+	task := &chart.Sections[0].Tasks[0]
+	task.PartName = "=HYPERLINK(\"http://x\",\"y\")"
+	task.PPICNotes = "@SUM(A1)"
+	task.PriorityAlpha = "-A"
+	task.NJO = "+NJO-001"
+
+	data, err := services.RenderGanttCSV(chart)
+	require.NoError(t, err)
+	records, err := utils.ReadSpreadsheet("gantt.csv", data)
+	require.NoError(t, err)
+	assert.Equal(t, "'+NJO-001", records[1][1])
+	assert.Equal(t, "'=HYPERLINK(\"http://x\",\"y\")", records[1][2])
+	assert.Equal(t, "'-A", records[1][4])
+	assert.Equal(t, "'@SUM(A1)", records[1][10])
+	assert.Equal(t, "25", records[1][7], "numbers are not escaped")
+
+	// The import reads the original text back
+	columns, err := models.MapPPICImportColumns(records[0])
+	require.NoError(t, err)
+	req, _ := columns.ParseRow(records[1], importMachineIDs)
+	assert.Equal(t, "+NJO-001", req.NJO)
+	assert.Equal(t, "=HYPERLINK(\"http://x\",\"y\")", req.PartName)
+	assert.Equal(t, "@SUM(A1)", req.PPICNotes)
+}
+
+func TestRenderGanttXLSX_ReadsBack(t *testing.T) {
+	data, err := services.RenderGanttXLSX(exportChart(t, 2))
+	require.NoError(t, err)
+
+	rows, err := utils.ReadXLSX(data)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "NJO", rows[0][1])
+	assert.Equal(t, "NJO-002", rows[2][1])
+	assert.Equal(t, "Urgent", rows[1][3])
+	assert.Equal(t, "25", rows[1][7])
+	assert.Equal(t, "45663", rows[1][8]) // 2025-01-06 as an Excel date
+
+	// The priority color is a cell fill
+	assert.Contains(t, string(unzipPart(t, data, "xl/styles.xml")), `<fgColor rgb="FFFD7E14"/>`)
+}
+
+func TestExcelSerialAndColumnNames(t *testing.T) {
+	assert.Equal(t, 45663.0, utils.ExcelSerial(mustDate(t, "2025-01-06")))
+	assert.Equal(t, 45663.5, utils.ExcelSerial(at(6, 12)))
+	assert.Equal(t, "A", utils.XLSXColumnName(0))
+	assert.Equal(t, "Z", utils.XLSXColumnName(25))
+	assert.Equal(t, "AB", utils.XLSXColumnName(27))
+}
+
+func TestRenderGanttPDF_PagesAndCrossReferences(t *testing.T) {
+	data, err := services.RenderGanttPDF(exportChart(t, 60), time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	require.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4")))
+	assert.Contains(t, string(data), "/Count 2") // 60 tasks need a second page
+
+	// Every xref entry points at its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(data)
+	require.NotNil(t, startxref)
+	xrefOffset, _ := strconv.Atoi(string(startxref[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(data[xrefOffset:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(data[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))), "object %d", i+1)
+	}
+}
+
+func TestRenderGanttPDF_NoTasks(t *testing.T) {
+	data, err := services.RenderGanttPDF(&models.GanttChartResponse{}, time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "/Count 1")
+}
+
+func TestPDFFitText(t *testing.T) {
+	assert.Equal(t, "Bracket", utils.PDFFitText("Bracket", 7, 100))
+	fitted := utils.PDFFitText("A very long part name that does not fit", 7, 60)
+	assert.True(t, strings.HasSuffix(fitted, "..."))
+	assert.LessOrEqual(t, utils.PDFTextWidth(fitted, 7), 60.0)
+}
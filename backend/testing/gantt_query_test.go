@@ -0,0 +1,490 @@
+package testing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Gantt Query Tests
+// =============================================================================
+
+// fakeBoard is an in-memory board served through database/sql, counting round trips.
+// Every query waits latency to stand in for the network round trip to Postgres
+type fakeBoard struct {
+	schedules   []models.PPICSchedule
+	assignments map[int64][]models.MachineAssignment
+	latency     time.Duration
+	queries     int64
+
+	mu  sync.Mutex
+	log []fakeQuery // Only kept on boards without latency, for checking the SQL
+}
+
+type fakeQuery struct {
+	sql  string
+	args []driver.Value
+}
+
+var (
+	fakeBoardsMu sync.Mutex
+	fakeBoards   = map[string]*fakeBoard{}
+	registerFake sync.Once
+)
+
+// openFakeBoard returns a database holding n schedules with three machine assignments each
+func openFakeBoard(tb testing.TB, n int, latency time.Duration) (*sql.DB, *fakeBoard) {
+	registerFake.Do(func() { sql.Register("fakeboard", fakeBoardDriver{}) })
+
+	board := &fakeBoard{latency: latency, assignments: map[int64][]models.MachineAssignment{}}
+	for i := 1; i <= n; i++ {
+		id := int64(i)
+		board.schedules = append(board.schedules, models.PPICSchedule{
+			ID: id, NJO: fmt.Sprintf("NJO-%05d", i), PartName: "Bracket", Priority: models.PriorityMedium,
+			MaterialStatus: models.MaterialReady, Status: models.ScheduleStatusPending,
+			StartDate: at(6, 0), FinishDate: at(10, 0), Version: 1,
+		})
+		for seq := 1; seq <= 3; seq++ {
+			board.assignments[id] = append(board.assignments[id], models.MachineAssignment{
+				ID: id*10 + int64(seq), ScheduleID: id, MachineID: int64(seq), MachineName: fmt.Sprintf("CNC %02d", seq),
+				MachineCode: fmt.Sprintf("CNC-%02d", seq), Sequence: seq, TargetHours: 4, Status: models.AssignmentStatusPending, Version: 1,
+			})
+		}
+	}
+
+	name := fmt.Sprintf("%s-%d", tb.Name(), time.Now().UnixNano())
+	fakeBoardsMu.Lock()
+	fakeBoards[name] = board
+	fakeBoardsMu.Unlock()
+
+	db, err := sql.Open("fakeboard", name)
+	require.NoError(tb, err)
+	tb.Cleanup(func() { db.Close() })
+	return db, board
+}
+
+func (b *fakeBoard) Queries() int64 {
+	return atomic.LoadInt64(&b.queries)
+}
+
+// record adds a statement to the log on boards without latency
+func (b *fakeBoard) record(query string, args []driver.Value) {
+	if b.latency > 0 {
+		return
+	}
+	b.mu.Lock()
+	b.log = append(b.log, fakeQuery{sql: query, args: args})
+	b.mu.Unlock()
+}
+
+func (b *fakeBoard) query(query string, args []driver.Value) (driver.Rows, error) {
+	atomic.AddInt64(&b.queries, 1)
+	if b.latency > 0 {
+		time.Sleep(b.latency)
+	}
+	b.record(query, args)
+
+	if strings.Contains(query, "FOR UPDATE") {
+		rows := &fakeRows{columns: 1}
+		for _, arg := range args {
+			rows.values = append(rows.values, []driver.Value{arg})
+		}
+		return rows, nil
+	}
+	if strings.Contains(query, "FROM machine_assignments ma") && strings.Contains(query, "JOIN machines m") {
+		rows := &fakeRows{columns: 15}
+		for _, arg := range args {
+			for _, a := range b.assignments[arg.(int64)] {
+				rows.values = append(rows.values, []driver.Value{
+					a.ID, a.ScheduleID, a.MachineID, a.MachineName, a.MachineCode, int64(a.Sequence), a.TargetHours,
+					nil, nil, fakeTime(a.ActualStart), fakeTime(a.ActualEnd), a.Status, int64(a.Version), at(1, 0), at(1, 0),
+				})
+			}
+		}
+		return rows, nil
+	}
+	if strings.Contains(query, "FROM ppic_schedules") {
+		// Lot queries select the lots of the parent in the first argument
+		lotsOf := int64(0)
+		if strings.Contains(query, "WHERE parent_schedule_id = $1") {
+			lotsOf = args[0].(int64)
+		}
+		rows := &fakeRows{columns: 19}
+		for _, s := range b.schedules {
+			var parentID, lotNumber driver.Value
+			if s.ParentScheduleID != nil {
+				parentID, lotNumber = *s.ParentScheduleID, int64(*s.LotNumber)
+			}
+			if lotsOf != 0 && parentID != lotsOf {
+				continue
+			}
+			rows.values = append(rows.values, []driver.Value{
+				s.ID, s.NJO, s.PartName, s.Priority, s.PriorityAlpha, s.MaterialStatus, s.Status, int64(s.Progress), s.ProgressOverride,
+				s.StartDate, s.FinishDate, s.PPICNotes, int64(s.Version), int64(s.Quantity), parentID, lotNumber,
+				s.CreatedBy, at(1, 0), at(1, 0),
+			})
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("fakeboard: unexpected query %q", query)
+}
+
+func fakeTime(t *time.Time) driver.Value {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+type fakeBoardDriver struct{}
+
+func (fakeBoardDriver) Open(name string) (driver.Conn, error) {
+	fakeBoardsMu.Lock()
+	defer fakeBoardsMu.Unlock()
+	board, ok := fakeBoards[name]
+	if !ok {
+		return nil, fmt.Errorf("fakeboard: unknown board %q", name)
+	}
+	return &fakeConn{board: board}, nil
+}
+
+type fakeConn struct{ board *fakeBoard }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakeboard: prepared statements are not supported")
+}
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.board.record("BEGIN", nil)
+	return fakeTx{board: c.board}, nil
+}
+
+// ExecContext records the statement; one row is affected if the last argument is a schedule ID on
+// the board. Conditional updates ("AND version = $n") end with the ID and the version, which must match
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	c.board.record(query, values)
+
+	idArg, version := len(values)-1, int64(0)
+	if strings.Contains(query, "AND version = $") && len(values) >= 2 {
+		version, _ = values[idArg].(int64)
+		idArg--
+	}
+
+	var affected int64
+	if idArg >= 0 {
+		if id, ok := values[idArg].(int64); ok && id >= 1 && id <= int64(len(c.board.schedules)) {
+			if version == 0 || int64(c.board.schedules[id-1].Version) == version {
+				affected = 1
+			}
+		}
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+type fakeTx struct{ board *fakeBoard }
+
+func (tx fakeTx) Commit() error {
+	tx.board.record("COMMIT", nil)
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.board.record("ROLLBACK", nil)
+	return nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return c.board.query(query, values)
+}
+
+type fakeRows struct {
+	columns int
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string {
+	columns := make([]string, r.columns)
+	for i := range columns {
+		columns[i] = fmt.Sprintf("c%d", i)
+	}
+	return columns
+}
+func (r *fakeRows) Close() error { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
+
+// loadSchedulesPerRow is the previous listing: one assignment query per schedule row
+func loadSchedulesPerRow(db *sql.DB) ([]models.PPICSchedule, error) {
+	rows, err := db.Query(`SELECT ps.id, ps.njo, ps.part_name, ps.priority, ps.priority_alpha, ps.material_status,
+		ps.status, ps.progress, ps.progress_override, ps.start_date, ps.finish_date, ps.ppic_notes, ps.version, ps.quantity,
+		ps.parent_schedule_id, ps.lot_number, ps.created_by, ps.created_at, ps.updated_at FROM ppic_schedules ps`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.PPICSchedule
+	for rows.Next() {
+		var s models.PPICSchedule
+		if err := rows.Scan(&s.ID, &s.NJO, &s.PartName, &s.Priority, &s.PriorityAlpha, &s.MaterialStatus, &s.Status, &s.Progress,
+			&s.ProgressOverride, &s.StartDate, &s.FinishDate, &s.PPICNotes, &s.Version, &s.Quantity,
+			&s.ParentScheduleID, &s.LotNumber, &s.CreatedBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		assignmentRows, err := db.Query(`SELECT ma.id, ma.schedule_id, ma.machine_id, m.machine_name, m.machine_code,
+			ma.sequence, ma.target_hours, ma.scheduled_start, ma.scheduled_end, ma.actual_start, ma.actual_end, ma.status,
+			ma.version, ma.created_at, ma.updated_at FROM machine_assignments ma JOIN machines m ON ma.machine_id = m.id WHERE ma.schedule_id = $1`, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		for assignmentRows.Next() {
+			var a models.MachineAssignment
+			if err := assignmentRows.Scan(&a.ID, &a.ScheduleID, &a.MachineID, &a.MachineName, &a.MachineCode, &a.Sequence, &a.TargetHours,
+				&a.ScheduledStart, &a.ScheduledEnd, &a.ActualStart, &a.ActualEnd, &a.Status, &a.Version, &a.CreatedAt, &a.UpdatedAt); err != nil {
+				assignmentRows.Close()
+				return nil, err
+			}
+			s.MachineAssignments = append(s.MachineAssignments, a)
+		}
+		assignmentRows.Close()
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func TestGetWithFilters_BatchLoadsAssignments(t *testing.T) {
+	db, board := openFakeBoard(t, 2500, 0)
+	repo := repository.NewPPICScheduleRepository(db)
+
+	schedules, err := repo.GetWithFilters(models.GanttFilterRequest{})
+	require.NoError(t, err)
+	require.Len(t, schedules, 2500)
+
+	// One schedule query plus one assignment query per 1000 schedules
+	assert.Equal(t, int64(4), board.Queries())
+	for _, s := range []models.PPICSchedule{schedules[0], schedules[1500], schedules[2499]} {
+		require.Len(t, s.MachineAssignments, 3)
+		assert.Equal(t, s.ID, s.MachineAssignments[0].ScheduleID)
+		assert.Equal(t, 1, s.MachineAssignments[0].Sequence)
+		assert.Equal(t, "CNC-03", s.MachineAssignments[2].MachineCode)
+	}
+}
+
+func TestGetAllAndByMachine_BatchLoadAssignments(t *testing.T) {
+	db, board := openFakeBoard(t, 300, 0)
+	repo := repository.NewPPICScheduleRepository(db)
+
+	schedules, err := repo.GetAll()
+	require.NoError(t, err)
+	require.Len(t, schedules, 300)
+	assert.Equal(t, int64(2), board.Queries())
+
+	schedules, err = repo.GetSchedulesByMachine(2)
+	require.NoError(t, err)
+	require.Len(t, schedules, 300)
+	assert.Len(t, schedules[299].MachineAssignments, 3)
+	assert.Equal(t, int64(4), board.Queries())
+}
+
+func TestGetWithFilters_DateWindow(t *testing.T) {
+	db, board := openFakeBoard(t, 1, 0)
+	repo := repository.NewPPICScheduleRepository(db)
+
+	_, err := repo.GetWithFilters(models.GanttFilterRequest{Priority: models.PriorityUrgent, WindowStart: "2025-01-06", WindowDays: 14})
+	require.NoError(t, err)
+
+	require.Len(t, board.log, 2)
+	schedules := board.log[0]
+	assert.Contains(t, schedules.sql, "ps.priority = $1")
+	assert.Contains(t, schedules.sql, "ps.finish_date >= CAST($2 AS DATE) AND ps.start_date < CAST($3 AS DATE) + CAST($4 AS INTEGER)")
+	assert.Equal(t, []driver.Value{models.PriorityUrgent, "2025-01-06", "2025-01-06", int64(14)}, schedules.args)
+	assert.Contains(t, board.log[1].sql, "ma.schedule_id IN ($1)")
+}
+
+func TestScheduleTx_LocksInIDOrder(t *testing.T) {
+	db, board := openFakeBoard(t, 3, 0)
+	repo := repository.NewPPICScheduleRepository(db)
+
+	tx, err := repo.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Lock([]int64{1, 2, 3}))
+	require.NoError(t, tx.SetDates(2, at(13, 0), at(14, 0)))
+	assert.Error(t, tx.SetDates(9, at(13, 0), at(14, 0)), "a missing schedule fails the transaction")
+	require.NoError(t, tx.Rollback())
+
+	require.Len(t, board.log, 5)
+	assert.Equal(t, "BEGIN", board.log[0].sql)
+	lock := board.log[1]
+	assert.Contains(t, lock.sql, "WHERE id IN ($1, $2, $3) AND deleted_at IS NULL AND scenario_id IS NULL")
+	assert.Contains(t, lock.sql, "ORDER BY id")
+	assert.Contains(t, lock.sql, "FOR UPDATE")
+	assert.Contains(t, board.log[2].sql, "UPDATE ppic_schedules SET start_date = $1, finish_date = $2")
+	assert.Equal(t, "ROLLBACK", board.log[4].sql)
+}
+
+func TestScheduleTx_LocksMachinesInIDOrder(t *testing.T) {
+	db, board := openFakeBoard(t, 1, 0)
+	repo := repository.NewPPICScheduleRepository(db).ForScenario(4)
+
+	tx, err := repo.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.LockMachines(nil), "no machines, no query")
+	require.NoError(t, tx.LockMachines([]int64{7, 3}))
+	require.NoError(t, tx.Rollback())
+
+	require.Len(t, board.log, 3)
+	lock := board.log[1]
+	assert.Contains(t, lock.sql, "SELECT id FROM machines")
+	assert.Contains(t, lock.sql, "WHERE id IN ($1, $2)")
+	assert.Contains(t, lock.sql, "ORDER BY id")
+	assert.Contains(t, lock.sql, "FOR UPDATE")
+	assert.NotContains(t, lock.sql, "scenario_id", "machines are shared by every scenario")
+	assert.Equal(t, []driver.Value{int64(7), int64(3)}, lock.args)
+}
+
+func TestScheduleTx_RollbackAfterCommit(t *testing.T) {
+	db, board := openFakeBoard(t, 1, 0)
+	repo := repository.NewPPICScheduleRepository(db).ForScenario(4)
+
+	tx, err := repo.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Lock([]int64{1}))
+	require.NoError(t, tx.Commit())
+	assert.NoError(t, tx.Rollback(), "the deferred rollback after a commit is a no-op")
+
+	require.Len(t, board.log, 3)
+	assert.Contains(t, board.log[1].sql, "scenario_id = 4")
+	assert.Equal(t, "COMMIT", board.log[2].sql)
+}
+
+func TestScheduleTx_UpdateChecksVersion(t *testing.T) {
+	db, board := openFakeBoard(t, 2, 0)
+	repo := repository.NewPPICScheduleRepository(db)
+
+	tx, err := repo.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	board.schedules[1].Version = 3
+	current, stale := 3, 2
+	require.NoError(t, tx.Update(2, &models.UpdatePPICScheduleRequest{PPICNotes: "Rush", Version: &current}, nil, nil))
+	update := board.log[1]
+	assert.Contains(t, update.sql, "version = version + 1")
+	assert.Contains(t, update.sql, "AND version = $3")
+	assert.Equal(t, []driver.Value{"Rush", int64(2), int64(3)}, update.args)
+
+	err = tx.Update(2, &models.UpdatePPICScheduleRequest{PPICNotes: "Rush", Version: &stale}, nil, nil)
+	assert.ErrorIs(t, err, models.ErrStaleVersion)
+
+	// Server-side writes (imports, cascades) don't carry a version and aren't checked
+	require.NoError(t, tx.Update(2, &models.UpdatePPICScheduleRequest{PPICNotes: "Rush"}, nil, nil))
+	assert.NotContains(t, board.log[len(board.log)-1].sql, "AND version =")
+}
+
+func TestScheduleTx_UpdateKeepsStartedAssignments(t *testing.T) {
+	db, board := openFakeBoard(t, 1, 0)
+	repo := repository.NewPPICScheduleRepository(db)
+	started := at(6, 8)
+	board.assignments[1][0].Status = models.AssignmentStatusInProgress
+	board.assignments[1][0].ActualStart = &started
+
+	tx, err := repo.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// Sequence 1 is matched by ID and sequence 2 by sequence; sequence 3 is dropped
+	require.NoError(t, tx.Update(1, &models.UpdatePPICScheduleRequest{MachineAssignments: []models.UpdateMachineAssignmentRequest{
+		{ID: 11, MachineID: 1, Sequence: 1, TargetHours: 6},
+		{MachineID: 2, Sequence: 2, TargetHours: 5},
+	}}, nil, nil))
+
+	var updated, deleted []driver.Value
+	var progress *fakeQuery
+	for i, q := range board.log {
+		switch {
+		case strings.Contains(q.sql, "UPDATE machine_assignments"):
+			assert.NotContains(t, q.sql, "status", "status and actual times are kept")
+			updated = append(updated, q.args[len(q.args)-1])
+		case strings.Contains(q.sql, "DELETE FROM machine_assignments"):
+			assert.NotContains(t, q.sql, "schedule_id")
+			deleted = append(deleted, q.args...)
+		case strings.Contains(q.sql, "SET progress = $1, status = $2"):
+			progress = &board.log[i]
+		}
+	}
+	assert.Equal(t, []driver.Value{int64(11), int64(12)}, updated)
+	assert.Equal(t, []driver.Value{int64(13)}, deleted)
+	// Progress is derived again in the same transaction: half of sequence 1's 4 of 12 hours
+	require.NotNil(t, progress)
+	assert.Equal(t, []driver.Value{int64(17), models.ScheduleStatusInProgress, int64(1)}, progress.args)
+
+	// The started sequence can neither be dropped nor moved to another machine
+	err = tx.Update(1, &models.UpdatePPICScheduleRequest{MachineAssignments: []models.UpdateMachineAssignmentRequest{
+		{MachineID: 2, Sequence: 2, TargetHours: 5},
+	}}, nil, nil)
+	assert.ErrorContains(t, err, "sequence 1 has already started on CNC 01 and can't be removed")
+	err = tx.Update(1, &models.UpdatePPICScheduleRequest{MachineAssignments: []models.UpdateMachineAssignmentRequest{
+		{ID: 11, MachineID: 3, Sequence: 1, TargetHours: 6},
+	}}, nil, nil)
+	assert.ErrorContains(t, err, "can't move to another machine")
+}
+
+// =============================================================================
+// Benchmark Tests
+// =============================================================================
+
+// The benchmarks load a board of 2000 schedules with a simulated 100µs round trip per query.
+// Compare ns/op and queries/op:
+//
+//	go test ./testing -run '^$' -bench 'ScheduleListing'
+func benchmarkScheduleListing(b *testing.B, load func(db *sql.DB) ([]models.PPICSchedule, error)) {
+	db, board := openFakeBoard(b, 2000, 100*time.Microsecond)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := load(db); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(board.Queries())/float64(b.N), "queries/op")
+}
+
+func BenchmarkScheduleListing_PerRowAssignments(b *testing.B) {
+	benchmarkScheduleListing(b, loadSchedulesPerRow)
+}
+
+func BenchmarkScheduleListing_BatchedAssignments(b *testing.B) {
+	benchmarkScheduleListing(b, func(db *sql.DB) ([]models.PPICSchedule, error) {
+		return repository.NewPPICScheduleRepository(db).GetWithFilters(models.GanttFilterRequest{})
+	})
+}
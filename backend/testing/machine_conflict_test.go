@@ -0,0 +1,79 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Machine Window Overlap Tests
+// =============================================================================
+
+func TestWindowsOverlap(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return v
+	}
+
+	testCases := []struct {
+		name     string
+		aStart   string
+		aEnd     string
+		bStart   string
+		bEnd     string
+		expected bool
+	}{
+		{"Partial overlap", "2025-01-06T08:00:00Z", "2025-01-06T12:00:00Z", "2025-01-06T10:00:00Z", "2025-01-06T14:00:00Z", true},
+		{"Contained", "2025-01-06T08:00:00Z", "2025-01-06T17:00:00Z", "2025-01-06T10:00:00Z", "2025-01-06T11:00:00Z", true},
+		{"Back to back", "2025-01-06T08:00:00Z", "2025-01-06T12:00:00Z", "2025-01-06T12:00:00Z", "2025-01-06T16:00:00Z", false},
+		{"Separate days", "2025-01-06T08:00:00Z", "2025-01-06T17:00:00Z", "2025-01-07T08:00:00Z", "2025-01-07T17:00:00Z", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, models.WindowsOverlap(at(tc.aStart), at(tc.aEnd), at(tc.bStart), at(tc.bEnd)))
+			assert.Equal(t, tc.expected, models.WindowsOverlap(at(tc.bStart), at(tc.bEnd), at(tc.aStart), at(tc.aEnd)))
+		})
+	}
+}
+
+func TestCreateMachineAssignmentRequest_ParseScheduledWindow(t *testing.T) {
+	t.Run("Empty window", func(t *testing.T) {
+		req := models.CreateMachineAssignmentRequest{MachineID: 1, Sequence: 1}
+		start, end, err := req.ParseScheduledWindow()
+		require.NoError(t, err)
+		assert.Nil(t, start)
+		assert.Nil(t, end)
+	})
+
+	t.Run("RFC3339 window", func(t *testing.T) {
+		req := models.CreateMachineAssignmentRequest{ScheduledStart: "2024-01-01T08:00:00Z", ScheduledEnd: "2024-01-01T16:30:00Z"}
+		start, end, err := req.ParseScheduledWindow()
+		require.NoError(t, err)
+		assert.Equal(t, 8.5, end.Sub(*start).Hours())
+	})
+
+	t.Run("Datetime-local window", func(t *testing.T) {
+		req := models.CreateMachineAssignmentRequest{ScheduledStart: "2024-01-01T08:00", ScheduledEnd: "2024-01-01T12:00"}
+		_, _, err := req.ParseScheduledWindow()
+		assert.NoError(t, err)
+	})
+
+	t.Run("End before start", func(t *testing.T) {
+		req := models.CreateMachineAssignmentRequest{ScheduledStart: "2024-01-01T12:00:00Z", ScheduledEnd: "2024-01-01T08:00:00Z"}
+		_, _, err := req.ParseScheduledWindow()
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid format", func(t *testing.T) {
+		req := models.CreateMachineAssignmentRequest{ScheduledStart: "01/01/2024"}
+		_, _, err := req.ParseScheduledWindow()
+		assert.Error(t, err)
+	})
+}
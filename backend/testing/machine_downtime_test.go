@@ -0,0 +1,100 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Machine Downtime Tests
+// =============================================================================
+
+func TestWithDowntime_BlocksFullyCoveredDays(t *testing.T) {
+	plant := models.NewWorkingCalendar(nil, nil) // Mon-Fri 08:00-17:00
+	downtimes := []models.MachineDowntime{
+		{ID: 1, MachineID: 1, Type: models.DowntimeBreakdown, StartTime: at(7, 6), EndTime: at(8, 12)},
+	}
+
+	calendar := plant.WithDowntime(downtimes)
+	assert.False(t, calendar.IsWorkingDay(mustDate(t, "2025-01-07")))
+	assert.Equal(t, "Breakdown", calendar.Day(mustDate(t, "2025-01-07")).Note)
+	// Only the morning of Wednesday is down
+	assert.True(t, calendar.IsWorkingDay(mustDate(t, "2025-01-08")))
+	assert.Equal(t, mustDate(t, "2025-01-08"), calendar.NextWorkingDay(mustDate(t, "2025-01-07")))
+
+	// The plant calendar itself is unchanged
+	assert.True(t, plant.IsWorkingDay(mustDate(t, "2025-01-07")))
+}
+
+func TestWithDowntime_CoverageIsPerMachine(t *testing.T) {
+	plant := models.NewWorkingCalendar(nil, nil)
+
+	// Two machines each down for half of Thursday leave the day working
+	halves := []models.MachineDowntime{
+		{MachineID: 1, Type: models.DowntimeCalibration, StartTime: at(9, 8), EndTime: at(9, 13)},
+		{MachineID: 2, Type: models.DowntimeCalibration, StartTime: at(9, 12), EndTime: at(9, 17)},
+	}
+	assert.True(t, plant.WithDowntime(halves).IsWorkingDay(mustDate(t, "2025-01-09")))
+
+	// Back-to-back windows of one machine cover it
+	halves[1].MachineID = 1
+	assert.False(t, plant.WithDowntime(halves).IsWorkingDay(mustDate(t, "2025-01-09")))
+}
+
+func TestOverlappingDowntime(t *testing.T) {
+	downtimes := []models.MachineDowntime{
+		{ID: 2, StartTime: at(7, 13), EndTime: at(7, 15)},
+		{ID: 1, StartTime: at(7, 8), EndTime: at(7, 10)},
+	}
+
+	first := models.OverlappingDowntime(downtimes, at(7, 9), at(7, 14))
+	require.NotNil(t, first)
+	assert.Equal(t, int64(1), first.ID)
+
+	// Touching ends don't overlap
+	assert.Nil(t, models.OverlappingDowntime(downtimes, at(7, 10), at(7, 13)))
+}
+
+func TestScheduleImpact_CascadeSkipsDowntime(t *testing.T) {
+	planner, board := impactPlanner(t)
+	board[2].MachineAssignments = []models.MachineAssignment{{MachineID: 7, Sequence: 1}}
+	planner.UseDowntime(func(machineID int64) ([]models.MachineDowntime, error) {
+		if machineID != 7 {
+			return nil, nil
+		}
+		return []models.MachineDowntime{
+			{MachineID: 7, Type: models.DowntimePlannedMaintenance, StartTime: at(11, 0), EndTime: at(12, 0)},
+		}, nil
+	})
+
+	// B would sit on Jan 11-12, but its machine is down on the 11th
+	require.NoError(t, planner.Move(1, mustDate(t, "2025-01-08"), mustDate(t, "2025-01-10"), models.ChangeCauseManual))
+	require.NoError(t, planner.Cascade(1))
+	changes := planner.Changes()
+
+	require.Len(t, changes, 3)
+	assert.Equal(t, "2025-01-12", changes[1].NewStartDate)
+	assert.Equal(t, "2025-01-13", changes[1].NewFinishDate)
+	assert.Equal(t, "2025-01-14", changes[2].NewStartDate)
+}
+
+func TestPlanAutoSchedule_BooksAroundDowntime(t *testing.T) {
+	schedules := []models.PPICSchedule{
+		pendingSchedule(t, 1, models.PriorityMedium, models.MachineAssignment{MachineID: 1, Sequence: 1, TargetHours: 4}),
+	}
+	ctx := autoScheduleContext()
+	ctx.Downtime = map[int64][]models.MachineDowntime{
+		1: {{MachineID: 1, Type: models.DowntimeBreakdown, StartTime: at(6, 9), EndTime: at(6, 11)}},
+	}
+
+	plan := services.PlanAutoSchedule(schedules, ctx)
+	require.Len(t, plan.Scheduled, 1)
+	op := plan.Scheduled[0].Operations[0]
+	assert.Equal(t, at(6, 11), op.ScheduledStart)
+	assert.Equal(t, at(6, 15), op.ScheduledEnd)
+}
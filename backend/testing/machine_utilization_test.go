@@ -0,0 +1,113 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Machine Utilization Tests
+// =============================================================================
+
+// The default calendar is Monday-Friday 08:00-17:00: 9 available hours a day
+var utilizationMachines = []models.Machine{
+	{ID: 1, MachineCode: "CNC-01", MachineName: "CNC 01", MachineType: "CNC", Location: "Plant A", Status: models.MachineStatusActive},
+	{ID: 2, MachineCode: "MILL-01", MachineName: "Mill 01", MachineType: "Milling", Location: "Plant A", Status: models.MachineStatusMaintenance},
+}
+
+func utilizationAssignment(machineID int64, hours float64, start, end int) models.MachineAssignment {
+	return models.MachineAssignment{
+		MachineID:      machineID,
+		TargetHours:    hours,
+		ScheduledStart: timePtr(at(start/100, start%100)),
+		ScheduledEnd:   timePtr(at(end/100, end%100)),
+		Status:         models.AssignmentStatusPending,
+	}
+}
+
+func TestBuildMachineUtilization_DailyPlannedAndAvailable(t *testing.T) {
+	assignments := []models.MachineAssignment{
+		// Mon 13:00 - Tue 12:00 spans 4 + 4 working hours, so 8 target hours split evenly
+		utilizationAssignment(1, 8, 613, 712),
+		utilizationAssignment(1, 6, 708, 714),
+	}
+	report := services.BuildMachineUtilization(utilizationMachines[:1], nil, assignments,
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-07"), models.UtilizationPeriodDay, at(1, 0))
+
+	require.Len(t, report.Machines, 1)
+	periods := report.Machines[0].Periods
+	require.Len(t, periods, 2)
+
+	assert.Equal(t, "2025-01-06", periods[0].PeriodStart)
+	assert.Equal(t, 4.0, periods[0].PlannedHours)
+	assert.Equal(t, 9.0, periods[0].AvailableHours)
+	assert.InDelta(t, 44.4, periods[0].LoadPercent, 0.01)
+	assert.False(t, periods[0].Overloaded)
+
+	assert.Equal(t, 10.0, periods[1].PlannedHours)
+	assert.True(t, periods[1].Overloaded)
+	assert.Equal(t, 1, report.Machines[0].OverloadedPeriods)
+	assert.Equal(t, 1, report.OverloadedMachines)
+
+	assert.Equal(t, 14.0, report.Machines[0].Total.PlannedHours)
+	assert.Equal(t, 18.0, report.Machines[0].Total.AvailableHours)
+}
+
+func TestBuildMachineUtilization_ActualHours(t *testing.T) {
+	done := models.MachineAssignment{MachineID: 1, ActualStart: timePtr(at(6, 8)), ActualEnd: timePtr(at(6, 14)), Status: models.AssignmentStatusCompleted}
+	running := models.MachineAssignment{MachineID: 1, ActualStart: timePtr(at(7, 22)), Status: models.AssignmentStatusInProgress}
+
+	report := services.BuildMachineUtilization(utilizationMachines[:1], nil, []models.MachineAssignment{done, running},
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-08"), models.UtilizationPeriodDay, at(8, 3))
+
+	periods := report.Machines[0].Periods
+	assert.Equal(t, 6.0, periods[0].ActualHours)
+	assert.InDelta(t, 66.7, periods[0].UtilizationPercent, 0.01)
+	assert.Equal(t, 2.0, periods[1].ActualHours) // Running assignments count up to now
+	assert.Equal(t, 3.0, periods[2].ActualHours)
+	assert.Equal(t, 0.0, periods[0].PlannedHours)
+}
+
+func TestBuildMachineUtilization_WeeklyBuckets(t *testing.T) {
+	// Fri 3 Jan to Wed 15 Jan: a partial week, a full week, then another partial week
+	report := services.BuildMachineUtilization(utilizationMachines[:1], nil, nil,
+		mustDate(t, "2025-01-03"), mustDate(t, "2025-01-15"), models.UtilizationPeriodWeek, at(1, 0))
+
+	periods := report.Machines[0].Periods
+	require.Len(t, periods, 3)
+	assert.Equal(t, "2025-01-03", periods[0].PeriodStart)
+	assert.Equal(t, "2025-01-05", periods[0].PeriodEnd)
+	assert.Equal(t, 9.0, periods[0].AvailableHours)
+	assert.Equal(t, "2025-01-06", periods[1].PeriodStart)
+	assert.Equal(t, "2025-01-12", periods[1].PeriodEnd)
+	assert.Equal(t, 45.0, periods[1].AvailableHours)
+	assert.Equal(t, "2025-01-13", periods[2].PeriodStart)
+	assert.Equal(t, 27.0, periods[2].AvailableHours)
+}
+
+func TestBuildMachineUtilization_UnavailableMachineIsOverloadedByAnyPlan(t *testing.T) {
+	report := services.BuildMachineUtilization(utilizationMachines[1:], nil, []models.MachineAssignment{utilizationAssignment(2, 2, 608, 610)},
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-06"), models.UtilizationPeriodDay, at(1, 0))
+
+	period := report.Machines[0].Periods[0]
+	assert.Equal(t, 0.0, period.AvailableHours)
+	assert.Equal(t, 2.0, period.PlannedHours)
+	assert.Equal(t, 0.0, period.LoadPercent)
+	assert.True(t, period.Overloaded)
+}
+
+func TestWorkingHoursBetween(t *testing.T) {
+	calendar := models.NewWorkingCalendar(nil, nil)
+	assert.Equal(t, 9.0, calendar.WorkingHoursBetween(at(6, 0), at(7, 0)))
+	assert.Equal(t, 5.0, calendar.WorkingHoursBetween(at(6, 12), at(7, 0)))
+	assert.Equal(t, 0.0, calendar.WorkingHoursBetween(at(4, 0), at(6, 0))) // Weekend
+	assert.Equal(t, 0.0, calendar.WorkingHoursBetween(at(7, 0), at(6, 0)))
+
+	night := models.NewWorkingCalendar([]models.PlantShift{{DayOfWeek: 1, StartTime: "22:00", EndTime: "06:00"}}, nil)
+	assert.Equal(t, 6.0, night.WorkingHoursBetween(at(7, 0), at(8, 0))) // Monday's night shift runs into Tuesday
+}
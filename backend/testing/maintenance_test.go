@@ -0,0 +1,103 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Preventive Maintenance Tests
+// =============================================================================
+
+func TestEvaluateMaintenancePlan_CalendarInterval(t *testing.T) {
+	days := 7
+	plan := models.MaintenancePlan{ID: 1, IntervalDays: &days, LastCompletedAt: at(6, 15)}
+
+	status := models.EvaluateMaintenancePlan(plan, 0, at(12, 23))
+	require.NotNil(t, status.NextDueDate)
+	assert.Equal(t, "2025-01-13", *status.NextDueDate)
+	assert.False(t, status.Due)
+
+	// Due on the due date, overdue from the day after
+	status = models.EvaluateMaintenancePlan(plan, 0, at(13, 8))
+	assert.True(t, status.Due)
+	assert.Equal(t, models.WorkOrderTriggerCalendar, status.DueTrigger)
+	assert.False(t, status.Overdue)
+
+	status = models.EvaluateMaintenancePlan(plan, 0, at(16, 8))
+	assert.True(t, status.Overdue)
+	assert.Equal(t, 3, status.DaysOverdue)
+}
+
+func TestEvaluateMaintenancePlan_RunHourInterval(t *testing.T) {
+	hours := 100.0
+	plan := models.MaintenancePlan{ID: 1, IntervalRunHours: &hours, LastCompletedAt: at(1, 0)}
+
+	status := models.EvaluateMaintenancePlan(plan, 60.5, at(20, 0))
+	require.NotNil(t, status.RunHoursRemaining)
+	assert.Equal(t, 39.5, *status.RunHoursRemaining)
+	assert.False(t, status.Due)
+	assert.Nil(t, status.NextDueDate)
+
+	status = models.EvaluateMaintenancePlan(plan, 100, at(20, 0))
+	assert.True(t, status.Due)
+	assert.Equal(t, models.WorkOrderTriggerRunHours, status.DueTrigger)
+	assert.False(t, status.Overdue)
+
+	status = models.EvaluateMaintenancePlan(plan, 112.25, at(20, 0))
+	assert.True(t, status.Overdue)
+	assert.Equal(t, 12.25, status.RunHoursOver)
+}
+
+func TestEvaluateMaintenancePlan_WhicheverComesFirst(t *testing.T) {
+	days, hours := 30, 50.0
+	plan := models.MaintenancePlan{IntervalDays: &days, IntervalRunHours: &hours, LastCompletedAt: at(1, 0)}
+
+	// The run hours are reached long before the calendar interval
+	status := models.EvaluateMaintenancePlan(plan, 55, at(10, 0))
+	assert.True(t, status.Due)
+	assert.Equal(t, models.WorkOrderTriggerRunHours, status.DueTrigger)
+}
+
+func TestRunHoursBetween_CountsOverlapOnce(t *testing.T) {
+	end1, end2 := at(6, 12), at(6, 14)
+	intervals := []models.MachineRunInterval{
+		{Start: at(6, 8), End: &end1},
+		{Start: at(6, 10), End: &end2}, // Process stage of the same job
+		{Start: at(6, 16)},             // Still running
+	}
+
+	assert.Equal(t, 8.0, models.RunHoursBetween(intervals, at(6, 0), at(6, 18)))
+	// Clipped to the range
+	assert.Equal(t, 6.0, models.RunHoursBetween(intervals, at(6, 9), at(6, 17)))
+	assert.Equal(t, 0.0, models.RunHoursBetween(intervals, at(7, 0), at(7, 0).Add(-time.Hour)))
+}
+
+func TestCheckWorkOrderTransition(t *testing.T) {
+	assert.NoError(t, models.CheckWorkOrderTransition(models.WorkOrderStatusOpen, models.WorkOrderStatusInProgress))
+	assert.NoError(t, models.CheckWorkOrderTransition(models.WorkOrderStatusOpen, models.WorkOrderStatusCompleted))
+	assert.NoError(t, models.CheckWorkOrderTransition(models.WorkOrderStatusInProgress, models.WorkOrderStatusCancelled))
+	assert.Error(t, models.CheckWorkOrderTransition(models.WorkOrderStatusCompleted, models.WorkOrderStatusInProgress))
+	assert.Error(t, models.CheckWorkOrderTransition(models.WorkOrderStatusCancelled, models.WorkOrderStatusOpen))
+}
+
+func TestSortOverdueMaintenance(t *testing.T) {
+	statuses := []models.MaintenancePlanStatus{
+		{MaintenancePlan: models.MaintenancePlan{ID: 1}, RunHoursOver: 20},
+		{MaintenancePlan: models.MaintenancePlan{ID: 2}, DaysOverdue: 2},
+		{MaintenancePlan: models.MaintenancePlan{ID: 3}, DaysOverdue: 5},
+		{MaintenancePlan: models.MaintenancePlan{ID: 4}, RunHoursOver: 30},
+	}
+
+	models.SortOverdueMaintenance(statuses)
+	var ids []int64
+	for _, s := range statuses {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []int64{3, 2, 4, 1}, ids)
+}
@@ -0,0 +1,130 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// OEE Tests
+// =============================================================================
+
+func oeeRun(start time.Time, end *time.Time) models.MachineRunInterval {
+	return models.MachineRunInterval{Start: start, End: end}
+}
+
+func oeeParts(producedAt time.Time, good, scrap int, idealSeconds float64) models.ProductionReport {
+	return models.ProductionReport{MachineID: 1, ProducedAt: producedAt, GoodQuantity: good, ScrapQuantity: scrap, IdealCycleSeconds: idealSeconds}
+}
+
+func TestBuildOEEReport_AvailabilityPerformanceQuality(t *testing.T) {
+	data := map[int64]models.OEEMachineData{1: {
+		Runs:    []models.MachineRunInterval{oeeRun(at(6, 8), timePtr(at(6, 16)))},
+		Stops:   []models.AndonEvent{andonEvent(2, at(6, 10), at(6, 11))},
+		Reports: []models.ProductionReport{oeeParts(at(6, 12), 90, 10, 216)},
+	}}
+
+	report := services.BuildOEEReport(utilizationMachines[:1], nil, data,
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-06"), models.UtilizationPeriodDay, at(20, 0))
+	require.Len(t, report.Machines, 1)
+	require.Len(t, report.Machines[0].Periods, 1)
+
+	day := report.Machines[0].Periods[0]
+	assert.Equal(t, "2025-01-06", day.PeriodStart)
+	assert.Equal(t, 9.0, day.PlannedHours)
+	assert.Equal(t, 7.0, day.RunHours, "the andon stop is not run time")
+	assert.Equal(t, 2.0, day.DowntimeHours)
+	assert.Equal(t, 6.0, day.IdealHours) // 100 parts x 216 s
+	assert.Equal(t, 77.8, day.Availability)
+	assert.Equal(t, 85.7, day.Performance)
+	assert.Equal(t, 90.0, day.Quality)
+	assert.Equal(t, 60.0, day.OEE)
+	assert.Equal(t, day.OEE, report.Total.OEE)
+}
+
+func TestBuildOEEReport_PlannedDowntimeBreakdownAndOvertime(t *testing.T) {
+	data := map[int64]models.OEEMachineData{1: {
+		Runs: []models.MachineRunInterval{oeeRun(at(7, 10), timePtr(at(7, 19)))},
+		Downtime: []models.MachineDowntime{
+			{MachineID: 1, Type: models.DowntimePlannedMaintenance, StartTime: at(7, 8), EndTime: at(7, 10)},
+			{MachineID: 1, Type: models.DowntimeBreakdown, StartTime: at(7, 12), EndTime: at(7, 13)},
+		},
+	}}
+
+	report := services.BuildOEEReport(utilizationMachines[:1], nil, data,
+		mustDate(t, "2025-01-07"), mustDate(t, "2025-01-07"), models.UtilizationPeriodDay, at(20, 0))
+	day := report.Machines[0].Periods[0]
+	// 9 working hours less 2 planned maintenance, plus 2 hours run after the shift
+	assert.Equal(t, 9.0, day.PlannedHours)
+	assert.Equal(t, 8.0, day.RunHours)
+	assert.Equal(t, 88.9, day.Availability)
+	// Nothing reported: no quality and no OEE
+	assert.Equal(t, 0.0, day.Quality)
+	assert.Equal(t, 0.0, day.OEE)
+}
+
+func TestBuildOEEReport_ShiftsTakeOvertimeAndLateReports(t *testing.T) {
+	calendar := models.NewWorkingCalendar([]models.PlantShift{
+		{Name: "Morning", DayOfWeek: int(time.Monday), StartTime: "06:00", EndTime: "14:00"},
+		{Name: "Afternoon", DayOfWeek: int(time.Monday), StartTime: "14:00", EndTime: "22:00"},
+	}, nil)
+	data := map[int64]models.OEEMachineData{1: {
+		Runs: []models.MachineRunInterval{oeeRun(at(6, 6), timePtr(at(6, 23)))},
+		Reports: []models.ProductionReport{
+			oeeParts(at(6, 13), 10, 0, 1800),
+			oeeParts(at(6, 22).Add(30*time.Minute), 20, 0, 1080),
+		},
+	}}
+
+	report := services.BuildOEEReport(utilizationMachines[:1], map[int64]*models.WorkingCalendar{1: calendar}, data,
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-06"), models.OEEPeriodShift, at(7, 12))
+	periods := report.Machines[0].Periods
+	require.Len(t, periods, 2)
+
+	assert.Equal(t, "Morning", periods[0].Shift)
+	assert.Equal(t, "2025-01-06T06:00:00Z", periods[0].PeriodStart)
+	assert.Equal(t, "2025-01-06T14:00:00Z", periods[0].PeriodEnd)
+	assert.Equal(t, 8.0, periods[0].PlannedHours)
+	assert.Equal(t, 62.5, periods[0].Performance)
+
+	// The hour run after 22:00 and the parts reported at 22:30 belong to the afternoon shift
+	assert.Equal(t, "Afternoon", periods[1].Shift)
+	assert.Equal(t, 9.0, periods[1].PlannedHours)
+	assert.Equal(t, 100.0, periods[1].Availability)
+	assert.Equal(t, 20, periods[1].GoodQuantity)
+	assert.Equal(t, 66.7, periods[1].Performance)
+	assert.Equal(t, 17.0, report.Machines[0].Total.RunHours)
+}
+
+func TestBuildOEEReport_WeeksCountOpenRunsToNow(t *testing.T) {
+	data := map[int64]models.OEEMachineData{1: {
+		Runs: []models.MachineRunInterval{
+			oeeRun(at(3, 8), timePtr(at(3, 17))),
+			oeeRun(at(8, 8), nil), // Still running
+		},
+	}}
+
+	// Fri 3 .. Wed 8, now Wed 12:00
+	report := services.BuildOEEReport(utilizationMachines[:1], nil, data,
+		mustDate(t, "2025-01-03"), mustDate(t, "2025-01-08"), models.UtilizationPeriodWeek, at(8, 12))
+	periods := report.Machines[0].Periods
+	require.Len(t, periods, 2)
+
+	assert.Equal(t, "2025-01-03", periods[0].PeriodStart)
+	assert.Equal(t, "2025-01-05", periods[0].PeriodEnd)
+	assert.Equal(t, 100.0, periods[0].Availability)
+
+	// Mon and Tue 9 hours each, Wed up to now 4
+	assert.Equal(t, "2025-01-06", periods[1].PeriodStart)
+	assert.Equal(t, "2025-01-08", periods[1].PeriodEnd)
+	assert.Equal(t, 22.0, periods[1].PlannedHours)
+	assert.Equal(t, 4.0, periods[1].RunHours)
+	assert.Equal(t, 18.2, periods[1].Availability)
+	assert.Equal(t, 31.0, report.Total.PlannedHours)
+}
@@ -0,0 +1,101 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Baseline Variance Tests
+// =============================================================================
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func baselineSchedule(t *testing.T, id int64, start, finish string, assignments ...models.BaselineAssignment) models.BaselineSchedule {
+	return models.BaselineSchedule{
+		ScheduleID:  id,
+		StartDate:   mustDate(t, start),
+		FinishDate:  mustDate(t, finish),
+		Assignments: assignments,
+	}
+}
+
+func TestComputeBaselineVariance_ScheduleSlip(t *testing.T) {
+	baseline := []models.BaselineSchedule{
+		baselineSchedule(t, 1, "2025-01-06", "2025-01-08"),
+		baselineSchedule(t, 2, "2025-01-06", "2025-01-10"),
+		baselineSchedule(t, 3, "2025-01-06", "2025-01-07"),
+		baselineSchedule(t, 4, "2025-01-06", "2025-01-07"), // Deleted since
+	}
+	current := []models.PPICSchedule{
+		scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-08"),
+		scenarioSchedule(t, 2, "NJO-B", "2025-01-08", "2025-01-14"),
+		scenarioSchedule(t, 3, "NJO-C", "2025-01-06", "2025-01-06"),
+		scenarioSchedule(t, 5, "NJO-E", "2025-01-09", "2025-01-09"), // Created after the baseline
+	}
+
+	variance := services.ComputeBaselineVariance(baseline, current)
+	require.Len(t, variance.Schedules, 4)
+
+	// Worst slip first, schedules outside the baseline last
+	assert.Equal(t, int64(2), variance.Schedules[0].ScheduleID)
+	assert.Equal(t, 2, *variance.Schedules[0].StartSlipDays)
+	assert.Equal(t, 4, *variance.Schedules[0].FinishSlipDays)
+	assert.Equal(t, int64(5), variance.Schedules[3].ScheduleID)
+	assert.False(t, variance.Schedules[3].InBaseline)
+	assert.Nil(t, variance.Schedules[3].FinishSlipDays)
+
+	summary := variance.Summary
+	assert.Equal(t, 4, summary.TotalSchedules)
+	assert.Equal(t, 1, summary.LateCount)
+	assert.Equal(t, 1, summary.EarlyCount)
+	assert.Equal(t, 1, summary.OnTrackCount)
+	assert.Equal(t, 1, summary.NotInBaselineCount)
+	assert.Equal(t, 1, summary.RemovedCount)
+	assert.Equal(t, 4, summary.MaxFinishSlipDays)
+	assert.Equal(t, 1.0, summary.AverageFinishSlipDays)
+}
+
+func TestComputeBaselineVariance_AssignmentSlip(t *testing.T) {
+	baseline := []models.BaselineSchedule{
+		baselineSchedule(t, 1, "2025-01-06", "2025-01-07",
+			models.BaselineAssignment{ScheduleID: 1, MachineID: 1, Sequence: 1, ScheduledStart: timePtr(at(6, 8)), ScheduledEnd: timePtr(at(6, 16))},
+		),
+	}
+
+	// Rescheduled half a day later, then actually finished another 6 hours late
+	schedule := scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-07",
+		models.MachineAssignment{
+			ID: 11, MachineID: 2, Sequence: 1,
+			ScheduledStart: timePtr(at(6, 20)), ScheduledEnd: timePtr(at(7, 4)),
+			ActualStart: timePtr(at(6, 20)), ActualEnd: timePtr(at(7, 10)),
+		},
+		models.MachineAssignment{ID: 12, MachineID: 3, Sequence: 2},
+	)
+
+	variance := services.ComputeBaselineVariance(baseline, []models.PPICSchedule{schedule})
+	require.Len(t, variance.Schedules, 1)
+	assignments := variance.Schedules[0].Assignments
+	require.Len(t, assignments, 2)
+
+	first := assignments[0]
+	assert.Equal(t, at(6, 16), *first.BaselineEnd)
+	require.NotNil(t, first.BaselineSlipDays)
+	assert.Equal(t, 0.5, *first.BaselineSlipDays)
+	assert.Equal(t, 0.0, *first.ActualStartSlipDays)
+	assert.Equal(t, 0.25, *first.ActualEndSlipDays)
+
+	// Added after the baseline and never scheduled: nothing to compare
+	second := assignments[1]
+	assert.Nil(t, second.BaselineEnd)
+	assert.Nil(t, second.BaselineSlipDays)
+	assert.Nil(t, second.ActualEndSlipDays)
+}
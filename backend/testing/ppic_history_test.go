@@ -0,0 +1,92 @@
+package testing
+
+import (
+	"fmt"
+	"testing"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// PPIC Schedule History Tests
+// =============================================================================
+
+func changesByField(changes []models.PPICScheduleChange) map[string]models.PPICScheduleChange {
+	byField := make(map[string]models.PPICScheduleChange, len(changes))
+	for _, change := range changes {
+		key := change.Field
+		if change.Sequence != nil {
+			key = fmt.Sprintf("%s#%d", change.Field, *change.Sequence)
+		}
+		byField[key] = change
+	}
+	return byField
+}
+
+func TestDiffPPICSchedule_ScheduleFields(t *testing.T) {
+	before := scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-08")
+	before.Status = models.ScheduleStatusPending
+	after := before
+	after.StartDate = mustDate(t, "2025-01-07")
+	after.FinishDate = mustDate(t, "2025-01-09")
+	after.Status = models.ScheduleStatusInProgress
+	after.Progress = 40
+
+	changes := changesByField(models.DiffPPICSchedule(&before, &after))
+	require.Len(t, changes, 4)
+
+	assert.Equal(t, "2025-01-06", changes["start_date"].OldValue)
+	assert.Equal(t, "2025-01-07", changes["start_date"].NewValue)
+	assert.Equal(t, "2025-01-09", changes["finish_date"].NewValue)
+	assert.Equal(t, "pending", changes["status"].OldValue)
+	assert.Equal(t, "in_progress", changes["status"].NewValue)
+	assert.Equal(t, "0", changes["progress"].OldValue)
+	assert.Equal(t, "40", changes["progress"].NewValue)
+	assert.Equal(t, models.ChangeEntitySchedule, changes["status"].Entity)
+	assert.Equal(t, int64(1), changes["status"].ScheduleID)
+}
+
+func TestDiffPPICSchedule_NoChanges(t *testing.T) {
+	schedule := scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-08",
+		models.MachineAssignment{ID: 11, MachineID: 1, MachineCode: "CNC-01", Sequence: 1, TargetHours: 4},
+	)
+	same := schedule
+
+	assert.Empty(t, models.DiffPPICSchedule(&schedule, &same))
+}
+
+func TestDiffPPICSchedule_MachineAssignmentsMatchedBySequence(t *testing.T) {
+	before := scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-08",
+		models.MachineAssignment{ID: 11, MachineID: 1, MachineCode: "CNC-01", Sequence: 1, TargetHours: 4, ScheduledEnd: timePtr(at(6, 12))},
+		models.MachineAssignment{ID: 12, MachineID: 2, MachineCode: "MILL-01", Sequence: 2, TargetHours: 2},
+	)
+	// Editing a schedule re-creates its assignments, so IDs change
+	after := scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-08",
+		models.MachineAssignment{ID: 21, MachineID: 1, MachineCode: "CNC-01", Sequence: 1, TargetHours: 4.5, ScheduledEnd: timePtr(at(6, 13))},
+		models.MachineAssignment{ID: 23, MachineID: 3, MachineCode: "GRIND-01", Sequence: 3, TargetHours: 1},
+	)
+
+	changes := changesByField(models.DiffPPICSchedule(&before, &after))
+	require.Len(t, changes, 4)
+
+	assert.Equal(t, "4", changes["target_hours#1"].OldValue)
+	assert.Equal(t, "4.5", changes["target_hours#1"].NewValue)
+	assert.Equal(t, "2025-01-06T12:00:00Z", changes["scheduled_end#1"].OldValue)
+	assert.Equal(t, "2025-01-06T13:00:00Z", changes["scheduled_end#1"].NewValue)
+	assert.Equal(t, int64(21), *changes["scheduled_end#1"].AssignmentID)
+	assert.Equal(t, models.ChangeEntityMachineAssignment, changes["scheduled_end#1"].Entity)
+
+	// Removed and added routing steps
+	assert.Equal(t, "MILL-01", changes["machine#2"].OldValue)
+	assert.Equal(t, "", changes["machine#2"].NewValue)
+	assert.Equal(t, "", changes["machine#3"].OldValue)
+	assert.Equal(t, "GRIND-01", changes["machine#3"].NewValue)
+}
+
+func TestChangeCauses(t *testing.T) {
+	assert.Equal(t, "cascade from NJO NJO-A", models.CascadeChangeCause("NJO-A"))
+	assert.Equal(t, "scenario promoted: Rush order", models.ScenarioPromotedChangeCause("Rush order"))
+}
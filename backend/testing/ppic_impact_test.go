@@ -0,0 +1,180 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Schedule Impact (Dry Run) Tests
+// =============================================================================
+
+// impactBoard is A (Jan 6-8) -> B (Jan 9-10) -> C (Jan 11-12), plus E (Jan 6-8) -> B, all finish-to-start
+func impactBoard(t *testing.T) (map[int64]*models.PPICSchedule, []models.PPICLink) {
+	schedule := func(id int64, njo, start, finish string) *models.PPICSchedule {
+		return &models.PPICSchedule{ID: id, NJO: njo, PartName: "Bracket", StartDate: mustDate(t, start), FinishDate: mustDate(t, finish)}
+	}
+	board := map[int64]*models.PPICSchedule{
+		1: schedule(1, "NJO-A", "2025-01-06", "2025-01-08"),
+		2: schedule(2, "NJO-B", "2025-01-09", "2025-01-10"),
+		3: schedule(3, "NJO-C", "2025-01-11", "2025-01-12"),
+		5: schedule(5, "NJO-E", "2025-01-06", "2025-01-08"),
+	}
+	links := []models.PPICLink{
+		{ID: 1, SourceScheduleID: 1, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart},
+		{ID: 2, SourceScheduleID: 2, TargetScheduleID: 3, LinkType: models.LinkTypeFinishToStart},
+		{ID: 3, SourceScheduleID: 5, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart},
+	}
+	return board, links
+}
+
+func impactPlanner(t *testing.T) (*services.ScheduleImpactPlanner, map[int64]*models.PPICSchedule) {
+	board, links := impactBoard(t)
+	lookup := func(id int64) (*models.PPICSchedule, error) {
+		return board[id], nil
+	}
+	return services.NewScheduleImpactPlanner(models.AllDaysCalendar(), links, lookup), board
+}
+
+func TestScheduleImpact_CascadeListsEveryMove(t *testing.T) {
+	planner, board := impactPlanner(t)
+
+	require.NoError(t, planner.Move(1, mustDate(t, "2025-01-08"), mustDate(t, "2025-01-10"), models.ChangeCauseManual))
+	require.NoError(t, planner.Cascade(1))
+	impact, err := planner.Impact()
+	require.NoError(t, err)
+
+	assert.True(t, impact.DryRun)
+	assert.Empty(t, impact.Conflicts)
+	require.Len(t, impact.Changes, 3)
+	assert.Equal(t, models.ScheduleDateChange{
+		ScheduleID: 2, NJO: "NJO-B", PartName: "Bracket",
+		OldStartDate: "2025-01-09", OldFinishDate: "2025-01-10", NewStartDate: "2025-01-11", NewFinishDate: "2025-01-12",
+		ShiftDays: 2, Cause: models.CascadeChangeCause("NJO-A"),
+	}, impact.Changes[1])
+	assert.Equal(t, "2025-01-13", impact.Changes[2].NewStartDate)
+	assert.Equal(t, models.CascadeChangeCause("NJO-B"), impact.Changes[2].Cause)
+
+	// Nothing is written back to the stored schedules
+	assert.Equal(t, mustDate(t, "2025-01-09"), board[2].StartDate)
+}
+
+func TestScheduleImpact_ReportsBrokenPredecessorLinks(t *testing.T) {
+	planner, _ := impactPlanner(t)
+
+	// Pulling A earlier leaves B where it is: the dependents are only ever pushed later
+	require.NoError(t, planner.Move(1, mustDate(t, "2025-01-02"), mustDate(t, "2025-01-04"), models.ChangeCauseManual))
+	require.NoError(t, planner.Cascade(1))
+	impact, err := planner.Impact()
+	require.NoError(t, err)
+	require.Len(t, impact.Changes, 1)
+	assert.Empty(t, impact.Conflicts)
+
+	// Pulling B before E finishes breaks that link
+	require.NoError(t, planner.Move(2, mustDate(t, "2025-01-07"), mustDate(t, "2025-01-08"), models.ChangeCauseManual))
+	impact, err = planner.Impact()
+	require.NoError(t, err)
+	require.Len(t, impact.Conflicts, 1)
+	conflict := impact.Conflicts[0]
+	assert.Equal(t, int64(3), conflict.LinkID)
+	assert.Equal(t, "NJO-E", conflict.SourceNJO)
+	assert.Equal(t, "NJO-B", conflict.TargetNJO)
+	assert.Equal(t, "2025-01-09", conflict.RequiredDate)
+	assert.Equal(t, "NJO-B must start on or after 2025-01-09 (finish-to-start link from NJO-E)", conflict.Message)
+}
+
+func TestScheduleImpact_CascadeKeepsEveryPredecessorLink(t *testing.T) {
+	planner, _ := impactPlanner(t)
+
+	// E pushes B (and C after it) to the 11th
+	require.NoError(t, planner.Move(5, mustDate(t, "2025-01-08"), mustDate(t, "2025-01-10"), models.ChangeCauseManual))
+	require.NoError(t, planner.Cascade(5))
+	// A moves later too, but B has to wait for E, not A
+	require.NoError(t, planner.Move(1, mustDate(t, "2025-01-07"), mustDate(t, "2025-01-09"), models.ChangeCauseManual))
+	require.NoError(t, planner.Cascade(1))
+
+	b, err := planner.Schedule(2)
+	require.NoError(t, err)
+	assert.Equal(t, mustDate(t, "2025-01-11"), b.StartDate)
+	assert.Equal(t, mustDate(t, "2025-01-12"), b.FinishDate)
+	impact, err := planner.Impact()
+	require.NoError(t, err)
+	assert.Empty(t, impact.Conflicts)
+}
+
+func TestScheduleImpact_DiamondWaitsForTheLatestBranch(t *testing.T) {
+	// A -> B -> D and A -> C -> D; C is the longer branch. Either walking order gives the same D
+	schedule := func(id int64, njo, start, finish string) *models.PPICSchedule {
+		return &models.PPICSchedule{ID: id, NJO: njo, StartDate: mustDate(t, start), FinishDate: mustDate(t, finish)}
+	}
+	toB := models.PPICLink{ID: 1, SourceScheduleID: 1, TargetScheduleID: 2, LinkType: models.LinkTypeFinishToStart}
+	toC := models.PPICLink{ID: 2, SourceScheduleID: 1, TargetScheduleID: 3, LinkType: models.LinkTypeFinishToStart}
+	rest := []models.PPICLink{
+		{ID: 3, SourceScheduleID: 2, TargetScheduleID: 4, LinkType: models.LinkTypeFinishToStart},
+		{ID: 4, SourceScheduleID: 3, TargetScheduleID: 4, LinkType: models.LinkTypeFinishToStart},
+	}
+
+	for _, links := range [][]models.PPICLink{append([]models.PPICLink{toB, toC}, rest...), append([]models.PPICLink{toC, toB}, rest...)} {
+		board := map[int64]*models.PPICSchedule{
+			1: schedule(1, "NJO-A", "2025-01-06", "2025-01-07"),
+			2: schedule(2, "NJO-B", "2025-01-08", "2025-01-09"),
+			3: schedule(3, "NJO-C", "2025-01-08", "2025-01-11"),
+			4: schedule(4, "NJO-D", "2025-01-12", "2025-01-13"),
+		}
+		planner := services.NewScheduleImpactPlanner(models.AllDaysCalendar(), links, func(id int64) (*models.PPICSchedule, error) {
+			return board[id], nil
+		})
+
+		require.NoError(t, planner.Move(1, mustDate(t, "2025-01-08"), mustDate(t, "2025-01-09"), models.ChangeCauseManual))
+		require.NoError(t, planner.Cascade(1))
+
+		d, err := planner.Schedule(4)
+		require.NoError(t, err)
+		assert.Equal(t, mustDate(t, "2025-01-14"), d.StartDate, "D starts after C, the later branch")
+		assert.Equal(t, mustDate(t, "2025-01-15"), d.FinishDate)
+		impact, err := planner.Impact()
+		require.NoError(t, err)
+		assert.Len(t, impact.Changes, 4)
+		assert.Empty(t, impact.Conflicts)
+	}
+}
+
+func TestScheduleImpact_NewLink(t *testing.T) {
+	planner, _ := impactPlanner(t)
+
+	// A new C -> E link is not satisfied and nothing has moved yet
+	newLink := models.PPICLink{SourceScheduleID: 3, TargetScheduleID: 5, LinkType: models.LinkTypeStartToStart, LagDays: 1}
+	impact, err := planner.Impact(newLink)
+	require.NoError(t, err)
+	assert.Empty(t, impact.Changes)
+	require.Len(t, impact.Conflicts, 1)
+	assert.Equal(t, int64(0), impact.Conflicts[0].LinkID)
+	assert.Equal(t, "2025-01-12", impact.Conflicts[0].RequiredDate)
+
+	// Moving E onto the new link satisfies it, but breaks E -> B
+	require.NoError(t, planner.Move(5, mustDate(t, "2025-01-12"), mustDate(t, "2025-01-14"), models.ChangeCauseLinkCreated))
+	impact, err = planner.Impact(newLink)
+	require.NoError(t, err)
+	require.Len(t, impact.Changes, 1)
+	assert.Equal(t, models.ChangeCauseLinkCreated, impact.Changes[0].Cause)
+	require.Len(t, impact.Conflicts, 1)
+	assert.Equal(t, int64(3), impact.Conflicts[0].LinkID)
+	assert.Equal(t, "2025-01-15", impact.Conflicts[0].RequiredDate)
+}
+
+func TestScheduleImpact_UnchangedAndMissingSchedules(t *testing.T) {
+	planner, _ := impactPlanner(t)
+
+	// Moving onto the same dates is not a change
+	require.NoError(t, planner.Move(3, mustDate(t, "2025-01-11"), mustDate(t, "2025-01-12"), models.ChangeCauseManual))
+	impact, err := planner.Impact()
+	require.NoError(t, err)
+	assert.Empty(t, impact.Changes)
+
+	assert.Error(t, planner.Move(99, mustDate(t, "2025-01-11"), mustDate(t, "2025-01-12"), models.ChangeCauseManual))
+}
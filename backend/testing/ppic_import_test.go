@@ -0,0 +1,179 @@
+package testing
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// PPIC Schedule Import Tests
+// =============================================================================
+
+var importHeader = []string{"NJO", "Part Name", "Priority", "Material Status", "Start Date", "Finish Date", "Machine 1", "Hours 1", "Machine 2", "Hours 2"}
+
+var importMachineIDs = map[string]int64{"CNC-01": 1, "MILL-01": 2}
+
+func TestMapPPICImportColumns_MissingRequiredColumns(t *testing.T) {
+	_, err := models.MapPPICImportColumns([]string{"NJO", "Part Name", "Priority"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "material_status, start_date, finish_date")
+}
+
+func TestMapPPICImportColumns_MachineHoursWithoutMachine(t *testing.T) {
+	header := append([]string{"NJO", "Part", "Priority", "Material", "Start", "Finish"}, "Target Hours 2")
+	_, err := models.MapPPICImportColumns(header)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "machine 2")
+}
+
+func TestPPICImportColumns_ParseRow(t *testing.T) {
+	columns, err := models.MapPPICImportColumns(importHeader)
+	require.NoError(t, err)
+
+	req, errs := columns.ParseRow(
+		[]string{" NJO-001 ", "Bracket", "top urgent", "ready", "06/01/2025", "45667", "cnc-01", "4,5", "", ""},
+		importMachineIDs,
+	)
+	require.Empty(t, errs)
+
+	assert.Equal(t, "NJO-001", req.NJO)
+	assert.Equal(t, models.PriorityTopUrgent, req.Priority)
+	assert.Equal(t, models.MaterialReady, req.MaterialStatus)
+	assert.Equal(t, "2025-01-06", req.StartDate)
+	assert.Equal(t, "2025-01-10", req.FinishDate) // Excel serial number
+	require.Len(t, req.MachineAssignments, 1)
+	assert.Equal(t, int64(1), req.MachineAssignments[0].MachineID)
+	assert.Equal(t, 1, req.MachineAssignments[0].Sequence)
+	assert.Equal(t, 4.5, req.MachineAssignments[0].TargetHours)
+}
+
+func TestPPICImportColumns_ParseRowReportsEveryError(t *testing.T) {
+	columns, err := models.MapPPICImportColumns(importHeader)
+	require.NoError(t, err)
+
+	_, errs := columns.ParseRow(
+		[]string{"NJO-002", "", "Critical", "Ready", "2025-13-01", "2025-01-10", "LATHE-09", "2", "MILL-01", "x"},
+		importMachineIDs,
+	)
+
+	require.Len(t, errs, 5)
+	assert.Equal(t, "part_name is required", errs[0])
+	assert.Contains(t, errs[1], `invalid priority "Critical"`)
+	assert.Contains(t, errs[2], "start_date")
+	assert.Contains(t, errs[3], `unknown machine code "LATHE-09"`)
+	assert.Contains(t, errs[4], `machine 2: invalid hours "x"`)
+}
+
+func TestPPICImportColumns_ParseRowMachineWindow(t *testing.T) {
+	columns, err := models.MapPPICImportColumns(append(importHeader, "Machine 1 Start", "Machine 1 End"))
+	require.NoError(t, err)
+
+	req, errs := columns.ParseRow(
+		[]string{"NJO-001", "Bracket", "Low", "Ready", "2025-01-06", "2025-01-06", "CNC-01", "4", "", "", "2025-01-06 08:00", "45663.5"},
+		importMachineIDs,
+	)
+	require.Empty(t, errs)
+	require.Len(t, req.MachineAssignments, 1)
+	assert.Equal(t, "2025-01-06T08:00:00Z", req.MachineAssignments[0].ScheduledStart)
+	assert.Equal(t, "2025-01-06T12:00:00Z", req.MachineAssignments[0].ScheduledEnd) // Excel date-time serial
+}
+
+func TestPPICImportBookings_OverlappingRows(t *testing.T) {
+	columns, err := models.MapPPICImportColumns(append(importHeader, "Machine 1 Start", "Machine 1 End"))
+	require.NoError(t, err)
+	parse := func(rowNumber int, njo, machine, start, end string) *models.PPICImportRow {
+		req, errs := columns.ParseRow([]string{njo, "Bracket", "Low", "Ready", "2025-01-06", "2025-01-06", machine, "", "", "", start, end}, importMachineIDs)
+		require.Empty(t, errs)
+		return &models.PPICImportRow{Row: rowNumber, NJO: njo, Create: req}
+	}
+
+	bookings := models.PPICImportBookings{}
+	first := parse(2, "NJO-001", "CNC-01", "2025-01-06 08:00", "2025-01-06 12:00")
+	require.Empty(t, bookings.Check(first))
+	bookings.Add(first)
+
+	// Same machine, overlapping the first row
+	second := parse(3, "NJO-002", "CNC-01", "2025-01-06 10:00", "2025-01-06 14:00")
+	errs := bookings.Check(second)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "overlaps NJO NJO-001 sequence 1 on row 2")
+
+	// Back to back on the same machine, or the same time on another machine, is fine
+	assert.Empty(t, bookings.Check(parse(4, "NJO-003", "CNC-01", "2025-01-06 12:00", "2025-01-06 15:00")))
+	assert.Empty(t, bookings.Check(parse(5, "NJO-004", "MILL-01", "2025-01-06 10:00", "2025-01-06 14:00")))
+}
+
+func TestPPICImportRow_UpdateRequest(t *testing.T) {
+	row := models.PPICImportRow{Create: &models.CreatePPICScheduleRequest{
+		NJO: "NJO-001", PartName: "Bracket", Priority: models.PriorityUrgent, MaterialStatus: models.MaterialPending,
+		StartDate: "2025-01-06", FinishDate: "2025-01-08",
+		MachineAssignments: []models.CreateMachineAssignmentRequest{{MachineID: 2, Sequence: 1, TargetHours: 3}},
+	}}
+
+	req := row.UpdateRequest()
+	assert.Equal(t, "Bracket", req.PartName)
+	assert.Equal(t, models.PriorityUrgent, req.Priority)
+	assert.Equal(t, "2025-01-08", req.FinishDate)
+	require.Len(t, req.MachineAssignments, 1)
+	assert.Equal(t, int64(2), req.MachineAssignments[0].MachineID)
+	assert.Equal(t, 3.0, req.MachineAssignments[0].TargetHours)
+}
+
+func TestIsBlankImportRow(t *testing.T) {
+	assert.True(t, models.IsBlankImportRow([]string{"", "  ", ""}))
+	assert.False(t, models.IsBlankImportRow([]string{"", "NJO-001"}))
+}
+
+func TestReadSpreadsheet_CSV(t *testing.T) {
+	data := []byte("\xef\xbb\xbfNJO;Part Name;Hours 1\nNJO-001;Bracket;4,5\n")
+
+	rows, err := utils.ReadSpreadsheet("schedules.CSV", data)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"NJO", "Part Name", "Hours 1"}, {"NJO-001", "Bracket", "4,5"}}, rows)
+}
+
+func TestReadSpreadsheet_UnsupportedType(t *testing.T) {
+	_, err := utils.ReadSpreadsheet("schedules.xls", []byte("data"))
+	assert.Error(t, err)
+}
+
+func TestReadSpreadsheet_XLSX(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Schedules" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>NJO</t></si><si><t>Start Date</t></si><si><r><t>NJO-</t></r><r><t>001</t></r></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+			<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3"><v>45663</v></c></row>
+			<row r="4"><c r="B4" t="inlineStr"><is><t>note</t></is></c></row>
+		</sheetData></worksheet>`,
+	}
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+
+	rows, err := utils.ReadSpreadsheet("schedules.xlsx", buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"NJO", "Start Date"},
+		{},
+		{"NJO-001", "", "45663"},
+		{"", "note"},
+	}, rows)
+}
@@ -0,0 +1,244 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDate(t *testing.T, s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatalf("invalid date %s: %v", s, err)
+	}
+	return d
+}
+
+// =============================================================================
+// Link Type Validation Tests
+// =============================================================================
+
+func TestValidateLinkType(t *testing.T) {
+	validTypes := []string{"0", "1", "2", "3"}
+	for _, lt := range validTypes {
+		assert.True(t, models.ValidateLinkType(lt), "Link type %s should be valid", lt)
+	}
+
+	invalidTypes := []string{"", "4", "-1", "FS", "finish-to-start"}
+	for _, lt := range invalidTypes {
+		assert.False(t, models.ValidateLinkType(lt), "Link type %q should be invalid", lt)
+	}
+}
+
+func TestGetLinkTypeName(t *testing.T) {
+	assert.Equal(t, "finish-to-start", models.GetLinkTypeName(models.LinkTypeFinishToStart))
+	assert.Equal(t, "start-to-start", models.GetLinkTypeName(models.LinkTypeStartToStart))
+	assert.Equal(t, "finish-to-finish", models.GetLinkTypeName(models.LinkTypeFinishToFinish))
+	assert.Equal(t, "start-to-finish", models.GetLinkTypeName(models.LinkTypeStartToFinish))
+	assert.Equal(t, "unknown", models.GetLinkTypeName("9"))
+}
+
+// =============================================================================
+// Link Constraint Tests
+// =============================================================================
+
+// On the all-days calendar lag and lead are plain calendar days
+
+func TestIsLinkSatisfied(t *testing.T) {
+	cal := models.AllDaysCalendar()
+	sourceStart := mustDate(t, "2025-01-06")
+	sourceFinish := mustDate(t, "2025-01-10")
+
+	testCases := []struct {
+		name         string
+		linkType     string
+		targetStart  string
+		targetFinish string
+		expected     bool
+	}{
+		{"FS - starts day after source finish", models.LinkTypeFinishToStart, "2025-01-11", "2025-01-13", true},
+		{"FS - starts on source finish", models.LinkTypeFinishToStart, "2025-01-10", "2025-01-13", false},
+		{"SS - starts with source", models.LinkTypeStartToStart, "2025-01-06", "2025-01-08", true},
+		{"SS - starts before source", models.LinkTypeStartToStart, "2025-01-05", "2025-01-08", false},
+		{"FF - finishes with source", models.LinkTypeFinishToFinish, "2025-01-01", "2025-01-10", true},
+		{"FF - finishes before source", models.LinkTypeFinishToFinish, "2025-01-07", "2025-01-09", false},
+		{"SF - finishes after source start", models.LinkTypeStartToFinish, "2025-01-01", "2025-01-07", true},
+		{"SF - finishes before source start", models.LinkTypeStartToFinish, "2025-01-01", "2025-01-05", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := cal.IsLinkSatisfied(tc.linkType, 0, sourceStart, sourceFinish,
+				mustDate(t, tc.targetStart), mustDate(t, tc.targetFinish))
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestIsLinkSatisfied_WithLagAndLead(t *testing.T) {
+	cal := models.AllDaysCalendar()
+	sourceStart := mustDate(t, "2025-01-06")
+	sourceFinish := mustDate(t, "2025-01-10")
+
+	testCases := []struct {
+		name         string
+		linkType     string
+		lagDays      int
+		targetStart  string
+		targetFinish string
+		expected     bool
+	}{
+		{"FS 2 day lag - day after finish is too early", models.LinkTypeFinishToStart, 2, "2025-01-11", "2025-01-12", false},
+		{"FS 2 day lag - waits 2 days", models.LinkTypeFinishToStart, 2, "2025-01-13", "2025-01-14", true},
+		{"FS 2 day lead - overlaps the source", models.LinkTypeFinishToStart, -2, "2025-01-09", "2025-01-12", true},
+		{"FS 2 day lead - overlaps too much", models.LinkTypeFinishToStart, -2, "2025-01-08", "2025-01-12", false},
+		{"SS 1 day lag - starts a day after source", models.LinkTypeStartToStart, 1, "2025-01-07", "2025-01-09", true},
+		{"SS 1 day lag - starts with source", models.LinkTypeStartToStart, 1, "2025-01-06", "2025-01-09", false},
+		{"SS 2 day lead - starts 2 days before source", models.LinkTypeStartToStart, -2, "2025-01-04", "2025-01-06", true},
+		{"SS 2 day lead - starts 3 days before source", models.LinkTypeStartToStart, -2, "2025-01-03", "2025-01-06", false},
+		{"FF 3 day lag - finishes 3 days after source", models.LinkTypeFinishToFinish, 3, "2025-01-11", "2025-01-13", true},
+		{"FF 3 day lag - finishes 2 days after source", models.LinkTypeFinishToFinish, 3, "2025-01-11", "2025-01-12", false},
+		{"FF 1 day lead - finishes a day before source", models.LinkTypeFinishToFinish, -1, "2025-01-07", "2025-01-09", true},
+		{"FF 1 day lead - finishes 2 days before source", models.LinkTypeFinishToFinish, -1, "2025-01-07", "2025-01-08", false},
+		{"SF 2 day lag - finishes 2 days after source start", models.LinkTypeStartToFinish, 2, "2025-01-01", "2025-01-08", true},
+		{"SF 2 day lag - finishes a day after source start", models.LinkTypeStartToFinish, 2, "2025-01-01", "2025-01-07", false},
+		{"SF 1 day lead - finishes a day before source start", models.LinkTypeStartToFinish, -1, "2025-01-01", "2025-01-05", true},
+		{"SF 1 day lead - finishes 2 days before source start", models.LinkTypeStartToFinish, -1, "2025-01-01", "2025-01-04", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := cal.IsLinkSatisfied(tc.linkType, tc.lagDays, sourceStart, sourceFinish,
+				mustDate(t, tc.targetStart), mustDate(t, tc.targetFinish))
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestLinkedTargetDates_PreservesDuration(t *testing.T) {
+	cal := models.AllDaysCalendar()
+	sourceStart := mustDate(t, "2025-01-06")
+	sourceFinish := mustDate(t, "2025-01-10")
+	targetStart := mustDate(t, "2025-01-01")
+	targetFinish := mustDate(t, "2025-01-03")
+
+	testCases := []struct {
+		linkType      string
+		expectedStart string
+		expectedEnd   string
+	}{
+		{models.LinkTypeFinishToStart, "2025-01-11", "2025-01-13"},
+		{models.LinkTypeStartToStart, "2025-01-06", "2025-01-08"},
+		{models.LinkTypeFinishToFinish, "2025-01-08", "2025-01-10"},
+		{models.LinkTypeStartToFinish, "2025-01-04", "2025-01-06"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(models.GetLinkTypeName(tc.linkType), func(t *testing.T) {
+			start, finish := cal.LinkedTargetDates(tc.linkType, 0, sourceStart, sourceFinish, targetStart, targetFinish)
+			assert.Equal(t, tc.expectedStart, start.Format("2006-01-02"))
+			assert.Equal(t, tc.expectedEnd, finish.Format("2006-01-02"))
+			assert.True(t, cal.IsLinkSatisfied(tc.linkType, 0, sourceStart, sourceFinish, start, finish))
+		})
+	}
+}
+
+func TestLinkedTargetDates_WithLagAndLead(t *testing.T) {
+	cal := models.AllDaysCalendar()
+	sourceStart := mustDate(t, "2025-01-06")
+	sourceFinish := mustDate(t, "2025-01-10")
+	targetStart := mustDate(t, "2025-01-01")
+	targetFinish := mustDate(t, "2025-01-03")
+
+	testCases := []struct {
+		name          string
+		linkType      string
+		lagDays       int
+		expectedStart string
+		expectedEnd   string
+	}{
+		{"FS with 2 day lag (heat treatment)", models.LinkTypeFinishToStart, 2, "2025-01-13", "2025-01-15"},
+		{"FS with 1 day lead (overlap)", models.LinkTypeFinishToStart, -1, "2025-01-10", "2025-01-12"},
+		{"SS with 1 day lag", models.LinkTypeStartToStart, 1, "2025-01-07", "2025-01-09"},
+		{"SS with 2 day lead", models.LinkTypeStartToStart, -2, "2025-01-04", "2025-01-06"},
+		{"FF with 3 day lag", models.LinkTypeFinishToFinish, 3, "2025-01-11", "2025-01-13"},
+		{"FF with 2 day lead", models.LinkTypeFinishToFinish, -2, "2025-01-06", "2025-01-08"},
+		{"SF with 2 day lag", models.LinkTypeStartToFinish, 2, "2025-01-06", "2025-01-08"},
+		{"SF with 1 day lead", models.LinkTypeStartToFinish, -1, "2025-01-03", "2025-01-05"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, finish := cal.LinkedTargetDates(tc.linkType, tc.lagDays, sourceStart, sourceFinish, targetStart, targetFinish)
+			assert.Equal(t, tc.expectedStart, start.Format("2006-01-02"))
+			assert.Equal(t, tc.expectedEnd, finish.Format("2006-01-02"))
+			assert.True(t, cal.IsLinkSatisfied(tc.linkType, tc.lagDays, sourceStart, sourceFinish, start, finish))
+		})
+	}
+}
+
+// =============================================================================
+// Link Graph Tests
+// =============================================================================
+
+func TestFindLinkPath(t *testing.T) {
+	links := []models.PPICLink{
+		{ID: 1, SourceScheduleID: 1, TargetScheduleID: 2},
+		{ID: 2, SourceScheduleID: 2, TargetScheduleID: 3},
+		{ID: 3, SourceScheduleID: 4, TargetScheduleID: 5},
+	}
+
+	assert.Equal(t, []int64{1, 2, 3}, models.FindLinkPath(links, 1, 3))
+	assert.Nil(t, models.FindLinkPath(links, 3, 1))
+	assert.Nil(t, models.FindLinkPath(links, 1, 5))
+}
+
+func TestCascadeScheduleIDs(t *testing.T) {
+	links := []models.PPICLink{
+		{SourceScheduleID: 7, TargetScheduleID: 2}, // Predecessor of 2
+		{SourceScheduleID: 2, TargetScheduleID: 3},
+		{SourceScheduleID: 3, TargetScheduleID: 1},
+		{SourceScheduleID: 2, TargetScheduleID: 1},
+		{SourceScheduleID: 8, TargetScheduleID: 3}, // Predecessor of a dependent only
+		{SourceScheduleID: 4, TargetScheduleID: 5},
+	}
+
+	assert.Equal(t, []int64{1, 2, 3, 7}, models.CascadeScheduleIDs(links, 2))
+	assert.Equal(t, []int64{4, 5}, models.CascadeScheduleIDs(links, 4))
+	assert.Equal(t, []int64{6}, models.CascadeScheduleIDs(links, 6))
+}
+
+func TestFindLinkCycles(t *testing.T) {
+	t.Run("No cycles", func(t *testing.T) {
+		links := []models.PPICLink{
+			{SourceScheduleID: 1, TargetScheduleID: 2},
+			{SourceScheduleID: 1, TargetScheduleID: 3},
+			{SourceScheduleID: 2, TargetScheduleID: 3},
+		}
+		assert.Empty(t, models.FindLinkCycles(links))
+	})
+
+	t.Run("Three task loop", func(t *testing.T) {
+		links := []models.PPICLink{
+			{SourceScheduleID: 1, TargetScheduleID: 2},
+			{SourceScheduleID: 2, TargetScheduleID: 3},
+			{SourceScheduleID: 3, TargetScheduleID: 1},
+		}
+		cycles := models.FindLinkCycles(links)
+		assert.Len(t, cycles, 1)
+		assert.Equal(t, []int64{1, 2, 3, 1}, cycles[0])
+	})
+
+	t.Run("Two independent loops", func(t *testing.T) {
+		links := []models.PPICLink{
+			{SourceScheduleID: 1, TargetScheduleID: 2},
+			{SourceScheduleID: 2, TargetScheduleID: 1},
+			{SourceScheduleID: 5, TargetScheduleID: 6},
+			{SourceScheduleID: 6, TargetScheduleID: 5},
+		}
+		assert.Len(t, models.FindLinkCycles(links), 2)
+	})
+}
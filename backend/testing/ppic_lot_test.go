@@ -0,0 +1,178 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// PPIC Lot (Split / Merge) Tests
+// =============================================================================
+
+func lotSchedule(t *testing.T, id int64, number, quantity int, start, finish string) models.PPICSchedule {
+	parentID := int64(1)
+	return models.PPICSchedule{
+		ID: id, NJO: "NJO-001", PartName: "Bracket", ParentScheduleID: &parentID, LotNumber: &number, Quantity: quantity,
+		Status: models.ScheduleStatusPending, StartDate: mustDate(t, start), FinishDate: mustDate(t, finish),
+	}
+}
+
+func TestValidateLotQuantities(t *testing.T) {
+	req := models.SplitPPICScheduleRequest{Lots: []models.SplitLotRequest{{Quantity: 60}, {Quantity: 40}}}
+
+	total, err := req.ValidateLotQuantities(100)
+	require.NoError(t, err)
+	assert.Equal(t, 100, total)
+
+	// Without a recorded quantity the lots set it
+	total, err = req.ValidateLotQuantities(0)
+	require.NoError(t, err)
+	assert.Equal(t, 100, total)
+
+	_, err = req.ValidateLotQuantities(120)
+	assert.EqualError(t, err, "lot quantities add up to 100, but the schedule quantity is 120")
+
+	single := models.SplitPPICScheduleRequest{Lots: []models.SplitLotRequest{{Quantity: 100}}}
+	_, err = single.ValidateLotQuantities(100)
+	assert.Error(t, err)
+}
+
+func TestLotSpanAndQuantity(t *testing.T) {
+	lots := []models.PPICSchedule{
+		lotSchedule(t, 2, 1, 60, "2025-01-08", "2025-01-10"),
+		lotSchedule(t, 3, 2, 40, "2025-01-06", "2025-01-09"),
+	}
+
+	start, finish := models.LotSpan(lots)
+	assert.Equal(t, mustDate(t, "2025-01-06"), start)
+	assert.Equal(t, mustDate(t, "2025-01-10"), finish)
+	assert.Equal(t, 100, models.LotQuantity(lots))
+	assert.Equal(t, "Bracket - Lot 2", models.LotTaskName(&lots[1]))
+}
+
+func TestDeriveSplitProgress_WeightedByQuantity(t *testing.T) {
+	lots := []models.PPICSchedule{
+		lotSchedule(t, 2, 1, 75, "2025-01-06", "2025-01-08"),
+		lotSchedule(t, 3, 2, 25, "2025-01-09", "2025-01-10"),
+	}
+
+	progress, status, ok := models.DeriveSplitProgress(lots)
+	require.True(t, ok)
+	assert.Equal(t, 0, progress)
+	assert.Equal(t, models.ScheduleStatusPending, status)
+
+	lots[0].Progress, lots[0].Status = 100, models.ScheduleStatusCompleted
+	progress, status, _ = models.DeriveSplitProgress(lots)
+	assert.Equal(t, 75, progress)
+	assert.Equal(t, models.ScheduleStatusInProgress, status)
+
+	lots[1].Progress, lots[1].Status = 100, models.ScheduleStatusCompleted
+	progress, status, _ = models.DeriveSplitProgress(lots)
+	assert.Equal(t, 100, progress)
+	assert.Equal(t, models.ScheduleStatusCompleted, status)
+
+	_, _, ok = models.DeriveSplitProgress(nil)
+	assert.False(t, ok)
+}
+
+func TestProrateAssignments(t *testing.T) {
+	routing := []models.MachineAssignment{
+		progressAssignment(1, 8, models.AssignmentStatusPending),
+		progressAssignment(2, 5, models.AssignmentStatusPending),
+	}
+	routing[0].MachineID, routing[1].MachineID = 11, 12
+	routing[0].ScheduledStart = timePtr(at(6, 8))
+
+	prorated := models.ProrateAssignments(routing, 0.25)
+	assert.Equal(t, []models.CreateMachineAssignmentRequest{
+		{MachineID: 11, Sequence: 1, TargetHours: 2},
+		{MachineID: 12, Sequence: 2, TargetHours: 1.25},
+	}, prorated)
+}
+
+func TestMergeLotAssignments(t *testing.T) {
+	first := lotSchedule(t, 2, 1, 60, "2025-01-06", "2025-01-08")
+	second := lotSchedule(t, 3, 2, 40, "2025-01-09", "2025-01-10")
+	first.MachineAssignments = []models.MachineAssignment{
+		{MachineID: 11, Sequence: 1, TargetHours: 6},
+		{MachineID: 12, Sequence: 2, TargetHours: 3},
+	}
+	second.MachineAssignments = []models.MachineAssignment{
+		{MachineID: 21, Sequence: 1, TargetHours: 4, ScheduledStart: timePtr(at(9, 8))},
+		{MachineID: 13, Sequence: 3, TargetHours: 1},
+	}
+
+	// Per sequence: the machine of the lowest numbered lot, the hours of all lots, no windows
+	merged := models.MergeLotAssignments([]models.PPICSchedule{second, first})
+	assert.Equal(t, []models.CreateMachineAssignmentRequest{
+		{MachineID: 11, Sequence: 1, TargetHours: 10},
+		{MachineID: 12, Sequence: 2, TargetHours: 3},
+		{MachineID: 13, Sequence: 3, TargetHours: 1},
+	}, merged)
+}
+
+func TestWorkRecorded(t *testing.T) {
+	schedule := models.PPICSchedule{MachineAssignments: []models.MachineAssignment{
+		progressAssignment(1, 8, models.AssignmentStatusPending),
+	}}
+	assert.False(t, models.WorkRecorded(&schedule))
+
+	schedule.MachineAssignments = append(schedule.MachineAssignments, progressAssignment(2, 8, models.AssignmentStatusInProgress))
+	assert.True(t, models.WorkRecorded(&schedule))
+}
+
+func TestShiftLot_MovesByWorkingDaysWithMachineWindows(t *testing.T) {
+	// Thu 2 - Fri 3 with a window Fri 3 14:00-18:00, moved 1 working day on the Mon-Fri calendar
+	lot := lotSchedule(t, 2, 1, 10, "2025-01-02", "2025-01-03")
+	lot.MachineAssignments = []models.MachineAssignment{
+		{ID: 5, MachineID: 1, Sequence: 1, ScheduledStart: timePtr(at(3, 14)), ScheduledEnd: timePtr(at(3, 18))},
+		{ID: 6, MachineID: 2, Sequence: 2},
+	}
+	calendar := models.NewWorkingCalendar(nil, nil)
+
+	moved := models.ShiftLot(calendar, lot, 1)
+	assert.Equal(t, "2025-01-03", moved.StartDate.Format("2006-01-02"))
+	assert.Equal(t, "2025-01-06", moved.FinishDate.Format("2006-01-02"), "keeps 2 working days across the weekend")
+	assert.Equal(t, at(6, 14), *moved.MachineAssignments[0].ScheduledStart)
+	assert.Equal(t, at(6, 18), *moved.MachineAssignments[0].ScheduledEnd)
+	assert.Nil(t, moved.MachineAssignments[1].ScheduledStart)
+	// The original is left alone
+	assert.Equal(t, at(3, 14), *lot.MachineAssignments[0].ScheduledStart)
+
+	back := models.ShiftLot(calendar, moved, calendar.WorkingDaysOffset(moved.StartDate, lot.StartDate))
+	assert.Equal(t, lot.StartDate, back.StartDate)
+	assert.Equal(t, lot.FinishDate, back.FinishDate)
+	assert.Equal(t, at(3, 14), *back.MachineAssignments[0].ScheduledStart)
+}
+
+func TestGetLots_ReadsLotColumns(t *testing.T) {
+	db, board := openFakeBoard(t, 3, 0)
+	parentID := int64(1)
+	for i, number := range []int{2, 1} {
+		board.schedules[i+1].ParentScheduleID = &parentID
+		board.schedules[i+1].LotNumber = &number
+		board.schedules[i+1].Quantity = 50
+	}
+	repo := repository.NewPPICScheduleRepository(db)
+
+	lots, err := repo.GetLots(1)
+	require.NoError(t, err)
+	require.Len(t, lots, 2)
+	for _, l := range lots {
+		require.NotNil(t, l.ParentScheduleID)
+		assert.Equal(t, int64(1), *l.ParentScheduleID)
+		assert.Equal(t, 50, l.Quantity)
+		assert.Len(t, l.MachineAssignments, 3)
+	}
+	assert.Contains(t, board.log[0].sql, "ORDER BY lot_number")
+
+	parent, err := repo.GetByID(1)
+	require.NoError(t, err)
+	assert.Nil(t, parent.ParentScheduleID)
+	assert.Nil(t, parent.LotNumber)
+}
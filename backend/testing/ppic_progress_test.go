@@ -0,0 +1,96 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// Derived Schedule Progress Tests
+// =============================================================================
+
+func progressAssignment(sequence int, hours float64, status string) models.MachineAssignment {
+	ma := models.MachineAssignment{ID: int64(sequence), Sequence: sequence, TargetHours: hours, Status: status}
+	switch status {
+	case models.AssignmentStatusInProgress:
+		ma.ActualStart = timePtr(at(6, 8))
+	case models.AssignmentStatusCompleted:
+		ma.ActualStart = timePtr(at(6, 8))
+		ma.ActualEnd = timePtr(at(6, 16))
+	}
+	return ma
+}
+
+func TestDeriveScheduleProgress_NothingStarted(t *testing.T) {
+	progress, status, ok := models.DeriveScheduleProgress([]models.MachineAssignment{
+		progressAssignment(1, 8, models.AssignmentStatusPending),
+		progressAssignment(2, 2, models.AssignmentStatusPending),
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 0, progress)
+	assert.Equal(t, models.ScheduleStatusPending, status)
+}
+
+func TestDeriveScheduleProgress_WeightedByTargetHours(t *testing.T) {
+	// 6h done + half of 2h in progress out of 10h
+	progress, status, ok := models.DeriveScheduleProgress([]models.MachineAssignment{
+		progressAssignment(1, 6, models.AssignmentStatusCompleted),
+		progressAssignment(2, 2, models.AssignmentStatusInProgress),
+		progressAssignment(3, 2, models.AssignmentStatusPending),
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 70, progress)
+	assert.Equal(t, models.ScheduleStatusInProgress, status)
+}
+
+func TestDeriveScheduleProgress_FirstActualStartMovesToInProgress(t *testing.T) {
+	ma := progressAssignment(1, 8, models.AssignmentStatusPending)
+	ma.ActualStart = timePtr(at(6, 8))
+	progress, status, _ := models.DeriveScheduleProgress([]models.MachineAssignment{ma, progressAssignment(2, 1000, models.AssignmentStatusPending)})
+	assert.Equal(t, models.ScheduleStatusInProgress, status)
+	assert.Equal(t, 1, progress) // Started work never shows as 0%
+}
+
+func TestDeriveScheduleProgress_CompletedWhenLastSequenceFinishes(t *testing.T) {
+	// Sequence order, not slice order, decides which step is last
+	progress, status, _ := models.DeriveScheduleProgress([]models.MachineAssignment{
+		progressAssignment(3, 2, models.AssignmentStatusCompleted),
+		progressAssignment(1, 6, models.AssignmentStatusCompleted),
+		progressAssignment(2, 2, models.AssignmentStatusInProgress),
+	})
+	assert.Equal(t, 100, progress)
+	assert.Equal(t, models.ScheduleStatusCompleted, status)
+
+	// Everything but the last step done is still in progress
+	progress, status, _ = models.DeriveScheduleProgress([]models.MachineAssignment{
+		progressAssignment(1, 99, models.AssignmentStatusCompleted),
+		progressAssignment(2, 0.1, models.AssignmentStatusPending),
+	})
+	assert.Equal(t, 99, progress)
+	assert.Equal(t, models.ScheduleStatusInProgress, status)
+}
+
+func TestDeriveScheduleProgress_NoTargetHoursCountsAssignmentsEqually(t *testing.T) {
+	progress, _, _ := models.DeriveScheduleProgress([]models.MachineAssignment{
+		progressAssignment(1, 0, models.AssignmentStatusCompleted),
+		progressAssignment(2, 0, models.AssignmentStatusPending),
+		progressAssignment(3, 0, models.AssignmentStatusPending),
+		progressAssignment(4, 0, models.AssignmentStatusPending),
+	})
+	assert.Equal(t, 25, progress)
+}
+
+func TestDeriveScheduleProgress_NoAssignments(t *testing.T) {
+	_, _, ok := models.DeriveScheduleProgress(nil)
+	assert.False(t, ok)
+}
+
+func TestValidateScheduleAndAssignmentStatus(t *testing.T) {
+	assert.True(t, models.ValidateScheduleStatus(models.ScheduleStatusOnHold))
+	assert.False(t, models.ValidateScheduleStatus("done"))
+	assert.True(t, models.ValidateAssignmentStatus(models.AssignmentStatusCompleted))
+	assert.False(t, models.ValidateAssignmentStatus(models.ScheduleStatusOnHold))
+}
@@ -0,0 +1,132 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+	"ganttpro-backend/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// PPIC Scenario Comparison Tests
+// =============================================================================
+
+func scenarioSchedule(t *testing.T, id int64, njo, start, finish string, assignments ...models.MachineAssignment) models.PPICSchedule {
+	return models.PPICSchedule{
+		ID:                 id,
+		NJO:                njo,
+		StartDate:          mustDate(t, start),
+		FinishDate:         mustDate(t, finish),
+		MachineAssignments: assignments,
+	}
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestCompareScenarioSchedules_ClassifiesChanges(t *testing.T) {
+	live := []models.PPICSchedule{
+		scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-08"),
+		scenarioSchedule(t, 2, "NJO-B", "2025-01-06", "2025-01-10"),
+		scenarioSchedule(t, 3, "NJO-C", "2025-01-06", "2025-01-07"),
+		scenarioSchedule(t, 4, "NJO-D", "2025-01-09", "2025-01-09"), // Created after the scenario
+	}
+	scenario := []models.PPICSchedule{
+		scenarioSchedule(t, 101, "NJO-A", "2025-01-06", "2025-01-08"),
+		scenarioSchedule(t, 102, "NJO-B", "2025-01-08", "2025-01-13"),
+		scenarioSchedule(t, 105, "NJO-E", "2025-01-14", "2025-01-15"),
+	}
+	sources := []models.ScenarioScheduleSource{
+		{ScheduleID: 101, SourceScheduleID: int64Ptr(1)},
+		{ScheduleID: 102, SourceScheduleID: int64Ptr(2)},
+		{ScheduleID: 103, SourceScheduleID: int64Ptr(3), Deleted: true},
+		{ScheduleID: 105},
+	}
+
+	comparison := services.CompareScenarioSchedules(live, scenario, sources)
+	require.Len(t, comparison.Schedules, 5)
+
+	changes := make(map[string]models.ScenarioScheduleDiff)
+	for _, diff := range comparison.Schedules {
+		changes[diff.NJO] = diff
+	}
+	assert.Equal(t, models.ScenarioChangeUnchanged, changes["NJO-A"].Change)
+	assert.Equal(t, models.ScenarioChangeMoved, changes["NJO-B"].Change)
+	assert.Equal(t, 3, changes["NJO-B"].FinishDeltaDays)
+	assert.Equal(t, models.ScenarioChangeRemoved, changes["NJO-C"].Change)
+	assert.Nil(t, changes["NJO-C"].ScenarioScheduleID)
+	assert.Equal(t, models.ScenarioChangeLiveOnly, changes["NJO-D"].Change)
+	assert.Equal(t, models.ScenarioChangeAdded, changes["NJO-E"].Change)
+	assert.Nil(t, changes["NJO-E"].LiveScheduleID)
+
+	// Live-only schedules are not scenario changes
+	assert.Equal(t, 3, comparison.ChangedCount)
+
+	require.NotNil(t, comparison.LiveFinish)
+	require.NotNil(t, comparison.ScenarioFinish)
+	assert.Equal(t, mustDate(t, "2025-01-10"), *comparison.LiveFinish)
+	assert.Equal(t, mustDate(t, "2025-01-15"), *comparison.ScenarioFinish)
+}
+
+func TestCompareScenarioSchedules_MachineLoad(t *testing.T) {
+	live := []models.PPICSchedule{
+		scenarioSchedule(t, 1, "NJO-A", "2025-01-06", "2025-01-08",
+			models.MachineAssignment{MachineID: 1, MachineName: "Lathe", TargetHours: 8},
+			models.MachineAssignment{MachineID: 2, MachineName: "Mill", TargetHours: 4},
+		),
+	}
+	scenario := []models.PPICSchedule{
+		scenarioSchedule(t, 101, "NJO-A", "2025-01-06", "2025-01-08",
+			models.MachineAssignment{MachineID: 1, MachineName: "Lathe", TargetHours: 2},
+			models.MachineAssignment{MachineID: 3, MachineName: "Grinder", TargetHours: 6},
+		),
+	}
+	sources := []models.ScenarioScheduleSource{{ScheduleID: 101, SourceScheduleID: int64Ptr(1)}}
+
+	comparison := services.CompareScenarioSchedules(live, scenario, sources)
+	require.Len(t, comparison.MachineLoad, 3)
+
+	loads := make(map[int64]models.ScenarioMachineLoad)
+	for _, load := range comparison.MachineLoad {
+		loads[load.MachineID] = load
+	}
+	assert.Equal(t, -6.0, loads[1].DeltaHours)
+	assert.Equal(t, -4.0, loads[2].DeltaHours)
+	assert.Equal(t, 6.0, loads[3].DeltaHours)
+	assert.Equal(t, 0.0, loads[3].LiveHours)
+
+	// Sorted by machine name
+	assert.Equal(t, "Grinder", comparison.MachineLoad[0].MachineName)
+}
+
+func TestCompareScenarioSchedules_EmptyBoards(t *testing.T) {
+	comparison := services.CompareScenarioSchedules(nil, nil, nil)
+	assert.Empty(t, comparison.Schedules)
+	assert.Empty(t, comparison.MachineLoad)
+	assert.Nil(t, comparison.LiveFinish)
+	assert.Nil(t, comparison.ScenarioFinish)
+}
+
+func TestScenarioPromoteConflicts_LiveScheduleDeletedAfterScenario(t *testing.T) {
+	created := at(6, 8)
+	live := []models.ScenarioLiveState{
+		{NJO: "NJO-A", UpdatedAt: at(5, 8)},
+		{NJO: "NJO-B", UpdatedAt: at(6, 10), Deleted: true}, // Deleted on the live board after the scenario was copied
+	}
+
+	conflicts := models.ScenarioPromoteConflicts(created, live, nil)
+	assert.Equal(t, []string{"NJO-B (deleted)"}, conflicts, "promoting must not bring the deleted schedule back")
+
+	// Changed schedules and links added after the scenario are conflicts too; older links are not
+	live[0].UpdatedAt = at(7, 8)
+	links := []models.ScenarioLiveLink{
+		{SourceNJO: "NJO-A", TargetNJO: "NJO-B", CreatedAt: at(1, 8)},
+		{SourceNJO: "NJO-A", TargetNJO: "NJO-C", CreatedAt: at(6, 9)},
+	}
+	conflicts = models.ScenarioPromoteConflicts(created, live, links)
+	assert.Equal(t, []string{"NJO-A", "NJO-B (deleted)", "link NJO-A -> NJO-C"}, conflicts)
+}
@@ -0,0 +1,135 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Routing Template Tests
+// =============================================================================
+
+func routingMachines() []models.Machine {
+	return []models.Machine{
+		{ID: 1, MachineCode: "CNC-01", MachineName: "CNC 1", MachineType: "CNC Milling", Status: models.MachineStatusMaintenance},
+		{ID: 2, MachineCode: "CNC-02", MachineName: "CNC 2", MachineType: "CNC Milling", Status: models.MachineStatusActive},
+		{ID: 3, MachineCode: "CNC-03", MachineName: "CNC 3", MachineType: "cnc milling", Status: models.MachineStatusActive},
+		{ID: 4, MachineCode: "EDM-01", MachineName: "EDM 1", MachineType: "EDM", Status: models.MachineStatusActive},
+		{ID: 5, MachineCode: "GRD-01", MachineName: "Grinder 1", MachineType: "Grinding", Status: models.MachineStatusOffline},
+		{ID: 6, MachineCode: "LTH-01", MachineName: "Lathe 1", MachineType: "Lathe", Status: models.MachineStatusActive},
+	}
+}
+
+func routingTemplate() *models.RoutingTemplate {
+	grinder, edm := int64(5), int64(4)
+	return &models.RoutingTemplate{
+		ID:   7,
+		Name: "Mold insert",
+		Steps: models.NewRoutingTemplateSteps([]models.RoutingTemplateStepRequest{
+			{Operation: "Roughing", MachineType: "CNC Milling", TargetHours: 6},
+			{Operation: "Grinding", MachineID: &grinder, TargetHours: 3, Alternatives: []models.RoutingMachineRequest{
+				{MachineType: "Lathe", TargetHours: 4},
+				{MachineID: &edm},
+			}},
+			{Operation: "Finishing", MachineID: &edm, TargetHours: 2},
+		}),
+	}
+}
+
+func TestNewRoutingTemplateSteps_NumbersInOrder(t *testing.T) {
+	steps := routingTemplate().Steps
+
+	require.Len(t, steps, 3)
+	for i, step := range steps {
+		assert.Equal(t, i+1, step.Sequence)
+	}
+	require.Len(t, steps[1].Alternatives, 2)
+	assert.Equal(t, 1, steps[1].Alternatives[0].Preference)
+	assert.Equal(t, 2, steps[1].Alternatives[1].Preference)
+}
+
+func TestValidateRoutingSteps(t *testing.T) {
+	machineID := int64(1)
+	steps := []models.RoutingTemplateStepRequest{{MachineType: "CNC Milling"}, {MachineID: &machineID}}
+	assert.NoError(t, models.ValidateRoutingSteps(steps, 5))
+
+	assert.EqualError(t, models.ValidateRoutingSteps(steps, 1), "a routing template can have at most 1 steps")
+	assert.Error(t, models.ValidateRoutingSteps(nil, 5))
+
+	missing := []models.RoutingTemplateStepRequest{{MachineType: "CNC Milling"}, {Operation: "Deburr"}}
+	assert.EqualError(t, models.ValidateRoutingSteps(missing, 5), "step 2: machine_id or machine_type is required")
+
+	badAlternative := []models.RoutingTemplateStepRequest{{MachineType: "EDM", Alternatives: []models.RoutingMachineRequest{{}}}}
+	assert.EqualError(t, models.ValidateRoutingSteps(badAlternative, 5), "step 1, alternative 1: machine_id or machine_type is required")
+}
+
+func TestApplyRoutingTemplate_ResolvesMachines(t *testing.T) {
+	applied, err := models.ApplyRoutingTemplate(routingTemplate(), routingMachines(), nil, nil)
+	require.NoError(t, err)
+
+	// By type: the first active machine of the type (CNC-01 is in maintenance), matched case-insensitively
+	// Grinder is offline, so the first alternative runs the step with its own target hours
+	assert.Equal(t, []models.CreateMachineAssignmentRequest{
+		{MachineID: 2, Sequence: 1, TargetHours: 6},
+		{MachineID: 6, Sequence: 2, TargetHours: 4},
+		{MachineID: 4, Sequence: 3, TargetHours: 2},
+	}, applied.MachineAssignments)
+
+	require.Len(t, applied.Steps, 3)
+	assert.Equal(t, "Roughing", applied.Steps[0].Operation)
+	require.Len(t, applied.Steps[0].Alternatives, 1)
+	assert.Equal(t, int64(3), applied.Steps[0].Alternatives[0].ID)
+	require.Len(t, applied.Steps[1].Alternatives, 1)
+	assert.Equal(t, int64(4), applied.Steps[1].Alternatives[0].ID)
+	assert.False(t, applied.Steps[1].Overridden)
+}
+
+func TestApplyRoutingTemplate_OverridesAndSkips(t *testing.T) {
+	overrides := []models.CreateMachineAssignmentRequest{
+		{MachineID: 3, Sequence: 1, ScheduledStart: "2025-01-06T08:00"}, // Keeps the template's hours
+		{MachineID: 6, Sequence: 4, TargetHours: 1.5},                   // Extra step
+	}
+
+	applied, err := models.ApplyRoutingTemplate(routingTemplate(), routingMachines(), overrides, []int{3})
+	require.NoError(t, err)
+
+	assert.Equal(t, []models.CreateMachineAssignmentRequest{
+		{MachineID: 3, Sequence: 1, TargetHours: 6, ScheduledStart: "2025-01-06T08:00"},
+		{MachineID: 6, Sequence: 2, TargetHours: 4},
+		{MachineID: 6, Sequence: 4, TargetHours: 1.5},
+	}, applied.MachineAssignments)
+	assert.True(t, applied.Steps[0].Overridden)
+	assert.True(t, applied.Steps[2].Overridden)
+}
+
+func TestApplyRoutingTemplate_Errors(t *testing.T) {
+	machines := routingMachines()
+	for i := range machines {
+		if machines[i].MachineType == "CNC Milling" || machines[i].MachineType == "cnc milling" {
+			machines[i].Status = models.MachineStatusInactive
+		}
+	}
+
+	_, err := models.ApplyRoutingTemplate(routingTemplate(), machines, nil, nil)
+	assert.EqualError(t, err, "sequence 1 (Roughing): no active machine can run this step. Choose a machine for it")
+
+	// Overriding the step resolves it
+	_, err = models.ApplyRoutingTemplate(routingTemplate(), machines, []models.CreateMachineAssignmentRequest{{MachineID: 6, Sequence: 1}}, nil)
+	assert.NoError(t, err)
+
+	_, err = models.ApplyRoutingTemplate(routingTemplate(), routingMachines(), []models.CreateMachineAssignmentRequest{{MachineID: 99, Sequence: 2}}, nil)
+	assert.EqualError(t, err, "sequence 2: machine 99 not found")
+
+	_, err = models.ApplyRoutingTemplate(routingTemplate(), routingMachines(), []models.CreateMachineAssignmentRequest{{MachineID: 2, Sequence: 1}, {MachineID: 3, Sequence: 1}}, nil)
+	assert.EqualError(t, err, "sequence 1 is overridden more than once")
+}
+
+func TestNewPlanningSettings_Defaults(t *testing.T) {
+	assert.Equal(t, models.DefaultMaxMachinesPerSchedule, models.NewPlanningSettings(nil).MaxMachinesPerSchedule)
+	assert.Equal(t, models.DefaultMaxMachinesPerSchedule, models.NewPlanningSettings(map[string]string{models.SettingMaxMachinesPerSchedule: "abc"}).MaxMachinesPerSchedule)
+	assert.Equal(t, 8, models.NewPlanningSettings(map[string]string{models.SettingMaxMachinesPerSchedule: "8"}).MaxMachinesPerSchedule)
+}
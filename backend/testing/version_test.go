@@ -0,0 +1,35 @@
+package testing
+
+import (
+	"testing"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// =============================================================================
+// Optimistic Concurrency Tests
+// =============================================================================
+
+func TestParseIfMatch(t *testing.T) {
+	for _, header := range []string{`3`, `"3"`, `W/"3"`, ` "3" `} {
+		version, err := models.ParseIfMatch(header)
+		require.NoError(t, err, header)
+		assert.Equal(t, 3, version, header)
+	}
+
+	for _, header := range []string{`*`, `"abc"`, `"0"`, `"-1"`, `"3", "4"`} {
+		_, err := models.ParseIfMatch(header)
+		assert.Error(t, err, header)
+	}
+}
+
+func TestVersionETag_RoundTrips(t *testing.T) {
+	assert.Equal(t, `"7"`, models.VersionETag(7))
+
+	version, err := models.ParseIfMatch(models.VersionETag(7))
+	require.NoError(t, err)
+	assert.Equal(t, 7, version)
+}
@@ -0,0 +1,118 @@
+package testing
+
+import (
+	"testing"
+	"time"
+
+	"ganttpro-backend/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// =============================================================================
+// Working Calendar Tests
+// =============================================================================
+
+// 2025-01-06 is a Monday
+func weekdayCalendar(t *testing.T) *models.WorkingCalendar {
+	return models.NewWorkingCalendar(nil, []models.CalendarException{
+		{Date: mustDate(t, "2025-01-08"), Type: models.CalendarExceptionHoliday, Name: "Plant holiday"},
+		{Date: mustDate(t, "2025-01-11"), Type: models.CalendarExceptionWorkingDay, Name: "Saturday overtime", StartTime: "08:00", EndTime: "12:00"},
+	})
+}
+
+func TestWorkingCalendar_DefaultIsWeekdays(t *testing.T) {
+	cal := models.NewWorkingCalendar(nil, nil)
+
+	assert.True(t, cal.IsWorkingDay(mustDate(t, "2025-01-06")))
+	assert.False(t, cal.IsWorkingDay(mustDate(t, "2025-01-04")), "Saturday is not a working day by default")
+	assert.False(t, cal.IsWorkingDay(mustDate(t, "2025-01-05")), "Sunday is not a working day by default")
+	assert.Equal(t, 9.0, cal.WorkingHoursOn(mustDate(t, "2025-01-06")))
+}
+
+func TestWorkingCalendar_Exceptions(t *testing.T) {
+	cal := weekdayCalendar(t)
+
+	assert.False(t, cal.IsWorkingDay(mustDate(t, "2025-01-08")), "Holiday is not a working day")
+	assert.True(t, cal.IsWorkingDay(mustDate(t, "2025-01-11")), "Special working day overrides the weekend")
+	assert.Equal(t, 4.0, cal.WorkingHoursOn(mustDate(t, "2025-01-11")))
+	assert.Equal(t, "Plant holiday", cal.Day(mustDate(t, "2025-01-08")).Note)
+}
+
+func TestWorkingCalendar_AddWorkingDays(t *testing.T) {
+	cal := weekdayCalendar(t)
+
+	// Tue 7 + 1 skips the Wed 8 holiday
+	assert.Equal(t, "2025-01-09", cal.AddWorkingDays(mustDate(t, "2025-01-07"), 1).Format("2006-01-02"))
+	// Fri 10 + 1 lands on the special Saturday
+	assert.Equal(t, "2025-01-11", cal.AddWorkingDays(mustDate(t, "2025-01-10"), 1).Format("2006-01-02"))
+	// Mon 13 - 1 goes back to the special Saturday
+	assert.Equal(t, "2025-01-11", cal.AddWorkingDays(mustDate(t, "2025-01-13"), -1).Format("2006-01-02"))
+	assert.Equal(t, "2025-01-07", cal.AddWorkingDays(mustDate(t, "2025-01-07"), 0).Format("2006-01-02"))
+}
+
+func TestWorkingCalendar_WorkingDaysBetween(t *testing.T) {
+	cal := weekdayCalendar(t)
+
+	// Mon 6 .. Fri 10 minus the holiday
+	assert.Equal(t, 4, cal.WorkingDaysBetween(mustDate(t, "2025-01-06"), mustDate(t, "2025-01-10")))
+	// A non-working range still counts as one day
+	assert.Equal(t, 1, cal.WorkingDaysBetween(mustDate(t, "2025-01-12"), mustDate(t, "2025-01-12")))
+}
+
+func TestWorkingCalendar_AddWorkingHours(t *testing.T) {
+	cal := weekdayCalendar(t)
+
+	start := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 1, 6, 12, 0, 0, 0, time.UTC), cal.AddWorkingHours(start, 4))
+
+	// 9h on Mon + 9h on Tue, then the Wed holiday, 2h into Thu
+	assert.Equal(t, time.Date(2025, 1, 9, 10, 0, 0, 0, time.UTC), cal.AddWorkingHours(start, 20))
+
+	// Starting outside working hours begins at the next shift
+	evening := time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC), cal.AddWorkingHours(evening, 1))
+}
+
+func TestWorkingCalendar_OvernightShift(t *testing.T) {
+	cal := models.NewWorkingCalendar([]models.PlantShift{
+		{DayOfWeek: int(time.Monday), StartTime: "22:00", EndTime: "06:00"},
+	}, nil)
+
+	assert.Equal(t, 8.0, cal.WorkingHoursOn(mustDate(t, "2025-01-06")))
+
+	// Tuesday 02:00 is still inside Monday's night shift
+	start := time.Date(2025, 1, 7, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 1, 7, 5, 0, 0, 0, time.UTC), cal.AddWorkingHours(start, 3))
+}
+
+func TestWorkingCalendar_LinkedTargetDatesSkipNonWorkingDays(t *testing.T) {
+	cal := weekdayCalendar(t)
+
+	// Source finishes Tue 7; FS target starts the next working day (Thu 9)
+	// and keeps its 3 working days: Thu 9, Fri 10, special Sat 11
+	start, finish := cal.LinkedTargetDates(models.LinkTypeFinishToStart, 0,
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-07"),
+		mustDate(t, "2025-01-01"), mustDate(t, "2025-01-03"))
+	assert.Equal(t, "2025-01-09", start.Format("2006-01-02"))
+	assert.Equal(t, "2025-01-11", finish.Format("2006-01-02"))
+
+	// Lag counts working days too: finish Tue 7 + 1 + 1 lag = Fri 10
+	constraint, onStart := cal.LinkConstraintDate(models.LinkTypeFinishToStart, 1,
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-07"))
+	assert.True(t, onStart)
+	assert.Equal(t, "2025-01-10", constraint.Format("2006-01-02"))
+
+	assert.False(t, cal.IsLinkSatisfied(models.LinkTypeFinishToStart, 0,
+		mustDate(t, "2025-01-06"), mustDate(t, "2025-01-07"),
+		mustDate(t, "2025-01-08"), mustDate(t, "2025-01-09")))
+}
+
+func TestParseShiftClock(t *testing.T) {
+	minutes, err := models.ParseShiftClock("07:30")
+	assert.NoError(t, err)
+	assert.Equal(t, 450, minutes)
+
+	_, err = models.ParseShiftClock("25:00")
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// ICalEvent is one VEVENT of an iCalendar feed
+type ICalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time // Exclusive; for all-day events the day after the last day
+	AllDay      bool
+}
+
+// WriteICalendar renders events as an iCalendar (RFC 5545) feed named calendarName
+func WriteICalendar(calendarName string, events []ICalEvent, stamp time.Time) []byte {
+	var b strings.Builder
+	line := func(content string) {
+		b.WriteString(foldICalLine(content))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//GanttPro//Production Schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICalText(calendarName))
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeICalText(event.UID))
+		line("DTSTAMP:" + formatICalTime(stamp))
+		if event.AllDay {
+			line("DTSTART;VALUE=DATE:" + event.Start.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + event.End.Format("20060102"))
+		} else {
+			line("DTSTART:" + formatICalTime(event.Start))
+			line("DTEND:" + formatICalTime(event.End))
+		}
+		line("SUMMARY:" + escapeICalText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION:" + escapeICalText(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION:" + escapeICalText(event.Location))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	return []byte(b.String())
+}
+
+func formatICalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func escapeICalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICalLine splits lines longer than 75 octets; continuation lines start with a space.
+// Lines are never split inside a UTF-8 character
+func foldICalLine(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // The leading space counts toward the next line
+	}
+	b.WriteString(s)
+	return b.String()
+}
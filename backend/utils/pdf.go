@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A4 landscape page size in points
+const (
+	PDFA4LandscapeWidth  = 842.0
+	PDFA4LandscapeHeight = 595.0
+)
+
+// PDFDocument draws simple vector pages (rectangles, lines, Helvetica text).
+// Coordinates are in points from the top-left corner of the page
+type PDFDocument struct {
+	width  float64
+	height float64
+	pages  []*bytes.Buffer
+}
+
+func NewPDFDocument(width, height float64) *PDFDocument {
+	return &PDFDocument{width: width, height: height}
+}
+
+// AddPage starts a new page; drawing goes to the last page
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// PageCount returns the number of pages
+func (d *PDFDocument) PageCount() int {
+	return len(d.pages)
+}
+
+func (d *PDFDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// FillRect draws a filled rectangle; color is "#RRGGBB"
+func (d *PDFDocument) FillRect(x, y, w, h float64, color string) {
+	r, g, b := pdfColor(color)
+	fmt.Fprintf(d.page(), "%s %s %s rg %s %s %s %s re f\n",
+		pdfNumber(r), pdfNumber(g), pdfNumber(b), pdfNumber(x), pdfNumber(d.height-y-h), pdfNumber(w), pdfNumber(h))
+}
+
+// StrokeRect draws a rectangle outline
+func (d *PDFDocument) StrokeRect(x, y, w, h float64, color string, lineWidth float64) {
+	r, g, b := pdfColor(color)
+	fmt.Fprintf(d.page(), "%s w %s %s %s RG %s %s %s %s re S\n",
+		pdfNumber(lineWidth), pdfNumber(r), pdfNumber(g), pdfNumber(b), pdfNumber(x), pdfNumber(d.height-y-h), pdfNumber(w), pdfNumber(h))
+}
+
+// Line draws a straight line
+func (d *PDFDocument) Line(x1, y1, x2, y2 float64, color string, lineWidth float64) {
+	r, g, b := pdfColor(color)
+	fmt.Fprintf(d.page(), "%s w %s %s %s RG %s %s m %s %s l S\n",
+		pdfNumber(lineWidth), pdfNumber(r), pdfNumber(g), pdfNumber(b), pdfNumber(x1), pdfNumber(d.height-y1), pdfNumber(x2), pdfNumber(d.height-y2))
+}
+
+// Text draws a single line of text with its baseline at y. Characters outside Latin-1 print as '?'
+func (d *PDFDocument) Text(x, y, size float64, bold bool, color, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	r, g, b := pdfColor(color)
+	fmt.Fprintf(d.page(), "BT /%s %s Tf %s %s %s rg %s %s Td (%s) Tj ET\n",
+		font, pdfNumber(size), pdfNumber(r), pdfNumber(g), pdfNumber(b), pdfNumber(x), pdfNumber(d.height-y), pdfString(text))
+}
+
+// PDFTextWidth estimates the width of Helvetica text (average glyph width)
+func PDFTextWidth(text string, size float64) float64 {
+	return float64(len([]rune(text))) * size * 0.52
+}
+
+// PDFFitText shortens text with "..." so it fits in width
+func PDFFitText(text string, size, width float64) string {
+	if PDFTextWidth(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && PDFTextWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	if len(runes) == 0 {
+		return ""
+	}
+	return string(runes) + "..."
+}
+
+// Bytes renders the document as a PDF file
+func (d *PDFDocument) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	// Object numbers: 1 catalog, 2 page tree, 3-4 fonts, then a page and its content stream per page
+	var objects []string
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range d.pages {
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(content.Bytes()); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfNumber(d.width), pdfNumber(d.height), 6+2*i))
+		objects = append(objects, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.String()))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes(), nil
+}
+
+// pdfColor converts "#RRGGBB" to 0-1 components; anything else is black
+func pdfColor(hex string) (float64, float64, float64) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return 0, 0, 0
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+	return float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255
+}
+
+func pdfNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+// pdfString escapes text for a PDF literal string in WinAnsi (Latin-1) encoding
+func pdfString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r >= 0x20 && r < 0x7f:
+			b.WriteByte(byte(r))
+		case r >= 0xa0 && r <= 0xff:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXCell is one cell of a generated worksheet
+type XLSXCell struct {
+	Value interface{} // string, int, int64, float64, time.Time or nil
+	Bold  bool
+	Fill  string // Background color "#RRGGBB", empty for none
+}
+
+// XLSXSheet is a single worksheet
+type XLSXSheet struct {
+	Name         string
+	ColumnWidths []float64 // Widths in characters, by column; 0 keeps the default
+	FreezeHeader bool      // Keep the first row visible while scrolling
+	Rows         [][]XLSXCell
+}
+
+// xlsxStyle is a unique cell format; its index in the style list is the cell's s attribute
+type xlsxStyle struct {
+	bold   bool
+	fill   string
+	numFmt int
+}
+
+const (
+	xlsxDateFormat     = 164 // yyyy-mm-dd
+	xlsxDateTimeFormat = 165 // yyyy-mm-dd hh:mm
+)
+
+// WriteXLSX renders a single worksheet as an XLSX file. Dates are written as Excel dates
+func WriteXLSX(sheet XLSXSheet) ([]byte, error) {
+	styles := []xlsxStyle{{}}
+	styleIndex := map[xlsxStyle]int{{}: 0}
+	styleOf := func(cell XLSXCell) int {
+		style := xlsxStyle{bold: cell.Bold, fill: strings.ToUpper(strings.TrimPrefix(cell.Fill, "#"))}
+		if t, ok := cell.Value.(time.Time); ok {
+			style.numFmt = xlsxDateFormat
+			if t.Hour() != 0 || t.Minute() != 0 {
+				style.numFmt = xlsxDateTimeFormat
+			}
+		}
+		if i, ok := styleIndex[style]; ok {
+			return i
+		}
+		styleIndex[style] = len(styles)
+		styles = append(styles, style)
+		return len(styles) - 1
+	}
+
+	var sheetXML strings.Builder
+	sheetXML.WriteString(xml.Header)
+	sheetXML.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if sheet.FreezeHeader {
+		sheetXML.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	}
+	if len(sheet.ColumnWidths) > 0 {
+		sheetXML.WriteString("<cols>")
+		for i, width := range sheet.ColumnWidths {
+			if width > 0 {
+				fmt.Fprintf(&sheetXML, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, formatXLSXNumber(width))
+			}
+		}
+		sheetXML.WriteString("</cols>")
+	}
+	sheetXML.WriteString("<sheetData>")
+	for r, row := range sheet.Rows {
+		fmt.Fprintf(&sheetXML, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := XLSXColumnName(c) + strconv.Itoa(r+1)
+			style := styleOf(cell)
+			switch v := cell.Value.(type) {
+			case nil:
+				if style != 0 {
+					fmt.Fprintf(&sheetXML, `<c r="%s" s="%d"/>`, ref, style)
+				}
+			case string:
+				fmt.Fprintf(&sheetXML, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escapeXML(v))
+			case int:
+				fmt.Fprintf(&sheetXML, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+			case int64:
+				fmt.Fprintf(&sheetXML, `<c r="%s" s="%d"><v>%d</v></c>`, ref, style, v)
+			case float64:
+				fmt.Fprintf(&sheetXML, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, formatXLSXNumber(v))
+			case time.Time:
+				fmt.Fprintf(&sheetXML, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, formatXLSXNumber(ExcelSerial(v)))
+			default:
+				return nil, fmt.Errorf("unsupported XLSX cell value %T in %s", cell.Value, ref)
+			}
+		}
+		sheetXML.WriteString("</row>")
+	}
+	sheetXML.WriteString("</sheetData></worksheet>")
+
+	name := sheet.Name
+	if name == "" {
+		name = "Sheet1"
+	}
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="` + escapeXML(name) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`},
+		{"xl/styles.xml", xlsxStylesXML(styles)},
+		{"xl/worksheets/sheet1.xml", sheetXML.String()},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, part := range parts {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xlsxStylesXML builds styles.xml: font 1 is bold, fills 0 and 1 are the two fills Excel requires
+func xlsxStylesXML(styles []xlsxStyle) string {
+	fills := []string{}
+	fillIndex := map[string]int{}
+	for _, style := range styles {
+		if style.fill != "" {
+			if _, ok := fillIndex[style.fill]; !ok {
+				fillIndex[style.fill] = len(fills) + 2
+				fills = append(fills, style.fill)
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	fmt.Fprintf(&b, `<numFmts count="2"><numFmt numFmtId="%d" formatCode="yyyy-mm-dd"/><numFmt numFmtId="%d" formatCode="yyyy-mm-dd hh:mm"/></numFmts>`,
+		xlsxDateFormat, xlsxDateTimeFormat)
+	b.WriteString(`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>`)
+	fmt.Fprintf(&b, `<fills count="%d"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>`, len(fills)+2)
+	for _, fill := range fills {
+		fmt.Fprintf(&b, `<fill><patternFill patternType="solid"><fgColor rgb="FF%s"/><bgColor indexed="64"/></patternFill></fill>`, escapeXML(fill))
+	}
+	b.WriteString(`</fills>`)
+	b.WriteString(`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>`)
+	b.WriteString(`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+	fmt.Fprintf(&b, `<cellXfs count="%d">`, len(styles))
+	for _, style := range styles {
+		fontID, fillID := 0, 0
+		if style.bold {
+			fontID = 1
+		}
+		if style.fill != "" {
+			fillID = fillIndex[style.fill]
+		}
+		fmt.Fprintf(&b, `<xf numFmtId="%d" fontId="%d" fillId="%d" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1" applyFill="1"/>`,
+			style.numFmt, fontID, fillID)
+	}
+	b.WriteString(`</cellXfs></styleSheet>`)
+	return b.String()
+}
+
+// ExcelSerial converts a time to an Excel date serial number (days since 1899-12-30)
+func ExcelSerial(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return wall.Sub(epoch).Hours() / 24
+}
+
+// XLSXColumnName converts a zero-based column index to its letters, e.g. 27 -> "AB"
+func XLSXColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+func formatXLSXNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -178,6 +178,296 @@ class ApiService {
     });
   }
 
+  // Machine Downtime endpoints
+  async getMachineDowntimes(filters = {}) {
+    // filters: { machine_id, type, start_date, end_date }
+    const params = new URLSearchParams(filters).toString();
+    const endpoint = params ? `/machine-downtimes?${params}` : '/machine-downtimes';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async createMachineDowntime(downtimeData) {
+    // downtimeData: { machine_id, type, reason, start_time, end_time }
+    // type: planned_maintenance | breakdown | calibration; returns { downtime, affected_assignments }
+    return this.request('/machine-downtimes', {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(downtimeData),
+    });
+  }
+
+  async updateMachineDowntime(downtimeId, downtimeData) {
+    return this.request(`/machine-downtimes/${downtimeId}`, {
+      method: 'PUT',
+      auth: true,
+      body: JSON.stringify(downtimeData),
+    });
+  }
+
+  async deleteMachineDowntime(downtimeId) {
+    return this.request(`/machine-downtimes/${downtimeId}`, {
+      method: 'DELETE',
+      auth: true,
+    });
+  }
+
+  // Preventive Maintenance endpoints
+  async getMaintenancePlans(machineId = null) {
+    const endpoint = machineId ? `/maintenance/plans?machine_id=${machineId}` : '/maintenance/plans';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async getMaintenancePlan(planId) {
+    return this.request(`/maintenance/plans/${planId}`, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async createMaintenancePlan(planData) {
+    // planData: { machine_id, name, description, interval_days, interval_run_hours, estimated_hours, last_completed_at }
+    return this.request('/maintenance/plans', {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(planData),
+    });
+  }
+
+  async updateMaintenancePlan(planId, planData) {
+    return this.request(`/maintenance/plans/${planId}`, {
+      method: 'PUT',
+      auth: true,
+      body: JSON.stringify(planData),
+    });
+  }
+
+  async deleteMaintenancePlan(planId) {
+    return this.request(`/maintenance/plans/${planId}`, {
+      method: 'DELETE',
+      auth: true,
+    });
+  }
+
+  async getOverdueMaintenance() {
+    return this.request('/maintenance/overdue', {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async getMaintenanceWorkOrders(filters = {}) {
+    // filters: { machine_id, plan_id, status }
+    const params = new URLSearchParams(filters).toString();
+    const endpoint = params ? `/maintenance/work-orders?${params}` : '/maintenance/work-orders';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async getMaintenanceWorkOrder(workOrderId) {
+    return this.request(`/maintenance/work-orders/${workOrderId}`, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async generateMaintenanceWorkOrders() {
+    return this.request('/maintenance/work-orders/generate', {
+      method: 'POST',
+      auth: true,
+    });
+  }
+
+  async createMaintenanceWorkOrder(planId, workOrderData = {}) {
+    return this.request(`/maintenance/plans/${planId}/work-orders`, {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(workOrderData),
+    });
+  }
+
+  async startMaintenanceWorkOrder(workOrderId) {
+    return this.request(`/maintenance/work-orders/${workOrderId}/start`, {
+      method: 'POST',
+      auth: true,
+    });
+  }
+
+  async completeMaintenanceWorkOrder(workOrderId, completionData) {
+    // completionData: { technician, notes, completed_at }
+    return this.request(`/maintenance/work-orders/${workOrderId}/complete`, {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(completionData),
+    });
+  }
+
+  async cancelMaintenanceWorkOrder(workOrderId, notes = '') {
+    return this.request(`/maintenance/work-orders/${workOrderId}/cancel`, {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify({ notes }),
+    });
+  }
+
+  // Andon endpoints
+  async getAndonReasonCodes(includeInactive = false) {
+    const endpoint = includeInactive ? '/andon/reason-codes?include_inactive=true' : '/andon/reason-codes';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async createAndonReasonCode(codeData) {
+    // codeData: { code, name, parent_id, description, sort_order }
+    return this.request('/admin/andon/reason-codes', {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(codeData),
+    });
+  }
+
+  async updateAndonReasonCode(codeId, codeData) {
+    return this.request(`/admin/andon/reason-codes/${codeId}`, {
+      method: 'PUT',
+      auth: true,
+      body: JSON.stringify(codeData),
+    });
+  }
+
+  async deleteAndonReasonCode(codeId) {
+    return this.request(`/admin/andon/reason-codes/${codeId}`, {
+      method: 'DELETE',
+      auth: true,
+    });
+  }
+
+  async getAndonBoard() {
+    return this.request('/andon/board', {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async getAndonEvents(filters = {}) {
+    // filters: { machine_id, reason_code_id, job_order_id, status, start_date, end_date }
+    const params = new URLSearchParams(filters).toString();
+    const endpoint = params ? `/andon/events?${params}` : '/andon/events';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async getAndonEvent(eventId) {
+    return this.request(`/andon/events/${eventId}`, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async createAndonEvent(eventData) {
+    // eventData: { machine_id, reason_code_id, job_order_id, start_time, end_time, notes }
+    return this.request('/andon/events', {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(eventData),
+    });
+  }
+
+  async updateAndonEvent(eventId, eventData) {
+    return this.request(`/andon/events/${eventId}`, {
+      method: 'PUT',
+      auth: true,
+      body: JSON.stringify(eventData),
+    });
+  }
+
+  async closeAndonEvent(eventId, closeData = {}) {
+    // closeData: { end_time, reason_code_id, notes }
+    return this.request(`/andon/events/${eventId}/close`, {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(closeData),
+    });
+  }
+
+  async deleteAndonEvent(eventId) {
+    return this.request(`/andon/events/${eventId}`, {
+      method: 'DELETE',
+      auth: true,
+    });
+  }
+
+  async getDowntimePareto(filters = {}) {
+    // filters: { start_date, end_date, machine_id, group_by: reason | category }
+    const params = new URLSearchParams(filters).toString();
+    const endpoint = params ? `/andon/pareto?${params}` : '/andon/pareto';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  // Production report & OEE endpoints
+  async getProductionReports(filters = {}) {
+    // filters: { machine_id, schedule_id, assignment_id, start_date, end_date }
+    const params = new URLSearchParams(filters).toString();
+    const endpoint = params ? `/production-reports?${params}` : '/production-reports';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async getProductionReport(reportId) {
+    return this.request(`/production-reports/${reportId}`, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async createProductionReport(reportData) {
+    // reportData: { machine_id, schedule_id, assignment_id, job_order_id, produced_at, good_quantity, scrap_quantity, scrap_reason, ideal_cycle_seconds, notes }
+    return this.request('/production-reports', {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(reportData),
+    });
+  }
+
+  async updateProductionReport(reportId, reportData) {
+    return this.request(`/production-reports/${reportId}`, {
+      method: 'PUT',
+      auth: true,
+      body: JSON.stringify(reportData),
+    });
+  }
+
+  async deleteProductionReport(reportId) {
+    return this.request(`/production-reports/${reportId}`, {
+      method: 'DELETE',
+      auth: true,
+    });
+  }
+
+  async getMachineOEE(filters) {
+    // filters: { start_date, end_date, period: shift | day | week, machine_type, location, machine_id }
+    const params = new URLSearchParams(filters).toString();
+    return this.request(`/machines/oee?${params}`, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
   // Job Order endpoints
   async getAllJobOrders() {
     return this.request('/job-orders', {
@@ -210,7 +500,7 @@ class ApiService {
   }
 
   async updateJobOrder(jobOrderId, jobOrderData) {
-    // jobOrderData: { project, item, note, deadline, operator_id, status }
+    // jobOrderData: { project, item, note, deadline, operator_id, status, version }
     return this.request(`/job-orders/${jobOrderId}`, {
       method: 'PUT',
       auth: true,
@@ -251,8 +541,9 @@ class ApiService {
   }
 
   async createPPICSchedule(scheduleData) {
-    // scheduleData: { njo, part_name, priority, priority_alpha, material_status,
-    //                 start_date, finish_date, ppic_notes, machine_assignments }
+    // scheduleData: { njo, part_name, priority, priority_alpha, material_status, quantity,
+    //                 start_date, finish_date, ppic_notes, machine_assignments,
+    //                 routing_template_id, skip_sequences }
     return this.request('/ppic-schedules', {
       method: 'POST',
       auth: true,
@@ -262,7 +553,8 @@ class ApiService {
 
   async updatePPICSchedule(scheduleId, scheduleData) {
     // scheduleData: { part_name, priority, material_status, status, progress,
-    //                 start_date, finish_date, ppic_notes, machine_assignments }
+    //                 start_date, finish_date, ppic_notes, machine_assignments, version }
+    // version is the one last read; the update fails with 409 if the schedule changed since
     return this.request(`/ppic-schedules/${scheduleId}`, {
       method: 'PUT',
       auth: true,
@@ -277,6 +569,24 @@ class ApiService {
     });
   }
 
+  async splitPPICSchedule(scheduleId, splitData) {
+    // splitData: { lots: [{ quantity, start_date, finish_date, ppic_notes, machine_assignments }], version }
+    return this.request(`/ppic-schedules/${scheduleId}/split`, {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(splitData),
+    });
+  }
+
+  async mergePPICLots(scheduleId, mergeData = {}) {
+    // mergeData: { lot_ids, version }; without lot_ids every lot is merged back into the schedule
+    return this.request(`/ppic-schedules/${scheduleId}/merge`, {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(mergeData),
+    });
+  }
+
   async getSchedulesByMachine(machineId) {
     return this.request(`/ppic-schedules/machine/${machineId}`, {
       method: 'GET',
@@ -318,6 +628,66 @@ class ApiService {
     });
   }
 
+  // Routing Template endpoints
+  async getRoutingTemplates(partFamily = '') {
+    const endpoint = partFamily ? `/routing-templates?part_family=${encodeURIComponent(partFamily)}` : '/routing-templates';
+    return this.request(endpoint, {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async createRoutingTemplate(templateData) {
+    // templateData: { name, part_family, description,
+    //                 steps: [{ operation, machine_type, machine_id, target_hours, alternatives }] }
+    return this.request('/routing-templates', {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(templateData),
+    });
+  }
+
+  async updateRoutingTemplate(templateId, templateData) {
+    return this.request(`/routing-templates/${templateId}`, {
+      method: 'PUT',
+      auth: true,
+      body: JSON.stringify(templateData),
+    });
+  }
+
+  async deleteRoutingTemplate(templateId) {
+    return this.request(`/routing-templates/${templateId}`, {
+      method: 'DELETE',
+      auth: true,
+    });
+  }
+
+  async applyRoutingTemplate(templateId, overrides = {}) {
+    // overrides: { machine_assignments, skip_sequences }; returns the machine_assignments for createPPICSchedule
+    return this.request(`/routing-templates/${templateId}/apply`, {
+      method: 'POST',
+      auth: true,
+      body: JSON.stringify(overrides),
+    });
+  }
+
+  // Settings endpoints
+  async getPlanningSettings() {
+    return this.request('/settings/planning', {
+      method: 'GET',
+      auth: true,
+    });
+  }
+
+  async updatePlanningSettings(settings) {
+    // settings: { max_machines_per_schedule } (Admin only)
+    return this.request('/admin/settings/planning', {
+      method: 'PUT',
+      auth: true,
+      body: JSON.stringify(settings),
+    });
+  }
+
   // Google Sheets endpoints
   async getPartNameByOrderNumber(orderNumber) {
     return this.request(`/google-sheets/part-name/${encodeURIComponent(orderNumber)}`, {